@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"golang.org/x/oauth2"
+	"video-manager/internal/config"
+	credentials_store "video-manager/internal/credentials-store"
+	oauth_consent "video-manager/internal/oauth-consent"
+	secret_store "video-manager/internal/secret-store"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// AuthorizeCommand Sub-command obtaining a refresh token with the OAuth device flow, instead of serving the API
+const AuthorizeCommand = "authorize"
+
+// Obtain a refresh token for a host with the OAuth device flow, and save it in the state store.
+// A running service picks it up on its next refresh, a new one right away
+func runAuthorize(ctx context.Context, cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet(AuthorizeCommand, flag.ExitOnError)
+	hostName := flags.String("host", "", "Name of the host to authorize. Defaults to the default host")
+	clientId := flags.String("client-id", "", `Client ID of a "TVs and Limited Input devices" OAuth client. Defaults to the host one`)
+	clientSecret := flags.String("client-secret", "", "Client secret matching -client-id. Defaults to the host one")
+	_ = flags.Parse(args)
+
+	if cfg.OAuth.StateStore == "" {
+		log.Fatalf("oauth.stateStore (OAUTH_STATE_STORE_NAME) is required to save the refresh token")
+	}
+	host := cfg.Host()
+	if *hostName != "" {
+		host = nil
+		for i := range cfg.Hosts {
+			if cfg.Hosts[i].Name == *hostName {
+				host = &cfg.Hosts[i]
+			}
+		}
+		if host == nil {
+			log.Fatalf(`No host is named "%s"`, *hostName)
+		}
+	}
+	if host.Type != config.Youtube {
+		log.Fatalf(`The %s host "%s" doesn't support the OAuth consent`, host.Type, host.Name)
+	}
+
+	proxy, err := makeDaprClient(cfg.Dapr.GrpcPort, cfg.Dapr.MaxRequestSizeMb)
+	if err != nil {
+		log.Fatalf("Error during init : %s", err.Error())
+	}
+	creds := video_hosting.YoutubeStoreCredentials{ClientId: host.ClientId, ClientSecret: host.ClientSecret}
+	if host.SecretName != "" {
+		secret, err := secret_store.NewDaprSecretStore(proxy, cfg.Secrets.Store).Get(ctx, host.SecretName)
+		if err != nil {
+			log.Fatalf(`Could not read secret "%s" from store "%s" : %s`, host.SecretName, cfg.Secrets.Store, err.Error())
+		}
+		creds = creds.WithSecret(secret)
+	}
+	if *clientId != "" {
+		creds.ClientId, creds.ClientSecret = *clientId, *clientSecret
+	}
+	store, err := video_hosting.NewYoutubeStore(ctx, &creds, nil)
+	if err != nil {
+		log.Fatalf("Error during init : %s", err.Error())
+	}
+
+	token, err := oauth_consent.Device(ctx, store.DeviceOAuthConfig(), func(auth *oauth2.DeviceAuthResponse) {
+		fmt.Printf("To grant access to the Youtube account of host \"%s\", open %s and enter the code %s\n",
+			host.Name, auth.VerificationURI, auth.UserCode)
+	})
+	if err != nil {
+		log.Fatalf("Could not obtain a refresh token : %s", err.Error())
+	}
+	// A refresh token can only be used by the client it was granted to, which may differ from the host one
+	err = credentials_store.NewDaprCredentialsStore(proxy, cfg.OAuth.StateStore).Save(ctx, host.Name, map[string]string{
+		video_hosting.YoutubeSecretClientId:     creds.ClientId,
+		video_hosting.YoutubeSecretClientSecret: creds.ClientSecret,
+		video_hosting.YoutubeSecretRefreshToken: token.RefreshToken,
+	})
+	if err != nil {
+		log.Fatalf(`Could not save the refresh token in store "%s" : %s`, cfg.OAuth.StateStore, err.Error())
+	}
+	fmt.Printf("Refresh token of host \"%s\" saved in state store \"%s\"\n", host.Name, cfg.OAuth.StateStore)
+}
@@ -0,0 +1,182 @@
+package catalog_controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"video-manager/internal/catalog"
+	object_storage "video-manager/internal/object-storage"
+	"video-manager/internal/problem"
+	progress_broker "video-manager/internal/progress-broker"
+	video_hosting "video-manager/internal/video-hosting"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+type CatalogController[B object_storage.BindingProxy, P progress_broker.PubSubProxy] struct {
+	Service *video_store_service.VideoStoreService[B, P]
+}
+
+// ShowAccount godoc
+// @Summary      List the recorded videos
+// @Description  List the videos uploaded or modified through the service, without querying the hosting platform.
+// @Description  With storageKey, only the video uploaded from this recording is listed
+// @Tags         catalog
+// @Produce      json
+// @Param        storageKey  query     string  false  "Key of the recording on the object storage"
+// @Success      200         {array}   catalog.Entry
+// @Failure      401         {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403         {object}  problem.Problem "Missing scope"
+// @Failure      404         {object}  problem.Problem "No catalog configured"
+// @Failure      503         {object}  problem.Problem "The state store is unavailable"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /catalog/videos [get]
+func (cc *CatalogController[B, P]) ListVideos(c *gin.Context) {
+	if storageKey := c.Query("storageKey"); storageKey != "" {
+		cc.findBySource(c, storageKey)
+		return
+	}
+	cc.list(c, catalog.Video)
+}
+
+// ShowAccount godoc
+// @Summary      Get a recorded video
+// @Description  Retrieve how a video was uploaded (job, recording, metadata) through the service
+// @Tags         catalog
+// @Produce      json
+// @Param        id   path      string  true  "Video ID"
+// @Success      200  {object}  catalog.Entry
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No catalog configured, or video not recorded"
+// @Failure      503  {object}  problem.Problem "The state store is unavailable"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /catalog/videos/{id} [get]
+func (cc *CatalogController[B, P]) RetrieveVideo(c *gin.Context) {
+	cc.retrieve(c, catalog.Video)
+}
+
+// ShowAccount godoc
+// @Summary      List the recorded playlists
+// @Description  List the playlists created or modified through the service, without querying the hosting platform
+// @Tags         catalog
+// @Produce      json
+// @Success      200  {array}   catalog.Entry
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No catalog configured"
+// @Failure      503  {object}  problem.Problem "The state store is unavailable"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /catalog/playlists [get]
+func (cc *CatalogController[B, P]) ListPlaylists(c *gin.Context) {
+	cc.list(c, catalog.Playlist)
+}
+
+// ShowAccount godoc
+// @Summary      Get a recorded playlist
+// @Description  Retrieve a playlist created or modified through the service, along with the videos added to it
+// @Tags         catalog
+// @Produce      json
+// @Param        id   path      string  true  "Playlist ID"
+// @Success      200  {object}  catalog.Entry
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No catalog configured, or playlist not recorded"
+// @Failure      503  {object}  problem.Problem "The state store is unavailable"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /catalog/playlists/{id} [get]
+func (cc *CatalogController[B, P]) RetrievePlaylist(c *gin.Context) {
+	cc.retrieve(c, catalog.Playlist)
+}
+
+// ShowAccount godoc
+// @Summary      Get the last reconciliation report
+// @Description  Summary of the last comparison of the recorded videos and playlists with the hosting platform,
+// @Description  listing those deleted, edited or whose processing failed outside the service.
+// @Description  Each difference is also published as a "video.drifted" or "playlist.drifted" event
+// @Tags         catalog
+// @Produce      json
+// @Success      200  {object}  video_store_service.ReconcileReport
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No catalog configured, or no reconciliation ran yet"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /reconcile/report [get]
+func (cc *CatalogController[B, P]) ReconcileReport(c *gin.Context) {
+	if _, ok := cc.catalog(c); !ok {
+		return
+	}
+	report := video_store_service.FromContext(c, cc.Service).LastReconcileReport()
+	if report == nil {
+		problem.AbortWith(c, problem.NotFound, `No reconciliation ran yet !`)
+		return
+	}
+	c.SecureJSON(http.StatusOK, report)
+}
+
+// The catalog of the selected host. Aborts if there is none
+func (cc *CatalogController[B, P]) catalog(c *gin.Context) (video_store_service.Catalog, bool) {
+	records := video_store_service.FromContext(c, cc.Service).Catalog
+	if records == nil {
+		problem.AbortWith(c, problem.NotFound, `No catalog is configured !`)
+		return nil, false
+	}
+	return records, true
+}
+
+func (cc *CatalogController[B, P]) list(c *gin.Context, kind catalog.Kind) {
+	records, ok := cc.catalog(c)
+	if !ok {
+		return
+	}
+	entries, err := records.List(c, video_store_service.FromContext(c, cc.Service).Host, kind)
+	if err != nil {
+		abortUnavailable(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+func (cc *CatalogController[B, P]) retrieve(c *gin.Context, kind catalog.Kind) {
+	records, ok := cc.catalog(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	entry, err := records.Get(c, video_store_service.FromContext(c, cc.Service).Host, kind, id)
+	if err != nil {
+		abortUnavailable(c, err)
+		return
+	}
+	if entry == nil {
+		problem.AbortWith(c, problem.NotFound, `No %s "%s" is recorded !`, kind, id)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func (cc *CatalogController[B, P]) findBySource(c *gin.Context, storageKey string) {
+	records, ok := cc.catalog(c)
+	if !ok {
+		return
+	}
+	entry, err := records.FindBySource(c, video_store_service.FromContext(c, cc.Service).Host, storageKey)
+	if err != nil {
+		abortUnavailable(c, err)
+		return
+	}
+	entries := []catalog.Entry{}
+	if entry != nil {
+		entries = append(entries, *entry)
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+func abortUnavailable(c *gin.Context, err error) {
+	problem.Abort(c, video_hosting.NewRequestError(video_hosting.StorageUnavailable,
+		fmt.Errorf("could not read the catalog : %w", err)))
+}
@@ -0,0 +1,165 @@
+package catalog_controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-manager/internal/catalog"
+	mock_object_storage "video-manager/internal/mock/object-storage"
+	mock_progress_broker "video-manager/internal/mock/progress-broker"
+	mock_video_hosting "video-manager/internal/mock/video-hosting"
+	"video-manager/internal/problem"
+	video_hosting "video-manager/internal/video-hosting"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+// Catalog holding a single recorded video, "vid" uploaded from "recording.mp4"
+type fakeCatalog struct {
+	err error
+}
+
+var recorded = catalog.Entry{Kind: catalog.Video, Id: "vid", Host: "youtube", JobId: "job", StorageKey: "recording.mp4"}
+
+func (fc *fakeCatalog) Get(_ context.Context, host string, kind catalog.Kind, id string) (*catalog.Entry, error) {
+	if fc.err != nil || host != recorded.Host || kind != recorded.Kind || id != recorded.Id {
+		return nil, fc.err
+	}
+	entry := recorded
+	return &entry, nil
+}
+
+func (fc *fakeCatalog) Update(_ context.Context, _ string, _ catalog.Kind, _ string, _ func(entry *catalog.Entry)) (*catalog.Entry, error) {
+	return nil, fmt.Errorf("read only")
+}
+
+func (fc *fakeCatalog) Remove(_ context.Context, _ string, _ catalog.Kind, _ string) error {
+	return fmt.Errorf("read only")
+}
+
+func (fc *fakeCatalog) List(_ context.Context, host string, kind catalog.Kind) ([]catalog.Entry, error) {
+	if fc.err != nil {
+		return nil, fc.err
+	}
+	if host != recorded.Host || kind != recorded.Kind {
+		return []catalog.Entry{}, nil
+	}
+	return []catalog.Entry{recorded}, nil
+}
+
+func (fc *fakeCatalog) FindBySource(ctx context.Context, host string, storageKey string) (*catalog.Entry, error) {
+	if storageKey != recorded.StorageKey {
+		return nil, fc.err
+	}
+	return fc.Get(ctx, host, catalog.Video, recorded.Id)
+}
+
+type testController = CatalogController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]
+
+func Setup(records video_store_service.Catalog) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cc := &testController{Service: &video_store_service.VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{
+		Host:    "youtube",
+		Catalog: records,
+	}}
+	router := gin.New()
+	router.GET("/v1/catalog/videos", cc.ListVideos)
+	router.GET("/v1/catalog/videos/:id", cc.RetrieveVideo)
+	router.GET("/v1/catalog/playlists", cc.ListPlaylists)
+	router.GET("/v1/catalog/playlists/:id", cc.RetrievePlaylist)
+	router.GET("/v1/reconcile/report", cc.ReconcileReport)
+	return router
+}
+
+func get(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestCatalogController_ListVideos(t *testing.T) {
+	router := Setup(&fakeCatalog{})
+	w := get(router, "/v1/catalog/videos")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []catalog.Entry{recorded}, decode[[]catalog.Entry](t, w))
+
+	w = get(router, "/v1/catalog/playlists")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, decode[[]catalog.Entry](t, w))
+}
+
+func TestCatalogController_ListVideos_BySource(t *testing.T) {
+	router := Setup(&fakeCatalog{})
+	w := get(router, "/v1/catalog/videos?storageKey=recording.mp4")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []catalog.Entry{recorded}, decode[[]catalog.Entry](t, w))
+
+	w = get(router, "/v1/catalog/videos?storageKey=other.mp4")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, decode[[]catalog.Entry](t, w))
+}
+
+func TestCatalogController_Retrieve(t *testing.T) {
+	router := Setup(&fakeCatalog{})
+	w := get(router, "/v1/catalog/videos/vid")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, recorded, decode[catalog.Entry](t, w))
+
+	w = get(router, "/v1/catalog/playlists/vid")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, problem.NotFound, decode[problem.Problem](t, w).Type)
+}
+
+func TestCatalogController_Unavailable(t *testing.T) {
+	router := Setup(&fakeCatalog{err: fmt.Errorf("state store unavailable")})
+	w := get(router, "/v1/catalog/videos")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	w = get(router, "/v1/catalog/videos/vid")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestCatalogController_NoCatalog(t *testing.T) {
+	router := Setup(nil)
+	w := get(router, "/v1/catalog/videos")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = get(router, "/v1/reconcile/report")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCatalogController_ReconcileReport(t *testing.T) {
+	host := mock_video_hosting.NewMockIVideoHost(gomock.NewController(t))
+	svc := &video_store_service.VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{
+		Host:    "youtube",
+		Catalog: &fakeCatalog{},
+		VidHost: host,
+	}
+	router := gin.New()
+	router.GET("/v1/reconcile/report", (&testController{Service: svc}).ReconcileReport)
+	// Nothing to report yet
+	w := get(router, "/v1/reconcile/report")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The recorded video was deleted from the host
+	host.EXPECT().ListVideos(gomock.Any(), []string{"vid"}).Return([]*video_hosting.Video{}, nil)
+	_, err := svc.Reconcile(context.Background())
+	assert.Nil(t, err)
+	w = get(router, "/v1/reconcile/report")
+	assert.Equal(t, http.StatusOK, w.Code)
+	report := decode[video_store_service.ReconcileReport](t, w)
+	assert.Equal(t, 1, report.Videos)
+	assert.Len(t, report.Drifts, 1)
+	assert.Equal(t, video_store_service.Missing, report.Drifts[0].Reason)
+}
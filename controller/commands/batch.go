@@ -0,0 +1,229 @@
+package commands_controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"regexp"
+	"strconv"
+	"video-manager/internal/problem"
+	rate_limiter "video-manager/internal/rate-limiter"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+var (
+	// Allowed names of an operation
+	refPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	// A reference to a field of the result of a previous operation, "${ref.field}"
+	referencePattern = regexp.MustCompile(`\$\{([A-Za-z0-9_-]+)\.([A-Za-z0-9_]+)\}`)
+)
+
+// Operation A single command of a batch
+type Operation struct {
+	// Name the next operations can reference the result of, with "${ref.field}" in their payload.
+	// Defaults to the position of the operation in the batch, starting at 0
+	Ref string `json:"ref" example:"playlist"`
+	Command
+}
+
+// BatchBody Operations to run in order, at most 100
+type BatchBody struct {
+	Operations []Operation `json:"operations" binding:"required,min=1,max=100,dive"`
+	// Skip all the remaining operations as soon as one fails
+	StopOnError bool `json:"stopOnError"`
+}
+
+// OperationStatus Outcome of a single operation
+type OperationStatus string
+
+const (
+	Succeeded OperationStatus = "succeeded"
+	Failed    OperationStatus = "failed"
+	// Skipped The operation wasn't run, a previous one failed
+	Skipped OperationStatus = "skipped"
+)
+
+// OperationResult Outcome of a single operation of a batch
+type OperationResult struct {
+	Ref    string          `json:"ref" example:"playlist"`
+	Type   CommandType     `json:"type" example:"playlist.create"`
+	Status OperationStatus `json:"status" example:"succeeded"`
+	// Video or playlist created or updated by the operation, if any
+	Result any `json:"result,omitempty" swaggertype:"object"`
+	// Why the operation failed
+	Error *problem.Problem `json:"error,omitempty"`
+}
+
+// BatchResult Outcome of each operation of a batch, in the same order
+type BatchResult struct {
+	// Whether all the operations succeeded
+	Succeeded bool              `json:"succeeded"`
+	Results   []OperationResult `json:"results"`
+}
+
+// ShowAccount godoc
+// @Summary      Run a batch of operations
+// @Description  Run several commands in order (create a playlist, upload videos, set their thumbnails, add them to the playlist...).
+// @Description  A payload can use the result of a previous operation with "${ref.field}", such as "${playlist.id}".
+// @Description  The outcome of each operation is returned, even when some failed
+// @Tags         batch
+// @Accept       json
+// @Produce      json
+// @Param 		 batch body BatchBody true "Operations to run"
+// @Success      200  {object}  BatchResult
+// @Failure      400  {object}  problem.Problem "Invalid batch"
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /batch [post]
+func (cc *CommandController[B, P]) Batch(c *gin.Context) {
+	var body BatchBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
+		return
+	}
+	if err := assignRefs(body.Operations); err != nil {
+		problem.AbortWith(c, problem.BadRequest, `%s !`, err.Error())
+		return
+	}
+	batch := BatchResult{Succeeded: true, Results: make([]OperationResult, 0, len(body.Operations))}
+	// JSON fields of the result of each successful operation, by ref
+	outputs := map[string]map[string]any{}
+	for i, op := range body.Operations {
+		res := OperationResult{Ref: op.Ref, Type: op.Type}
+		if !batch.Succeeded && body.StopOnError {
+			res.Status = Skipped
+			batch.Results = append(batch.Results, res)
+			continue
+		}
+		// Each operation counts as a request, the batch itself paying for the first one
+		if i > 0 {
+			if err := rate_limiter.Allow(c); err != nil {
+				res.Status, res.Error = Failed, problem.New(problem.RateLimited, err.Error())
+				batch.Succeeded = false
+				batch.Results = append(batch.Results, res)
+				continue
+			}
+		}
+		result, err := cc.runOperation(c, op, outputs)
+		if err != nil {
+			log.Warnf("Operation %s (%s) of a batch failed : %s", op.Ref, op.Type, err.Error())
+			res.Status, res.Error = Failed, operationProblem(err)
+			batch.Succeeded = false
+		} else {
+			res.Status, res.Result = Succeeded, result
+			outputs[op.Ref] = fields(result)
+		}
+		batch.Results = append(batch.Results, res)
+	}
+	c.SecureJSON(http.StatusOK, batch)
+}
+
+// Run a single operation of a batch, after replacing the references in its payload
+func (cc *CommandController[B, P]) runOperation(c *gin.Context, op Operation, outputs map[string]map[string]any) (any, error) {
+	payload, err := resolve(op.Payload, outputs)
+	if err != nil {
+		return nil, err
+	}
+	cmd := op.Command
+	cmd.Payload = payload
+	// Without a host of its own, the operation runs on the host selected by the request
+	svc := video_store_service.FromContext(c, cc.Service)
+	if cmd.Host != "" {
+		if svc, err = cc.service(cmd.Host); err != nil {
+			return nil, err
+		}
+	}
+	return run(c, svc, &cmd)
+}
+
+// Name the operations without a ref after their position, and make sure all the refs are distinct
+func assignRefs(ops []Operation) error {
+	seen := map[string]bool{}
+	for i := range ops {
+		if ops[i].Ref == "" {
+			ops[i].Ref = strconv.Itoa(i)
+		}
+		if !refPattern.MatchString(ops[i].Ref) {
+			return fmt.Errorf(`invalid ref "%s", only letters, digits, "-" and "_" are allowed`, ops[i].Ref)
+		}
+		if seen[ops[i].Ref] {
+			return fmt.Errorf(`ref "%s" is used by several operations`, ops[i].Ref)
+		}
+		seen[ops[i].Ref] = true
+	}
+	return nil
+}
+
+// Replace each "${ref.field}" in the string values of payload with the field of the result of the operation "ref"
+func resolve(payload json.RawMessage, outputs map[string]map[string]any) (json.RawMessage, error) {
+	if len(payload) == 0 {
+		return payload, nil
+	}
+	var decoded any
+	if err := unmarshal(payload, &decoded); err != nil {
+		return nil, &invalidCommandError{fmt.Errorf("invalid payload : %w", err)}
+	}
+	var unresolved error
+	var replace func(v any) any
+	replace = func(v any) any {
+		switch value := v.(type) {
+		case string:
+			return referencePattern.ReplaceAllStringFunc(value, func(ref string) string {
+				parts := referencePattern.FindStringSubmatch(ref)
+				field, ok := outputs[parts[1]][parts[2]]
+				if !ok && unresolved == nil {
+					unresolved = &invalidCommandError{fmt.Errorf(`unresolved reference %s, no previous successful operation has this result`, ref)}
+				}
+				return fmt.Sprint(field)
+			})
+		case []any:
+			for i := range value {
+				value[i] = replace(value[i])
+			}
+		case map[string]any:
+			for key := range value {
+				value[key] = replace(value[key])
+			}
+		}
+		return v
+	}
+	decoded = replace(decoded)
+	if unresolved != nil {
+		return nil, unresolved
+	}
+	return json.Marshal(decoded)
+}
+
+// The JSON fields of the result of an operation, that the next ones can reference
+func fields(result any) map[string]any {
+	out := map[string]any{}
+	if result == nil {
+		return out
+	}
+	data, err := json.Marshal(result)
+	if err == nil {
+		_ = unmarshal(data, &out)
+	}
+	return out
+}
+
+// Unmarshal data into target, keeping the numbers as written
+func unmarshal(data []byte, target any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+// Why an operation failed, as it would have been reported for a single request
+func operationProblem(err error) *problem.Problem {
+	var ice *invalidCommandError
+	if errors.As(err, &ice) {
+		return problem.New(problem.BadRequest, err.Error())
+	}
+	return problem.FromError(err)
+}
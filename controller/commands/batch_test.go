@@ -0,0 +1,134 @@
+package commands_controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-manager/internal/problem"
+	rate_limiter "video-manager/internal/rate-limiter"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// Post body to the batch handler
+func batch(t *testing.T, deps *mocked, body string) (*httptest.ResponseRecorder, BatchResult) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/batch", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	deps.controller.Batch(c)
+	var res BatchResult
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w, res
+}
+
+func TestCommandController_Batch(t *testing.T) {
+	deps := Setup(t)
+	deps.videoStore.EXPECT().CreatePlaylist(gomock.Any(), &sampleMetadata).Return(&video_hosting.Playlist{Id: "pl"}, nil)
+	deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), "vid", "pl").Return(nil)
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(&video_hosting.Video{Id: "vid"}, nil)
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), "vid", gomock.Any()).
+		DoAndReturn(func(_ any, _ string, replacement *video_hosting.Video) (*video_hosting.Video, error) {
+			assert.Equal(t, "Part of pl", replacement.Description)
+			return replacement, nil
+		})
+
+	w, res := batch(t, deps, `{"operations": [
+		{"ref": "playlist", "type": "playlist.create", "payload": {"title": "testTitle", "description": "testDescription", "visibility": "unlisted"}},
+		{"type": "playlist.item.add", "payload": {"videoId": "vid", "playlistId": "${playlist.id}"}},
+		{"type": "video.update", "payload": {"videoId": "vid", "title": "title", "description": "Part of ${playlist.id}", "visibility": "unlisted"}}
+	]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, res.Succeeded)
+	assert.Len(t, res.Results, 3)
+	assert.Equal(t, "playlist", res.Results[0].Ref)
+	assert.Equal(t, "pl", res.Results[0].Result.(map[string]any)["id"])
+	// Operations without a ref are named after their position
+	assert.Equal(t, "1", res.Results[1].Ref)
+	assert.Nil(t, res.Results[1].Result)
+	for _, op := range res.Results {
+		assert.Equal(t, Succeeded, op.Status)
+	}
+}
+
+func TestCommandController_Batch_Failure(t *testing.T) {
+	deps := Setup(t)
+	deps.videoStore.EXPECT().CreatePlaylist(gomock.Any(), gomock.Any()).
+		Return(nil, video_hosting.NewRequestError(video_hosting.QuotaExceeded, fmt.Errorf("quota exceeded")))
+	deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), "vid", "other").Return(nil)
+	body := `{"stopOnError": %t, "operations": [
+		{"ref": "playlist", "type": "playlist.create", "payload": {"title": "testTitle", "visibility": "unlisted"}},
+		{"type": "playlist.item.add", "payload": {"videoId": "vid", "playlistId": "${playlist.id}"}},
+		{"type": "playlist.item.add", "payload": {"videoId": "vid", "playlistId": "other"}}
+	]}`
+
+	// The next operations still run, those referencing the failed one fail as well
+	w, res := batch(t, deps, fmt.Sprintf(body, false))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, res.Succeeded)
+	assert.Equal(t, []OperationStatus{Failed, Failed, Succeeded}, statuses(res))
+	assert.Equal(t, problem.QuotaExceeded, res.Results[0].Error.Type)
+	assert.Equal(t, problem.BadRequest, res.Results[1].Error.Type)
+	assert.Contains(t, res.Results[1].Error.Detail, "${playlist.id}")
+
+	// Or are all skipped
+	deps.videoStore.EXPECT().CreatePlaylist(gomock.Any(), gomock.Any()).
+		Return(nil, video_hosting.NewRequestError(video_hosting.QuotaExceeded, fmt.Errorf("quota exceeded")))
+	_, res = batch(t, deps, fmt.Sprintf(body, true))
+	assert.Equal(t, []OperationStatus{Failed, Skipped, Skipped}, statuses(res))
+}
+
+func TestCommandController_Batch_RateLimited(t *testing.T) {
+	deps := Setup(t)
+	router := gin.New()
+	router.POST("/v1/batch", rate_limiter.NewRateLimiter(1, 2).Handler(), deps.controller.Batch)
+	deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), gomock.Any(), "pl").Return(nil).Times(2)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/batch", bytes.NewBufferString(`{"operations": [
+		{"type": "playlist.item.add", "payload": {"videoId": "1", "playlistId": "pl"}},
+		{"type": "playlist.item.add", "payload": {"videoId": "2", "playlistId": "pl"}},
+		{"type": "playlist.item.add", "payload": {"videoId": "3", "playlistId": "pl"}}
+	]}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	// Each operation counts as a request
+	var res BatchResult
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, []OperationStatus{Succeeded, Succeeded, Failed}, statuses(res))
+	assert.Equal(t, problem.RateLimited, res.Results[2].Error.Type)
+}
+
+func TestCommandController_Batch_Invalid(t *testing.T) {
+	deps := Setup(t)
+	for _, body := range []string{
+		`{}`,
+		`{"operations": []}`,
+		// Missing type
+		`{"operations": [{"payload": {}}]}`,
+		// Duplicated ref
+		`{"operations": [{"ref": "a", "type": "playlist.create", "payload": {}}, {"ref": "a", "type": "playlist.create", "payload": {}}]}`,
+		// Ref that can't be referenced
+		`{"operations": [{"ref": "a.b", "type": "playlist.create", "payload": {}}]}`,
+	} {
+		w, _ := batch(t, deps, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func statuses(res BatchResult) []OperationStatus {
+	out := make([]OperationStatus, len(res.Results))
+	for i, op := range res.Results {
+		out[i] = op.Status
+	}
+	return out
+}
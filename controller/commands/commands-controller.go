@@ -0,0 +1,355 @@
+package commands_controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"net/http"
+	"video-manager/internal/logger"
+	object_storage "video-manager/internal/object-storage"
+	progress_broker "video-manager/internal/progress-broker"
+	video_hosting "video-manager/internal/video-hosting"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+var (
+	log = logger.Build()
+)
+
+// CommandController Consume commands sent through a Dapr pubsub component
+type CommandController[B object_storage.BindingProxy, P progress_broker.PubSubProxy] struct {
+	// Service of the default host
+	Service *video_store_service.VideoStoreService[B, P]
+	// Services of all the hosts a command can target. Only the default host can be targeted if nil
+	Tenants *video_store_service.Tenants[B, P]
+	// All topics the Dapr sidecar should deliver to this app
+	Subscriptions []Subscription
+}
+
+// Subscription A Dapr programmatic subscription
+// https://docs.dapr.io/developing-applications/building-blocks/pubsub/subscription-methods/#programmatic-subscriptions
+type Subscription struct {
+	// Name of the Dapr pubsub component
+	PubsubName string `json:"pubsubname"`
+	// Topic to subscribe to
+	Topic string `json:"topic"`
+	// Route the sidecar has to POST the messages to
+	Route string `json:"route"`
+}
+
+// Status Tell the Dapr sidecar what to do with the message we just processed
+type Status string
+
+const (
+	// Success The message has been processed
+	Success Status = "SUCCESS"
+	// Retry The message couldn't be processed this time, but it may succeed later on
+	Retry Status = "RETRY"
+	// Drop The message will never be processed, there is no point in retrying
+	Drop Status = "DROP"
+)
+
+// Response expected by Dapr for each delivered message
+type Response struct {
+	Status Status `json:"status"`
+}
+
+// CommandType Operation to run on the video hosting platform
+type CommandType string
+
+const (
+	UploadVideo    CommandType = "video.upload"
+	UpdateVideo    CommandType = "video.update"
+	SetThumbnail   CommandType = "video.thumbnail.set"
+	CreatePlaylist CommandType = "playlist.create"
+	AddToPlaylist  CommandType = "playlist.item.add"
+	Publish        CommandType = "manifest.publish"
+)
+
+// Command A single operation to run, sent as the data of a CloudEvent
+type Command struct {
+	// Operation to run
+	Type CommandType `json:"type" binding:"required"`
+	// Name of the host to run the operation on. Defaults to the default host
+	Host string `json:"host"`
+	// Arguments of the operation, their shape depends on the command type
+	Payload json.RawMessage `json:"payload" binding:"required" swaggertype:"object"`
+}
+
+// UploadVideoPayload Upload a video from the object storage
+type UploadVideoPayload struct {
+	video_hosting.ItemMetadata
+	// Key to retrieve the video from the object storage
+	StorageKey string `json:"storageKey" binding:"required"`
+	// UUID of this uploading job
+	JobId string `json:"jobId" binding:"required"`
+}
+
+// UpdateVideoPayload Replace the metadata of an existing video
+type UpdateVideoPayload struct {
+	video_hosting.ItemMetadata
+	// ID of the video to update
+	VideoId string `json:"videoId" binding:"required"`
+}
+
+// SetThumbnailPayload Set the thumbnail of an existing video from the object storage
+type SetThumbnailPayload struct {
+	// ID of the video to update
+	VideoId string `json:"videoId" binding:"required"`
+	// Key to retrieve the thumbnail from the object storage
+	StorageKey string `json:"storageKey" binding:"required"`
+}
+
+// AddToPlaylistPayload Add an existing video to an existing playlist
+type AddToPlaylistPayload struct {
+	VideoId    string `json:"videoId" binding:"required"`
+	PlaylistId string `json:"playlistId" binding:"required"`
+}
+
+// PublishPayload Publish a recording as described by a manifest of the object storage
+type PublishPayload struct {
+	// Key to retrieve the manifest from the object storage
+	ManifestKey string `json:"manifestKey" binding:"required"`
+}
+
+// A CloudEvent envelope, as delivered by the Dapr sidecar
+type cloudEvent struct {
+	Id              string          `json:"id"`
+	Type            string          `json:"type"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+	DataBase64      string          `json:"data_base64"`
+}
+
+// Any error that can't be fixed by retrying the same message
+type invalidCommandError struct {
+	err error
+}
+
+func (e *invalidCommandError) Error() string {
+	return e.err.Error()
+}
+
+// ShowAccount godoc
+// @Summary      List Dapr subscriptions
+// @Description  Programmatic subscriptions, queried by the Dapr sidecar at startup
+// @Tags         dapr
+// @Produce      json
+// @Success      200  {array}  Subscription
+// @Router       /dapr/subscribe [get]
+func (cc *CommandController[B, P]) Subscribe(c *gin.Context) {
+	subs := cc.Subscriptions
+	if subs == nil {
+		subs = []Subscription{}
+	}
+	c.JSON(http.StatusOK, subs)
+}
+
+// ShowAccount godoc
+// @Summary      Run a command
+// @Description  Run a command delivered by the Dapr sidecar as a CloudEvent
+// @Tags         dapr
+// @Accept       json
+// @Produce      json
+// @Param 		 command body Command true "Command to run, wrapped in a CloudEvent"
+// @Success      200  {object}  Response
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /commands [post]
+func (cc *CommandController[B, P]) Handle(c *gin.Context) {
+	cc.handle(c, decodeCommand)
+}
+
+// ShowAccount godoc
+// @Summary      Upload a video from a legacy message
+// @Description  Upload a video from the object storage, as a video.upload command would. The CloudEvent data is the payload of the command.
+// @Description  Kept for the publishers of the "uploads" topic, formerly routed to POST /v1/videos
+// @Tags         dapr
+// @Accept       json
+// @Produce      json
+// @Param 		 payload body UploadVideoPayload true "Video to upload, wrapped in a CloudEvent"
+// @Success      200  {object}  Response
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /commands/uploads [post]
+func (cc *CommandController[B, P]) HandleUpload(c *gin.Context) {
+	cc.handle(c, func(evt *cloudEvent) (*Command, error) {
+		data, err := eventData(evt)
+		if err != nil {
+			return nil, err
+		}
+		return &Command{Type: UploadVideo, Payload: data}, nil
+	})
+}
+
+// Run the command decoded from the delivered CloudEvent, and tell the sidecar what to do with the message
+func (cc *CommandController[B, P]) handle(c *gin.Context, decode func(evt *cloudEvent) (*Command, error)) {
+	var evt cloudEvent
+	if err := c.ShouldBindJSON(&evt); err != nil {
+		log.Errorf("Dropping message, invalid CloudEvent : %s", err.Error())
+		c.JSON(http.StatusOK, Response{Status: Drop})
+		return
+	}
+	cmd, err := decode(&evt)
+	if err == nil {
+		var svc *video_store_service.VideoStoreService[B, P]
+		if svc, err = cc.service(cmd.Host); err == nil {
+			_, err = run(c, svc, cmd)
+		}
+	}
+	status := classify(err)
+	if err != nil {
+		log.Errorf("Command from message %s failed (%s) : %s", evt.Id, status, err.Error())
+	}
+	c.JSON(http.StatusOK, Response{Status: status})
+}
+
+// Run the command against the video store service svc.
+// Returns the video or playlist the command created or updated, if any
+func run[B object_storage.BindingProxy, P progress_broker.PubSubProxy](ctx context.Context, svc *video_store_service.VideoStoreService[B, P], cmd *Command) (any, error) {
+	switch cmd.Type {
+	case UploadVideo:
+		var p UploadVideoPayload
+		if err := decodePayload(cmd.Payload, &p); err != nil {
+			return nil, err
+		}
+		// An upload may outlast the delivery timeout of the sidecar, which then delivers the message again
+		return svc.UploadVideoFromStorageOnce(ctx, p.JobId, p.StorageKey, &p.ItemMetadata)
+	case UpdateVideo:
+		var p UpdateVideoPayload
+		if err := decodePayload(cmd.Payload, &p); err != nil {
+			return nil, err
+		}
+		vid, err := svc.VidHost.RetrieveVideo(ctx, p.VideoId)
+		if err != nil {
+			return nil, err
+		}
+		vid.Title = p.Title
+		vid.Description = p.Description
+		vid.Visibility = p.Visibility
+		return svc.UpdateVideo(ctx, p.VideoId, vid)
+	case SetThumbnail:
+		var p SetThumbnailPayload
+		if err := decodePayload(cmd.Payload, &p); err != nil {
+			return nil, err
+		}
+		return nil, svc.SetVideoThumbnailFromStorage(ctx, p.VideoId, p.StorageKey)
+	case CreatePlaylist:
+		var p video_hosting.ItemMetadata
+		if err := decodePayload(cmd.Payload, &p); err != nil {
+			return nil, err
+		}
+		return svc.CreatePlaylist(ctx, &p)
+	case AddToPlaylist:
+		var p AddToPlaylistPayload
+		if err := decodePayload(cmd.Payload, &p); err != nil {
+			return nil, err
+		}
+		return nil, svc.AddVideoToPlaylist(ctx, p.VideoId, p.PlaylistId)
+	case Publish:
+		var p PublishPayload
+		if err := decodePayload(cmd.Payload, &p); err != nil {
+			return nil, err
+		}
+		report, err := svc.Publish(ctx, p.ManifestKey)
+		if err != nil {
+			return nil, err
+		}
+		return report, nil
+	default:
+		return nil, &invalidCommandError{fmt.Errorf(`unknown command type "%s"`, cmd.Type)}
+	}
+}
+
+// The service of the host the command targets
+func (cc *CommandController[B, P]) service(host string) (*video_store_service.VideoStoreService[B, P], error) {
+	if cc.Tenants != nil {
+		svc, err := cc.Tenants.Get(host)
+		if err != nil {
+			return nil, &invalidCommandError{err}
+		}
+		return svc, nil
+	}
+	if host != "" {
+		return nil, &invalidCommandError{fmt.Errorf(`unknown host "%s"`, host)}
+	}
+	return cc.Service, nil
+}
+
+// Extract the command from the data of the CloudEvent
+func decodeCommand(evt *cloudEvent) (*Command, error) {
+	data, err := eventData(evt)
+	if err != nil {
+		return nil, err
+	}
+	var cmd Command
+	if err := decodePayload(data, &cmd); err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+// The JSON data of the CloudEvent
+func eventData(evt *cloudEvent) ([]byte, error) {
+	data := []byte(evt.Data)
+	if evt.DataBase64 != "" {
+		decoded, err := base64.StdEncoding.DecodeString(evt.DataBase64)
+		if err != nil {
+			return nil, &invalidCommandError{fmt.Errorf("invalid base64 data : %w", err)}
+		}
+		data = decoded
+	}
+	// Publishers may send the data as a JSON-encoded string instead of a JSON object
+	// (this is what the progress broker does), in which case we have to unwrap it first
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		data = []byte(str)
+	}
+	return data, nil
+}
+
+// Unmarshal and validate a JSON payload into target
+func decodePayload(data []byte, target any) error {
+	if len(data) == 0 {
+		return &invalidCommandError{fmt.Errorf("no data provided")}
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return &invalidCommandError{fmt.Errorf("invalid payload : %w", err)}
+	}
+	if err := binding.Validator.ValidateStruct(target); err != nil {
+		return &invalidCommandError{fmt.Errorf("invalid payload : %w", err)}
+	}
+	return nil
+}
+
+// Decide whether a failed command should be retried later on
+func classify(err error) Status {
+	if err == nil {
+		return Success
+	}
+	var ice *invalidCommandError
+	if errors.As(err, &ice) {
+		return Drop
+	}
+	var re *video_hosting.RequestError
+	if errors.As(err, &re) {
+		switch {
+		// Timeouts and rate limiting are transient by nature
+		case re.StatusCode == http.StatusRequestTimeout || re.StatusCode == http.StatusTooManyRequests:
+			return Retry
+		// Any other client error means the command itself is wrong
+		case re.StatusCode >= 400 && re.StatusCode < 500:
+			return Drop
+		}
+	}
+	// Anything else (object storage unavailable, network error, 5xx...) may succeed later on
+	return Retry
+}
@@ -0,0 +1,400 @@
+package commands_controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/dapr/go-sdk/client"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+	mock_object_storage "video-manager/internal/mock/object-storage"
+	mock_progress_broker "video-manager/internal/mock/progress-broker"
+	mock_video_hosting "video-manager/internal/mock/video-hosting"
+	object_storage "video-manager/internal/object-storage"
+	video_hosting "video-manager/internal/video-hosting"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+type mocked struct {
+	videoStore       *mock_video_hosting.MockIVideoHost
+	objectStoreProxy *mock_object_storage.MockBindingProxy
+	controller       *CommandController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]
+}
+
+var (
+	sampleVid = video_hosting.Video{
+		Id:          "testId",
+		Title:       "testTitle",
+		Description: "testDescription",
+		CreatedAt:   time.Unix(1662202180, 0).UTC(),
+		Visibility:  "unlisted",
+	}
+	sampleMetadata = video_hosting.ItemMetadata{
+		Description: "testDescription",
+		Title:       "testTitle",
+		Visibility:  "unlisted",
+	}
+)
+
+func Setup(t *testing.T) *mocked {
+	dir, err := os.MkdirTemp("", "assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := gomock.NewController(t)
+	objStoreProxy := mock_object_storage.NewMockBindingProxy(ctrl)
+	objectStore := object_storage.NewObjectStorage[*mock_object_storage.MockBindingProxy](dir, objStoreProxy)
+	vidHost := mock_video_hosting.NewMockIVideoHost(ctrl)
+	vss := video_store_service.VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{
+		ObjStore: objectStore,
+		VidHost:  vidHost,
+	}
+	controller := CommandController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{
+		Service:       &vss,
+		Subscriptions: []Subscription{{PubsubName: "pubsub", Topic: "commands", Route: "/v1/commands"}},
+	}
+	gin.SetMode(gin.TestMode)
+	return &mocked{
+		videoStore:       vidHost,
+		objectStoreProxy: objStoreProxy,
+		controller:       &controller,
+	}
+}
+
+func TestCommandController_Subscribe(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.controller.Subscribe(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var subs []Subscription
+	if err := json.Unmarshal(w.Body.Bytes(), &subs); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, deps.controller.Subscriptions, subs)
+}
+
+func TestCommandController_Subscribe_None(t *testing.T) {
+	deps := Setup(t)
+	deps.controller.Subscriptions = nil
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.controller.Subscribe(c)
+	// Dapr expects an empty array, not null
+	assert.Equal(t, "[]", w.Body.String())
+}
+
+func TestCommandController_Handle_InvalidEnvelope(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBufferString("{not json"))
+	deps.controller.Handle(c)
+	assertStatus(t, w, Drop)
+}
+
+func TestCommandController_Handle_UnknownCommand(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	setCommandAsBody(t, c, "video.explode", map[string]string{})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Drop)
+}
+
+func TestCommandController_Handle_InvalidPayload(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	// No storage key nor job ID
+	setCommandAsBody(t, c, UploadVideo, UploadVideoPayload{ItemMetadata: sampleMetadata})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Drop)
+}
+
+func TestCommandController_Handle_UploadVideo_Ok(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("aa")}, nil)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&sampleVid, nil)
+	setCommandAsBody(t, c, UploadVideo, UploadVideoPayload{ItemMetadata: sampleMetadata, StorageKey: "key", JobId: "job"})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Success)
+}
+
+func TestCommandController_Handle_UploadVideo_StorageError(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	// The video may not be available yet in the storage, this is worth a retry
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
+	setCommandAsBody(t, c, UploadVideo, UploadVideoPayload{ItemMetadata: sampleMetadata, StorageKey: "key", JobId: "job"})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Retry)
+}
+
+func TestCommandController_Handle_UploadVideo_Redelivered(t *testing.T) {
+	deps := Setup(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("aa")}, nil)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, _ any, _ any, _ any) (*video_hosting.Video, error) {
+			close(started)
+			<-release
+			return &sampleVid, nil
+		}).Times(1)
+	deliver := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		setCommandAsBody(t, c, UploadVideo, UploadVideoPayload{ItemMetadata: sampleMetadata, StorageKey: "key", JobId: "job"})
+		deps.controller.Handle(c)
+		return w
+	}
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- deliver() }()
+	<-started
+	// Delivered again while the upload is running, to be retried once it is done
+	assertStatus(t, deliver(), Retry)
+	close(release)
+	assertStatus(t, <-first, Success)
+}
+
+func TestCommandController_HandleUpload(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("aa")}, nil)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&sampleVid, nil)
+	// The data is the payload itself
+	p, err := json.Marshal(UploadVideoPayload{ItemMetadata: sampleMetadata, StorageKey: "key", JobId: "job"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setEventAsBody(t, c, cloudEvent{Id: "1", DataContentType: "application/json", Data: p})
+	deps.controller.HandleUpload(c)
+	assertStatus(t, w, Success)
+
+	// Still validated
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	setEventAsBody(t, c, cloudEvent{Id: "1", DataContentType: "application/json", Data: []byte(`{"title": "testTitle"}`)})
+	deps.controller.HandleUpload(c)
+	assertStatus(t, w, Drop)
+}
+
+func TestCommandController_Handle_UploadVideo_ShuttingDown(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.controller.Service.Drain(context.Background())
+	// Another instance will pick the command up
+	setCommandAsBody(t, c, UploadVideo, UploadVideoPayload{ItemMetadata: sampleMetadata, StorageKey: "key", JobId: "job"})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Retry)
+}
+
+func TestCommandController_Handle_Host(t *testing.T) {
+	deps := Setup(t)
+	backupHost := mock_video_hosting.NewMockIVideoHost(gomock.NewController(t))
+	backup := video_store_service.VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{
+		ObjStore: deps.controller.Service.ObjStore,
+		VidHost:  backupHost,
+	}
+	tenants, err := video_store_service.NewTenants("main", map[string]*video_store_service.VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{
+		"main":   deps.controller.Service,
+		"backup": &backup,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps.controller.Tenants = tenants
+	// Only the targeted host is called
+	backupHost.EXPECT().CreatePlaylist(gomock.Any(), gomock.Any()).Return(&video_hosting.Playlist{Id: "pid"}, nil)
+	p, err := json.Marshal(sampleMetadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := json.Marshal(Command{Type: CreatePlaylist, Host: "backup", Payload: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	setEventAsBody(t, c, cloudEvent{Id: "1", DataContentType: "application/json", Data: cmd})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Success)
+
+	// An unknown host will never be known
+	cmd, err = json.Marshal(Command{Type: CreatePlaylist, Host: "other", Payload: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	setEventAsBody(t, c, cloudEvent{Id: "1", DataContentType: "application/json", Data: cmd})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Drop)
+}
+
+func TestCommandController_Handle_UpdateVideo_Ok(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	current := sampleVid
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "testId").Return(&current, nil)
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), "testId", gomock.Any()).DoAndReturn(func(_ context.Context, id string, replacement *video_hosting.Video) (*video_hosting.Video, error) {
+		// Only the metadata must have been changed
+		assert.Equal(t, "newTitle", replacement.Title)
+		assert.Equal(t, sampleVid.CreatedAt, replacement.CreatedAt)
+		return replacement, nil
+	})
+	setCommandAsBody(t, c, UpdateVideo, UpdateVideoPayload{
+		ItemMetadata: video_hosting.ItemMetadata{Title: "newTitle", Visibility: "public"},
+		VideoId:      "testId",
+	})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Success)
+}
+
+func TestCommandController_Handle_SetThumbnail_NotFound(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("aa")}, nil)
+	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.RequestError{
+		StatusCode: http.StatusNotFound,
+		Err:        fmt.Errorf("not found"),
+	})
+	setCommandAsBody(t, c, SetThumbnail, SetThumbnailPayload{VideoId: "testId", StorageKey: "key"})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Drop)
+}
+
+func TestCommandController_Handle_CreatePlaylist_Ok(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.videoStore.EXPECT().CreatePlaylist(gomock.Any(), gomock.Any()).Return(&video_hosting.Playlist{Id: "pid"}, nil)
+	setCommandAsBody(t, c, CreatePlaylist, sampleMetadata)
+	deps.controller.Handle(c)
+	assertStatus(t, w, Success)
+}
+
+func TestCommandController_Handle_AddToPlaylist_Transient(t *testing.T) {
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		deps := Setup(t)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), "vid", "pid").Return(&video_hosting.RequestError{
+			StatusCode: code,
+			Err:        fmt.Errorf("test"),
+		})
+		setCommandAsBody(t, c, AddToPlaylist, AddToPlaylistPayload{VideoId: "vid", PlaylistId: "pid"})
+		deps.controller.Handle(c)
+		assertStatus(t, w, Retry)
+	}
+}
+
+func TestCommandController_Handle_StringData(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), "vid", "pid").Return(nil)
+	cmd := makeCommand(t, AddToPlaylist, AddToPlaylistPayload{VideoId: "vid", PlaylistId: "pid"})
+	// The command is sent as a JSON-encoded string
+	data, err := json.Marshal(string(cmd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setEventAsBody(t, c, cloudEvent{Id: "1", Data: data})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Success)
+}
+
+func TestCommandController_Handle_Base64Data(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), "vid", "pid").Return(nil)
+	cmd := makeCommand(t, AddToPlaylist, AddToPlaylistPayload{VideoId: "vid", PlaylistId: "pid"})
+	setEventAsBody(t, c, cloudEvent{Id: "1", DataBase64: base64.StdEncoding.EncodeToString(cmd)})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Success)
+}
+
+// Marshal a command of type cmdType with the provided payload
+func makeCommand(t *testing.T, cmdType CommandType, payload any) []byte {
+	p, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := json.Marshal(Command{Type: cmdType, Payload: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+// Set a CloudEvent wrapping a command as the JSON body of c
+func setCommandAsBody(t *testing.T, c *gin.Context, cmdType CommandType, payload any) {
+	setEventAsBody(t, c, cloudEvent{Id: "1", DataContentType: "application/json", Data: makeCommand(t, cmdType, payload)})
+}
+
+// Set the CloudEvent as the JSON body of c
+func setEventAsBody(t *testing.T, c *gin.Context, evt cloudEvent) {
+	buf, err := json.Marshal(evt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBuffer(buf))
+	c.Request.Header.Set("Content-Type", "application/cloudevents+json")
+}
+
+// Check the status returned to the Dapr sidecar
+func assertStatus(t *testing.T, w *httptest.ResponseRecorder, expected Status) {
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res Response
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, res.Status)
+}
+
+func TestCommandController_Handle_Publish(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	manifest := `{"title": "testTitle", "visibility": "unlisted", "storageKey": "key", "playlists": ["pl"]}`
+	gomock.InOrder(
+		deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).
+			Return(&client.BindingEvent{Data: []byte(base64.StdEncoding.EncodeToString([]byte(manifest)))}, nil),
+		deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("aa")}, nil),
+	)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&sampleVid, nil)
+	deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), sampleVid.Id, "pl").Return(nil)
+	setCommandAsBody(t, c, Publish, PublishPayload{ManifestKey: "manifest.json"})
+	deps.controller.Handle(c)
+	assertStatus(t, w, Success)
+}
+
+func TestCommandController_Handle_Publish_InvalidManifest(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).
+		Return(&client.BindingEvent{Data: []byte(base64.StdEncoding.EncodeToString([]byte(`{"title": "testTitle"}`)))}, nil)
+	setCommandAsBody(t, c, Publish, PublishPayload{ManifestKey: "manifest.json"})
+	deps.controller.Handle(c)
+	// The same manifest will never be valid
+	assertStatus(t, w, Drop)
+}
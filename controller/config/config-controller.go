@@ -0,0 +1,28 @@
+package config_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"video-manager/internal/config"
+)
+
+type ConfigController struct {
+	Config *config.Config
+}
+
+// ShowAccount godoc
+// @Summary      Get the configuration
+// @Description  Retrieve the configuration the service is running with, once the file and the environment are merged.
+// @Description  Secrets (API keys, client secrets, refresh tokens...) are redacted
+// @Tags         config
+// @Produce      json
+// @Success      200  {object}  config.Config
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      429  {object}  problem.Problem "Too many requests"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /config [get]
+func (cc *ConfigController) Retrieve(c *gin.Context) {
+	c.SecureJSON(http.StatusOK, cc.Config.Redacted())
+}
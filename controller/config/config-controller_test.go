@@ -0,0 +1,32 @@
+package config_controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-manager/internal/config"
+)
+
+func TestConfigController_Retrieve(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.Hosts = []config.Host{{Name: "youtube", Type: config.Youtube, ClientId: "id", ClientSecret: "secret", RefreshToken: "token"}}
+	cc := ConfigController{Config: &cfg}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	cc.Retrieve(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"secret"`)
+	assert.NotContains(t, w.Body.String(), `"token"`)
+
+	var retrieved config.Config
+	if err := json.Unmarshal(w.Body.Bytes(), &retrieved); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "id", retrieved.Hosts[0].ClientId)
+	assert.Equal(t, config.RedactedValue, retrieved.Hosts[0].RefreshToken)
+	assert.Equal(t, cfg.Health.CacheTTL, retrieved.Health.CacheTTL)
+}
@@ -0,0 +1,40 @@
+package health_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"video-manager/internal/health"
+)
+
+type HealthController struct {
+	Checker *health.Checker
+}
+
+// ShowAccount godoc
+// @Summary      Liveness probe
+// @Description  Always succeeds while the server is able to answer. Dependencies aren't checked, use /readyz for that
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  health.Report
+// @Router       /healthz [get]
+func (hc *HealthController) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Report{Status: health.Up, Checks: map[string]health.Result{}})
+}
+
+// ShowAccount godoc
+// @Summary      Readiness probe
+// @Description  Check all the dependencies of the service : the Dapr sidecar, the object storage, the pubsub and the video hosting platform.
+// @Description  Results are cached for a few seconds
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  health.Report
+// @Failure      503  {object}  health.Report "At least one dependency is down"
+// @Router       /readyz [get]
+func (hc *HealthController) Ready(c *gin.Context) {
+	report := hc.Checker.Report(c)
+	status := http.StatusOK
+	if report.Status != health.Up {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
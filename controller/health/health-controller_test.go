@@ -0,0 +1,61 @@
+package health_controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-manager/internal/health"
+)
+
+func Setup(checkErr error) *HealthController {
+	gin.SetMode(gin.TestMode)
+	return &HealthController{Checker: health.NewChecker(health.Options{}, health.Check{
+		Name: "test",
+		Run:  func(ctx context.Context) error { return checkErr },
+	})}
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) health.Report {
+	var report health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestHealthController_Live(t *testing.T) {
+	// Liveness doesn't depend on the dependencies
+	hc := Setup(fmt.Errorf("test"))
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	hc.Live(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, health.Up, decode(t, w).Status)
+}
+
+func TestHealthController_Ready_Ok(t *testing.T) {
+	hc := Setup(nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	hc.Ready(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	report := decode(t, w)
+	assert.Equal(t, health.Up, report.Status)
+	assert.Equal(t, health.Up, report.Checks["test"].Status)
+}
+
+func TestHealthController_Ready_Down(t *testing.T) {
+	hc := Setup(fmt.Errorf("test"))
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	hc.Ready(c)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	report := decode(t, w)
+	assert.Equal(t, health.Down, report.Status)
+	assert.Equal(t, "test", report.Checks["test"].Error)
+}
@@ -0,0 +1,70 @@
+package hosts_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	object_storage "video-manager/internal/object-storage"
+	"video-manager/internal/problem"
+	progress_broker "video-manager/internal/progress-broker"
+	video_hosting "video-manager/internal/video-hosting"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+// HostHeader Header selecting the host of a request, when its path doesn't
+const HostHeader = "X-Video-Host"
+
+type HostsController[B object_storage.BindingProxy, P progress_broker.PubSubProxy] struct {
+	Tenants *video_store_service.Tenants[B, P]
+}
+
+// Host A host the API can be used with
+type Host struct {
+	Name string `json:"name" example:"youtube"`
+	// Whether the host is used when none is selected
+	Default bool `json:"default"`
+	// Consumption of the daily quota, absent if the platform has no quota
+	Quota *video_hosting.QuotaStatus `json:"quota,omitempty"`
+}
+
+// Select Select the host of the request, from the "host" path parameter, then from the X-Video-Host header.
+// The default host is used if neither is set
+func (hc *HostsController[B, P]) Select(c *gin.Context) {
+	name := c.Param("host")
+	if name == "" {
+		name = c.GetHeader(HostHeader)
+	}
+	svc, err := hc.Tenants.Get(name)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	c.Set(video_store_service.ServiceKey, svc)
+	c.Next()
+}
+
+// ShowAccount godoc
+// @Summary      List the hosts
+// @Description  List all the hosts served by this deployment. Any route can be used with a specific host,
+// @Description  either with the X-Video-Host header or by prefixing its path with /v1/hosts/{host}
+// @Tags         hosts
+// @Produce      json
+// @Success      200  {array}   Host
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /hosts [get]
+func (hc *HostsController[B, P]) List(c *gin.Context) {
+	names := hc.Tenants.Names()
+	hosts := make([]Host, 0, len(names))
+	for _, name := range names {
+		svc, _ := hc.Tenants.Get(name)
+		host := Host{Name: name, Default: name == hc.Tenants.DefaultName()}
+		if svc.Quota != nil {
+			status := svc.Quota.Status()
+			host.Quota = &status
+		}
+		hosts = append(hosts, host)
+	}
+	c.JSON(http.StatusOK, hosts)
+}
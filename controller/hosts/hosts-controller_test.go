@@ -0,0 +1,109 @@
+package hosts_controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	mock_object_storage "video-manager/internal/mock/object-storage"
+	mock_progress_broker "video-manager/internal/mock/progress-broker"
+	mock_video_hosting "video-manager/internal/mock/video-hosting"
+	video_hosting "video-manager/internal/video-hosting"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+type testService = video_store_service.VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]
+
+type mocked struct {
+	main       *testService
+	backup     *testService
+	controller *HostsController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]
+}
+
+// Setup two hosts, "main" being the default one and the only one with a quota
+func Setup(t *testing.T) *mocked {
+	ctrl := gomock.NewController(t)
+	quota, err := video_hosting.NewQuotaGuard(mock_video_hosting.NewMockIVideoHost(ctrl), video_hosting.YoutubeQuotaCosts, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := &testService{VidHost: quota, Quota: quota}
+	backup := &testService{VidHost: mock_video_hosting.NewMockIVideoHost(ctrl)}
+	tenants, err := video_store_service.NewTenants("main", map[string]*testService{"main": main, "backup": backup})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	return &mocked{
+		main:       main,
+		backup:     backup,
+		controller: &HostsController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{Tenants: tenants},
+	}
+}
+
+// Route answering with the selected service
+func selected(deps *mocked) (*gin.Engine, **testService) {
+	var svc *testService
+	router := gin.New()
+	handler := func(c *gin.Context) {
+		svc = video_store_service.FromContext(c, (*testService)(nil))
+		c.Status(http.StatusOK)
+	}
+	router.GET("/v1/quota", deps.controller.Select, handler)
+	router.GET("/v1/hosts/:host/quota", deps.controller.Select, handler)
+	return router, &svc
+}
+
+func TestHostsController_Select(t *testing.T) {
+	deps := Setup(t)
+	router, svc := selected(deps)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/quota", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Same(t, deps.main, *svc)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/quota", nil)
+	req.Header.Set(HostHeader, "backup")
+	router.ServeHTTP(w, req)
+	assert.Same(t, deps.backup, *svc)
+
+	// The path takes precedence over the header
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/v1/hosts/main/quota", nil)
+	req.Header.Set(HostHeader, "backup")
+	router.ServeHTTP(w, req)
+	assert.Same(t, deps.main, *svc)
+}
+
+func TestHostsController_Select_Unknown(t *testing.T) {
+	deps := Setup(t)
+	router, svc := selected(deps)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/hosts/other/quota", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Nil(t, *svc)
+}
+
+func TestHostsController_List(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/hosts", nil)
+	deps.controller.List(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var hosts []Host
+	if err := json.Unmarshal(w.Body.Bytes(), &hosts); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, hosts, 2)
+	assert.Equal(t, Host{Name: "backup"}, hosts[0])
+	assert.Equal(t, "main", hosts[1].Name)
+	assert.True(t, hosts[1].Default)
+	assert.NotNil(t, hosts[1].Quota)
+}
@@ -0,0 +1,170 @@
+package oauth_controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"video-manager/internal/logger"
+	oauth_consent "video-manager/internal/oauth-consent"
+	"video-manager/internal/problem"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+var (
+	log = logger.Build()
+)
+
+// CredentialsSaver Persists the credentials obtained with a consent, so they survive a restart
+type CredentialsSaver interface {
+	Save(ctx context.Context, host string, secret map[string]string) error
+}
+
+// CallbackPath Path of the consent callback of the default host
+const CallbackPath = "/v1/auth/youtube/callback"
+
+// OAuthController Consent routes of a single host
+type OAuthController struct {
+	// Name of the host the consent is given for
+	Host    string
+	Consent *oauth_consent.Consent
+	Store   CredentialsSaver
+	// Credentials of the host, replaced once the consent is given
+	Credentials video_hosting.CredentialsRotator
+}
+
+// Authorized The outcome of a successful consent
+type Authorized struct {
+	// Name of the host now using the new refresh token
+	Host string `json:"host" example:"youtube"`
+	// Whether the refresh token differs from the previous one
+	Rotated bool `json:"rotated"`
+}
+
+// ShowAccount godoc
+// @Summary      Start the Youtube consent
+// @Description  Redirect to the Google consent screen, to grant the service access to a Youtube account.
+// @Description  Google then redirects to /v1/auth/youtube/callback. The flow must be completed within 10 minutes
+// @Tags         auth
+// @Success      302
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      500  {object}  problem.Problem
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /auth/youtube/start [get]
+func (oc *OAuthController) Start(c *gin.Context) {
+	consentUrl, err := oc.Consent.Start()
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, consentUrl)
+}
+
+// ShowAccount godoc
+// @Summary      Complete the Youtube consent
+// @Description  Called by Google once the user gave their consent. The refresh token is saved in the state store,
+// @Description  and used right away without restarting
+// @Tags         auth
+// @Produce      json
+// @Param        state  query     string  true  "State issued by /v1/auth/youtube/start"
+// @Param        code   query     string  true  "Authorization code"
+// @Success      200    {object}  Authorized
+// @Failure      400    {object}  problem.Problem "Unknown or expired state"
+// @Failure      403    {object}  problem.Problem "The user refused the consent"
+// @Failure      502    {object}  problem.Problem "Google refused the authorization code"
+// @Failure      503    {object}  problem.Problem "The refresh token couldn't be saved"
+// @Router       /auth/youtube/callback [get]
+func (oc *OAuthController) Callback(c *gin.Context) {
+	if reason := c.Query("error"); reason != "" {
+		problem.AbortWith(c, problem.Forbidden, `The consent was refused : %s`, reason)
+		return
+	}
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		problem.AbortWith(c, problem.BadRequest, `Missing "state" or "code", aborting !`)
+		return
+	}
+	token, err := oc.Consent.Complete(c, state, code)
+	if errors.Is(err, oauth_consent.ErrUnknownState) {
+		problem.AbortWith(c, problem.BadRequest, `%s, start the consent again !`, err.Error())
+		return
+	}
+	if err != nil {
+		problem.Abort(c, video_hosting.NewRequestError(video_hosting.Upstream, err))
+		return
+	}
+
+	secret := map[string]string{video_hosting.YoutubeSecretRefreshToken: token.RefreshToken}
+	if err := oc.Store.Save(c, oc.Host, secret); err != nil {
+		problem.Abort(c, video_hosting.NewRequestError(video_hosting.StorageUnavailable,
+			fmt.Errorf("could not save the refresh token : %w", err)))
+		return
+	}
+	rotated := oc.Credentials.RotateCredentials(secret)
+	log.Infof(`Consent given for host "%s", refresh token saved`, oc.Host)
+	c.JSON(http.StatusOK, Authorized{Host: oc.Host, Rotated: rotated})
+}
+
+// HostConsents The consent routes of each host supporting the consent, by host name, served under /v1/hosts/:host
+type HostConsents map[string]*OAuthController
+
+// HostRedirectUrl The callback URL of the host named "host", from redirectUrl, the URL of the callback of the default host.
+// Returns false if redirectUrl doesn't end with CallbackPath
+func HostRedirectUrl(redirectUrl string, host string) (string, bool) {
+	base, found := strings.CutSuffix(redirectUrl, CallbackPath)
+	if !found {
+		return "", false
+	}
+	return base + "/v1/hosts/" + host + "/auth/youtube/callback", true
+}
+
+// ShowAccount godoc
+// @Summary      Start the Youtube consent of a host
+// @Description  Same as /v1/auth/youtube/start, for the host of the path.
+// @Description  Google then redirects to /v1/hosts/{host}/auth/youtube/callback, or to /v1/auth/youtube/callback for the default host
+// @Tags         auth
+// @Param        host  path  string  true  "Host name"
+// @Success      302
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No host with this name supports the consent"
+// @Failure      500  {object}  problem.Problem
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /hosts/{host}/auth/youtube/start [get]
+func (hc HostConsents) Start(c *gin.Context) {
+	oc, ok := hc[c.Param("host")]
+	if !ok {
+		problem.AbortWith(c, problem.NotFound, `No host named "%s" supports the consent`, c.Param("host"))
+		return
+	}
+	oc.Start(c)
+}
+
+// ShowAccount godoc
+// @Summary      Complete the Youtube consent of a host
+// @Description  Same as /v1/auth/youtube/callback, for the host of the path
+// @Tags         auth
+// @Produce      json
+// @Param        host   path      string  true  "Host name"
+// @Param        state  query     string  true  "State issued by /v1/hosts/{host}/auth/youtube/start"
+// @Param        code   query     string  true  "Authorization code"
+// @Success      200    {object}  Authorized
+// @Failure      400    {object}  problem.Problem "Unknown or expired state"
+// @Failure      403    {object}  problem.Problem "The user refused the consent"
+// @Failure      502    {object}  problem.Problem "Google refused the authorization code"
+// @Failure      503    {object}  problem.Problem "The refresh token couldn't be saved"
+// @Router       /hosts/{host}/auth/youtube/callback [get]
+func (hc HostConsents) Callback(c *gin.Context) {
+	oc, ok := hc[c.Param("host")]
+	if !ok {
+		// This route is unauthenticated, it doesn't tell which hosts exist
+		problem.AbortWith(c, problem.BadRequest, `%s, start the consent again !`, oauth_consent.ErrUnknownState.Error())
+		return
+	}
+	oc.Callback(c)
+}
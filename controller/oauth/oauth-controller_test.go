@@ -0,0 +1,214 @@
+package oauth_controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	oauth_consent "video-manager/internal/oauth-consent"
+	"video-manager/internal/problem"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// Credentials store keeping the saved credentials in memory
+type fakeStore struct {
+	saved map[string]map[string]string
+	err   error
+}
+
+func (fs *fakeStore) Save(_ context.Context, host string, secret map[string]string) error {
+	if fs.err != nil {
+		return fs.err
+	}
+	fs.saved[host] = secret
+	return nil
+}
+
+// Host keeping the rotated credentials in memory
+type fakeRotator struct {
+	secret map[string]string
+}
+
+func (fr *fakeRotator) RotateCredentials(secret map[string]string) bool {
+	fr.secret = secret
+	return true
+}
+
+type mocked struct {
+	store   *fakeStore
+	rotator *fakeRotator
+}
+
+// Setup a controller whose authorization server grants the refresh token "refresh" for the code "code"
+func Setup(t *testing.T) (*OAuthController, mocked) {
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("code") != "code" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"access","token_type":"Bearer","expires_in":3600,"refresh_token":"refresh"}`)
+	}))
+	t.Cleanup(server.Close)
+	config := &oauth2.Config{
+		ClientID:    "id",
+		RedirectURL: "https://video-store.example.com/v1/auth/youtube/callback",
+		Endpoint:    oauth2.Endpoint{AuthURL: "https://accounts.example.com/auth", TokenURL: server.URL},
+	}
+	m := mocked{store: &fakeStore{saved: map[string]map[string]string{}}, rotator: &fakeRotator{}}
+	return &OAuthController{
+		Host:        "youtube",
+		Consent:     oauth_consent.NewConsent(func() *oauth2.Config { return config }, 0),
+		Store:       m.store,
+		Credentials: m.rotator,
+	}, m
+}
+
+// Start a consent, returning the state to complete it with
+func start(t *testing.T, oc *OAuthController) string {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/auth/youtube/start", nil)
+	oc.Start(c)
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "accounts.example.com", location.Host)
+	return location.Query().Get("state")
+}
+
+func callback(oc *OAuthController, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/auth/youtube/callback?"+query, nil)
+	oc.Callback(c)
+	return w
+}
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) problem.Problem {
+	var p problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestOAuthController_Callback(t *testing.T) {
+	oc, m := Setup(t)
+	state := start(t, oc)
+
+	w := callback(oc, url.Values{"state": {state}, "code": {"code"}}.Encode())
+	assert.Equal(t, http.StatusOK, w.Code)
+	var authorized Authorized
+	if err := json.Unmarshal(w.Body.Bytes(), &authorized); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Authorized{Host: "youtube", Rotated: true}, authorized)
+	// The refresh token is both saved and used right away
+	expected := map[string]string{video_hosting.YoutubeSecretRefreshToken: "refresh"}
+	assert.Equal(t, expected, m.store.saved["youtube"])
+	assert.Equal(t, expected, m.rotator.secret)
+}
+
+func TestOAuthController_Callback_Refused(t *testing.T) {
+	oc, _ := Setup(t)
+	w := callback(oc, "error=access_denied")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, problem.Forbidden, decodeProblem(t, w).Type)
+}
+
+func TestOAuthController_Callback_InvalidState(t *testing.T) {
+	oc, m := Setup(t)
+	start(t, oc)
+
+	w := callback(oc, "code=code")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = callback(oc, url.Values{"state": {"forged"}, "code": {"code"}}.Encode())
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, decodeProblem(t, w).Detail, "start the consent again")
+	assert.Empty(t, m.store.saved)
+	assert.Nil(t, m.rotator.secret)
+}
+
+func TestOAuthController_Callback_InvalidCode(t *testing.T) {
+	oc, m := Setup(t)
+	state := start(t, oc)
+
+	w := callback(oc, url.Values{"state": {state}, "code": {"other"}}.Encode())
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, problem.Upstream, decodeProblem(t, w).Type)
+	assert.Empty(t, m.store.saved)
+}
+
+func TestOAuthController_Callback_SaveError(t *testing.T) {
+	oc, m := Setup(t)
+	m.store.err = fmt.Errorf("state store unavailable")
+	state := start(t, oc)
+
+	w := callback(oc, url.Values{"state": {state}, "code": {"code"}}.Encode())
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	// A refresh token that couldn't be saved isn't used, it would be lost on the next restart
+	assert.Nil(t, m.rotator.secret)
+}
+
+func TestHostConsents(t *testing.T) {
+	oc, m := Setup(t)
+	consents := HostConsents{"youtube": oc}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/hosts/youtube/auth/youtube/start", nil)
+	c.Params = gin.Params{{Key: "host", Value: "youtube"}}
+	consents.Start(c)
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/hosts/youtube/auth/youtube/callback?"+url.Values{"state": {location.Query().Get("state")}, "code": {"code"}}.Encode(), nil)
+	c.Params = gin.Params{{Key: "host", Value: "youtube"}}
+	consents.Callback(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, m.store.saved["youtube"])
+}
+
+func TestHostConsents_UnknownHost(t *testing.T) {
+	oc, _ := Setup(t)
+	consents := HostConsents{"youtube": oc}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/hosts/other/auth/youtube/start", nil)
+	c.Params = gin.Params{{Key: "host", Value: "other"}}
+	consents.Start(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The callback doesn't tell which hosts exist
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/hosts/other/auth/youtube/callback?state=state&code=code", nil)
+	c.Params = gin.Params{{Key: "host", Value: "other"}}
+	consents.Callback(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, decodeProblem(t, w).Detail, "start the consent again")
+}
+
+func TestHostRedirectUrl(t *testing.T) {
+	redirectUrl, ok := HostRedirectUrl("https://video-store.example.com/v1/auth/youtube/callback", "backup")
+	assert.True(t, ok)
+	assert.Equal(t, "https://video-store.example.com/v1/hosts/backup/auth/youtube/callback", redirectUrl)
+
+	_, ok = HostRedirectUrl("https://video-store.example.com/oauth/callback", "backup")
+	assert.False(t, ok)
+}
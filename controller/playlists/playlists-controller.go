@@ -3,7 +3,9 @@ package playlists_controller
 import (
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"video-manager/internal/etag"
 	object_storage "video-manager/internal/object-storage"
+	"video-manager/internal/problem"
 	progress_broker "video-manager/internal/progress-broker"
 	video_hosting "video-manager/internal/video-hosting"
 	video_store_service "video-manager/pkg/video-store-service"
@@ -21,23 +23,24 @@ type PlaylistController[B object_storage.BindingProxy, P progress_broker.PubSubP
 // @Produce      json
 // @Param 		 meta body video_hosting.ItemMetadata true "Required data to create a playlist"
 // @Success      200  {object}  video_hosting.Playlist
-// @Failure      400	"Required metata are wrong in some ways"
-// @Failure      500
+// @Failure      400  {object}  problem.Problem "Required metata are wrong in some ways"
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /playlists [post]
 func (vc *PlaylistController[S, P]) Create(c *gin.Context) {
 	var target video_hosting.ItemMetadata
-	if err := c.BindJSON(&target); err != nil {
-		c.String(http.StatusBadRequest, `invalid body provided: %s !`, err.Error())
+	if err := c.ShouldBindJSON(&target); err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
 		return
 	}
-	vid, err := vc.Service.VidHost.CreatePlaylist(&target)
+	vid, err := video_store_service.FromContext(c, vc.Service).CreatePlaylist(c, &target)
 	if err != nil {
-		if re, ok := err.(*video_hosting.RequestError); ok {
-			c.String(re.StatusCode, re.Error())
-		} else {
-			c.Status(http.StatusInternalServerError)
-			_ = c.Error(err)
-		}
+		problem.Abort(c, err)
 		return
 	}
 	c.SecureJSON(http.StatusOK, vid)
@@ -49,27 +52,35 @@ func (vc *PlaylistController[S, P]) Create(c *gin.Context) {
 // @Tags         playlists
 // @Produce      json
 // @Param        id   path      int  true  "Playlist ID"
+// @Param        If-None-Match  header  string  false  "ETag of the version of the playlist already known"
 // @Success      200  {object}  video_hosting.Playlist
-// @Failure      400
-// @Failure      404  {string}  string "No playlist with this ID"
-// @Failure      500
+// @Header       200  {string}  ETag  "Version of the playlist"
+// @Success      304  "The known version is still the current one"
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No playlist with this ID"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /playlists/{id} [get]
 func (vc *PlaylistController[S, P]) Retrieve(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.String(http.StatusBadRequest, `No id provided !`)
+		problem.AbortWith(c, problem.BadRequest, `No id provided !`)
 		return
 	}
-	playlist, err := vc.Service.VidHost.RetrievePlaylist(id)
+	playlist, err := video_store_service.FromContext(c, vc.Service).VidHost.RetrievePlaylist(c, id)
 	if err != nil {
-		if re, ok := err.(*video_hosting.RequestError); ok {
-			c.String(re.StatusCode, re.Error())
-		} else {
-			c.Status(http.StatusInternalServerError)
-			_ = c.Error(err)
-		}
+		problem.Abort(c, err)
 		return
 	}
+	if etag.NotModified(c, playlist.ETag) {
+		return
+	}
+	etag.Set(c, playlist.ETag)
 	c.SecureJSON(http.StatusOK, playlist)
 }
 
@@ -80,61 +91,127 @@ func (vc *PlaylistController[S, P]) Retrieve(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id   path      int  true  "Playlist ID"
+// @Param        If-Match  header  string  false  "Only change the playlist if it still has this ETag"
 // @Param 		 playlist body video_hosting.Playlist true "Updated playlist"
 // @Success      200 {object}  video_hosting.Playlist
-// @Failure      400
-// @Failure      404  {string}  string "No playlist with this ID"
-// @Failure      500
+// @Header       200  {string}  ETag  "Version of the playlist"
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No playlist with this ID"
+// @Failure      412  {object}  problem.Problem "The playlist changed since the version of If-Match"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /playlists/{id} [put]
 func (vc *PlaylistController[S, P]) Update(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.String(http.StatusBadRequest, `No id provided !`)
+		problem.AbortWith(c, problem.BadRequest, `No id provided !`)
 		return
 	}
 	var target video_hosting.Playlist
-	if err := c.BindJSON(&target); err != nil {
-		c.String(http.StatusBadRequest, `invalid body provided: %s !`, err.Error())
+	if err := c.ShouldBindJSON(&target); err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
+		return
+	}
+	svc := video_store_service.FromContext(c, vc.Service)
+	if err := svc.IfMatchPlaylist(c, id, etag.IfMatch(c)); err != nil {
+		problem.Abort(c, err)
 		return
 	}
-	vid, err := vc.Service.VidHost.UpdatePlaylist(id, &target)
+	vid, err := svc.UpdatePlaylist(c, id, &target)
 	if err != nil {
-		if re, ok := err.(*video_hosting.RequestError); ok {
-			c.String(re.StatusCode, re.Error())
-		} else {
-			c.Status(http.StatusInternalServerError)
-			_ = c.Error(err)
-		}
+		problem.Abort(c, err)
 		return
 	}
+	etag.Set(c, vid.ETag)
 	c.SecureJSON(http.StatusOK, vid)
 }
 
+// ShowAccount godoc
+// @Summary      Patch a playlist
+// @Description  Change some attributes of the playlist by ID with a JSON merge patch (RFC 7396), the others are left untouched.
+// @Description  Only the title, description and visibility can be patched, a null value resets the attribute
+// @Tags         playlists
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        id     path      int  true  "Playlist ID"
+// @Param        If-Match  header  string  false  "Only change the playlist if it still has this ETag"
+// @Param 		 patch  body      video_hosting.Playlist true "Attributes to change"
+// @Success      200 {object}  video_hosting.Playlist
+// @Header       200  {string}  ETag  "Version of the playlist"
+// @Failure      400  {object}  problem.Problem "Not a merge patch, read-only attributes patched or invalid patched attributes"
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No playlist with this ID"
+// @Failure      412  {object}  problem.Problem "The playlist changed since the version of If-Match"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /playlists/{id} [patch]
+func (vc *PlaylistController[S, P]) Patch(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		problem.AbortWith(c, problem.BadRequest, `No id provided !`)
+		return
+	}
+	if c.Request == nil || c.Request.Body == nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: no body !`)
+		return
+	}
+	data, err := c.GetRawData()
+	if err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
+		return
+	}
+	playlist, err := video_store_service.FromContext(c, vc.Service).PatchPlaylist(c, id, data, etag.IfMatch(c))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	etag.Set(c, playlist.ETag)
+	c.SecureJSON(http.StatusOK, playlist)
+}
+
 // ShowAccount godoc
 // @Summary      Delete a playlist
 // @Description  Delete the playlist by ID if it exists
 // @Tags         playlists
 // @Produce      json
 // @Param        id   path      int  true  "Playlist ID"
+// @Param        If-Match  header  string  false  "Only change the playlist if it still has this ETag"
 // @Success      204
-// @Failure      400
-// @Failure      404  {string}  string "No playlist with this ID"
-// @Failure      500
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No playlist with this ID"
+// @Failure      412  {object}  problem.Problem "The playlist changed since the version of If-Match"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /playlists/{id} [delete]
 func (vc *PlaylistController[S, P]) Delete(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.String(http.StatusBadRequest, `No id provided !`)
+		problem.AbortWith(c, problem.BadRequest, `No id provided !`)
+		return
+	}
+	svc := video_store_service.FromContext(c, vc.Service)
+	if err := svc.IfMatchPlaylist(c, id, etag.IfMatch(c)); err != nil {
+		problem.Abort(c, err)
 		return
 	}
-	err := vc.Service.VidHost.DeletePlaylist(id)
+	err := svc.DeletePlaylist(c, id)
 	if err != nil {
-		if re, ok := err.(*video_hosting.RequestError); ok {
-			c.String(re.StatusCode, re.Error())
-		} else {
-			c.Status(http.StatusInternalServerError)
-			_ = c.Error(err)
-		}
+		problem.Abort(c, err)
 		return
 	}
 	c.String(http.StatusNoContent, "")
@@ -148,29 +225,30 @@ func (vc *PlaylistController[S, P]) Delete(c *gin.Context) {
 // @Param        pid   path      int  true  "Playlist ID"
 // @Param        vid   path      int  true  "Video ID"
 // @Success      204
-// @Failure      400
-// @Failure      404  {string}  string "Either the playlist or video don't exists"
-// @Failure      500
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "Either the playlist or video don't exists"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /playlists/{pid}/videos/{vid} [put]
 func (vc *PlaylistController[S, P]) AddVideo(c *gin.Context) {
 	pId := c.Param("id")
 	if pId == "" {
-		c.String(http.StatusBadRequest, `No playlist id provided !`)
+		problem.AbortWith(c, problem.BadRequest, `No playlist id provided !`)
 		return
 	}
 	vId := c.Param("vid")
 	if vId == "" {
-		c.String(http.StatusBadRequest, `No video id provided !`)
+		problem.AbortWith(c, problem.BadRequest, `No video id provided !`)
 		return
 	}
-	err := vc.Service.VidHost.AddVideoToPlaylist(vId, pId)
+	err := video_store_service.FromContext(c, vc.Service).AddVideoToPlaylist(c, vId, pId)
 	if err != nil {
-		if re, ok := err.(*video_hosting.RequestError); ok {
-			c.String(re.StatusCode, re.Error())
-		} else {
-			c.Status(http.StatusInternalServerError)
-			_ = c.Error(err)
-		}
+		problem.Abort(c, err)
 		return
 	}
 	c.String(http.StatusNoContent, "")
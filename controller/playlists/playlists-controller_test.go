@@ -2,7 +2,6 @@ package playlists_controller
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
@@ -12,6 +11,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 	mock_object_storage "video-manager/internal/mock/object-storage"
@@ -45,14 +45,13 @@ var (
 )
 
 func Setup(t *testing.T) *mocked {
-	ctx := context.Background()
 	dir, err := os.MkdirTemp("", "assets")
 	if err != nil {
 		t.Fatal(err)
 	}
 	objStoreCtrl := gomock.NewController(t)
 	proxy := mock_object_storage.NewMockBindingProxy(objStoreCtrl)
-	objectStore := object_storage.NewObjectStorage[*mock_object_storage.MockBindingProxy](&ctx, dir, proxy)
+	objectStore := object_storage.NewObjectStorage[*mock_object_storage.MockBindingProxy](dir, proxy)
 	vidCtrl := gomock.NewController(t)
 	vidHost := mock_video_hosting.NewMockIVideoHost(vidCtrl)
 	vss := video_store_service.VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{
@@ -90,7 +89,7 @@ func Test_PlaylistController_Delete_Error_Deletion(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	// Setting the deletion to fail
-	deps.videoStore.EXPECT().DeletePlaylist(gomock.Any()).Return(fmt.Errorf("test"))
+	deps.videoStore.EXPECT().DeletePlaylist(gomock.Any(), gomock.Any()).Return(fmt.Errorf("test"))
 
 	deps.controller.Delete(c)
 	assert.Equal(t, 1, len(c.Errors))
@@ -102,7 +101,7 @@ func Test_PlaylistController_Delete_Error_NotFound(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	// Setting the deletion to fail
-	deps.videoStore.EXPECT().DeletePlaylist(gomock.Any()).Return(&video_hosting.RequestError{
+	deps.videoStore.EXPECT().DeletePlaylist(gomock.Any(), gomock.Any()).Return(&video_hosting.RequestError{
 		StatusCode: 404,
 		Err:        fmt.Errorf("not found"),
 	})
@@ -118,7 +117,7 @@ func Test_PlaylistController_Delete_Ok(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	// Setting the deletion to pass
-	deps.videoStore.EXPECT().DeletePlaylist(gomock.Any()).Return(nil)
+	deps.videoStore.EXPECT().DeletePlaylist(gomock.Any(), gomock.Any()).Return(nil)
 
 	deps.controller.Delete(c)
 	assert.Equal(t, 0, len(c.Errors))
@@ -155,7 +154,7 @@ func Test_PlaylistController_Update_Error_NotFound(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, samplePlaylist)
-	deps.videoStore.EXPECT().UpdatePlaylist(gomock.Any(), gomock.Any()).Return(nil, &video_hosting.RequestError{
+	deps.videoStore.EXPECT().UpdatePlaylist(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, &video_hosting.RequestError{
 		StatusCode: 404,
 		Err:        fmt.Errorf("not found"),
 	})
@@ -171,7 +170,7 @@ func Test_PlaylistController_Update_Error_Other(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, samplePlaylist)
-	deps.videoStore.EXPECT().UpdatePlaylist(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
+	deps.videoStore.EXPECT().UpdatePlaylist(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
 	// Testing with an empty ID
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	deps.controller.Update(c)
@@ -183,7 +182,7 @@ func Test_PlaylistController_Update_Ok(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, samplePlaylist)
-	deps.videoStore.EXPECT().UpdatePlaylist(gomock.Any(), gomock.Any()).Return(&samplePlaylist, nil)
+	deps.videoStore.EXPECT().UpdatePlaylist(gomock.Any(), gomock.Any(), gomock.Any()).Return(&samplePlaylist, nil)
 	// Testing with an empty ID
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	deps.controller.Update(c)
@@ -202,6 +201,75 @@ func Test_PlaylistController_Update_Ok(t *testing.T) {
 	assert.Equal(t, samplePlaylist, updatedPlaylist)
 }
 
+func Test_PlaylistController_Patch_Ok(t *testing.T) {
+	deps := Setup(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(`{"visibility": "public"}`))
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.videoStore.EXPECT().RetrievePlaylist(gomock.Any(), "testId").Return(&samplePlaylist, nil)
+	patched := samplePlaylist
+	patched.Visibility = video_hosting.Public
+	deps.videoStore.EXPECT().UpdatePlaylist(gomock.Any(), "testId", &patched).Return(&patched, nil)
+	deps.controller.Patch(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func Test_PlaylistController_Patch_Error_Payload(t *testing.T) {
+	deps := Setup(t)
+	for _, body := range []string{`not json`, `{"itemCount": 3}`, `{"title": 3}`} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(body))
+		c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+		deps.controller.Patch(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	// No body at all
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.controller.Patch(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func Test_PlaylistController_ETag(t *testing.T) {
+	deps := Setup(t)
+	tagged := samplePlaylist
+	tagged.ETag = "v1"
+	deps.videoStore.EXPECT().RetrievePlaylist(gomock.Any(), "testId").Return(&tagged, nil).AnyTimes()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("If-None-Match", `"v0"`)
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.controller.Retrieve(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+
+	// The new version is returned along with the update
+	updated := tagged
+	updated.Title, updated.ETag = "newTitle", "v2"
+	deps.videoStore.EXPECT().UpdatePlaylist(gomock.Any(), "testId", gomock.Any()).Return(&updated, nil)
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(`{"title": "newTitle"}`))
+	c.Request.Header.Set("If-Match", `"v1"`)
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.controller.Patch(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"v2"`, w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPatch, "/", bytes.NewBufferString(`{"title": "otherTitle"}`))
+	c.Request.Header.Set("If-Match", `"v0"`)
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.controller.Patch(c)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
 func Test_PlaylistController_Retrieve_Error_Id(t *testing.T) {
 	deps := Setup(t)
 	w := httptest.NewRecorder()
@@ -223,7 +291,7 @@ func Test_PlaylistController_Retrieve_Error_NotFound(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, samplePlaylist)
-	deps.videoStore.EXPECT().RetrievePlaylist(gomock.Any()).Return(nil, &video_hosting.RequestError{
+	deps.videoStore.EXPECT().RetrievePlaylist(gomock.Any(), gomock.Any()).Return(nil, &video_hosting.RequestError{
 		StatusCode: 404,
 		Err:        fmt.Errorf("not found"),
 	})
@@ -238,7 +306,7 @@ func Test_PlaylistController_Retrieve_Error_Other(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, samplePlaylist)
-	deps.videoStore.EXPECT().RetrievePlaylist(gomock.Any()).Return(nil, fmt.Errorf("test"))
+	deps.videoStore.EXPECT().RetrievePlaylist(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
 	// Testing with an empty ID
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	deps.controller.Retrieve(c)
@@ -250,7 +318,7 @@ func Test_PlaylistController_Retrieve_Ok(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, samplePlaylist)
-	deps.videoStore.EXPECT().RetrievePlaylist(gomock.Any()).Return(&samplePlaylist, nil)
+	deps.videoStore.EXPECT().RetrievePlaylist(gomock.Any(), gomock.Any()).Return(&samplePlaylist, nil)
 	// Testing with an empty ID
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	deps.controller.Retrieve(c)
@@ -295,6 +363,25 @@ func Test_PlaylistController_Create_Error_Payload_NoBody(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func Test_PlaylistController_Create_TitleLength(t *testing.T) {
+	deps := Setup(t)
+	deps.controller.Service.HostMetadata = video_hosting.YoutubeVideoStore{}
+	// Youtube playlists titles are longer than the videos ones
+	deps.videoStore.EXPECT().CreatePlaylist(gomock.Any(), gomock.Any()).Return(&samplePlaylist, nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	setJsonAsBody(t, c, video_hosting.ItemMetadata{Title: strings.Repeat("a", 150), Visibility: "unlisted"})
+	deps.controller.Create(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Refused by the host
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	setJsonAsBody(t, c, video_hosting.ItemMetadata{Title: strings.Repeat("a", 151), Visibility: "unlisted"})
+	deps.controller.Create(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 // Set the payload as the JSON body of c
 func setJsonAsBody(t *testing.T, c *gin.Context, payload any) {
 	buf, err := json.Marshal(payload)
@@ -0,0 +1,52 @@
+package publish_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	object_storage "video-manager/internal/object-storage"
+	"video-manager/internal/problem"
+	progress_broker "video-manager/internal/progress-broker"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+type PublishController[B object_storage.BindingProxy, P progress_broker.PubSubProxy] struct {
+	Service *video_store_service.VideoStoreService[B, P]
+}
+
+// PublishBody POST body required to publish a recording from its manifest
+type PublishBody struct {
+	// Key to retrieve the manifest from the object storage
+	ManifestKey string `json:"manifestKey" binding:"required" example:"recordings/1234/manifest.yaml"`
+}
+
+// ShowAccount godoc
+// @Summary      Publish a recording
+// @Description  Upload a recording and apply all its settings (tags, thumbnail, captions, playlists, schedule),
+// @Description  as described by a JSON or YAML manifest stored on the object storage.
+// @Description  Once the manifest is validated, the outcome of each step is returned, even if one failed
+// @Tags         publish
+// @Accept       json
+// @Produce      json
+// @Param 		 publish body PublishBody true "Manifest to publish"
+// @Success      200  {object}  video_store_service.PublishReport
+// @Failure      400  {object}  problem.Problem "Invalid manifest"
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      503  {object}  problem.Problem "The manifest couldn't be retrieved from the object storage"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /publish [post]
+func (pc *PublishController[B, P]) Publish(c *gin.Context) {
+	var body PublishBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
+		return
+	}
+	report, err := video_store_service.FromContext(c, pc.Service).Publish(c, body.ManifestKey)
+	// The manifest itself couldn't be used, nothing was published
+	if report == nil {
+		problem.Abort(c, err)
+		return
+	}
+	c.SecureJSON(http.StatusOK, report)
+}
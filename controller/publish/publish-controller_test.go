@@ -0,0 +1,123 @@
+package publish_controller
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/dapr/go-sdk/client"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	mock_object_storage "video-manager/internal/mock/object-storage"
+	mock_progress_broker "video-manager/internal/mock/progress-broker"
+	mock_video_hosting "video-manager/internal/mock/video-hosting"
+	object_storage "video-manager/internal/object-storage"
+	"video-manager/internal/problem"
+	video_hosting "video-manager/internal/video-hosting"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+type mocked struct {
+	videoStore       *mock_video_hosting.MockIVideoHost
+	objectStoreProxy *mock_object_storage.MockBindingProxy
+	controller       *PublishController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]
+}
+
+func Setup(t *testing.T) *mocked {
+	dir, err := os.MkdirTemp("", "assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := gomock.NewController(t)
+	objStoreProxy := mock_object_storage.NewMockBindingProxy(ctrl)
+	objectStore := object_storage.NewObjectStorage[*mock_object_storage.MockBindingProxy](dir, objStoreProxy)
+	vidHost := mock_video_hosting.NewMockIVideoHost(ctrl)
+	vss := video_store_service.VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{
+		ObjStore: objectStore,
+		VidHost:  vidHost,
+	}
+	gin.SetMode(gin.TestMode)
+	return &mocked{
+		videoStore:       vidHost,
+		objectStoreProxy: objStoreProxy,
+		controller:       &PublishController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{Service: &vss},
+	}
+}
+
+// Serve the manifest, and then the recording
+func serveManifest(deps *mocked, manifest string) {
+	gomock.InOrder(
+		deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).
+			Return(&client.BindingEvent{Data: []byte(base64.StdEncoding.EncodeToString([]byte(manifest)))}, nil),
+		deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("aa")}, nil).AnyTimes(),
+	)
+}
+
+func publish(deps *mocked, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/publish", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	deps.controller.Publish(c)
+	return w
+}
+
+func TestPublishController_Publish(t *testing.T) {
+	deps := Setup(t)
+	serveManifest(deps, "title: Session 1\nvisibility: public\nstorageKey: video.mp4\ntags: [rpg]\n")
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{Id: "vid"}, nil)
+	deps.videoStore.EXPECT().SetVideoTags(gomock.Any(), "vid", []string{"rpg"}).Return(nil)
+
+	w := publish(deps, `{"manifestKey": "manifest.yaml"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report video_store_service.PublishReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, report.Succeeded)
+	assert.Equal(t, "vid", report.Video.Id)
+	assert.Len(t, report.Steps, 2)
+}
+
+func TestPublishController_Publish_StepFailed(t *testing.T) {
+	deps := Setup(t)
+	serveManifest(deps, "title: Session 1\nvisibility: public\nstorageKey: video.mp4\ntags: [rpg]\n")
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{Id: "vid"}, nil)
+	deps.videoStore.EXPECT().SetVideoTags(gomock.Any(), "vid", gomock.Any()).
+		Return(video_hosting.NewRequestError(video_hosting.InvalidMetadata, fmt.Errorf("invalid tags")))
+
+	// The video is already uploaded, the report tells what was done
+	w := publish(deps, `{"manifestKey": "manifest.yaml"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report video_store_service.PublishReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, report.Succeeded)
+	assert.Equal(t, video_store_service.StepFailed, report.Steps[1].Status)
+}
+
+func TestPublishController_Publish_Invalid(t *testing.T) {
+	deps := Setup(t)
+	w := publish(deps, `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).
+		Return(&client.BindingEvent{Data: []byte(base64.StdEncoding.EncodeToString([]byte("title: [")))}, nil)
+	w = publish(deps, `{"manifestKey": "manifest.yaml"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var p problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, problem.InvalidMetadata, p.Type)
+
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("no such key"))
+	w = publish(deps, `{"manifestKey": "manifest.yaml"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,35 @@
+package quota_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	object_storage "video-manager/internal/object-storage"
+	"video-manager/internal/problem"
+	progress_broker "video-manager/internal/progress-broker"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+type QuotaController[B object_storage.BindingProxy, P progress_broker.PubSubProxy] struct {
+	Service *video_store_service.VideoStoreService[B, P]
+}
+
+// ShowAccount godoc
+// @Summary      Get the quota consumption
+// @Description  Retrieve the consumption of the daily quota of the video hosting platform
+// @Tags         quota
+// @Produce      json
+// @Success      200  {object}  video_hosting.QuotaStatus
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "The hosting platform has no quota"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /quota [get]
+func (qc *QuotaController[B, P]) Retrieve(c *gin.Context) {
+	quota := video_store_service.FromContext(c, qc.Service).Quota
+	if quota == nil {
+		problem.AbortWith(c, problem.NotFound, `The video hosting platform has no quota !`)
+		return
+	}
+	c.SecureJSON(http.StatusOK, quota.Status())
+}
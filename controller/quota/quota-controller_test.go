@@ -0,0 +1,70 @@
+package quota_controller
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	mock_object_storage "video-manager/internal/mock/object-storage"
+	mock_progress_broker "video-manager/internal/mock/progress-broker"
+	mock_video_hosting "video-manager/internal/mock/video-hosting"
+	video_hosting "video-manager/internal/video-hosting"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+type mocked struct {
+	videoStore *mock_video_hosting.MockIVideoHost
+	controller *QuotaController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]
+}
+
+func Setup(t *testing.T, withQuota bool) *mocked {
+	ctrl := gomock.NewController(t)
+	vidHost := mock_video_hosting.NewMockIVideoHost(ctrl)
+	vss := video_store_service.VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{
+		VidHost: vidHost,
+	}
+	if withQuota {
+		quota, err := video_hosting.NewQuotaGuard(vidHost, video_hosting.YoutubeQuotaCosts, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vss.VidHost = quota
+		vss.Quota = quota
+	}
+	gin.SetMode(gin.TestMode)
+	return &mocked{
+		videoStore: vidHost,
+		controller: &QuotaController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{Service: &vss},
+	}
+}
+
+func TestQuotaController_Retrieve_Ok(t *testing.T) {
+	deps := Setup(t, true)
+	deps.videoStore.EXPECT().DeleteVideo(gomock.Any(), "1").Return(nil)
+	err := deps.controller.Service.DeleteVideo(context.Background(), "1")
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.controller.Retrieve(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var status video_hosting.QuotaStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(100), status.Limit)
+	assert.Equal(t, video_hosting.YoutubeQuotaCosts.DeleteVideo, status.Used)
+	assert.Equal(t, 100-video_hosting.YoutubeQuotaCosts.DeleteVideo, status.Remaining)
+}
+
+func TestQuotaController_Retrieve_NoQuota(t *testing.T) {
+	deps := Setup(t, false)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.controller.Retrieve(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
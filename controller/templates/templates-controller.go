@@ -0,0 +1,52 @@
+package templates_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	object_storage "video-manager/internal/object-storage"
+	"video-manager/internal/problem"
+	progress_broker "video-manager/internal/progress-broker"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+type TemplatesController[B object_storage.BindingProxy, P progress_broker.PubSubProxy] struct {
+	Service *video_store_service.VideoStoreService[B, P]
+}
+
+// RenderBody POST body required to preview a template
+type RenderBody struct {
+	// Values the template is rendered with
+	Variables map[string]any `json:"variables"`
+}
+
+// ShowAccount godoc
+// @Summary      Preview a template
+// @Description  Render the title and description of a metadata template, as they would be uploaded.
+// @Description  The result is checked against the limits of the video metadata
+// @Tags         templates
+// @Accept       json
+// @Produce      json
+// @Param        name       path      string      true  "Template name"
+// @Param 		 variables  body      RenderBody  true  "Values to render the template with"
+// @Success      200  {object}  templates.Rendered
+// @Failure      400  {object}  problem.Problem "The template can't be rendered with these variables"
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No template with this name"
+// @Failure      500  {object}  problem.Problem
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /templates/{name}/render [post]
+func (tc *TemplatesController[B, P]) Render(c *gin.Context) {
+	var body RenderBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
+		return
+	}
+	rendered, err := video_store_service.FromContext(c, tc.Service).RenderTemplate(c, c.Param("name"), body.Variables)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	c.SecureJSON(http.StatusOK, rendered)
+}
@@ -0,0 +1,77 @@
+package templates_controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	mock_client "video-manager/internal/mock/dapr"
+	mock_object_storage "video-manager/internal/mock/object-storage"
+	mock_progress_broker "video-manager/internal/mock/progress-broker"
+	"video-manager/internal/problem"
+	"video-manager/internal/templates"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+func Setup(defined map[string]templates.Template) *TemplatesController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy] {
+	vss := video_store_service.VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{}
+	if defined != nil {
+		vss.Templates = templates.NewTemplates[*mock_client.MockClient](defined, "", nil)
+	}
+	gin.SetMode(gin.TestMode)
+	return &TemplatesController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{Service: &vss}
+}
+
+func render(ctrl *TemplatesController[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy], name string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/templates/"+name+"/render", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "name", Value: name}}
+	ctrl.Render(c)
+	return w
+}
+
+func TestTemplatesController_Render(t *testing.T) {
+	ctrl := Setup(map[string]templates.Template{
+		"session": {Title: "Session {{.Number}} – {{.Campaign}}", Description: "Recorded on {{.Date}}"},
+	})
+	w := render(ctrl, "session", `{"variables": {"Number": 12, "Campaign": "Dragons", "Date": "2023-04-01"}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rendered templates.Rendered
+	if err := json.Unmarshal(w.Body.Bytes(), &rendered); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Session 12 – Dragons", rendered.Title)
+	assert.Equal(t, "Recorded on 2023-04-01", rendered.Description)
+}
+
+func TestTemplatesController_Render_Invalid(t *testing.T) {
+	ctrl := Setup(map[string]templates.Template{"session": {Title: "{{.Title}}"}})
+	// Missing variable
+	w := render(ctrl, "session", `{"variables": {}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var p problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, problem.InvalidMetadata, p.Type)
+	// Rendered title over the limits
+	w = render(ctrl, "session", `{"variables": {"Title": "`+strings.Repeat("a", 101)+`"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	// Not a JSON body
+	w = render(ctrl, "session", `variables`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTemplatesController_Render_NotFound(t *testing.T) {
+	w := render(Setup(map[string]templates.Template{}), "none", `{}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	// Without any template at all
+	w = render(Setup(nil), "none", `{}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
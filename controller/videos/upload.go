@@ -0,0 +1,191 @@
+package videos_controller
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"video-manager/internal/problem"
+	video_hosting "video-manager/internal/video-hosting"
+	video_store_service "video-manager/pkg/video-store-service"
+)
+
+// Media type of an uploaded video of unknown format
+const octetStream = "application/octet-stream"
+
+// UploadVideoBody Metadata of a video uploaded with the request itself
+type UploadVideoBody struct {
+	// Required metadata to upload a video
+	video_hosting.ItemMetadata
+	// UUID of this uploading job, necessary to tell the jobs apart
+	// when multiple are running concurrently
+	JobId string `json:"jobId" binding:"required"`
+	// Name of a metadata template. If set, the title and description are rendered from it
+	Template string `json:"template" example:"session"`
+	// Values the template is rendered with
+	Variables map[string]any `json:"variables"`
+}
+
+// ShowAccount godoc
+// @Summary      Upload a video directly
+// @Description  Upload a video sent with the request to the video hosting platform, without going through the object storage.
+// @Description  The video is streamed to the hosting platform as it is received, and handled as any other upload job.
+// @Description  As multipart/form-data, a "metadata" part holding an UploadVideoBody as JSON must come before the "file" part.
+// @Description  Otherwise, the body is the video itself, and its metadata are given in the query.
+// @Description  Either way, the video must be sent as video/* or application/octet-stream, and fit within DAPR_MAX_REQUEST_SIZE_MB
+// @Tags         videos
+// @Accept       mpfd
+// @Accept       octet-stream
+// @Produce      json
+// @Param        metadata     formData  string  false  "UploadVideoBody as JSON, for a multipart/form-data body"
+// @Param        file         formData  file    false  "The video, for a multipart/form-data body"
+// @Param        jobId        query     string  false  "UUID of this uploading job, for a raw body"
+// @Param        title        query     string  false  "Title of the video, for a raw body"
+// @Param        description  query     string  false  "Description of the video, for a raw body"
+// @Param        visibility   query     string  false  "Visibility of the video, for a raw body"  Enums(public, unlisted, private)
+// @Success      200  {object}  video_hosting.Video
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No template with this name"
+// @Failure      413  {object}  problem.Problem "The video is too large"
+// @Failure      415  {object}  problem.Problem "The file is not a video"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /videos/upload [post]
+func (vc *VideoController[S, P]) Upload(c *gin.Context) {
+	if c.Request == nil || c.Request.Body == nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: no body !`)
+		return
+	}
+	body := &limitedBody{ReadCloser: c.Request.Body}
+	if vc.MaxUploadSize > 0 {
+		if c.Request.ContentLength > vc.MaxUploadSize {
+			vc.abortTooLarge(c)
+			return
+		}
+		body.ReadCloser = http.MaxBytesReader(c.Writer, c.Request.Body, vc.MaxUploadSize)
+	}
+	c.Request.Body = body
+
+	var target UploadVideoBody
+	var content io.Reader
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	switch {
+	case mediaType == "multipart/form-data":
+		var ok bool
+		if content, ok = vc.readMultipart(c, body, &target); !ok {
+			return
+		}
+	case isVideo(mediaType):
+		target = UploadVideoBody{
+			ItemMetadata: video_hosting.ItemMetadata{
+				Title:       c.Query("title"),
+				Description: c.Query("description"),
+				Visibility:  video_hosting.Visibility(c.Query("visibility")),
+			},
+			JobId: c.Query("jobId"),
+		}
+		content = body
+	default:
+		problem.AbortWith(c, problem.UnsupportedMediaType, `a video must be sent as multipart/form-data, video/* or %s, not "%s"`, octetStream, mediaType)
+		return
+	}
+
+	if !vc.applyTemplate(c, target.Template, target.Variables, &target.ItemMetadata) {
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&target); err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid metadata provided: %s !`, err.Error())
+		return
+	}
+	vid, err := video_store_service.FromContext(c, vc.Service).UploadVideo(c, target.JobId, content, &video_hosting.ItemMetadata{
+		Description: target.Description,
+		Title:       target.Title,
+		Visibility:  target.Visibility,
+	})
+	if body.exceeded {
+		vc.abortTooLarge(c)
+		return
+	}
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	c.SecureJSON(http.StatusOK, vid)
+}
+
+// Decode the metadata part of a multipart/form-data body into target, and return the file part following it.
+// Returns false if the body is invalid, the response being already sent
+func (vc *VideoController[S, P]) readMultipart(c *gin.Context, body *limitedBody, target *UploadVideoBody) (io.Reader, bool) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
+		return nil, false
+	}
+	// The file is streamed as it is received, its metadata can't come after it
+	part, err := reader.NextPart()
+	if err == nil && part.FormName() != "metadata" {
+		err = errors.New(`the "metadata" part must come first`)
+	}
+	if err == nil {
+		err = json.NewDecoder(part).Decode(target)
+	}
+	if err == nil {
+		part, err = reader.NextPart()
+	}
+	if err == nil && part.FormName() != "file" {
+		err = errors.New(`the "file" part must follow the "metadata" part`)
+	}
+	if body.exceeded {
+		vc.abortTooLarge(c)
+		return nil, false
+	}
+	if err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
+		return nil, false
+	}
+	// A file part without a media type is an octet stream
+	// https://www.rfc-editor.org/rfc/rfc7578#section-4.4
+	mediaType := octetStream
+	if header := part.Header.Get("Content-Type"); header != "" {
+		mediaType, _, _ = mime.ParseMediaType(header)
+	}
+	if !isVideo(mediaType) {
+		problem.AbortWith(c, problem.UnsupportedMediaType, `the file must be sent as video/* or %s, not "%s"`, octetStream, mediaType)
+		return nil, false
+	}
+	return part, true
+}
+
+// Refuse a video larger than MaxUploadSize
+func (vc *VideoController[S, P]) abortTooLarge(c *gin.Context) {
+	problem.AbortWith(c, problem.PayloadTooLarge, `the video can't be larger than %d bytes`, vc.MaxUploadSize)
+}
+
+// Whether a body of this media type may be a video
+func isVideo(mediaType string) bool {
+	return mediaType == octetStream || strings.HasPrefix(mediaType, "video/")
+}
+
+// Request body remembering whether it was read past the size limit
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	n, err := lb.ReadCloser.Read(p)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		lb.exceeded = true
+	}
+	return n, err
+}
@@ -0,0 +1,231 @@
+package videos_controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	mock_client "video-manager/internal/mock/dapr"
+	"video-manager/internal/problem"
+	"video-manager/internal/templates"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// A part of a multipart/form-data body
+type formPart struct {
+	name        string
+	contentType string
+	content     string
+}
+
+// Set a multipart/form-data body made of parts, in order
+func setMultipartAsBody(t *testing.T, c *gin.Context, parts ...formPart) {
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+	for _, p := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, p.name, p.name))
+		if p.contentType != "" {
+			header.Set("Content-Type", p.contentType)
+		}
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write([]byte(p.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	c.Request, _ = http.NewRequest("POST", "/", buf)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+}
+
+func metadataPart(t *testing.T, body UploadVideoBody) formPart {
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return formPart{name: "metadata", contentType: "application/json", content: string(data)}
+}
+
+// Expect the video host to receive content, and the metadata checked by assertMeta
+func expectUpload(t *testing.T, deps *mocked, content string, assertMeta func(meta *video_hosting.ItemMetadata)) {
+	deps.videoStore.
+		EXPECT().
+		CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, meta *video_hosting.ItemMetadata, reader io.Reader, _ any) (*video_hosting.Video, error) {
+			data, err := io.ReadAll(reader)
+			assert.Nil(t, err)
+			assert.Equal(t, content, string(data))
+			assertMeta(meta)
+			return &sampleVid, nil
+		})
+}
+
+func Test_VideoController_Upload_Multipart_Ok(t *testing.T) {
+	deps := Setup(t, false)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	expectUpload(t, deps, "video content", func(meta *video_hosting.ItemMetadata) {
+		assert.Equal(t, sampleMetadata, *meta)
+	})
+	setMultipartAsBody(t, c,
+		metadataPart(t, UploadVideoBody{ItemMetadata: sampleMetadata, JobId: "test"}),
+		formPart{name: "file", contentType: "video/mp4", content: "video content"},
+	)
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func Test_VideoController_Upload_Multipart_Template(t *testing.T) {
+	deps := Setup(t, false)
+	deps.controller.Service.Templates = templates.NewTemplates[*mock_client.MockClient](map[string]templates.Template{
+		"session": {Title: "Session {{.Number}}", Description: "{{.Summary}}"},
+	}, "", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	expectUpload(t, deps, "video content", func(meta *video_hosting.ItemMetadata) {
+		assert.Equal(t, "Session 12", meta.Title)
+		assert.Equal(t, "The party meets a dragon", meta.Description)
+	})
+	setMultipartAsBody(t, c,
+		metadataPart(t, UploadVideoBody{
+			ItemMetadata: video_hosting.ItemMetadata{Visibility: "unlisted"},
+			JobId:        "test",
+			Template:     "session",
+			Variables:    map[string]any{"Number": 12, "Summary": "The party meets a dragon"},
+		}),
+		// Without a media type, the file is an octet stream
+		formPart{name: "file", content: "video content"},
+	)
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func Test_VideoController_Upload_Multipart_Error_PartsOrder(t *testing.T) {
+	deps := Setup(t, false)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	// The metadata can't be known before the file is sent
+	setMultipartAsBody(t, c,
+		formPart{name: "file", contentType: "video/mp4", content: "video content"},
+		metadataPart(t, UploadVideoBody{ItemMetadata: sampleMetadata, JobId: "test"}),
+	)
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Nor can a file be missing
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	setMultipartAsBody(t, c, metadataPart(t, UploadVideoBody{ItemMetadata: sampleMetadata, JobId: "test"}))
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func Test_VideoController_Upload_Multipart_Error_Metadata(t *testing.T) {
+	deps := Setup(t, false)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	// No job ID
+	setMultipartAsBody(t, c,
+		metadataPart(t, UploadVideoBody{ItemMetadata: sampleMetadata}),
+		formPart{name: "file", contentType: "video/mp4", content: "video content"},
+	)
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func Test_VideoController_Upload_Multipart_Error_NotAVideo(t *testing.T) {
+	deps := Setup(t, false)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	setMultipartAsBody(t, c,
+		metadataPart(t, UploadVideoBody{ItemMetadata: sampleMetadata, JobId: "test"}),
+		formPart{name: "file", contentType: "image/png", content: "image content"},
+	)
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	var p problem.Problem
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, problem.UnsupportedMediaType, p.Type)
+}
+
+func Test_VideoController_Upload_Raw_Ok(t *testing.T) {
+	deps := Setup(t, false)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	expectUpload(t, deps, "video content", func(meta *video_hosting.ItemMetadata) {
+		assert.Equal(t, sampleMetadata, *meta)
+	})
+	c.Request, _ = http.NewRequest("POST", "/?jobId=test&title=testTitle&description=testDescription&visibility=unlisted", strings.NewReader("video content"))
+	c.Request.Header.Set("Content-Type", "video/mp4")
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func Test_VideoController_Upload_Raw_Error(t *testing.T) {
+	deps := Setup(t, false)
+	// Not a video
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/?jobId=test&title=testTitle&visibility=unlisted", strings.NewReader("{}"))
+	c.Request.Header.Set("Content-Type", "application/json")
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	// No job ID
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/?title=testTitle&visibility=unlisted", strings.NewReader("video content"))
+	c.Request.Header.Set("Content-Type", "application/octet-stream")
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// No body at all
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func Test_VideoController_Upload_Error_TooLarge(t *testing.T) {
+	deps := Setup(t, false)
+	deps.controller.MaxUploadSize = 4
+	// Refused upfront when the length is known
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/?jobId=test&title=testTitle&visibility=unlisted", strings.NewReader("video content"))
+	c.Request.Header.Set("Content-Type", "video/mp4")
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// Or once the limit is reached otherwise
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	deps.videoStore.
+		EXPECT().
+		CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, _ any, reader io.Reader, _ any) (*video_hosting.Video, error) {
+			_, err := io.ReadAll(reader)
+			return nil, err
+		})
+	c.Request, _ = http.NewRequest("POST", "/?jobId=test&title=testTitle&visibility=unlisted", io.MultiReader(strings.NewReader("video content")))
+	c.Request.Header.Set("Content-Type", "video/mp4")
+	deps.controller.Upload(c)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	var p problem.Problem
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, problem.PayloadTooLarge, p.Type)
+}
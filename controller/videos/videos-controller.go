@@ -1,9 +1,14 @@
 package videos_controller
 
 import (
+	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"net/http"
+	"strings"
+	"video-manager/internal/etag"
 	object_storage "video-manager/internal/object-storage"
+	"video-manager/internal/problem"
 	progress_broker "video-manager/internal/progress-broker"
 	video_hosting "video-manager/internal/video-hosting"
 	video_store_service "video-manager/pkg/video-store-service"
@@ -11,6 +16,8 @@ import (
 
 type VideoController[B object_storage.BindingProxy, P progress_broker.PubSubProxy] struct {
 	Service *video_store_service.VideoStoreService[B, P]
+	// Largest video accepted by Upload, in bytes. No limit if 0
+	MaxUploadSize int64
 }
 
 // POST body required to create a new video on the hosting platform
@@ -23,77 +30,228 @@ type CreateVideoBody struct {
 	// UUID of this uploading job, necessary to tell the jobs apart
 	// when multiple are running concurrently
 	JobId string `json:"jobId" binding:"required"`
+	// Name of a metadata template. If set, the title and description are rendered from it
+	Template string `json:"template" example:"session"`
+	// Values the template is rendered with
+	Variables map[string]any `json:"variables"`
 }
 
 // ShowAccount godoc
 // @Summary      Upload a video
-// @Description  Upload a video from the object storage to the video hosting platform
+// @Description  Upload a video from the object storage to the video hosting platform.
+// @Description  With a template, the title and description are rendered from it and the variables instead
 // @Tags         videos
 // @Accept       json
 // @Produce      json
 // @Param 		 videometa body CreateVideoBody true "Required data to upload a video"
 // @Success      200  {object}  video_hosting.Video
-// @Failure      400
-// @Failure      404  {string}  string "No video with this ID"
-// @Failure      500
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No template with this name"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /videos [post]
 func (vc *VideoController[S, P]) Create(c *gin.Context) {
 	var target CreateVideoBody
-	if err := c.BindJSON(&target); err != nil {
-		c.String(http.StatusBadRequest, `invalid body provided: %s !`, err.Error())
+	// The title may come from a template, the body can only be validated once it's rendered
+	if c.Request == nil || c.Request.Body == nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: no body !`)
+		return
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&target); err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
+		return
+	}
+	if !vc.applyTemplate(c, target.Template, target.Variables, &target.ItemMetadata) {
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&target); err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
 		return
 	}
 	if target.StorageKey == "" {
-		c.String(http.StatusBadRequest, `No storage key provided, aborting !`)
+		problem.AbortWith(c, problem.BadRequest, `No storage key provided, aborting !`)
 		return
 	}
-	vid, err := vc.Service.UploadVideoFromStorage(target.JobId, target.StorageKey, &video_hosting.ItemMetadata{
+	vid, err := video_store_service.FromContext(c, vc.Service).UploadVideoFromStorage(c, target.JobId, target.StorageKey, &video_hosting.ItemMetadata{
 		Description: target.Description,
 		Title:       target.Title,
 		Visibility:  target.Visibility,
 	})
 	if err != nil {
-		if re, ok := err.(*video_hosting.RequestError); ok {
-			c.String(re.StatusCode, re.Error())
-		} else {
-			c.Status(http.StatusInternalServerError)
-			_ = c.Error(err)
-		}
+		problem.Abort(c, err)
 		return
 	}
 	c.SecureJSON(http.StatusOK, vid)
 }
 
+// Render the title and description of meta from the template "name", if any.
+// Returns false if the template couldn't be rendered, the response being already sent
+func (vc *VideoController[S, P]) applyTemplate(c *gin.Context, name string, variables map[string]any, meta *video_hosting.ItemMetadata) bool {
+	if name == "" {
+		return true
+	}
+	rendered, err := video_store_service.FromContext(c, vc.Service).RenderTemplate(c, name, variables)
+	if err != nil {
+		problem.Abort(c, err)
+		return false
+	}
+	meta.Title, meta.Description = rendered.Title, rendered.Description
+	return true
+}
+
 // ShowAccount godoc
 // @Summary      Get a video
-// @Description  Retrieve a video by ID
+// @Description  Retrieve a video by ID. Its statistics are only included with include=statistics
 // @Tags         videos
 // @Produce      json
-// @Param        id   path      int  true  "Video ID"
+// @Param        id       path      int     true   "Video ID"
+// @Param        If-None-Match  header  string  false  "ETag of the version of the video already known"
+// @Param        include  query     string  false  "Optional parts to include, comma separated"  Enums(statistics)
 // @Success      200  {object}  video_hosting.Video
-// @Failure      400
-// @Failure      404  {string}  string "No video with this ID"
-// @Failure      500
+// @Header       200  {string}  ETag  "Version of the video"
+// @Success      304  "The known version is still the current one"
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No video with this ID"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /videos/{id} [get]
 func (vc *VideoController[S, P]) Retrieve(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.String(http.StatusBadRequest, `No id provided !`)
+		problem.AbortWith(c, problem.BadRequest, `No id provided !`)
 		return
 	}
-	vid, err := vc.Service.VidHost.RetrieveVideo(id)
+	vid, err := video_store_service.FromContext(c, vc.Service).VidHost.RetrieveVideo(c, id)
 	if err != nil {
-		if re, ok := err.(*video_hosting.RequestError); ok {
-			c.String(re.StatusCode, re.Error())
-		} else {
-			c.Status(http.StatusInternalServerError)
-			_ = c.Error(err)
-		}
+		problem.Abort(c, err)
+		return
+	}
+	// The version of the video doesn't cover the statistics, a response including them is versioned on its own
+	tag := vid.ETag
+	if includes(c, "statistics") {
+		tag = etag.Variant(tag, "statistics", vid.Statistics)
+	} else {
+		vid.Statistics = nil
+	}
+	if etag.NotModified(c, tag) {
 		return
 	}
+	etag.Set(c, tag)
 	c.SecureJSON(http.StatusOK, vid)
 }
 
+// ShowAccount godoc
+// @Summary      Get the statistics of a video
+// @Description  Retrieve the view, like, comment and favorite counts of a video
+// @Tags         videos
+// @Produce      json
+// @Param        id   path      int  true  "Video ID"
+// @Success      200  {object}  video_hosting.Statistics
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No video with this ID, or the host doesn't count its audience"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /videos/{id}/stats [get]
+func (vc *VideoController[S, P]) Stats(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		problem.AbortWith(c, problem.BadRequest, `No id provided !`)
+		return
+	}
+	vid, err := video_store_service.FromContext(c, vc.Service).VidHost.RetrieveVideo(c, id)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	if vid.Statistics == nil {
+		problem.AbortWith(c, problem.NotFound, `No statistics are available for video "%s" !`, id)
+		return
+	}
+	c.SecureJSON(http.StatusOK, vid.Statistics)
+}
+
+// ShowAccount godoc
+// @Summary      Get the statistics of several videos
+// @Description  Retrieve the statistics of up to 50 videos at once, with a single call to the hosting platform.
+// @Description  The statistics are keyed by video ID, videos that don't exist are left out
+// @Tags         videos
+// @Produce      json
+// @Param        ids  query     string  true  "Video IDs, comma separated"
+// @Success      200  {object}  map[string]video_hosting.Statistics
+// @Failure      400  {object}  problem.Problem "No ID, or more than 50"
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /stats [get]
+func (vc *VideoController[S, P]) ListStats(c *gin.Context) {
+	ids := queryList(c, "ids")
+	if len(ids) == 0 {
+		problem.AbortWith(c, problem.BadRequest, `No ids provided !`)
+		return
+	}
+	if len(ids) > video_hosting.MaxListIds {
+		problem.AbortWith(c, problem.BadRequest, `At most %d ids can be provided, got %d !`, video_hosting.MaxListIds, len(ids))
+		return
+	}
+	videos, err := video_store_service.FromContext(c, vc.Service).VidHost.ListVideos(c, ids)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	stats := make(map[string]*video_hosting.Statistics, len(videos))
+	for _, vid := range videos {
+		if vid.Statistics != nil {
+			stats[vid.Id] = vid.Statistics
+		}
+	}
+	c.SecureJSON(http.StatusOK, stats)
+}
+
+// Whether the "include" query parameter lists part
+func includes(c *gin.Context, part string) bool {
+	for _, included := range queryList(c, "include") {
+		if included == part {
+			return true
+		}
+	}
+	return false
+}
+
+// The distinct values of the query parameter key, either repeated or comma separated
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	seen := map[string]bool{}
+	for _, param := range c.QueryArray(key) {
+		for _, value := range strings.Split(param, ",") {
+			value = strings.TrimSpace(value)
+			if value != "" && !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
 // ShowAccount godoc
 // @Summary      Update a video
 // @Description  Update the video by ID if it exists
@@ -101,33 +259,95 @@ func (vc *VideoController[S, P]) Retrieve(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id   path      int  true  "Video ID"
+// @Param        If-Match  header  string  false  "Only change the video if it still has this ETag"
 // @Param 		 video body video_hosting.Video true "Updated video"
 // @Success      200 {object}  video_hosting.Video
-// @Failure      400
-// @Failure      404  {string}  string "No video with this ID"
-// @Failure      500
+// @Header       200  {string}  ETag  "Version of the video"
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No video with this ID"
+// @Failure      412  {object}  problem.Problem "The video changed since the version of If-Match"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /videos/{id} [put]
 func (vc *VideoController[S, P]) Update(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.String(http.StatusBadRequest, `No id provided !`)
+		problem.AbortWith(c, problem.BadRequest, `No id provided !`)
 		return
 	}
 	var target video_hosting.Video
-	if err := c.BindJSON(&target); err != nil {
-		c.String(http.StatusBadRequest, `invalid body provided: %s !`, err.Error())
+	if err := c.ShouldBindJSON(&target); err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
+		return
+	}
+	svc := video_store_service.FromContext(c, vc.Service)
+	if err := svc.IfMatchVideo(c, id, etag.IfMatch(c)); err != nil {
+		problem.Abort(c, err)
 		return
 	}
-	vid, err := vc.Service.VidHost.UpdateVideo(id, &target)
+	vid, err := svc.UpdateVideo(c, id, &target)
 	if err != nil {
-		if re, ok := err.(*video_hosting.RequestError); ok {
-			c.String(re.StatusCode, re.Error())
-		} else {
-			c.Status(http.StatusInternalServerError)
-			_ = c.Error(err)
-		}
+		problem.Abort(c, err)
 		return
 	}
+	// Answered as a retrieval without include, which the ETag is the version of
+	vid.Statistics = nil
+	etag.Set(c, vid.ETag)
+	c.SecureJSON(http.StatusOK, vid)
+}
+
+// ShowAccount godoc
+// @Summary      Patch a video
+// @Description  Change some attributes of the video by ID with a JSON merge patch (RFC 7396), the others are left untouched.
+// @Description  Only the title, description and visibility can be patched, a null value resets the attribute
+// @Tags         videos
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        id     path      int  true  "Video ID"
+// @Param        If-Match  header  string  false  "Only change the video if it still has this ETag"
+// @Param 		 patch  body      video_hosting.Video true "Attributes to change"
+// @Success      200 {object}  video_hosting.Video
+// @Header       200  {string}  ETag  "Version of the video"
+// @Failure      400  {object}  problem.Problem "Not a merge patch, read-only attributes patched or invalid patched attributes"
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No video with this ID"
+// @Failure      412  {object}  problem.Problem "The video changed since the version of If-Match"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /videos/{id} [patch]
+func (vc *VideoController[S, P]) Patch(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		problem.AbortWith(c, problem.BadRequest, `No id provided !`)
+		return
+	}
+	if c.Request == nil || c.Request.Body == nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: no body !`)
+		return
+	}
+	data, err := c.GetRawData()
+	if err != nil {
+		problem.AbortWith(c, problem.BadRequest, `invalid body provided: %s !`, err.Error())
+		return
+	}
+	vid, err := video_store_service.FromContext(c, vc.Service).PatchVideo(c, id, data, etag.IfMatch(c))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	// Answered as a retrieval without include, which the ETag is the version of
+	vid.Statistics = nil
+	etag.Set(c, vid.ETag)
 	c.SecureJSON(http.StatusOK, vid)
 }
 
@@ -137,25 +357,33 @@ func (vc *VideoController[S, P]) Update(c *gin.Context) {
 // @Tags         videos
 // @Produce      json
 // @Param        id   path      int  true  "Video ID"
+// @Param        If-Match  header  string  false  "Only change the video if it still has this ETag"
 // @Success      204
-// @Failure      400
-// @Failure      404  {string}  string "No video with this ID"
-// @Failure      500
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      404  {object}  problem.Problem "No video with this ID"
+// @Failure      412  {object}  problem.Problem "The video changed since the version of If-Match"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /videos/{id} [delete]
 func (vc *VideoController[S, P]) Delete(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.String(http.StatusBadRequest, `No id provided !`)
+		problem.AbortWith(c, problem.BadRequest, `No id provided !`)
+		return
+	}
+	svc := video_store_service.FromContext(c, vc.Service)
+	if err := svc.IfMatchVideo(c, id, etag.IfMatch(c)); err != nil {
+		problem.Abort(c, err)
 		return
 	}
-	err := vc.Service.VidHost.DeleteVideo(id)
+	err := svc.DeleteVideo(c, id)
 	if err != nil {
-		if re, ok := err.(*video_hosting.RequestError); ok {
-			c.String(re.StatusCode, re.Error())
-		} else {
-			c.Status(http.StatusInternalServerError)
-			_ = c.Error(err)
-		}
+		problem.Abort(c, err)
 		return
 	}
 	c.String(http.StatusNoContent, "")
@@ -168,12 +396,18 @@ func (vc *VideoController[S, P]) Delete(c *gin.Context) {
 // @Accept       octet-stream
 // @Param        key   path      int  true  "Video ID"
 // @Success      204
-// @Failure      500
+// @Failure      401  {object}  problem.Problem "Missing or invalid credentials"
+// @Failure      403  {object}  problem.Problem "Missing scope"
+// @Failure      429  {object}  problem.Problem "Too many requests or hosting platform quota exceeded"
+// @Failure      500  {object}  problem.Problem
+// @Failure      503  {object}  problem.Problem "The host must be authorized again"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /videos/{id}/thumbnail/{tId} [post]
 func (vc *VideoController[S, P]) SetThumbnail(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.String(http.StatusBadRequest, `No id provided !`)
+		problem.AbortWith(c, problem.BadRequest, `No id provided !`)
 		return
 	}
 	// A thumbnail can be supplied two ways
@@ -181,18 +415,15 @@ func (vc *VideoController[S, P]) SetThumbnail(c *gin.Context) {
 	// Or with the thumbnail data in the request body
 	tTd := c.Param("tId")
 	var err error
+	svc := video_store_service.FromContext(c, vc.Service)
 	if tTd != "" {
-		err = vc.Service.SetVideoThumbnailFromStorage(c.Param("id"), c.Param("tId"))
+		err = svc.SetVideoThumbnailFromStorage(c, c.Param("id"), c.Param("tId"))
 	} else {
-		err = vc.Service.VidHost.UpdateVideoThumbnail(id, c.Request.Body)
+		err = svc.SetVideoThumbnail(c, id, c.Request.Body)
 	}
 
 	if err != nil {
-		if re, ok := err.(*video_hosting.RequestError); ok {
-			c.String(re.StatusCode, re.Error())
-		} else {
-			c.String(http.StatusInternalServerError, err.Error())
-		}
+		problem.Abort(c, err)
 		return
 	}
 
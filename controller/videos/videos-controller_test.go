@@ -2,7 +2,6 @@ package videos_controller
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/dapr/go-sdk/client"
@@ -16,11 +15,14 @@ import (
 	"strings"
 	"testing"
 	"time"
+	mock_client "video-manager/internal/mock/dapr"
 	mock_object_storage "video-manager/internal/mock/object-storage"
 	mock_progress_broker "video-manager/internal/mock/progress-broker"
 	mock_video_hosting "video-manager/internal/mock/video-hosting"
 	object_storage "video-manager/internal/object-storage"
+	"video-manager/internal/problem"
 	progress_broker "video-manager/internal/progress-broker"
+	"video-manager/internal/templates"
 	video_hosting "video-manager/internal/video-hosting"
 	video_store_service "video-manager/pkg/video-store-service"
 )
@@ -50,7 +52,6 @@ var (
 )
 
 func Setup(t *testing.T, initBroker bool) *mocked {
-	ctx := context.Background()
 	dir, err := os.MkdirTemp("", "assets")
 	if err != nil {
 		t.Fatal(err)
@@ -58,7 +59,7 @@ func Setup(t *testing.T, initBroker bool) *mocked {
 	ctrl := gomock.NewController(t)
 	// Initialize object storage
 	objStoreProxy := mock_object_storage.NewMockBindingProxy(ctrl)
-	objectStore := object_storage.NewObjectStorage[*mock_object_storage.MockBindingProxy](&ctx, dir, objStoreProxy)
+	objectStore := object_storage.NewObjectStorage[*mock_object_storage.MockBindingProxy](dir, objStoreProxy)
 
 	// Initialize video host
 	vidCtrl := gomock.NewController(t)
@@ -66,7 +67,7 @@ func Setup(t *testing.T, initBroker bool) *mocked {
 
 	//  Initialize event broker
 	psProxy := mock_progress_broker.NewMockPubSubProxy(ctrl)
-	broker, err := progress_broker.NewProgressBroker[*mock_progress_broker.MockPubSubProxy](&ctx, &psProxy, progress_broker.NewBrokerOptions{
+	broker, err := progress_broker.NewProgressBroker[*mock_progress_broker.MockPubSubProxy](&psProxy, progress_broker.NewBrokerOptions{
 		Component: "",
 		Topic:     "",
 	})
@@ -116,7 +117,7 @@ func Test_VideoController_Delete_Error_Deletion(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	// Setting the deletion to fail
-	deps.videoStore.EXPECT().DeleteVideo(gomock.Any()).Return(fmt.Errorf("test"))
+	deps.videoStore.EXPECT().DeleteVideo(gomock.Any(), gomock.Any()).Return(fmt.Errorf("test"))
 
 	deps.controller.Delete(c)
 	assert.Equal(t, 1, len(c.Errors))
@@ -128,7 +129,7 @@ func Test_VideoController_Delete_Error_NotFound(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	// Setting the deletion to fail
-	deps.videoStore.EXPECT().DeleteVideo(gomock.Any()).Return(&video_hosting.RequestError{
+	deps.videoStore.EXPECT().DeleteVideo(gomock.Any(), gomock.Any()).Return(&video_hosting.RequestError{
 		StatusCode: 404,
 		Err:        fmt.Errorf("not found"),
 	})
@@ -144,7 +145,7 @@ func Test_VideoController_Delete_Ok(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	// Setting the deletion to pass
-	deps.videoStore.EXPECT().DeleteVideo(gomock.Any()).Return(nil)
+	deps.videoStore.EXPECT().DeleteVideo(gomock.Any(), gomock.Any()).Return(nil)
 
 	deps.controller.Delete(c)
 	assert.Equal(t, 0, len(c.Errors))
@@ -181,7 +182,7 @@ func Test_VideoController_Update_Error_NotFound(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, sampleVid)
-	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), gomock.Any()).Return(nil, &video_hosting.RequestError{
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, &video_hosting.RequestError{
 		StatusCode: 404,
 		Err:        fmt.Errorf("not found"),
 	})
@@ -197,7 +198,7 @@ func Test_VideoController_Update_Error_Other(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, sampleVid)
-	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
 	// Testing with an empty ID
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	deps.controller.Update(c)
@@ -209,7 +210,7 @@ func Test_VideoController_Update_Ok(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, sampleVid)
-	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), gomock.Any()).Return(&sampleVid, nil)
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Return(&sampleVid, nil)
 	// Testing with an empty ID
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	deps.controller.Update(c)
@@ -228,6 +229,150 @@ func Test_VideoController_Update_Ok(t *testing.T) {
 	assert.Equal(t, sampleVid, updatedVid)
 }
 
+func Test_VideoController_Patch_Ok(t *testing.T) {
+	deps := Setup(t, false)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"title": "newTitle", "description": null}`))
+	c.Request.Header.Set("Content-Type", video_hosting.MergePatchContentType)
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "testId").Return(&sampleVid, nil)
+	// The untouched attributes, read-only ones included, are sent back as retrieved
+	patched := sampleVid
+	patched.Title, patched.Description = "newTitle", ""
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), "testId", &patched).Return(&patched, nil)
+	deps.controller.Patch(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updatedVid video_hosting.Video
+	if err := json.Unmarshal(w.Body.Bytes(), &updatedVid); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, patched, updatedVid)
+}
+
+func Test_VideoController_Patch_Error_ReadOnly(t *testing.T) {
+	deps := Setup(t, false)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"id": "other", "duration": 12}`))
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	// Refused without calling the host
+	deps.controller.Patch(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var p problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, problem.InvalidMetadata, p.Type)
+	assert.Equal(t, []video_hosting.FieldError{{Field: "duration", Reason: "is read-only"}, {Field: "id", Reason: "is read-only"}}, p.Errors)
+}
+
+func Test_VideoController_Patch_Error_NotFound(t *testing.T) {
+	deps := Setup(t, false)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"title": "newTitle"}`))
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "testId").
+		Return(nil, video_hosting.NewRequestError(video_hosting.NotFound, fmt.Errorf("no video")))
+	deps.controller.Patch(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_VideoController_Retrieve_ETag(t *testing.T) {
+	deps := Setup(t, false)
+	tagged := sampleVid
+	tagged.ETag = "v1"
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "testId").Return(&tagged, nil).Times(2)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.controller.Retrieve(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+
+	// The client already has this version
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("If-None-Match", `"v1"`)
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.controller.Retrieve(c)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+}
+
+func Test_VideoController_Retrieve_ETag_Statistics(t *testing.T) {
+	deps := Setup(t, false)
+	tagged := sampleVid
+	tagged.ETag = "v1"
+	tagged.Statistics = &video_hosting.Statistics{ViewCount: 10}
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "testId").DoAndReturn(func(_ any, _ string) (*video_hosting.Video, error) {
+		vid := tagged
+		return &vid, nil
+	}).Times(2)
+
+	// A response with the statistics has its own version
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/?include=statistics", nil)
+	c.Request.Header.Set("If-None-Match", `"v1"`)
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.controller.Retrieve(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, `"v1"`, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), "statistics")
+
+	// Known as long as the statistics don't change
+	withStats := w.Header().Get("ETag")
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/?include=statistics", nil)
+	c.Request.Header.Set("If-None-Match", withStats)
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.controller.Retrieve(c)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
+
+func Test_VideoController_IfMatch(t *testing.T) {
+	deps := Setup(t, false)
+	tagged := sampleVid
+	tagged.ETag = "v2"
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "testId").Return(&tagged, nil).AnyTimes()
+	// Another editor changed the video since v1, nothing is changed
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	deps.videoStore.EXPECT().DeleteVideo(gomock.Any(), gomock.Any()).Times(0)
+	for _, call := range []struct {
+		method  string
+		body    string
+		handler gin.HandlerFunc
+	}{
+		{http.MethodPut, `{"id": "testId", "title": "newTitle"}`, deps.controller.Update},
+		{http.MethodPatch, `{"title": "newTitle"}`, deps.controller.Patch},
+		{http.MethodDelete, ``, deps.controller.Delete},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(call.method, "/", strings.NewReader(call.body))
+		c.Request.Header.Set("If-Match", `"v1"`)
+		c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+		call.handler(c)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code, call.method)
+	}
+
+	// Still the expected version
+	deps.videoStore.EXPECT().DeleteVideo(gomock.Any(), "testId").Return(nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodDelete, "/", nil)
+	c.Request.Header.Set("If-Match", `"v2"`)
+	c.Params = []gin.Param{{Key: "id", Value: "testId"}}
+	deps.controller.Delete(c)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
 func Test_VideoController_Retrieve_Error_Id(t *testing.T) {
 	deps := Setup(t, false)
 	w := httptest.NewRecorder()
@@ -249,7 +394,7 @@ func Test_VideoController_Retrieve_Error_NotFound(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, sampleVid)
-	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any()).Return(nil, &video_hosting.RequestError{
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), gomock.Any()).Return(nil, &video_hosting.RequestError{
 		StatusCode: 404,
 		Err:        fmt.Errorf("not found"),
 	})
@@ -264,7 +409,7 @@ func Test_VideoController_Retrieve_Error_Other(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, sampleVid)
-	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any()).Return(nil, fmt.Errorf("test"))
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
 	// Testing with an empty ID
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	deps.controller.Retrieve(c)
@@ -276,7 +421,7 @@ func Test_VideoController_Retrieve_Ok(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	setJsonAsBody(t, c, sampleVid)
-	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any()).Return(&sampleVid, nil)
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), gomock.Any()).Return(&sampleVid, nil)
 	// Testing with an empty ID
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}}
 	deps.controller.Retrieve(c)
@@ -367,7 +512,7 @@ func Test_VideoController_Create_Ok(t *testing.T) {
 	deps.
 		videoStore.
 		EXPECT().
-		CreateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Return(&sampleVid, nil)
+		CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&sampleVid, nil)
 
 	body := CreateVideoBody{
 		ItemMetadata: video_hosting.ItemMetadata{
@@ -401,7 +546,7 @@ func Test_VideoController_Create_Ok_WithProgress(t *testing.T) {
 	deps.
 		videoStore.
 		EXPECT().
-		CreateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Return(&sampleVid, nil)
+		CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&sampleVid, nil)
 
 	body := CreateVideoBody{
 		ItemMetadata: video_hosting.ItemMetadata{
@@ -418,6 +563,67 @@ func Test_VideoController_Create_Ok_WithProgress(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func Test_VideoController_Create_Ok_Template(t *testing.T) {
+	deps := Setup(t, false)
+	deps.controller.Service.Templates = templates.NewTemplates[*mock_client.MockClient](map[string]templates.Template{
+		"session": {Title: "Session {{.Number}}", Description: "{{.Summary}}"},
+	}, "", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	deps.
+		objectStoreProxy.
+		EXPECT().
+		InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("aa")}, nil)
+	// The rendered metadata must be the ones uploaded
+	deps.
+		videoStore.
+		EXPECT().
+		CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, meta *video_hosting.ItemMetadata, _ any, _ any) (*video_hosting.Video, error) {
+			assert.Equal(t, "Session 12", meta.Title)
+			assert.Equal(t, "The party meets a dragon", meta.Description)
+			return &sampleVid, nil
+		})
+
+	body := CreateVideoBody{
+		ItemMetadata: video_hosting.ItemMetadata{Visibility: "unlisted"},
+		StorageKey:   "test",
+		JobId:        "test",
+		Template:     "session",
+		Variables:    map[string]any{"Number": 12, "Summary": "The party meets a dragon"},
+	}
+	setJsonAsBody(t, c, body)
+	deps.controller.Create(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func Test_VideoController_Create_Error_Template(t *testing.T) {
+	deps := Setup(t, false)
+	deps.controller.Service.Templates = templates.NewTemplates[*mock_client.MockClient](map[string]templates.Template{
+		"session": {Title: "Session {{.Number}}"},
+		"long":    {Title: "{{.Title}}"},
+	}, "", nil)
+	cases := []struct {
+		name string
+		body CreateVideoBody
+		code int
+	}{
+		{"unknown template", CreateVideoBody{Template: "none"}, http.StatusNotFound},
+		{"missing variable", CreateVideoBody{Template: "session", Variables: map[string]any{}}, http.StatusBadRequest},
+		{"title too long", CreateVideoBody{Template: "long", Variables: map[string]any{"Title": strings.Repeat("a", 101)}}, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			tc.body.ItemMetadata.Visibility, tc.body.StorageKey, tc.body.JobId = "unlisted", "test", "test"
+			setJsonAsBody(t, c, tc.body)
+			deps.controller.Create(c)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+}
+
 func TestVideoController_SetThumbnail_FromStorageKey_Ok(t *testing.T) {
 	deps := Setup(t, true)
 	w := httptest.NewRecorder()
@@ -428,7 +634,7 @@ func TestVideoController_SetThumbnail_FromStorageKey_Ok(t *testing.T) {
 		EXPECT().
 		InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("aa")}, nil)
 
-	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), gomock.Any()).Return(nil)
+	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}, {Key: "tId", Value: "meh"}}
 	deps.controller.SetThumbnail(c)
@@ -446,7 +652,10 @@ func TestVideoController_SetThumbnail_FromStorageKey_ThumbNotExists(t *testing.T
 
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}, {Key: "tId", Value: "meh"}}
 	deps.controller.SetThumbnail(c)
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	// The object storage is at fault, not the service
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), string(problem.StorageUnavailable))
 }
 
 func TestVideoController_SetThumbnail_FromStorageKey_YoutubeError(t *testing.T) {
@@ -458,7 +667,7 @@ func TestVideoController_SetThumbnail_FromStorageKey_YoutubeError(t *testing.T)
 		EXPECT().
 		InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("aa")}, nil)
 
-	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), gomock.Any()).Return(fmt.Errorf("test"))
+	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("test"))
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}, {Key: "tId", Value: "meh"}}
 	deps.controller.SetThumbnail(c)
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -477,12 +686,98 @@ func TestVideoController_SetThumbnail_FromBuffer_Ok(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Request, _ = http.NewRequest("POST", "/", bytes.NewBuffer([]byte("test")))
-	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), gomock.Any()).Return(nil)
+	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 	c.Params = []gin.Param{gin.Param{Key: "id", Value: "1"}, {Key: "tId", Value: ""}}
 	deps.controller.SetThumbnail(c)
 	assert.Equal(t, http.StatusNoContent, w.Code)
 }
 
+func Test_VideoController_Retrieve_Statistics(t *testing.T) {
+	deps := Setup(t, false)
+	stats := &video_hosting.Statistics{ViewCount: 100, LikeCount: 10}
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "1").DoAndReturn(func(_ any, _ string) (*video_hosting.Video, error) {
+		vid := sampleVid
+		vid.Statistics = stats
+		return &vid, nil
+	}).Times(2)
+	retrieve := func(path string) video_hosting.Video {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, path, nil)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		deps.controller.Retrieve(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var vid video_hosting.Video
+		if err := json.Unmarshal(w.Body.Bytes(), &vid); err != nil {
+			t.Fatal(err)
+		}
+		return vid
+	}
+	// Only included on demand
+	assert.Nil(t, retrieve("/v1/videos/1").Statistics)
+	assert.Equal(t, stats, retrieve("/v1/videos/1?include=thumbnails,statistics").Statistics)
+}
+
+func Test_VideoController_Stats(t *testing.T) {
+	deps := Setup(t, false)
+	stats := &video_hosting.Statistics{ViewCount: 100, CommentCount: 3}
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "1").Return(&video_hosting.Video{Id: "1", Statistics: stats}, nil)
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "2").Return(&video_hosting.Video{Id: "2"}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1"}}
+	deps.controller.Stats(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got video_hosting.Statistics
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, *stats, got)
+
+	// The host doesn't count the audience of this one
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "2"}}
+	deps.controller.Stats(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func Test_VideoController_ListStats(t *testing.T) {
+	deps := Setup(t, false)
+	// Duplicated IDs are only looked up once, in a single call
+	deps.videoStore.EXPECT().ListVideos(gomock.Any(), []string{"1", "2", "3"}).Return([]*video_hosting.Video{
+		{Id: "1", Statistics: &video_hosting.Statistics{ViewCount: 1}},
+		{Id: "3", Statistics: &video_hosting.Statistics{ViewCount: 3}},
+	}, nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/stats?ids=1,2&ids=3,1", nil)
+	deps.controller.ListStats(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stats map[string]video_hosting.Statistics
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, stats, 2)
+	assert.Equal(t, uint64(3), stats["3"].ViewCount)
+}
+
+func Test_VideoController_ListStats_InvalidIds(t *testing.T) {
+	deps := Setup(t, false)
+	ids := make([]string, video_hosting.MaxListIds+1)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i)
+	}
+	for _, query := range []string{"", "?ids=", "?ids=" + strings.Join(ids, ",")} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/v1/stats"+query, nil)
+		deps.controller.ListStats(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
+
 // Set the payload as the JSON body of c
 func setJsonAsBody(t *testing.T, c *gin.Context, payload any) {
 	buf, err := json.Marshal(payload)
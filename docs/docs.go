@@ -16,88 +16,117 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/playlists": {
-            "post": {
-                "description": "Creates a new playlist on the remote video hosting platform",
-                "consumes": [
-                    "application/json"
-                ],
+        "/auth/youtube/callback": {
+            "get": {
+                "description": "Called by Google once the user gave their consent. The refresh token is saved in the state store,\nand used right away without restarting",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "playlists"
+                    "auth"
                 ],
-                "summary": "Creates a new playlist",
+                "summary": "Complete the Youtube consent",
                 "parameters": [
                     {
-                        "description": "Required data to create a playlist",
-                        "name": "meta",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/video_hosting.ItemMetadata"
-                        }
+                        "type": "string",
+                        "description": "State issued by /v1/auth/youtube/start",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/video_hosting.Playlist"
+                            "$ref": "#/definitions/oauth_controller.Authorized"
                         }
                     },
                     "400": {
-                        "description": "Required metata are wrong in some ways"
+                        "description": "Unknown or expired state",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
-                    "500": {
-                        "description": "Internal Server Error"
+                    "403": {
+                        "description": "The user refused the consent",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Google refused the authorization code",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The refresh token couldn't be saved",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
             }
         },
-        "/playlists/{id}": {
+        "/auth/youtube/start": {
             "get": {
-                "description": "Retrieve a playlist by ID",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "playlists"
-                ],
-                "summary": "Get a playlist",
-                "parameters": [
+                "security": [
                     {
-                        "type": "integer",
-                        "description": "Playlist ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
                     }
                 ],
+                "description": "Redirect to the Google consent screen, to grant the service access to a Youtube account.\nGoogle then redirects to /v1/auth/youtube/callback. The flow must be completed within 10 minutes",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Start the Youtube consent",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "302": {
+                        "description": "Found"
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
                         "schema": {
-                            "$ref": "#/definitions/video_hosting.Playlist"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request"
-                    },
-                    "404": {
-                        "description": "No playlist with this ID",
+                    "403": {
+                        "description": "Missing scope",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error"
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
-            },
-            "put": {
-                "description": "Update a playlist by ID if it exists",
+            }
+        },
+        "/batch": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Run several commands in order (create a playlist, upload videos, set their thumbnails, add them to the playlist...).\nA payload can use the result of a previous operation with \"${ref.field}\", such as \"${playlist.id}\".\nThe outcome of each operation is returned, even when some failed",
                 "consumes": [
                     "application/json"
                 ],
@@ -105,24 +134,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "playlists"
+                    "batch"
                 ],
-                "summary": "Update a playlist",
+                "summary": "Run a batch of operations",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Playlist ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Updated playlist",
-                        "name": "playlist",
+                        "description": "Operations to run",
+                        "name": "batch",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/video_hosting.Playlist"
+                            "$ref": "#/definitions/commands_controller.BatchBody"
                         }
                     }
                 ],
@@ -130,164 +152,230 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/video_hosting.Playlist"
+                            "$ref": "#/definitions/commands_controller.BatchResult"
                         }
                     },
                     "400": {
-                        "description": "Bad Request"
+                        "description": "Invalid batch",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
-                    "404": {
-                        "description": "No playlist with this ID",
+                    "401": {
+                        "description": "Missing or invalid credentials",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error"
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete the playlist by ID if it exists",
+            }
+        },
+        "/catalog/playlists": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the playlists created or modified through the service, without querying the hosting platform",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "playlists"
-                ],
-                "summary": "Delete a playlist",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Playlist ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "catalog"
                 ],
+                "summary": "List the recorded playlists",
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/catalog.Entry"
+                            }
+                        }
                     },
-                    "400": {
-                        "description": "Bad Request"
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
                     "404": {
-                        "description": "No playlist with this ID",
+                        "description": "No catalog configured",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error"
+                    "503": {
+                        "description": "The state store is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
             }
         },
-        "/playlists/{pid}/videos/{vid}": {
-            "put": {
-                "description": "Add an existing playlist to an existing video",
+        "/catalog/playlists/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a playlist created or modified through the service, along with the videos added to it",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "playlists"
+                    "catalog"
                 ],
-                "summary": "Add a video to the selected playlist",
+                "summary": "Get a recorded playlist",
                 "parameters": [
                     {
-                        "type": "integer",
+                        "type": "string",
                         "description": "Playlist ID",
-                        "name": "pid",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Video ID",
-                        "name": "vid",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/catalog.Entry"
+                        }
                     },
-                    "400": {
-                        "description": "Bad Request"
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
                     "404": {
-                        "description": "Either the playlist or video don't exists",
+                        "description": "No catalog configured, or playlist not recorded",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error"
+                    "503": {
+                        "description": "The state store is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
             }
         },
-        "/videos": {
-            "post": {
-                "description": "Upload a video from the object storage to the video hosting platform",
-                "consumes": [
-                    "application/json"
+        "/catalog/videos": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "List the videos uploaded or modified through the service, without querying the hosting platform.\nWith storageKey, only the video uploaded from this recording is listed",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "videos"
+                    "catalog"
                 ],
-                "summary": "Upload a video",
+                "summary": "List the recorded videos",
                 "parameters": [
                     {
-                        "description": "Required data to upload a video",
-                        "name": "videometa",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/videos_controller.CreateVideoBody"
-                        }
+                        "type": "string",
+                        "description": "Key of the recording on the object storage",
+                        "name": "storageKey",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/video_hosting.Video"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/catalog.Entry"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request"
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
                     "404": {
-                        "description": "No video with this ID",
+                        "description": "No catalog configured",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error"
+                    "503": {
+                        "description": "The state store is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
             }
         },
-        "/videos/{id}": {
+        "/catalog/videos/{id}": {
             "get": {
-                "description": "Retrieve a video by ID",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve how a video was uploaded (job, recording, metadata) through the service",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "videos"
+                    "catalog"
                 ],
-                "summary": "Get a video",
+                "summary": "Get a recorded video",
                 "parameters": [
                     {
-                        "type": "integer",
+                        "type": "string",
                         "description": "Video ID",
                         "name": "id",
                         "in": "path",
@@ -298,25 +386,47 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/video_hosting.Video"
+                            "$ref": "#/definitions/catalog.Entry"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request"
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
                     "404": {
-                        "description": "No video with this ID",
+                        "description": "No catalog configured, or video not recorded",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error"
+                    "503": {
+                        "description": "The state store is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
-            },
-            "put": {
-                "description": "Update the video by ID if it exists",
+            }
+        },
+        "/commands": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Run a command delivered by the Dapr sidecar as a CloudEvent",
                 "consumes": [
                     "application/json"
                 ],
@@ -324,24 +434,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "videos"
+                    "dapr"
                 ],
-                "summary": "Update a video",
+                "summary": "Run a command",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Video ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Updated video",
-                        "name": "video",
+                        "description": "Command to run, wrapped in a CloudEvent",
+                        "name": "command",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/video_hosting.Video"
+                            "$ref": "#/definitions/commands_controller.Command"
                         }
                     }
                 ],
@@ -349,100 +452,2689 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/video_hosting.Video"
+                            "$ref": "#/definitions/commands_controller.Response"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request"
-                    },
-                    "404": {
-                        "description": "No video with this ID",
+                    "401": {
+                        "description": "Missing or invalid credentials",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error"
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete the video by ID if it exists",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "videos"
-                ],
-                "summary": "Delete a video",
+            }
+        },
+        "/commands/uploads": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Upload a video from the object storage, as a video.upload command would. The CloudEvent data is the payload of the command.\nKept for the publishers of the \"uploads\" topic, formerly routed to POST /v1/videos",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dapr"
+                ],
+                "summary": "Upload a video from a legacy message",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Video ID",
-                        "name": "id",
+                        "description": "Video to upload, wrapped in a CloudEvent",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/commands_controller.UploadVideoPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/commands_controller.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/config": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the configuration the service is running with, once the file and the environment are merged.\nSecrets (API keys, client secrets, refresh tokens...) are redacted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "config"
+                ],
+                "summary": "Get the configuration",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.Config"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/dapr/subscribe": {
+            "get": {
+                "description": "Programmatic subscriptions, queried by the Dapr sidecar at startup",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dapr"
+                ],
+                "summary": "List Dapr subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/commands_controller.Subscription"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Always succeeds while the server is able to answer. Dependencies aren't checked, use /readyz for that",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List all the hosts served by this deployment. Any route can be used with a specific host,\neither with the X-Video-Host header or by prefixing its path with /v1/hosts/{host}",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "hosts"
+                ],
+                "summary": "List the hosts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/hosts_controller.Host"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts/{host}/auth/youtube/callback": {
+            "get": {
+                "description": "Same as /v1/auth/youtube/callback, for the host of the path",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Complete the Youtube consent of a host",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Host name",
+                        "name": "host",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State issued by /v1/hosts/{host}/auth/youtube/start",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/oauth_controller.Authorized"
+                        }
                     },
                     "400": {
-                        "description": "Bad Request"
+                        "description": "Unknown or expired state",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "The user refused the consent",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Google refused the authorization code",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The refresh token couldn't be saved",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts/{host}/auth/youtube/start": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Same as /v1/auth/youtube/start, for the host of the path.\nGoogle then redirects to /v1/hosts/{host}/auth/youtube/callback, or to /v1/auth/youtube/callback for the default host",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Start the Youtube consent of a host",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Host name",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
                     "404": {
-                        "description": "No video with this ID",
+                        "description": "No host with this name supports the consent",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new playlist on the remote video hosting platform",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlists"
+                ],
+                "summary": "Creates a new playlist",
+                "parameters": [
+                    {
+                        "description": "Required data to create a playlist",
+                        "name": "meta",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.ItemMetadata"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Playlist"
+                        }
+                    },
+                    "400": {
+                        "description": "Required metata are wrong in some ways",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error"
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a playlist by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlists"
+                ],
+                "summary": "Get a playlist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version of the playlist already known",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Playlist"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the playlist"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "The known version is still the current one"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No playlist with this ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update a playlist by ID if it exists",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlists"
+                ],
+                "summary": "Update a playlist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only change the playlist if it still has this ETag",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Updated playlist",
+                        "name": "playlist",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Playlist"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Playlist"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the playlist"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No playlist with this ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "The playlist changed since the version of If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete the playlist by ID if it exists",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlists"
+                ],
+                "summary": "Delete a playlist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only change the playlist if it still has this ETag",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No playlist with this ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "The playlist changed since the version of If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change some attributes of the playlist by ID with a JSON merge patch (RFC 7396), the others are left untouched.\nOnly the title, description and visibility can be patched, a null value resets the attribute",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlists"
+                ],
+                "summary": "Patch a playlist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only change the playlist if it still has this ETag",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Attributes to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Playlist"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Playlist"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the playlist"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Not a merge patch, read-only attributes patched or invalid patched attributes",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No playlist with this ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "The playlist changed since the version of If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlists/{pid}/videos/{vid}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add an existing playlist to an existing video",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlists"
+                ],
+                "summary": "Add a video to the selected playlist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Playlist ID",
+                        "name": "pid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Video ID",
+                        "name": "vid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Either the playlist or video don't exists",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/publish": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Upload a recording and apply all its settings (tags, thumbnail, captions, playlists, schedule),\nas described by a JSON or YAML manifest stored on the object storage.\nOnce the manifest is validated, the outcome of each step is returned, even if one failed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "publish"
+                ],
+                "summary": "Publish a recording",
+                "parameters": [
+                    {
+                        "description": "Manifest to publish",
+                        "name": "publish",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/publish_controller.PublishBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_store_service.PublishReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid manifest",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The manifest couldn't be retrieved from the object storage",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/quota": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the consumption of the daily quota of the video hosting platform",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quota"
+                ],
+                "summary": "Get the quota consumption",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.QuotaStatus"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "The hosting platform has no quota",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Check all the dependencies of the service : the Dapr sidecar, the object storage, the pubsub and the video hosting platform.\nResults are cached for a few seconds",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "At least one dependency is down",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/reconcile/report": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Summary of the last comparison of the recorded videos and playlists with the hosting platform,\nlisting those deleted, edited or whose processing failed outside the service.\nEach difference is also published as a \"video.drifted\" or \"playlist.drifted\" event",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "catalog"
+                ],
+                "summary": "Get the last reconciliation report",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_store_service.ReconcileReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No catalog configured, or no reconciliation ran yet",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the statistics of up to 50 videos at once, with a single call to the hosting platform.\nThe statistics are keyed by video ID, videos that don't exist are left out",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "videos"
+                ],
+                "summary": "Get the statistics of several videos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video IDs, comma separated",
+                        "name": "ids",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "$ref": "#/definitions/video_hosting.Statistics"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "No ID, or more than 50",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{name}/render": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Render the title and description of a metadata template, as they would be uploaded.\nThe result is checked against the limits of the video metadata",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Preview a template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Values to render the template with",
+                        "name": "variables",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/templates_controller.RenderBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/templates.Rendered"
+                        }
+                    },
+                    "400": {
+                        "description": "The template can't be rendered with these variables",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No template with this name",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/videos": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Upload a video from the object storage to the video hosting platform.\nWith a template, the title and description are rendered from it and the variables instead",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "videos"
+                ],
+                "summary": "Upload a video",
+                "parameters": [
+                    {
+                        "description": "Required data to upload a video",
+                        "name": "videometa",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/videos_controller.CreateVideoBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Video"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No template with this name",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/videos/upload": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Upload a video sent with the request to the video hosting platform, without going through the object storage.\nThe video is streamed to the hosting platform as it is received, and handled as any other upload job.\nAs multipart/form-data, a \"metadata\" part holding an UploadVideoBody as JSON must come before the \"file\" part.\nOtherwise, the body is the video itself, and its metadata are given in the query.\nEither way, the video must be sent as video/* or application/octet-stream, and fit within DAPR_MAX_REQUEST_SIZE_MB",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "videos"
+                ],
+                "summary": "Upload a video directly",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UploadVideoBody as JSON, for a multipart/form-data body",
+                        "name": "metadata",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "The video, for a multipart/form-data body",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID of this uploading job, for a raw body",
+                        "name": "jobId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Title of the video, for a raw body",
+                        "name": "title",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Description of the video, for a raw body",
+                        "name": "description",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "public",
+                            "unlisted",
+                            "private"
+                        ],
+                        "type": "string",
+                        "description": "Visibility of the video, for a raw body",
+                        "name": "visibility",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Video"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No template with this name",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "The video is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "The file is not a video",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/videos/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a video by ID. Its statistics are only included with include=statistics",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "videos"
+                ],
+                "summary": "Get a video",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version of the video already known",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "enum": [
+                            "statistics"
+                        ],
+                        "type": "string",
+                        "description": "Optional parts to include, comma separated",
+                        "name": "include",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Video"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the video"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "The known version is still the current one"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No video with this ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update the video by ID if it exists",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "videos"
+                ],
+                "summary": "Update a video",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only change the video if it still has this ETag",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Updated video",
+                        "name": "video",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Video"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Video"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the video"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No video with this ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "The video changed since the version of If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete the video by ID if it exists",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "videos"
+                ],
+                "summary": "Delete a video",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only change the video if it still has this ETag",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No video with this ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "The video changed since the version of If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change some attributes of the video by ID with a JSON merge patch (RFC 7396), the others are left untouched.\nOnly the title, description and visibility can be patched, a null value resets the attribute",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "videos"
+                ],
+                "summary": "Patch a video",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only change the video if it still has this ETag",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Attributes to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Video"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Video"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the video"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Not a merge patch, read-only attributes patched or invalid patched attributes",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No video with this ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "The video changed since the version of If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/videos/{id}/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the view, like, comment and favorite counts of a video",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "videos"
+                ],
+                "summary": "Get the statistics of a video",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/video_hosting.Statistics"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No video with this ID, or the host doesn't count its audience",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/videos/{id}/thumbnail/{tId}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set the thumbnail of an existing video on the remote video hosting platform",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "videos"
+                ],
+                "summary": "Set the thumbnail of a video",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Video ID",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or hosting platform quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The host must be authorized again",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "catalog.Entry": {
+            "type": "object",
+            "properties": {
+                "captions": {
+                    "description": "Languages of the captions added to the video through the service",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "createdAt": {
+                    "description": "When the item was first recorded",
+                    "type": "string"
+                },
+                "host": {
+                    "description": "Name of the host the item is on",
+                    "type": "string",
+                    "example": "youtube"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "items": {
+                    "description": "IDs of the videos added to the playlist through the service",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "jobId": {
+                    "description": "Job that uploaded the video",
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "video"
+                },
+                "metadata": {
+                    "description": "Metadata, as last set through the service",
+                    "$ref": "#/definitions/video_hosting.ItemMetadata"
+                },
+                "publishAt": {
+                    "description": "When the video is scheduled to be published",
+                    "type": "string"
+                },
+                "storageKey": {
+                    "description": "Key of the uploaded recording on the object storage",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Keywords of the video, as last set through the service",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "thumbnailKey": {
+                    "description": "Key of the last thumbnail set from the object storage",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "description": "When the item was last modified through the service",
+                    "type": "string"
+                },
+                "uploadStatus": {
+                    "description": "Upload status of the video, as last seen on the host",
+                    "type": "string"
+                }
+            }
+        },
+        "commands_controller.BatchBody": {
+            "type": "object",
+            "required": [
+                "operations"
+            ],
+            "properties": {
+                "operations": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/commands_controller.Operation"
+                    }
+                },
+                "stopOnError": {
+                    "description": "Skip all the remaining operations as soon as one fails",
+                    "type": "boolean"
+                }
+            }
+        },
+        "commands_controller.BatchResult": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/commands_controller.OperationResult"
+                    }
+                },
+                "succeeded": {
+                    "description": "Whether all the operations succeeded",
+                    "type": "boolean"
+                }
+            }
+        },
+        "commands_controller.Command": {
+            "type": "object",
+            "required": [
+                "payload",
+                "type"
+            ],
+            "properties": {
+                "host": {
+                    "description": "Name of the host to run the operation on. Defaults to the default host",
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Arguments of the operation, their shape depends on the command type",
+                    "type": "object"
+                },
+                "type": {
+                    "description": "Operation to run",
+                    "type": "string"
+                }
+            }
+        },
+        "commands_controller.Operation": {
+            "type": "object",
+            "required": [
+                "payload",
+                "type"
+            ],
+            "properties": {
+                "host": {
+                    "description": "Name of the host to run the operation on. Defaults to the default host",
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Arguments of the operation, their shape depends on the command type",
+                    "type": "object"
+                },
+                "ref": {
+                    "description": "Name the next operations can reference the result of, with \"${ref.field}\" in their payload.\nDefaults to the position of the operation in the batch, starting at 0",
+                    "type": "string",
+                    "example": "playlist"
+                },
+                "type": {
+                    "description": "Operation to run",
+                    "type": "string"
+                }
+            }
+        },
+        "commands_controller.OperationResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Why the operation failed",
+                    "$ref": "#/definitions/problem.Problem"
+                },
+                "ref": {
+                    "type": "string",
+                    "example": "playlist"
+                },
+                "result": {
+                    "description": "Video or playlist created or updated by the operation, if any",
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "succeeded"
+                },
+                "type": {
+                    "description": "Operation to run",
+                    "type": "string",
+                    "example": "playlist.create"
+                }
+            }
+        },
+        "commands_controller.Response": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "commands_controller.Subscription": {
+            "type": "object",
+            "properties": {
+                "pubsubname": {
+                    "description": "Name of the Dapr pubsub component",
+                    "type": "string"
+                },
+                "route": {
+                    "description": "Route the sidecar has to POST the messages to",
+                    "type": "string"
+                },
+                "topic": {
+                    "description": "Topic to subscribe to",
+                    "type": "string"
+                }
+            }
+        },
+        "commands_controller.UploadVideoPayload": {
+            "type": "object",
+            "required": [
+                "jobId",
+                "storageKey",
+                "title",
+                "visibility"
+            ],
+            "properties": {
+                "description": {
+                    "description": "Short text describing the content of the item\nYoutube actually limits to 5000 bytes, which *isn't* 5000 characters. This is checked by its MetadataValidator\nhttps://developers.google.com/youtube/v3/docs/videos#properties",
+                    "type": "string",
+                    "maxLength": 5000
+                },
+                "jobId": {
+                    "description": "UUID of this uploading job",
+                    "type": "string"
+                },
+                "storageKey": {
+                    "description": "Key to retrieve the video from the object storage",
+                    "type": "string"
+                },
+                "title": {
+                    "description": "Title of the item\nIts length is limited by each host, and depends on the kind of item. This is checked by its MetadataValidator",
+                    "type": "string"
+                },
+                "visibility": {
+                    "description": "Visibility of the item",
+                    "type": "string"
+                }
+            }
+        },
+        "config.Auth": {
+            "type": "object",
+            "properties": {
+                "apiKeys": {
+                    "description": "Static API keys, as \"name:sha256:scope,scope\" separated by \";\"",
+                    "type": "string"
+                },
+                "jwksUrl": {
+                    "description": "URL or path of the JWKS document used to verify bearer tokens",
+                    "type": "string"
+                },
+                "jwtAudience": {
+                    "description": "Expected audience of bearer tokens",
+                    "type": "string"
+                },
+                "jwtIssuer": {
+                    "description": "Expected issuer of bearer tokens",
+                    "type": "string"
+                }
+            }
+        },
+        "config.Catalog": {
+            "type": "object",
+            "properties": {
+                "reconcileInterval": {
+                    "description": "How often the recorded items are compared with the hosts to detect the changes made outside the service, 0 disables it",
+                    "type": "string",
+                    "example": "1h0m0s"
+                },
+                "store": {
+                    "description": "Name of the Dapr state store component recording the uploaded videos and playlists. No catalog is kept if empty",
+                    "type": "string"
+                }
+            }
+        },
+        "config.Config": {
+            "type": "object",
+            "properties": {
+                "auth": {
+                    "$ref": "#/definitions/config.Auth"
+                },
+                "catalog": {
+                    "$ref": "#/definitions/config.Catalog"
+                },
+                "dapr": {
+                    "$ref": "#/definitions/config.Dapr"
+                },
+                "defaultHost": {
+                    "description": "Name of the host serving the API. Optional if there is a single host",
+                    "type": "string"
+                },
+                "health": {
+                    "$ref": "#/definitions/config.Health"
+                },
+                "hosts": {
+                    "description": "All the video hosting platforms the service can upload to",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/config.Host"
+                    }
+                },
+                "oauth": {
+                    "$ref": "#/definitions/config.OAuth"
+                },
+                "processing": {
+                    "$ref": "#/definitions/config.Processing"
+                },
+                "pubsub": {
+                    "$ref": "#/definitions/config.PubSub"
+                },
+                "rateLimit": {
+                    "$ref": "#/definitions/config.RateLimit"
+                },
+                "secrets": {
+                    "$ref": "#/definitions/config.Secrets"
+                },
+                "server": {
+                    "$ref": "#/definitions/config.Server"
+                },
+                "shutdown": {
+                    "$ref": "#/definitions/config.Shutdown"
+                },
+                "templates": {
+                    "$ref": "#/definitions/config.Templates"
+                },
+                "tracing": {
+                    "$ref": "#/definitions/config.Tracing"
+                }
+            }
+        },
+        "config.Dapr": {
+            "type": "object",
+            "properties": {
+                "appApiToken": {
+                    "description": "Token the sidecar must send along each command",
+                    "type": "string"
+                },
+                "grpcPort": {
+                    "description": "gRPC port of the sidecar",
+                    "type": "integer"
+                },
+                "maxRequestSizeMb": {
+                    "description": "Max size of a message received from the sidecar, in MB. Videos are received in a single message",
+                    "type": "integer"
+                },
+                "objectStore": {
+                    "description": "Name of the binding pointing to the object storage",
+                    "type": "string"
+                }
+            }
+        },
+        "config.Health": {
+            "type": "object",
+            "properties": {
+                "cacheTTL": {
+                    "description": "How long the result of each readiness check is reused",
+                    "type": "string",
+                    "example": "10s"
+                }
+            }
+        },
+        "config.Host": {
+            "type": "object",
+            "properties": {
+                "categoryId": {
+                    "description": "Category of the uploaded videos. Defaults to \"24\" (Entertainment) on Youtube",
+                    "type": "string"
+                },
+                "clientId": {
+                    "description": "OAuth client ID",
+                    "type": "string"
+                },
+                "clientSecret": {
+                    "description": "OAuth client secret",
+                    "type": "string"
+                },
+                "dailyQuota": {
+                    "description": "Daily quota of the platform, in units",
+                    "type": "integer"
+                },
+                "eventsTopic": {
+                    "description": "Topic of the lifecycle events of this host. Defaults to pubsub.eventsTopic",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Unique name of the host",
+                    "type": "string"
+                },
+                "progressTopic": {
+                    "description": "Topic of the upload progress events of this host. Defaults to pubsub.progressTopic",
+                    "type": "string"
+                },
+                "refreshToken": {
+                    "description": "OAuth refresh token of the account to upload with",
+                    "type": "string"
+                },
+                "secretName": {
+                    "description": "Name of the secret holding the credentials in the secret store.\nIts \"clientId\", \"clientSecret\" and \"refreshToken\" values replace the ones above",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Kind of platform",
+                    "type": "string"
+                }
+            }
+        },
+        "config.OAuth": {
+            "type": "object",
+            "properties": {
+                "redirectUrl": {
+                    "description": "Public URL of the consent callback, /v1/auth/youtube/callback. The consent routes are disabled if empty",
+                    "type": "string"
+                },
+                "stateStore": {
+                    "description": "Name of the Dapr state store component the refresh tokens obtained with a consent are saved in",
+                    "type": "string"
+                }
+            }
+        },
+        "config.Processing": {
+            "type": "object",
+            "properties": {
+                "pollInterval": {
+                    "description": "How often a freshly uploaded video is looked up until the host processed it, 0 disables it",
+                    "type": "string",
+                    "example": "30s"
+                },
+                "timeout": {
+                    "description": "How long a video is looked up at most, 0 means until it is processed",
+                    "type": "string",
+                    "example": "2h0m0s"
+                }
+            }
+        },
+        "config.PubSub": {
+            "type": "object",
+            "properties": {
+                "commandsName": {
+                    "description": "Name of the pubsub component to receive commands from. Defaults to Name",
+                    "type": "string"
+                },
+                "commandsTopic": {
+                    "description": "Topic to receive commands from",
+                    "type": "string"
+                },
+                "eventsTopic": {
+                    "description": "Topic of the lifecycle events",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the pubsub component. No events are published if empty",
+                    "type": "string"
+                },
+                "progressTopic": {
+                    "description": "Topic of the upload progress events",
+                    "type": "string"
+                }
+            }
+        },
+        "config.RateLimit": {
+            "type": "object",
+            "properties": {
+                "burst": {
+                    "description": "Maximum number of requests a client can make in a single burst",
+                    "type": "integer"
+                },
+                "rps": {
+                    "description": "Requests per second allowed for each client, 0 disables the rate limiting",
+                    "type": "number"
+                }
+            }
+        },
+        "config.Secrets": {
+            "type": "object",
+            "properties": {
+                "refreshInterval": {
+                    "description": "How often the secrets are read again to pick up rotated credentials, 0 disables the refresh",
+                    "type": "string",
+                    "example": "5m0s"
+                },
+                "store": {
+                    "description": "Name of the Dapr secret store component the hosts credentials can be read from",
+                    "type": "string"
+                }
+            }
+        },
+        "config.Server": {
+            "type": "object",
+            "properties": {
+                "ginMode": {
+                    "description": "Either \"debug\", \"release\" or \"test\"",
+                    "type": "string"
+                },
+                "port": {
+                    "description": "Listening port",
+                    "type": "integer"
+                },
+                "trustedProxies": {
+                    "description": "IPs or CIDRs of the reverse proxies allowed to set the client IP with X-Forwarded-For, separated by \",\".\nThe client IP is always the one of the connection if empty",
+                    "type": "string"
+                }
+            }
+        },
+        "config.Shutdown": {
+            "type": "object",
+            "properties": {
+                "drainTimeout": {
+                    "description": "How long the running uploads are waited for when shutting down",
+                    "type": "string",
+                    "example": "20s"
+                }
+            }
+        },
+        "config.Templates": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "Templates of the video metadata, by name. They take precedence over the stored ones",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/templates.Template"
                     }
+                },
+                "store": {
+                    "description": "Name of the Dapr state store component holding more templates, under the keys \"template-\u003cname\u003e\". Optional",
+                    "type": "string"
                 }
             }
         },
-        "/videos/{id}/thumbnail": {
-            "post": {
-                "description": "Set the thumbnail of an existing video on the remote video hosting platform",
-                "consumes": [
-                    "application/octet-stream"
-                ],
-                "tags": [
-                    "videos"
-                ],
-                "summary": "Set the thumbnail of a video",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Video ID",
-                        "name": "key",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Thumbnail content",
-                        "name": "thumb",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "integer"
-                        }
+        "config.Tracing": {
+            "type": "object",
+            "properties": {
+                "exporter": {
+                    "description": "Either \"none\", \"stdout\" or \"otlp\"",
+                    "type": "string"
+                },
+                "serviceName": {
+                    "description": "Name of the service in the traces",
+                    "type": "string"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.Result"
                     }
-                ],
-                "responses": {
-                    "204": {
-                        "description": "No Content"
-                    },
-                    "500": {
-                        "description": "Internal Server Error"
+                },
+                "status": {
+                    "description": "Up only if all dependencies are up",
+                    "type": "string"
+                }
+            }
+        },
+        "health.Result": {
+            "type": "object",
+            "properties": {
+                "checkedAt": {
+                    "description": "When the check actually ran. Results are cached, this may be in the past",
+                    "type": "string"
+                },
+                "durationMs": {
+                    "description": "Time spent running the check, in milliseconds",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "Why the dependency is down",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Up only if all dependencies are up",
+                    "type": "string"
+                }
+            }
+        },
+        "hosts_controller.Host": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "description": "Whether the host is used when none is selected",
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "youtube"
+                },
+                "quota": {
+                    "description": "Consumption of the daily quota, absent if the platform has no quota",
+                    "$ref": "#/definitions/video_hosting.QuotaStatus"
+                }
+            }
+        },
+        "oauth_controller.Authorized": {
+            "type": "object",
+            "properties": {
+                "host": {
+                    "description": "Name of the host now using the new refresh token",
+                    "type": "string",
+                    "example": "youtube"
+                },
+                "rotated": {
+                    "description": "Whether the refresh token differs from the previous one",
+                    "type": "boolean"
+                }
+            }
+        },
+        "problem.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "description": "Human-readable explanation specific to this occurrence of the problem",
+                    "type": "string"
+                },
+                "errors": {
+                    "description": "Fields of the metadata refused by the hosting platform, for an invalid-metadata problem",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/video_hosting.FieldError"
                     }
+                },
+                "instance": {
+                    "description": "Path of the request that caused the problem",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "HTTP status code",
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "description": "Short human-readable summary of the kind of problem",
+                    "type": "string",
+                    "example": "Resource not found"
+                },
+                "type": {
+                    "description": "URI identifying the kind of problem",
+                    "type": "string",
+                    "example": "urn:video-store:problem:not-found"
                 }
             }
-        }
-    },
-    "definitions": {
+        },
+        "publish_controller.PublishBody": {
+            "type": "object",
+            "required": [
+                "manifestKey"
+            ],
+            "properties": {
+                "manifestKey": {
+                    "description": "Key to retrieve the manifest from the object storage",
+                    "type": "string",
+                    "example": "recordings/1234/manifest.yaml"
+                }
+            }
+        },
+        "templates.Rendered": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "templates.Template": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Session {{.Number}} – {{.Campaign}}"
+                }
+            }
+        },
+        "templates_controller.RenderBody": {
+            "type": "object",
+            "properties": {
+                "variables": {
+                    "description": "Values the template is rendered with",
+                    "type": "object",
+                    "additionalProperties": {}
+                }
+            }
+        },
+        "video_hosting.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "JSON name of the field",
+                    "type": "string",
+                    "example": "title"
+                },
+                "reason": {
+                    "description": "Why the field is refused",
+                    "type": "string",
+                    "example": "must not contain \u003c or \u003e"
+                }
+            }
+        },
         "video_hosting.ItemMetadata": {
             "type": "object",
             "required": [
@@ -451,14 +3143,13 @@ const docTemplate = `{
             ],
             "properties": {
                 "description": {
-                    "description": "Short text describing the content of the item\nYoutube actually limits to 5000 bytes, which *isn't* 5000 characters\nhttps://developers.google.com/youtube/v3/docs/videos#properties",
+                    "description": "Short text describing the content of the item\nYoutube actually limits to 5000 bytes, which *isn't* 5000 characters. This is checked by its MetadataValidator\nhttps://developers.google.com/youtube/v3/docs/videos#properties",
                     "type": "string",
-                    "maxLength": 1000
+                    "maxLength": 5000
                 },
                 "title": {
-                    "description": "Title of the item\nThe max character limitation is currently taken from the Yt docs\nhttps://developers.google.com/youtube/v3/docs/videos#properties\nThis may change if another provider is requiring less than 100 characters",
-                    "type": "string",
-                    "maxLength": 100
+                    "description": "Title of the item\nIts length is limited by each host, and depends on the kind of item. This is checked by its MetadataValidator",
+                    "type": "string"
                 },
                 "visibility": {
                     "description": "Visibility of the item",
@@ -495,6 +3186,65 @@ const docTemplate = `{
                 "visibility": {
                     "description": "public/private/unlisted",
                     "type": "string"
+                },
+                "watchPrefix": {
+                    "description": "Url prefix necessary to watch the playlist. ie https://www.youtube.com/playlist?list= for Youtube",
+                    "type": "string"
+                }
+            }
+        },
+        "video_hosting.ProcessingProgress": {
+            "type": "object",
+            "properties": {
+                "partsProcessed": {
+                    "description": "Number of parts already processed",
+                    "type": "integer"
+                },
+                "partsTotal": {
+                    "description": "Estimated number of parts to process",
+                    "type": "integer"
+                },
+                "timeLeftMs": {
+                    "description": "Estimated time until the processing completes, in milliseconds",
+                    "type": "integer"
+                }
+            }
+        },
+        "video_hosting.QuotaStatus": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "description": "Total units available each day",
+                    "type": "integer"
+                },
+                "remaining": {
+                    "description": "Units still available until the next reset",
+                    "type": "integer"
+                },
+                "resetAt": {
+                    "description": "Time of the next reset",
+                    "type": "string"
+                },
+                "used": {
+                    "description": "Units consumed since the last reset",
+                    "type": "integer"
+                }
+            }
+        },
+        "video_hosting.Statistics": {
+            "type": "object",
+            "properties": {
+                "commentCount": {
+                    "type": "integer"
+                },
+                "favoriteCount": {
+                    "type": "integer"
+                },
+                "likeCount": {
+                    "type": "integer"
+                },
+                "viewCount": {
+                    "type": "integer"
                 }
             }
         },
@@ -513,9 +3263,32 @@ const docTemplate = `{
                     "description": "Video duration in seconds",
                     "type": "integer"
                 },
+                "failureReason": {
+                    "description": "Why the upload or its processing failed",
+                    "type": "string",
+                    "example": "codec"
+                },
                 "id": {
                     "type": "string"
                 },
+                "processingProgress": {
+                    "description": "Advancement of the processing, while it is running",
+                    "$ref": "#/definitions/video_hosting.ProcessingProgress"
+                },
+                "processingStatus": {
+                    "description": "Processing status on the hosting platform. The duration is only final once the processing succeeded",
+                    "type": "string",
+                    "example": "succeeded"
+                },
+                "rejectionReason": {
+                    "description": "Why the hosting platform rejected the video",
+                    "type": "string",
+                    "example": "duplicate"
+                },
+                "statistics": {
+                    "description": "Audience of the video, only when requested",
+                    "$ref": "#/definitions/video_hosting.Statistics"
+                },
                 "thumbnailUrl": {
                     "description": "Playlist thumbnail",
                     "type": "string"
@@ -524,33 +3297,165 @@ const docTemplate = `{
                     "description": "Video display name",
                     "type": "string"
                 },
+                "uploadStatus": {
+                    "description": "Upload status on the hosting platform, a failed or rejected upload won't ever be watchable",
+                    "type": "string",
+                    "example": "processed"
+                },
                 "visibility": {
                     "description": "public/private/unlisted",
                     "type": "string"
+                },
+                "watchPrefix": {
+                    "description": "Url prefix necessary to watch the video. ie https://www.youtube.com/watch?v= for Youtube",
+                    "type": "string"
+                }
+            }
+        },
+        "video_store_service.Drift": {
+            "type": "object",
+            "properties": {
+                "actual": {
+                    "description": "Metadata found on the host, absent if the item is missing",
+                    "$ref": "#/definitions/video_hosting.ItemMetadata"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "video"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "metadata"
+                },
+                "recorded": {
+                    "description": "Metadata as last set through the service",
+                    "$ref": "#/definitions/video_hosting.ItemMetadata"
+                },
+                "uploadStatus": {
+                    "description": "Upload status found on the host, only for failed processing",
+                    "type": "string",
+                    "example": "rejected"
+                }
+            }
+        },
+        "video_store_service.PublishReport": {
+            "type": "object",
+            "properties": {
+                "jobId": {
+                    "type": "string",
+                    "example": "recordings/1234/manifest.yaml"
+                },
+                "manifestKey": {
+                    "type": "string",
+                    "example": "recordings/1234/manifest.yaml"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/video_store_service.PublishStep"
+                    }
+                },
+                "succeeded": {
+                    "description": "Whether all the steps succeeded",
+                    "type": "boolean"
+                },
+                "video": {
+                    "description": "Published video, absent if the upload failed",
+                    "$ref": "#/definitions/video_hosting.Video"
+                }
+            }
+        },
+        "video_store_service.PublishStep": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Why the step failed",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "What the step does : \"upload\", \"tags\", \"thumbnail\", \"caption:\u003clanguage\u003e\", \"playlist:\u003cid\u003e\" or \"schedule\"",
+                    "type": "string",
+                    "example": "upload"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "done"
+                }
+            }
+        },
+        "video_store_service.ReconcileReport": {
+            "type": "object",
+            "properties": {
+                "drifts": {
+                    "description": "Every difference found",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/video_store_service.Drift"
+                    }
+                },
+                "error": {
+                    "description": "Why the comparison couldn't complete, the drifts found until then are still reported",
+                    "type": "string"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "host": {
+                    "description": "Name of the compared host",
+                    "type": "string",
+                    "example": "youtube"
+                },
+                "playlists": {
+                    "description": "Number of recorded playlists compared with the host",
+                    "type": "integer"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "videos": {
+                    "description": "Number of recorded videos compared with the host",
+                    "type": "integer"
                 }
             }
         },
         "videos_controller.CreateVideoBody": {
             "type": "object",
             "required": [
+                "jobId",
                 "storageKey",
                 "title",
                 "visibility"
             ],
             "properties": {
                 "description": {
-                    "description": "Short text describing the content of the item\nYoutube actually limits to 5000 bytes, which *isn't* 5000 characters\nhttps://developers.google.com/youtube/v3/docs/videos#properties",
+                    "description": "Short text describing the content of the item\nYoutube actually limits to 5000 bytes, which *isn't* 5000 characters. This is checked by its MetadataValidator\nhttps://developers.google.com/youtube/v3/docs/videos#properties",
                     "type": "string",
-                    "maxLength": 1000
+                    "maxLength": 5000
+                },
+                "jobId": {
+                    "description": "UUID of this uploading job, necessary to tell the jobs apart\nwhen multiple are running concurrently",
+                    "type": "string"
                 },
                 "storageKey": {
                     "description": "Key to retrieve the video from the object storage",
                     "type": "string"
                 },
-                "title": {
-                    "description": "Title of the item\nThe max character limitation is currently taken from the Yt docs\nhttps://developers.google.com/youtube/v3/docs/videos#properties\nThis may change if another provider is requiring less than 100 characters",
+                "template": {
+                    "description": "Name of a metadata template. If set, the title and description are rendered from it",
                     "type": "string",
-                    "maxLength": 100
+                    "example": "session"
+                },
+                "title": {
+                    "description": "Title of the item\nIts length is limited by each host, and depends on the kind of item. This is checked by its MetadataValidator",
+                    "type": "string"
+                },
+                "variables": {
+                    "description": "Values the template is rendered with",
+                    "type": "object",
+                    "additionalProperties": {}
                 },
                 "visibility": {
                     "description": "Visibility of the item",
@@ -558,6 +3463,20 @@ const docTemplate = `{
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "Static API key",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "JWT bearer token, as \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"net/http"
+	"strings"
+	"video-manager/internal/logger"
+	"video-manager/internal/problem"
+)
+
+var (
+	log = logger.Build()
+)
+
+// Scope A permission granted to a client
+type Scope string
+
+const (
+	VideosRead     Scope = "videos:read"
+	VideosWrite    Scope = "videos:write"
+	PlaylistsRead  Scope = "playlists:read"
+	PlaylistsWrite Scope = "playlists:write"
+	CommandsWrite  Scope = "commands:write"
+	QuotaRead      Scope = "quota:read"
+	ConfigRead     Scope = "config:read"
+	HostsRead      Scope = "hosts:read"
+	HostsAuthorize Scope = "hosts:authorize"
+	ReconcileRead  Scope = "reconcile:read"
+)
+
+const (
+	// Header to provide a static API key in
+	ApiKeyHeader = "X-API-Key"
+	// Header the Dapr sidecar uses to authenticate itself when APP_API_TOKEN is set
+	// https://docs.dapr.io/operations/security/app-api-token/
+	DaprApiTokenHeader = "dapr-api-token"
+	// Key under which the authenticated principal is stored in the gin context
+	PrincipalKey = "principal"
+	// Name of the principal used by the Dapr sidecar
+	DaprPrincipal = "dapr-sidecar"
+)
+
+// ApiKey A static API key. Only the key hash is known to the server
+type ApiKey struct {
+	// Name of the client using this key
+	Name string
+	// Hex-encoded SHA-256 hash of the key
+	Hash string
+	// Permissions granted to this key
+	Scopes []Scope
+}
+
+// Principal An authenticated client
+type Principal struct {
+	// Either the API key name or the JWT subject
+	Id string
+	// Permissions granted to this client
+	Scopes []Scope
+}
+
+// HasScopes Returns true if all the required scopes are granted to the principal
+func (p *Principal) HasScopes(required ...Scope) bool {
+	for _, r := range required {
+		granted := false
+		for _, s := range p.Scopes {
+			if s == r {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return false
+		}
+	}
+	return true
+}
+
+// Options All accepted credentials
+type Options struct {
+	// Static API keys
+	ApiKeys []ApiKey
+	// Either a http(s) URL or a path to a JWKS file. Bearer tokens are refused if empty
+	JwksLocation string
+	// Expected "iss" claim of bearer tokens. Not checked if empty
+	Issuer string
+	// Expected "aud" claim of bearer tokens. Not checked if empty
+	Audience string
+	// Token the Dapr sidecar sends with each request. Not checked if empty
+	DaprApiToken string
+}
+
+// Authenticator Check the credentials of all incoming requests
+type Authenticator struct {
+	// API keys, by hash
+	keys map[string]ApiKey
+	// Public keys for bearer tokens
+	jwks *KeySet
+	opt  Options
+}
+
+// NewAuthenticator Build an authenticator accepting the credentials described in opt.
+// The users and the Dapr sidecar are authenticated independently : the routes of either are open to anyone
+// if no credentials are configured for them, see Enabled
+func NewAuthenticator(opt Options) (*Authenticator, error) {
+	a := &Authenticator{keys: make(map[string]ApiKey, len(opt.ApiKeys)), opt: opt}
+	for _, k := range opt.ApiKeys {
+		hash := strings.ToLower(k.Hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha256.Size*2 {
+			return nil, fmt.Errorf(`API key "%s" hash must be an hex-encoded SHA-256 hash`, k.Name)
+		}
+		a.keys[hash] = k
+	}
+	if opt.JwksLocation != "" {
+		jwks, err := NewKeySet(opt.JwksLocation)
+		if err != nil {
+			return nil, err
+		}
+		a.jwks = jwks
+	}
+	return a, nil
+}
+
+// Enabled Returns false when no credentials are configured for the clients of a route requiring these scopes.
+// The users' routes are called with an API key or a bearer token. The commands routes (CommandsWrite) are called by
+// the Dapr sidecar with the Dapr API token, or by a user granted CommandsWrite : they are only open when neither is configured
+func (a *Authenticator) Enabled(scopes ...Scope) bool {
+	users := len(a.keys) > 0 || a.jwks != nil
+	for _, s := range scopes {
+		if s == CommandsWrite {
+			return users || a.opt.DaprApiToken != ""
+		}
+	}
+	return users
+}
+
+// Require Middleware rejecting any request that isn't authenticated (401) or lacks any of the required scopes (403)
+func (a *Authenticator) Require(scopes ...Scope) gin.HandlerFunc {
+	enabled := a.Enabled(scopes...)
+	return func(c *gin.Context) {
+		if !enabled {
+			c.Next()
+			return
+		}
+		principal, err := a.Authenticate(c.Request)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="video-store"`)
+			problem.AbortWith(c, problem.Unauthorized, `authentication failed: %s`, err.Error())
+			return
+		}
+		if !principal.HasScopes(scopes...) {
+			problem.AbortWith(c, problem.InsufficientScope, `"%s" is missing one of the required scopes %v`, principal.Id, scopes)
+			return
+		}
+		c.Set(PrincipalKey, principal)
+		c.Next()
+	}
+}
+
+// Authenticate Find out who is making the request
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if token := r.Header.Get(DaprApiTokenHeader); token != "" && a.opt.DaprApiToken != "" {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.opt.DaprApiToken)) != 1 {
+			return nil, fmt.Errorf("invalid Dapr API token")
+		}
+		return &Principal{Id: DaprPrincipal, Scopes: []Scope{CommandsWrite}}, nil
+	}
+	if key := r.Header.Get(ApiKeyHeader); key != "" {
+		return a.authenticateApiKey(key)
+	}
+	authz := r.Header.Get("Authorization")
+	if scheme, token, found := strings.Cut(authz, " "); found {
+		switch strings.ToLower(scheme) {
+		case "apikey":
+			return a.authenticateApiKey(token)
+		case "bearer":
+			return a.authenticateBearer(token)
+		}
+	}
+	return nil, fmt.Errorf("no credentials provided")
+}
+
+func (a *Authenticator) authenticateApiKey(key string) (*Principal, error) {
+	sum := sha256.Sum256([]byte(key))
+	k, ok := a.keys[hex.EncodeToString(sum[:])]
+	if !ok {
+		return nil, fmt.Errorf("invalid API key")
+	}
+	return &Principal{Id: k.Name, Scopes: k.Scopes}, nil
+}
+
+func (a *Authenticator) authenticateBearer(raw string) (*Principal, error) {
+	if a.jwks == nil {
+		return nil, fmt.Errorf("bearer tokens are not accepted")
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return a.jwks.Key(kid)
+	}, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bearer token : %w", err)
+	}
+	if a.opt.Issuer != "" && !claims.VerifyIssuer(a.opt.Issuer, true) {
+		return nil, fmt.Errorf("invalid bearer token : unexpected issuer")
+	}
+	if a.opt.Audience != "" && !claims.VerifyAudience(a.opt.Audience, true) {
+		return nil, fmt.Errorf("invalid bearer token : unexpected audience")
+	}
+	sub, _ := claims["sub"].(string)
+	return &Principal{Id: sub, Scopes: scopesFromClaims(claims)}, nil
+}
+
+// Extract the granted scopes from either the "scope" claim (space-separated string, RFC 8693)
+// or the "scp" claim (array of strings, used by some identity providers)
+func scopesFromClaims(claims jwt.MapClaims) []Scope {
+	var scopes []Scope
+	if s, ok := claims["scope"].(string); ok {
+		for _, sc := range strings.Fields(s) {
+			scopes = append(scopes, Scope(sc))
+		}
+	}
+	switch scp := claims["scp"].(type) {
+	case string:
+		for _, sc := range strings.Fields(scp) {
+			scopes = append(scopes, Scope(sc))
+		}
+	case []interface{}:
+		for _, sc := range scp {
+			if str, ok := sc.(string); ok {
+				scopes = append(scopes, Scope(str))
+			}
+		}
+	}
+	return scopes
+}
+
+// ParseApiKeys Parse a list of API keys formatted as "name:sha256:scope,scope;name:sha256:scope"
+func ParseApiKeys(in string) ([]ApiKey, error) {
+	var keys []ApiKey
+	for _, entry := range strings.Split(in, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// Scopes contains ":" as well, so only the first two are separators
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf(`invalid API key definition "%s", expected "name:sha256:scopes"`, entry)
+		}
+		key := ApiKey{Name: parts[0], Hash: parts[1]}
+		for _, s := range strings.Split(parts[2], ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				key.Scopes = append(key.Scopes, Scope(s))
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	testKey = "secret-key"
+	testKid = "test-kid"
+)
+
+type fixture struct {
+	authenticator *Authenticator
+	signingKey    *rsa.PrivateKey
+}
+
+func Setup(t *testing.T) *fixture {
+	gin.SetMode(gin.TestMode)
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksPath := writeJwks(t, testKid, &signingKey.PublicKey)
+	sum := sha256.Sum256([]byte(testKey))
+	a, err := NewAuthenticator(Options{
+		ApiKeys:      []ApiKey{{Name: "ci", Hash: hex.EncodeToString(sum[:]), Scopes: []Scope{VideosRead}}},
+		JwksLocation: jwksPath,
+		Issuer:       "https://issuer",
+		Audience:     "video-store",
+		DaprApiToken: "dapr-token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fixture{authenticator: a, signingKey: signingKey}
+}
+
+// Sign a token with the test key
+func (f *fixture) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKid
+	signed, err := token.SignedString(f.signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// Run a request with the provided headers through a route requiring "scopes"
+func (f *fixture) do(headers map[string]string, scopes ...Scope) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.GET("/", f.authenticator.Require(scopes...), func(c *gin.Context) {
+		c.String(http.StatusOK, c.MustGet(PrincipalKey).(*Principal).Id)
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "user",
+		"iss":   "https://issuer",
+		"aud":   "video-store",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "videos:read videos:write",
+	}
+}
+
+func TestAuthenticator_Disabled(t *testing.T) {
+	a, err := NewAuthenticator(Options{})
+	assert.Nil(t, err)
+	assert.False(t, a.Enabled())
+	router := gin.New()
+	router.GET("/", a.Require(VideosWrite), func(c *gin.Context) { c.Status(http.StatusOK) })
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthenticator_Partial(t *testing.T) {
+	sum := sha256.Sum256([]byte(testKey))
+	keys := []ApiKey{{Name: "ci", Hash: hex.EncodeToString(sum[:]), Scopes: []Scope{VideosRead}}}
+	serve := func(a *Authenticator, scope Scope) int {
+		router := gin.New()
+		router.GET("/", a.Require(scope), func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	// Only the sidecar must authenticate
+	a, err := NewAuthenticator(Options{DaprApiToken: "dapr-token"})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, serve(a, VideosRead))
+	assert.Equal(t, http.StatusUnauthorized, serve(a, CommandsWrite))
+
+	// Only the users must authenticate, the commands routes aren't open for all that
+	a, err = NewAuthenticator(Options{ApiKeys: keys})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, serve(a, VideosRead))
+	assert.Equal(t, http.StatusUnauthorized, serve(a, CommandsWrite))
+}
+
+func TestAuthenticator_InvalidHash(t *testing.T) {
+	_, err := NewAuthenticator(Options{ApiKeys: []ApiKey{{Name: "ci", Hash: "plaintext"}}})
+	assert.NotNil(t, err)
+}
+
+func TestAuthenticator_NoCredentials(t *testing.T) {
+	f := Setup(t)
+	w := f.do(nil, VideosRead)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+}
+
+func TestAuthenticator_ApiKey_Ok(t *testing.T) {
+	f := Setup(t)
+	w := f.do(map[string]string{ApiKeyHeader: testKey}, VideosRead)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ci", w.Body.String())
+	// The key can also be sent in the Authorization header
+	w = f.do(map[string]string{"Authorization": "ApiKey " + testKey}, VideosRead)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthenticator_ApiKey_Invalid(t *testing.T) {
+	f := Setup(t)
+	w := f.do(map[string]string{ApiKeyHeader: "wrong"}, VideosRead)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticator_ApiKey_MissingScope(t *testing.T) {
+	f := Setup(t)
+	w := f.do(map[string]string{ApiKeyHeader: testKey}, VideosWrite)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuthenticator_Bearer_Ok(t *testing.T) {
+	f := Setup(t)
+	w := f.do(map[string]string{"Authorization": "Bearer " + f.sign(t, validClaims())}, VideosRead, VideosWrite)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user", w.Body.String())
+}
+
+func TestAuthenticator_Bearer_ScpClaim(t *testing.T) {
+	f := Setup(t)
+	claims := validClaims()
+	delete(claims, "scope")
+	claims["scp"] = []string{"playlists:read"}
+	w := f.do(map[string]string{"Authorization": "Bearer " + f.sign(t, claims)}, PlaylistsRead)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthenticator_Bearer_MissingScope(t *testing.T) {
+	f := Setup(t)
+	w := f.do(map[string]string{"Authorization": "Bearer " + f.sign(t, validClaims())}, PlaylistsWrite)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuthenticator_Bearer_Expired(t *testing.T) {
+	f := Setup(t)
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	w := f.do(map[string]string{"Authorization": "Bearer " + f.sign(t, claims)}, VideosRead)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticator_Bearer_WrongIssuerOrAudience(t *testing.T) {
+	f := Setup(t)
+	claims := validClaims()
+	claims["iss"] = "https://someone-else"
+	w := f.do(map[string]string{"Authorization": "Bearer " + f.sign(t, claims)}, VideosRead)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	claims = validClaims()
+	claims["aud"] = "another-api"
+	w = f.do(map[string]string{"Authorization": "Bearer " + f.sign(t, claims)}, VideosRead)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticator_Bearer_WrongSignature(t *testing.T) {
+	f := Setup(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims())
+	token.Header["kid"] = testKid
+	signed, err := token.SignedString(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := f.do(map[string]string{"Authorization": "Bearer " + signed}, VideosRead)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticator_Bearer_HmacRefused(t *testing.T) {
+	f := Setup(t)
+	// Symmetric algorithms must never be accepted, as the "secret" would be the public key
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	token.Header["kid"] = testKid
+	signed, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := f.do(map[string]string{"Authorization": "Bearer " + signed}, VideosRead)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticator_DaprToken(t *testing.T) {
+	f := Setup(t)
+	w := f.do(map[string]string{DaprApiTokenHeader: "dapr-token"}, CommandsWrite)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, DaprPrincipal, w.Body.String())
+
+	w = f.do(map[string]string{DaprApiTokenHeader: "wrong"}, CommandsWrite)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The sidecar is only allowed to send commands
+	w = f.do(map[string]string{DaprApiTokenHeader: "dapr-token"}, VideosWrite)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestParseApiKeys(t *testing.T) {
+	keys, err := ParseApiKeys("ci:abcd:videos:read,videos:write; bot:ef01:playlists:read")
+	assert.Nil(t, err)
+	assert.Equal(t, []ApiKey{
+		{Name: "ci", Hash: "abcd", Scopes: []Scope{VideosRead, VideosWrite}},
+		{Name: "bot", Hash: "ef01", Scopes: []Scope{PlaylistsRead}},
+	}, keys)
+
+	keys, err = ParseApiKeys("")
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+
+	_, err = ParseApiKeys("ci-abcd")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Minimum delay between two fetches of a remote JWKS.
+// An unknown key ID triggers a refresh (the issuer may have rotated its keys), this
+// prevents any client from making us hammer the issuer with forged key IDs
+const jwksMinRefreshInterval = time.Minute
+
+// KeySet A set of public keys used to check JWT signatures, loaded from a JWKS document
+// https://www.rfc-editor.org/rfc/rfc7517
+type KeySet struct {
+	// Either a http(s) URL or a path to a local file
+	location string
+	// Public keys, by key ID
+	keys map[string]interface{}
+	// Last time the keys were fetched
+	fetchedAt time.Time
+	mu        sync.RWMutex
+	client    *http.Client
+}
+
+// A single JSON Web Key. Only the fields required to build a public key are declared
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// NewKeySet Load a JWKS from either a http(s) URL or a local file
+func NewKeySet(location string) (*KeySet, error) {
+	ks := &KeySet{location: location, client: &http.Client{Timeout: 10 * time.Second}}
+	if err := ks.refresh(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Key Retrieve the public key identified by kid.
+// If the key is unknown, the key set is refreshed once before giving up
+func (ks *KeySet) Key(kid string) (interface{}, error) {
+	ks.mu.RLock()
+	key, ok := ks.keys[kid]
+	canRefresh := ks.isRemote() && time.Since(ks.fetchedAt) > jwksMinRefreshInterval
+	ks.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if canRefresh {
+		if err := ks.refresh(); err != nil {
+			return nil, err
+		}
+		ks.mu.RLock()
+		key, ok = ks.keys[kid]
+		ks.mu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf(`unknown key ID "%s"`, kid)
+}
+
+// Fetch and parse the JWKS document again
+func (ks *KeySet) refresh() error {
+	raw, err := ks.read()
+	if err != nil {
+		return fmt.Errorf("could not read JWKS from %s : %w", ks.location, err)
+	}
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("invalid JWKS : %w", err)
+	}
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		// Keys that aren't meant to be used for signatures are ignored
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Warnf(`Ignoring JWK "%s" : %s`, jwk.Kid, err.Error())
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+	ks.mu.Unlock()
+	return nil
+}
+
+func (ks *KeySet) read() ([]byte, error) {
+	if !ks.isRemote() {
+		return os.ReadFile(ks.location)
+	}
+	res, err := ks.client.Get(ks.location)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return io.ReadAll(res.Body)
+}
+
+func (ks *KeySet) isRemote() bool {
+	return strings.HasPrefix(ks.location, "http://") || strings.HasPrefix(ks.location, "https://")
+}
+
+// Build the public key described by the JWK
+func (jwk *jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf(`unsupported curve "%s"`, jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf(`unsupported key type "%s"`, jwk.Kty)
+	}
+}
+
+// Decode a base64url-encoded big-endian integer
+func decodeBigInt(in string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(in, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Serialize a public key into a JWKS document
+func makeJwks(t *testing.T, kid string, pub interface{}) []byte {
+	enc := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	var jwk jsonWebKey
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		jwk = jsonWebKey{Kty: "RSA", Kid: kid, Use: "sig", N: enc(k.N), E: enc(big.NewInt(int64(k.E)))}
+	case *ecdsa.PublicKey:
+		jwk = jsonWebKey{Kty: "EC", Kid: kid, Crv: k.Curve.Params().Name, X: enc(k.X), Y: enc(k.Y)}
+	default:
+		t.Fatalf("unsupported key %T", pub)
+	}
+	b, err := json.Marshal(map[string]interface{}{"keys": []jsonWebKey{jwk}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Write a JWKS document in a temp file, returning its path
+func writeJwks(t *testing.T, kid string, pub interface{}) string {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, makeJwks(t, kid, pub), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKeySet_File_Rsa(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := NewKeySet(writeJwks(t, "rsa", &key.PublicKey))
+	assert.Nil(t, err)
+	pub, err := ks.Key("rsa")
+	assert.Nil(t, err)
+	assert.True(t, key.PublicKey.Equal(pub))
+
+	_, err = ks.Key("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestKeySet_File_Ec(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := NewKeySet(writeJwks(t, "ec", &key.PublicKey))
+	assert.Nil(t, err)
+	pub, err := ks.Key("ec")
+	assert.Nil(t, err)
+	assert.True(t, key.PublicKey.Equal(pub))
+}
+
+func TestKeySet_File_NotFound(t *testing.T) {
+	_, err := NewKeySet(filepath.Join(t.TempDir(), "nope.json"))
+	assert.NotNil(t, err)
+}
+
+func TestKeySet_Remote_RefreshOnUnknownKid(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The server first advertises an "old" key, then the rotated one
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kid := "old"
+		if atomic.AddInt32(&calls, 1) > 1 {
+			kid = "new"
+		}
+		_, _ = w.Write(makeJwks(t, kid, &key.PublicKey))
+	}))
+	defer server.Close()
+
+	ks, err := NewKeySet(server.URL)
+	assert.Nil(t, err)
+	// Too soon to refresh
+	_, err = ks.Key("new")
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Pretend the last fetch was a while ago
+	ks.fetchedAt = time.Now().Add(-2 * jwksMinRefreshInterval)
+	_, err = ks.Key("new")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,315 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dapr/go-sdk/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+	"video-manager/internal/tracing"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// Prefix of all the state keys of the catalog
+const keyPrefix = "catalog-"
+
+// Number of attempts to update an entry or an index modified concurrently by another replica
+const updateAttempts = 5
+
+// Kind Kind of item recorded in the catalog
+type Kind string
+
+const (
+	Video    Kind = "video"
+	Playlist Kind = "playlist"
+)
+
+// Entry An item created or modified through the service
+type Entry struct {
+	Kind Kind   `json:"kind" example:"video"`
+	Id   string `json:"id"`
+	// Name of the host the item is on
+	Host string `json:"host" example:"youtube"`
+	// Job that uploaded the video
+	JobId string `json:"jobId,omitempty"`
+	// Key of the uploaded recording on the object storage
+	StorageKey string `json:"storageKey,omitempty"`
+	// Key of the last thumbnail set from the object storage
+	ThumbnailKey string `json:"thumbnailKey,omitempty"`
+	// Keywords of the video, as last set through the service
+	Tags []string `json:"tags,omitempty"`
+	// Languages of the captions added to the video through the service
+	Captions []string `json:"captions,omitempty"`
+	// When the video is scheduled to be published
+	PublishAt *time.Time `json:"publishAt,omitempty"`
+	// Metadata, as last set through the service
+	Metadata video_hosting.ItemMetadata `json:"metadata"`
+	// IDs of the videos added to the playlist through the service
+	Items []string `json:"items,omitempty"`
+	// Upload status of the video, as last seen on the host
+	UploadStatus video_hosting.UploadStatus `json:"uploadStatus,omitempty"`
+	// When the item was first recorded
+	CreatedAt time.Time `json:"createdAt"`
+	// When the item was last modified through the service
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Catalog Records every video and playlist created or modified through the service in a state store,
+// telling which recording became which video and listing them without querying the hosting platform
+type Catalog[T StateProxy] struct {
+	// Name of the Dapr component to use
+	componentName string
+	// Client to query the backend store
+	client *T
+	// Current time, overridable in tests
+	now func() time.Time
+}
+
+// NewDaprCatalog Prod ready constructor for a catalog using a Dapr state store
+func NewDaprCatalog(daprClient *client.Client, component string) *Catalog[client.Client] {
+	return &Catalog[client.Client]{
+		componentName: component,
+		client:        daprClient,
+		now:           time.Now,
+	}
+}
+
+// NewCatalog General purpose catalog
+func NewCatalog[T StateProxy](component string, client T) *Catalog[T] {
+	return &Catalog[T]{
+		componentName: component,
+		client:        &client,
+		now:           time.Now,
+	}
+}
+
+// StateProxy Proxy to query the backend store
+type StateProxy interface {
+	SaveState(ctx context.Context, storeName, key string, data []byte, meta map[string]string, so ...client.StateOption) error
+	GetState(ctx context.Context, storeName, key string, meta map[string]string) (item *client.StateItem, err error)
+	GetBulkState(ctx context.Context, storeName string, keys []string, meta map[string]string, parallelism int32) ([]*client.BulkStateItem, error)
+	SaveBulkState(ctx context.Context, storeName string, items ...*client.SetStateItem) error
+	DeleteState(ctx context.Context, storeName, key string, meta map[string]string) error
+}
+
+// Get The entry of the item "id" of host. Nil if it isn't recorded
+func (c Catalog[T]) Get(ctx context.Context, host string, kind Kind, id string) (entry *Entry, err error) {
+	ctx, span := c.start(ctx, "get", host, kind)
+	defer func() { tracing.End(span, err) }()
+	return c.get(ctx, entryKey(host, kind, id))
+}
+
+// Update Apply fn to the entry of the item "id" of host, recording it first if needed.
+// fn is applied again to the entry read anew if it was modified meanwhile
+func (c Catalog[T]) Update(ctx context.Context, host string, kind Kind, id string, fn func(entry *Entry)) (entry *Entry, err error) {
+	ctx, span := c.start(ctx, "update", host, kind)
+	defer func() { tracing.End(span, err) }()
+	key := entryKey(host, kind, id)
+	var isNew bool
+	var previousSource string
+	for attempt := 0; ; attempt++ {
+		var etag string
+		entry, etag, err = c.entry(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		isNew = entry == nil
+		if isNew {
+			entry = &Entry{Kind: kind, Id: id, Host: host, CreatedAt: c.now().UTC()}
+		}
+		previousSource = entry.StorageKey
+		fn(entry)
+		entry.UpdatedAt = c.now().UTC()
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		if err = c.save(ctx, key, data, etag); err == nil {
+			break
+		}
+		if attempt == updateAttempts-1 {
+			return nil, fmt.Errorf(`could not update catalog entry "%s" : %w`, key, err)
+		}
+	}
+	if isNew {
+		err = c.updateIndex(ctx, indexKey(host, kind), func(ids []string) []string { return append(ids, id) })
+		if err != nil {
+			return nil, err
+		}
+	}
+	if entry.StorageKey != "" && entry.StorageKey != previousSource {
+		err = (*c.client).SaveState(ctx, c.componentName, sourceKey(host, entry.StorageKey), []byte(id), nil)
+	}
+	return entry, err
+}
+
+// Remove Forget the item "id" of host. Removing an item that isn't recorded does nothing
+func (c Catalog[T]) Remove(ctx context.Context, host string, kind Kind, id string) (err error) {
+	ctx, span := c.start(ctx, "remove", host, kind)
+	defer func() { tracing.End(span, err) }()
+	key := entryKey(host, kind, id)
+	entry, err := c.get(ctx, key)
+	if err != nil || entry == nil {
+		return err
+	}
+	if entry.StorageKey != "" {
+		if err = (*c.client).DeleteState(ctx, c.componentName, sourceKey(host, entry.StorageKey), nil); err != nil {
+			return err
+		}
+	}
+	if err = (*c.client).DeleteState(ctx, c.componentName, key, nil); err != nil {
+		return err
+	}
+	return c.updateIndex(ctx, indexKey(host, kind), func(ids []string) []string {
+		kept := ids[:0]
+		for _, other := range ids {
+			if other != id {
+				kept = append(kept, other)
+			}
+		}
+		return kept
+	})
+}
+
+// List All the recorded items of host of the provided kind, in the order they were recorded
+func (c Catalog[T]) List(ctx context.Context, host string, kind Kind) (entries []Entry, err error) {
+	ctx, span := c.start(ctx, "list", host, kind)
+	defer func() { tracing.End(span, err) }()
+	ids, _, err := c.index(ctx, indexKey(host, kind))
+	if err != nil {
+		return nil, err
+	}
+	entries = make([]Entry, 0, len(ids))
+	if len(ids) == 0 {
+		return entries, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = entryKey(host, kind, id)
+	}
+	items, err := (*c.client).GetBulkState(ctx, c.componentName, keys, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*client.BulkStateItem, len(items))
+	for _, item := range items {
+		byKey[item.Key] = item
+	}
+	for _, key := range keys {
+		item, ok := byKey[key]
+		if ok && item.Error != "" {
+			return nil, fmt.Errorf(`could not read catalog entry "%s" : %s`, key, item.Error)
+		}
+		// An item removed while listing
+		if !ok || len(item.Value) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(item.Value, &entry); err != nil {
+			return nil, fmt.Errorf(`invalid catalog entry "%s" : %w`, key, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// FindBySource The video of host uploaded from the recording "storageKey". Nil if there is none
+func (c Catalog[T]) FindBySource(ctx context.Context, host string, storageKey string) (entry *Entry, err error) {
+	ctx, span := c.start(ctx, "find", host, Video)
+	defer func() { tracing.End(span, err) }()
+	item, err := (*c.client).GetState(ctx, c.componentName, sourceKey(host, storageKey), nil)
+	if err != nil || item == nil || len(item.Value) == 0 {
+		return nil, err
+	}
+	return c.get(ctx, entryKey(host, Video, string(item.Value)))
+}
+
+// Read the entry under key. Nil if there is none
+func (c Catalog[T]) get(ctx context.Context, key string) (*Entry, error) {
+	entry, _, err := c.entry(ctx, key)
+	return entry, err
+}
+
+// Read the entry under key, along with its ETag. Nil if there is none
+func (c Catalog[T]) entry(ctx context.Context, key string) (*Entry, string, error) {
+	item, err := (*c.client).GetState(ctx, c.componentName, key, nil)
+	if err != nil || item == nil || len(item.Value) == 0 {
+		return nil, "", err
+	}
+	var entry Entry
+	if err := json.Unmarshal(item.Value, &entry); err != nil {
+		return nil, "", fmt.Errorf(`invalid catalog entry "%s" : %w`, key, err)
+	}
+	return &entry, item.Etag, nil
+}
+
+// Read the IDs of the index under key, along with its ETag
+func (c Catalog[T]) index(ctx context.Context, key string) ([]string, string, error) {
+	item, err := (*c.client).GetState(ctx, c.componentName, key, nil)
+	if err != nil || item == nil || len(item.Value) == 0 {
+		return nil, "", err
+	}
+	var ids []string
+	if err := json.Unmarshal(item.Value, &ids); err != nil {
+		return nil, "", fmt.Errorf(`invalid catalog index "%s" : %w`, key, err)
+	}
+	return ids, item.Etag, nil
+}
+
+// Replace the IDs of the index under key with fn(IDs).
+// Several replicas may update the same index, the update is retried if the index changed meanwhile
+func (c Catalog[T]) updateIndex(ctx context.Context, key string, fn func(ids []string) []string) error {
+	var err error
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		var ids []string
+		var etag string
+		var data []byte
+		ids, etag, err = c.index(ctx, key)
+		if err == nil {
+			data, err = json.Marshal(fn(ids))
+		}
+		if err != nil {
+			return err
+		}
+		if err = c.save(ctx, key, data, etag); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf(`could not update catalog index "%s" : %w`, key, err)
+}
+
+// Save data under key, unless it changed since it was read with etag. An empty etag means it didn't exist
+func (c Catalog[T]) save(ctx context.Context, key string, data []byte, etag string) error {
+	item := &client.SetStateItem{
+		Key:     key,
+		Value:   data,
+		Options: &client.StateOptions{Concurrency: client.StateConcurrencyFirstWrite},
+	}
+	if etag != "" {
+		item.Etag = &client.ETag{Value: etag}
+	}
+	return (*c.client).SaveBulkState(ctx, c.componentName, item)
+}
+
+func (c Catalog[T]) start(ctx context.Context, operation string, host string, kind Kind) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "catalog "+operation, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("dapr.state_store", c.componentName),
+			attribute.String("catalog.host", host),
+			attribute.String("catalog.kind", string(kind)),
+		))
+}
+
+func entryKey(host string, kind Kind, id string) string {
+	return fmt.Sprintf("%s%s-%s-%s", keyPrefix, host, kind, id)
+}
+
+func indexKey(host string, kind Kind) string {
+	return fmt.Sprintf("%s%s-%s-index", keyPrefix, host, kind)
+}
+
+func sourceKey(host string, storageKey string) string {
+	return fmt.Sprintf("%s%s-source-%s", keyPrefix, host, storageKey)
+}
@@ -0,0 +1,239 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"github.com/dapr/go-sdk/client"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// State store keeping everything in memory, with an ETag per key
+type fakeState struct {
+	values map[string][]byte
+	etags  map[string]int
+	// Number of next index updates to refuse, as if another replica updated it first
+	conflicts int
+	err       error
+}
+
+func newFakeState() *fakeState {
+	return &fakeState{values: map[string][]byte{}, etags: map[string]int{}}
+}
+
+func (fs *fakeState) SaveState(_ context.Context, _, key string, data []byte, _ map[string]string, _ ...client.StateOption) error {
+	if fs.err != nil {
+		return fs.err
+	}
+	fs.values[key] = data
+	fs.etags[key]++
+	return nil
+}
+
+func (fs *fakeState) GetState(_ context.Context, _, key string, _ map[string]string) (*client.StateItem, error) {
+	if fs.err != nil {
+		return nil, fs.err
+	}
+	item := &client.StateItem{Key: key, Value: fs.values[key]}
+	if _, ok := fs.values[key]; ok {
+		item.Etag = strconv.Itoa(fs.etags[key])
+	}
+	return item, nil
+}
+
+func (fs *fakeState) GetBulkState(_ context.Context, _ string, keys []string, _ map[string]string, _ int32) ([]*client.BulkStateItem, error) {
+	items := make([]*client.BulkStateItem, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, &client.BulkStateItem{Key: key, Value: fs.values[key]})
+	}
+	return items, nil
+}
+
+func (fs *fakeState) SaveBulkState(_ context.Context, _ string, items ...*client.SetStateItem) error {
+	for _, item := range items {
+		if fs.conflicts > 0 && strings.HasSuffix(item.Key, "-index") {
+			fs.conflicts--
+			return fmt.Errorf("possible etag mismatch")
+		}
+		if item.Etag != nil && item.Etag.Value != strconv.Itoa(fs.etags[item.Key]) {
+			return fmt.Errorf("possible etag mismatch")
+		}
+		fs.values[item.Key] = item.Value
+		fs.etags[item.Key]++
+	}
+	return nil
+}
+
+func (fs *fakeState) DeleteState(_ context.Context, _, key string, _ map[string]string) error {
+	delete(fs.values, key)
+	delete(fs.etags, key)
+	return nil
+}
+
+func Setup() (*Catalog[*fakeState], *fakeState) {
+	state := newFakeState()
+	c := NewCatalog[*fakeState]("statestore", state)
+	now := time.Unix(1662202180, 0)
+	c.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return c, state
+}
+
+func TestCatalog_Update(t *testing.T) {
+	c, _ := Setup()
+	ctx := context.Background()
+	created, err := c.Update(ctx, "youtube", Video, "1", func(entry *Entry) {
+		entry.JobId = "job"
+		entry.StorageKey = "recording.mp4"
+		entry.Metadata = video_hosting.ItemMetadata{Title: "title", Visibility: video_hosting.Unlisted}
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "youtube", created.Host)
+	assert.Equal(t, Video, created.Kind)
+
+	// Later modifications keep what they don't change
+	updated, err := c.Update(ctx, "youtube", Video, "1", func(entry *Entry) {
+		entry.Metadata.Title = "new title"
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "job", updated.JobId)
+	assert.Equal(t, "new title", updated.Metadata.Title)
+	assert.Equal(t, created.CreatedAt, updated.CreatedAt)
+	assert.True(t, updated.UpdatedAt.After(created.UpdatedAt))
+
+	got, err := c.Get(ctx, "youtube", Video, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, updated, got)
+	// Hosts and kinds are kept apart
+	got, err = c.Get(ctx, "backup", Video, "1")
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+	got, err = c.Get(ctx, "youtube", Playlist, "1")
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+}
+
+func TestCatalog_FindBySource(t *testing.T) {
+	c, _ := Setup()
+	ctx := context.Background()
+	_, err := c.Update(ctx, "youtube", Video, "1", func(entry *Entry) { entry.StorageKey = "recording.mp4" })
+	assert.Nil(t, err)
+
+	found, err := c.FindBySource(ctx, "youtube", "recording.mp4")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", found.Id)
+	found, err = c.FindBySource(ctx, "youtube", "other.mp4")
+	assert.Nil(t, err)
+	assert.Nil(t, found)
+}
+
+func TestCatalog_List(t *testing.T) {
+	c, _ := Setup()
+	ctx := context.Background()
+	for _, id := range []string{"1", "2", "3"} {
+		_, err := c.Update(ctx, "youtube", Video, id, func(entry *Entry) {})
+		assert.Nil(t, err)
+	}
+	// Updating a recorded item doesn't list it twice
+	_, err := c.Update(ctx, "youtube", Video, "1", func(entry *Entry) {})
+	assert.Nil(t, err)
+
+	entries, err := c.List(ctx, "youtube", Video)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "1", entries[0].Id)
+	assert.Equal(t, "3", entries[2].Id)
+
+	entries, err = c.List(ctx, "youtube", Playlist)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+	assert.NotNil(t, entries)
+}
+
+func TestCatalog_Remove(t *testing.T) {
+	c, state := Setup()
+	ctx := context.Background()
+	for _, id := range []string{"1", "2"} {
+		_, err := c.Update(ctx, "youtube", Video, id, func(entry *Entry) { entry.StorageKey = id + ".mp4" })
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, c.Remove(ctx, "youtube", Video, "1"))
+	// Removing twice does nothing
+	assert.Nil(t, c.Remove(ctx, "youtube", Video, "1"))
+
+	entries, err := c.List(ctx, "youtube", Video)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "2", entries[0].Id)
+	found, err := c.FindBySource(ctx, "youtube", "1.mp4")
+	assert.Nil(t, err)
+	assert.Nil(t, found)
+	assert.Len(t, state.values, 3)
+}
+
+func TestCatalog_Update_IndexConflict(t *testing.T) {
+	c, state := Setup()
+	ctx := context.Background()
+	// Another replica updated the index in between, the update is retried
+	state.conflicts = 2
+	_, err := c.Update(ctx, "youtube", Video, "1", func(entry *Entry) {})
+	assert.Nil(t, err)
+	entries, err := c.List(ctx, "youtube", Video)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	// Until it gives up
+	state.conflicts = updateAttempts
+	_, err = c.Update(ctx, "youtube", Video, "2", func(entry *Entry) {})
+	assert.NotNil(t, err)
+}
+
+func TestCatalog_Update_Conflict(t *testing.T) {
+	c, _ := Setup()
+	ctx := context.Background()
+	_, err := c.Update(ctx, "youtube", Video, "1", func(entry *Entry) {})
+	assert.Nil(t, err)
+	// Another replica modifies the entry in between, the update is applied again to its version
+	concurrent := false
+	updated, err := c.Update(ctx, "youtube", Video, "1", func(entry *Entry) {
+		if !concurrent {
+			concurrent = true
+			_, err := c.Update(ctx, "youtube", Video, "1", func(entry *Entry) { entry.Tags = []string{"tag"} })
+			assert.Nil(t, err)
+		}
+		entry.Metadata.Title = "title"
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tag"}, updated.Tags)
+	assert.Equal(t, "title", updated.Metadata.Title)
+	got, err := c.Get(ctx, "youtube", Video, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, updated, got)
+
+	// Until it gives up
+	_, err = c.Update(ctx, "youtube", Video, "1", func(entry *Entry) {
+		_, err := c.Update(ctx, "youtube", Video, "1", func(entry *Entry) {})
+		assert.Nil(t, err)
+	})
+	assert.NotNil(t, err)
+}
+
+func TestCatalog_Error(t *testing.T) {
+	c, state := Setup()
+	state.err = fmt.Errorf("state store unavailable")
+	_, err := c.Update(context.Background(), "youtube", Video, "1", func(entry *Entry) {})
+	assert.NotNil(t, err)
+	_, err = c.List(context.Background(), "youtube", Video)
+	assert.NotNil(t, err)
+
+	state.err = nil
+	state.values[entryKey("youtube", Video, "1")] = []byte("not json")
+	_, err = c.Get(context.Background(), "youtube", Video, "1")
+	assert.NotNil(t, err)
+}
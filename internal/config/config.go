@@ -0,0 +1,398 @@
+package config
+
+import (
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"video-manager/internal/templates"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// RedactedValue Replaces the value of every secret in the redacted configuration
+const RedactedValue = "<redacted>"
+
+// HostType Kind of video hosting platform
+type HostType string
+
+const (
+	Youtube HostType = "youtube"
+)
+
+// Config The whole service configuration.
+// Each setting can be defined in the configuration file, and overridden by the environment variable in its "env" tag
+type Config struct {
+	Server     Server     `yaml:"server" toml:"server" json:"server"`
+	Dapr       Dapr       `yaml:"dapr" toml:"dapr" json:"dapr"`
+	PubSub     PubSub     `yaml:"pubsub" toml:"pubsub" json:"pubsub"`
+	Auth       Auth       `yaml:"auth" toml:"auth" json:"auth"`
+	RateLimit  RateLimit  `yaml:"rateLimit" toml:"rateLimit" json:"rateLimit"`
+	Tracing    Tracing    `yaml:"tracing" toml:"tracing" json:"tracing"`
+	Health     Health     `yaml:"health" toml:"health" json:"health"`
+	Shutdown   Shutdown   `yaml:"shutdown" toml:"shutdown" json:"shutdown"`
+	Secrets    Secrets    `yaml:"secrets" toml:"secrets" json:"secrets"`
+	OAuth      OAuth      `yaml:"oauth" toml:"oauth" json:"oauth"`
+	Catalog    Catalog    `yaml:"catalog" toml:"catalog" json:"catalog"`
+	Processing Processing `yaml:"processing" toml:"processing" json:"processing"`
+	Templates  Templates  `yaml:"templates" toml:"templates" json:"templates"`
+	// All the video hosting platforms the service can upload to
+	Hosts []Host `yaml:"hosts" toml:"hosts" json:"hosts"`
+	// Name of the host serving the API. Optional if there is a single host
+	DefaultHost string `yaml:"defaultHost" toml:"defaultHost" json:"defaultHost"`
+}
+
+type Server struct {
+	// Listening port
+	Port int `yaml:"port" toml:"port" json:"port" env:"APP_PORT"`
+	// Either "debug", "release" or "test"
+	GinMode string `yaml:"ginMode" toml:"ginMode" json:"ginMode" env:"GIN_MODE"`
+	// IPs or CIDRs of the reverse proxies allowed to set the client IP with X-Forwarded-For, separated by ",".
+	// The client IP is always the one of the connection if empty
+	TrustedProxies string `yaml:"trustedProxies" toml:"trustedProxies" json:"trustedProxies" env:"TRUSTED_PROXIES"`
+}
+
+type Dapr struct {
+	// gRPC port of the sidecar
+	GrpcPort int `yaml:"grpcPort" toml:"grpcPort" json:"grpcPort" env:"DAPR_GRPC_PORT"`
+	// Max size of a message received from the sidecar, in MB. Videos are received in a single message
+	MaxRequestSizeMb int `yaml:"maxRequestSizeMb" toml:"maxRequestSizeMb" json:"maxRequestSizeMb" env:"DAPR_MAX_REQUEST_SIZE_MB"`
+	// Name of the binding pointing to the object storage
+	ObjectStore string `yaml:"objectStore" toml:"objectStore" json:"objectStore" env:"OBJECT_STORE_NAME"`
+	// Token the sidecar must send along each command
+	AppApiToken string `yaml:"appApiToken" toml:"appApiToken" json:"appApiToken" env:"APP_API_TOKEN" secret:"true"`
+}
+
+type PubSub struct {
+	// Name of the pubsub component. No events are published if empty
+	Name string `yaml:"name" toml:"name" json:"name" env:"PUBSUB_NAME"`
+	// Topic of the upload progress events
+	ProgressTopic string `yaml:"progressTopic" toml:"progressTopic" json:"progressTopic" env:"PUBSUB_TOPIC_PROGRESS"`
+	// Topic of the lifecycle events
+	EventsTopic string `yaml:"eventsTopic" toml:"eventsTopic" json:"eventsTopic" env:"PUBSUB_TOPIC_EVENTS"`
+	// Name of the pubsub component to receive commands from. Defaults to Name
+	CommandsName string `yaml:"commandsName" toml:"commandsName" json:"commandsName" env:"PUBSUB_COMMANDS_NAME"`
+	// Topic to receive commands from
+	CommandsTopic string `yaml:"commandsTopic" toml:"commandsTopic" json:"commandsTopic" env:"PUBSUB_TOPIC_COMMANDS"`
+}
+
+type Auth struct {
+	// Static API keys, as "name:sha256:scope,scope" separated by ";"
+	ApiKeys string `yaml:"apiKeys" toml:"apiKeys" json:"apiKeys" env:"AUTH_API_KEYS" secret:"true"`
+	// URL or path of the JWKS document used to verify bearer tokens
+	JwksUrl string `yaml:"jwksUrl" toml:"jwksUrl" json:"jwksUrl" env:"AUTH_JWKS_URL"`
+	// Expected issuer of bearer tokens
+	JwtIssuer string `yaml:"jwtIssuer" toml:"jwtIssuer" json:"jwtIssuer" env:"AUTH_JWT_ISSUER"`
+	// Expected audience of bearer tokens
+	JwtAudience string `yaml:"jwtAudience" toml:"jwtAudience" json:"jwtAudience" env:"AUTH_JWT_AUDIENCE"`
+}
+
+type RateLimit struct {
+	// Requests per second allowed for each client, 0 disables the rate limiting
+	Rps float64 `yaml:"rps" toml:"rps" json:"rps" env:"RATE_LIMIT_RPS"`
+	// Maximum number of requests a client can make in a single burst
+	Burst int `yaml:"burst" toml:"burst" json:"burst" env:"RATE_LIMIT_BURST"`
+}
+
+type Tracing struct {
+	// Either "none", "stdout" or "otlp"
+	Exporter string `yaml:"exporter" toml:"exporter" json:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	// Name of the service in the traces
+	ServiceName string `yaml:"serviceName" toml:"serviceName" json:"serviceName" env:"OTEL_SERVICE_NAME"`
+}
+
+type Health struct {
+	// How long the result of each readiness check is reused
+	CacheTTL Duration `yaml:"cacheTTL" toml:"cacheTTL" json:"cacheTTL" env:"HEALTH_CACHE_TTL" swaggertype:"string" example:"10s"`
+}
+
+type Shutdown struct {
+	// How long the running uploads are waited for when shutting down
+	DrainTimeout Duration `yaml:"drainTimeout" toml:"drainTimeout" json:"drainTimeout" env:"SHUTDOWN_DRAIN_TIMEOUT" swaggertype:"string" example:"20s"`
+}
+
+type Secrets struct {
+	// Name of the Dapr secret store component the hosts credentials can be read from
+	Store string `yaml:"store" toml:"store" json:"store" env:"SECRET_STORE_NAME"`
+	// How often the secrets are read again to pick up rotated credentials, 0 disables the refresh
+	RefreshInterval Duration `yaml:"refreshInterval" toml:"refreshInterval" json:"refreshInterval" env:"SECRET_REFRESH_INTERVAL" swaggertype:"string" example:"5m0s"`
+}
+
+type OAuth struct {
+	// Public URL of the consent callback, /v1/auth/youtube/callback. The consent routes are disabled if empty
+	RedirectUrl string `yaml:"redirectUrl" toml:"redirectUrl" json:"redirectUrl" env:"OAUTH_REDIRECT_URL"`
+	// Name of the Dapr state store component the refresh tokens obtained with a consent are saved in
+	StateStore string `yaml:"stateStore" toml:"stateStore" json:"stateStore" env:"OAUTH_STATE_STORE_NAME"`
+}
+
+type Catalog struct {
+	// Name of the Dapr state store component recording the uploaded videos and playlists. No catalog is kept if empty
+	Store string `yaml:"store" toml:"store" json:"store" env:"CATALOG_STORE_NAME"`
+	// How often the recorded items are compared with the hosts to detect the changes made outside the service, 0 disables it
+	ReconcileInterval Duration `yaml:"reconcileInterval" toml:"reconcileInterval" json:"reconcileInterval" env:"CATALOG_RECONCILE_INTERVAL" swaggertype:"string" example:"1h0m0s"`
+}
+
+type Templates struct {
+	// Name of the Dapr state store component holding more templates, under the keys "template-<name>". Optional
+	Store string `yaml:"store" toml:"store" json:"store" env:"TEMPLATES_STORE_NAME"`
+	// Templates of the video metadata, by name. They take precedence over the stored ones
+	Items map[string]templates.Template `yaml:"items" toml:"items" json:"items"`
+}
+
+type Processing struct {
+	// How often a freshly uploaded video is looked up until the host processed it, 0 disables it
+	PollInterval Duration `yaml:"pollInterval" toml:"pollInterval" json:"pollInterval" env:"PROCESSING_POLL_INTERVAL" swaggertype:"string" example:"30s"`
+	// How long a video is looked up at most, 0 means until it is processed
+	Timeout Duration `yaml:"timeout" toml:"timeout" json:"timeout" env:"PROCESSING_TIMEOUT" swaggertype:"string" example:"2h0m0s"`
+}
+
+// Host A video hosting platform, and the credentials to use it.
+// The environment variables only override the default host
+type Host struct {
+	// Unique name of the host
+	Name string `yaml:"name" toml:"name" json:"name"`
+	// Kind of platform
+	Type HostType `yaml:"type" toml:"type" json:"type"`
+	// OAuth client ID
+	ClientId string `yaml:"clientId" toml:"clientId" json:"clientId" env:"YT_CLIENT_ID"`
+	// OAuth client secret
+	ClientSecret string `yaml:"clientSecret" toml:"clientSecret" json:"clientSecret" env:"YT_CLIENT_SECRET" secret:"true"`
+	// OAuth refresh token of the account to upload with
+	RefreshToken string `yaml:"refreshToken" toml:"refreshToken" json:"refreshToken" env:"YT_REFRESH_TOKEN" secret:"true"`
+	// Name of the secret holding the credentials in the secret store.
+	// Its "clientId", "clientSecret" and "refreshToken" values replace the ones above
+	SecretName string `yaml:"secretName" toml:"secretName" json:"secretName" env:"YT_SECRET_NAME"`
+	// Category of the uploaded videos. Defaults to "24" (Entertainment) on Youtube
+	CategoryId string `yaml:"categoryId" toml:"categoryId" json:"categoryId" env:"YT_CATEGORY_ID"`
+	// Topic of the upload progress events of this host. Defaults to pubsub.progressTopic
+	ProgressTopic string `yaml:"progressTopic" toml:"progressTopic" json:"progressTopic"`
+	// Topic of the lifecycle events of this host. Defaults to pubsub.eventsTopic
+	EventsTopic string `yaml:"eventsTopic" toml:"eventsTopic" json:"eventsTopic"`
+	// Daily quota of the platform, in units
+	DailyQuota int64 `yaml:"dailyQuota" toml:"dailyQuota" json:"dailyQuota" env:"YT_DAILY_QUOTA"`
+}
+
+// Duration A time.Duration written as "10s", "1m30s"...
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Default Configuration used for every setting neither in the file nor in the environment
+func Default() Config {
+	return Config{
+		Server:     Server{Port: 8080},
+		Dapr:       Dapr{GrpcPort: 50001, MaxRequestSizeMb: 2000},
+		PubSub:     PubSub{ProgressTopic: "upload-state", EventsTopic: "video-store-events", CommandsTopic: "video-store-commands"},
+		RateLimit:  RateLimit{Rps: 10, Burst: 20},
+		Tracing:    Tracing{Exporter: "none", ServiceName: "video-store"},
+		Health:     Health{CacheTTL: Duration(10 * time.Second)},
+		Shutdown:   Shutdown{DrainTimeout: Duration(20 * time.Second)},
+		Secrets:    Secrets{RefreshInterval: Duration(5 * time.Minute)},
+		Catalog:    Catalog{ReconcileInterval: Duration(time.Hour)},
+		Processing: Processing{PollInterval: Duration(30 * time.Second), Timeout: Duration(2 * time.Hour)},
+	}
+}
+
+// Default values of each host
+var hostDefaults = map[HostType]Host{
+	Youtube: {DailyQuota: video_hosting.YoutubeDefaultDailyQuota},
+}
+
+// Load the configuration file at path, if any, then apply the environment overrides.
+// The file is either YAML or TOML, depending on its extension. The result still has to be validated
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := decodeFile(path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	// Environment variables can only target the default host, which is created if needed
+	host := cfg.defaultHostIndex()
+	if host < 0 && len(cfg.Hosts) == 0 && hasEnv(reflect.TypeOf(Host{})) {
+		cfg.Hosts = append(cfg.Hosts, Host{Name: string(Youtube), Type: Youtube})
+		host = 0
+	}
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem()); err != nil {
+		return nil, err
+	}
+	if host >= 0 {
+		if err := applyEnv(reflect.ValueOf(&cfg.Hosts[host]).Elem()); err != nil {
+			return nil, err
+		}
+	}
+	for i := range cfg.Hosts {
+		if cfg.Hosts[i].DailyQuota == 0 {
+			cfg.Hosts[i].DailyQuota = hostDefaults[cfg.Hosts[i].Type].DailyQuota
+		}
+		if cfg.Hosts[i].ProgressTopic == "" {
+			cfg.Hosts[i].ProgressTopic = cfg.PubSub.ProgressTopic
+		}
+		if cfg.Hosts[i].EventsTopic == "" {
+			cfg.Hosts[i].EventsTopic = cfg.PubSub.EventsTopic
+		}
+	}
+	if cfg.PubSub.CommandsName == "" {
+		cfg.PubSub.CommandsName = cfg.PubSub.Name
+	}
+	return &cfg, nil
+}
+
+// Host Returns the default host, serving the API. Nil if there is none
+func (cfg *Config) Host() *Host {
+	i := cfg.defaultHostIndex()
+	if i < 0 {
+		return nil
+	}
+	return &cfg.Hosts[i]
+}
+
+// Proxies Returns the trusted reverse proxies. Nil if there is none
+func (s *Server) Proxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(s.TrustedProxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
+// Redacted Returns a copy of the configuration, with all secrets replaced
+func (cfg *Config) Redacted() Config {
+	redacted := *cfg
+	redacted.Hosts = append([]Host(nil), cfg.Hosts...)
+	redact(reflect.ValueOf(&redacted).Elem())
+	for i := range redacted.Hosts {
+		redact(reflect.ValueOf(&redacted.Hosts[i]).Elem())
+	}
+	return redacted
+}
+
+// Index of the default host, -1 if there is none
+func (cfg *Config) defaultHostIndex() int {
+	if cfg.DefaultHost == "" && len(cfg.Hosts) == 1 {
+		return 0
+	}
+	for i, host := range cfg.Hosts {
+		if host.Name == cfg.DefaultHost {
+			return i
+		}
+	}
+	return -1
+}
+
+// Decode the YAML or TOML file at path into cfg
+func decodeFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read configuration file : %w", err)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, cfg)
+	case ".toml":
+		err = toml.Unmarshal(content, cfg)
+	default:
+		return fmt.Errorf(`unsupported configuration file extension "%s", expected ".yaml", ".yml" or ".toml"`, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid configuration file %s : %w", path, err)
+	}
+	return nil
+}
+
+// Whether any environment variable targets a field of t
+func hasEnv(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := t.Field(i).Tag.Lookup("env"); ok {
+			if _, set := os.LookupEnv(name); set {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Override each field of v tagged with "env" with the value of the matching environment variable, if set.
+// Slices (the hosts) are skipped
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(value); err != nil {
+				return err
+			}
+			continue
+		}
+		name, ok := field.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		raw, set := os.LookupEnv(name)
+		if !set {
+			continue
+		}
+		if err := setFromString(value, raw); err != nil {
+			return fmt.Errorf(`invalid value "%s" for %s : %w`, raw, name, err)
+		}
+	}
+	return nil
+}
+
+// Parse raw into v, according to the type of v
+func setFromString(v reflect.Value, raw string) error {
+	if v.Type() == reflect.TypeOf(Duration(0)) {
+		return v.Addr().Interface().(*Duration).UnmarshalText([]byte(raw))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(parsed)
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(parsed)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// Replace each non-empty field of v tagged as secret
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			redact(value)
+			continue
+		}
+		if field.Tag.Get("secret") == "true" && value.Kind() == reflect.String && value.String() != "" {
+			value.SetString(RedactedValue)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"video-manager/internal/templates"
+)
+
+const yamlConfig = `
+server:
+  port: 9090
+dapr:
+  objectStore: object-store
+pubsub:
+  name: pubsub
+health:
+  cacheTTL: 30s
+templates:
+  items:
+    session:
+      title: "Session {{.Number}}"
+      description: "{{.Summary}}"
+defaultHost: main
+hosts:
+  - name: main
+    type: youtube
+    clientId: id
+    clientSecret: secret
+    refreshToken: token
+  - name: backup
+    type: youtube
+    clientId: id2
+    clientSecret: secret2
+    refreshToken: token2
+    dailyQuota: 500000
+    categoryId: "20"
+    progressTopic: backup-upload-state
+`
+
+const tomlConfig = `
+defaultHost = "main"
+
+[server]
+port = 9090
+
+[dapr]
+objectStore = "object-store"
+
+[pubsub]
+name = "pubsub"
+
+[health]
+cacheTTL = "30s"
+
+[templates.items.session]
+title = "Session {{.Number}}"
+description = "{{.Summary}}"
+
+[[hosts]]
+name = "main"
+type = "youtube"
+clientId = "id"
+clientSecret = "secret"
+refreshToken = "token"
+
+[[hosts]]
+name = "backup"
+type = "youtube"
+clientId = "id2"
+clientSecret = "secret2"
+refreshToken = "token2"
+dailyQuota = 500000
+categoryId = "20"
+progressTopic = "backup-upload-state"
+`
+
+// Write content in a temporary file named name
+func writeConfig(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func assertSampleConfig(t *testing.T, cfg *Config) {
+	assert.Nil(t, cfg.Validate())
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.Equal(t, "object-store", cfg.Dapr.ObjectStore)
+	assert.Equal(t, Duration(30*time.Second), cfg.Health.CacheTTL)
+	// Commands are received from the events pubsub by default
+	assert.Equal(t, "pubsub", cfg.PubSub.CommandsName)
+	// Untouched settings keep their default value
+	assert.Equal(t, 50001, cfg.Dapr.GrpcPort)
+	assert.Equal(t, "upload-state", cfg.PubSub.ProgressTopic)
+	assert.Len(t, cfg.Hosts, 2)
+	assert.Equal(t, "main", cfg.Host().Name)
+	assert.Equal(t, int64(10000), cfg.Hosts[0].DailyQuota)
+	assert.Equal(t, int64(500000), cfg.Hosts[1].DailyQuota)
+	assert.Equal(t, "20", cfg.Hosts[1].CategoryId)
+	// Each host publishes on the global topics unless told otherwise
+	assert.Equal(t, "upload-state", cfg.Hosts[0].ProgressTopic)
+	assert.Equal(t, "backup-upload-state", cfg.Hosts[1].ProgressTopic)
+	assert.Equal(t, "video-store-events", cfg.Hosts[1].EventsTopic)
+	assert.Equal(t, templates.Template{Title: "Session {{.Number}}", Description: "{{.Summary}}"}, cfg.Templates.Items["session"])
+}
+
+func TestLoad_Yaml(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "config.yaml", yamlConfig))
+	assert.Nil(t, err)
+	assertSampleConfig(t, cfg)
+}
+
+func TestLoad_Toml(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "config.toml", tomlConfig))
+	assert.Nil(t, err)
+	assertSampleConfig(t, cfg)
+}
+
+func TestLoad_InvalidFile(t *testing.T) {
+	_, err := Load(writeConfig(t, "config.json", "{}"))
+	assert.NotNil(t, err)
+	_, err = Load(writeConfig(t, "config.yaml", "server: ["))
+	assert.NotNil(t, err)
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.NotNil(t, err)
+}
+
+func TestLoad_EnvOverride(t *testing.T) {
+	t.Setenv("APP_PORT", "8081")
+	t.Setenv("DAPR_GRPC_PORT", "50002")
+	t.Setenv("DAPR_MAX_REQUEST_SIZE_MB", "100")
+	t.Setenv("RATE_LIMIT_RPS", "2.5")
+	t.Setenv("SHUTDOWN_DRAIN_TIMEOUT", "1m")
+	t.Setenv("YT_REFRESH_TOKEN", "other")
+	cfg, err := Load(writeConfig(t, "config.yml", yamlConfig))
+	assert.Nil(t, err)
+	assert.Equal(t, 8081, cfg.Server.Port)
+	assert.Equal(t, 50002, cfg.Dapr.GrpcPort)
+	assert.Equal(t, 100, cfg.Dapr.MaxRequestSizeMb)
+	assert.Equal(t, 2.5, cfg.RateLimit.Rps)
+	assert.Equal(t, Duration(time.Minute), cfg.Shutdown.DrainTimeout)
+	// Only the default host is overridden
+	assert.Equal(t, "other", cfg.Hosts[0].RefreshToken)
+	assert.Equal(t, "token2", cfg.Hosts[1].RefreshToken)
+}
+
+func TestLoad_EnvOnly(t *testing.T) {
+	t.Setenv("OBJECT_STORE_NAME", "object-store")
+	t.Setenv("YT_CLIENT_ID", "id")
+	t.Setenv("YT_CLIENT_SECRET", "secret")
+	t.Setenv("YT_REFRESH_TOKEN", "token")
+	cfg, err := Load("")
+	assert.Nil(t, err)
+	assert.Nil(t, cfg.Validate())
+	// A Youtube host is created from the environment
+	assert.Equal(t, Host{Name: "youtube", Type: Youtube, ClientId: "id", ClientSecret: "secret", RefreshToken: "token", DailyQuota: 10000,
+		ProgressTopic: "upload-state", EventsTopic: "video-store-events"}, *cfg.Host())
+}
+
+func TestLoad_EnvSecret(t *testing.T) {
+	t.Setenv("OBJECT_STORE_NAME", "object-store")
+	t.Setenv("YT_SECRET_NAME", "youtube-credentials")
+	cfg, err := Load("")
+	assert.Nil(t, err)
+	// The credentials are read from the secret store, which must be defined
+	assert.Contains(t, cfg.Validate().Error(), "secrets.store")
+	t.Setenv("SECRET_STORE_NAME", "vault")
+	t.Setenv("SECRET_REFRESH_INTERVAL", "1m")
+	cfg, err = Load("")
+	assert.Nil(t, err)
+	assert.Nil(t, cfg.Validate())
+	assert.Equal(t, "youtube-credentials", cfg.Host().SecretName)
+	assert.Equal(t, Duration(time.Minute), cfg.Secrets.RefreshInterval)
+}
+
+func TestLoad_InvalidEnv(t *testing.T) {
+	t.Setenv("DAPR_GRPC_PORT", "port")
+	_, err := Load("")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "DAPR_GRPC_PORT")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = 0
+	cfg.Tracing.Exporter = "zipkin"
+	cfg.Catalog.ReconcileInterval = -1
+	cfg.Processing.Timeout = -1
+	cfg.Templates.Items = map[string]templates.Template{"session": {Title: "Session {{.Number"}}
+	cfg.Hosts = []Host{{Name: "a", Type: Youtube}, {Name: "a", Type: "vimeo"}}
+	err := cfg.Validate()
+	assert.NotNil(t, err)
+	// All the problems are reported at once
+	for _, expected := range []string{"server.port", "tracing.exporter", "dapr.objectStore", "defaultHost",
+		"hosts[0].clientId", "hosts[1].name", "hosts[1].type", "hosts[0].dailyQuota", "catalog.reconcileInterval", "processing.timeout",
+		"templates.items.session"} {
+		assert.Contains(t, err.Error(), expected)
+	}
+
+	// No host at all
+	cfg = Default()
+	cfg.Dapr.ObjectStore = "object-store"
+	err = cfg.Validate()
+	assert.NotNil(t, err)
+	assert.Len(t, strings.Split(err.Error(), "\n"), 1)
+	assert.Contains(t, err.Error(), "hosts")
+
+	// Unknown default host
+	cfg.Hosts = []Host{{Name: "a", Type: Youtube, ClientId: "id", ClientSecret: "secret", RefreshToken: "token", DailyQuota: 1}}
+	assert.Nil(t, cfg.Validate())
+	cfg.DefaultHost = "b"
+	assert.Contains(t, cfg.Validate().Error(), `no host is named "b"`)
+}
+
+func TestConfig_Validate_OAuth(t *testing.T) {
+	cfg := Default()
+	cfg.Dapr.ObjectStore = "object-store"
+	cfg.Hosts = []Host{{Name: "a", Type: Youtube, ClientId: "id", ClientSecret: "secret", DailyQuota: 1}}
+	assert.Contains(t, cfg.Validate().Error(), "hosts[0].refreshToken")
+
+	// The refresh token can be obtained with a consent
+	cfg.OAuth.StateStore = "statestore"
+	assert.Nil(t, cfg.Validate())
+	cfg.OAuth.RedirectUrl = "https://video-store.example.com/v1/auth/youtube/callback"
+	assert.Nil(t, cfg.Validate())
+
+	cfg.OAuth.RedirectUrl = "/v1/auth/youtube/callback"
+	cfg.OAuth.StateStore = ""
+	err := cfg.Validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "oauth.redirectUrl")
+	assert.Contains(t, err.Error(), "oauth.stateStore")
+}
+
+func TestConfig_Validate_TrustedProxies(t *testing.T) {
+	cfg := Default()
+	cfg.Dapr.ObjectStore = "object-store"
+	cfg.Hosts = []Host{{Name: "a", Type: Youtube, ClientId: "id", ClientSecret: "secret", RefreshToken: "token", DailyQuota: 1}}
+	assert.Nil(t, cfg.Server.Proxies())
+
+	cfg.Server.TrustedProxies = "10.0.0.1, 192.168.0.0/16,"
+	assert.Equal(t, []string{"10.0.0.1", "192.168.0.0/16"}, cfg.Server.Proxies())
+	assert.Nil(t, cfg.Validate())
+
+	cfg.Server.TrustedProxies = "10.0.0.1,proxy"
+	assert.Contains(t, cfg.Validate().Error(), `server.trustedProxies (TRUSTED_PROXIES) : must be IPs or CIDRs separated by ",", got "proxy"`)
+}
+
+func TestConfig_Validate_Auth(t *testing.T) {
+	cfg := Default()
+	cfg.Dapr.ObjectStore = "object-store"
+	cfg.Hosts = []Host{{Name: "a", Type: Youtube, ClientId: "id", ClientSecret: "secret", RefreshToken: "token", DailyQuota: 1}}
+	cfg.Auth.ApiKeys = "ci:abcd:videos:read"
+	assert.Nil(t, cfg.Validate())
+	assert.Len(t, cfg.Warnings(), 1)
+	assert.Contains(t, cfg.Warnings()[0], "APP_API_TOKEN")
+
+	// The sidecar couldn't deliver the commands otherwise
+	cfg.PubSub.CommandsName = "pubsub"
+	assert.Contains(t, cfg.Validate().Error(), "dapr.appApiToken")
+	cfg.Dapr.AppApiToken = "token"
+	assert.Nil(t, cfg.Validate())
+	assert.Empty(t, cfg.Warnings())
+
+	// The users don't have to authenticate
+	cfg.Auth.ApiKeys = ""
+	assert.Nil(t, cfg.Validate())
+	assert.Len(t, cfg.Warnings(), 1)
+	assert.Contains(t, cfg.Warnings()[0], "Only the Dapr sidecar")
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "config.yaml", yamlConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Auth.ApiKeys = "keys"
+	redacted := cfg.Redacted()
+	assert.Equal(t, RedactedValue, redacted.Auth.ApiKeys)
+	// Empty secrets stay empty, to tell whether they are set
+	assert.Empty(t, redacted.Dapr.AppApiToken)
+	for _, host := range redacted.Hosts {
+		assert.Equal(t, RedactedValue, host.ClientSecret)
+		assert.Equal(t, RedactedValue, host.RefreshToken)
+		assert.NotEqual(t, RedactedValue, host.ClientId)
+	}
+	// The original configuration is untouched
+	assert.Equal(t, "keys", cfg.Auth.ApiKeys)
+	assert.Equal(t, "secret", cfg.Hosts[0].ClientSecret)
+}
+
+func TestDuration_Text(t *testing.T) {
+	d := Duration(90 * time.Second)
+	text, err := d.MarshalText()
+	assert.Nil(t, err)
+	assert.Equal(t, "1m30s", string(text))
+	assert.NotNil(t, d.UnmarshalText([]byte("soon")))
+	assert.Nil(t, d.UnmarshalText([]byte("2s")))
+	assert.Equal(t, Duration(2*time.Second), d)
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+)
+
+// Validate Checks every setting, returning all the problems at once
+func (cfg *Config) Validate() error {
+	var errs []error
+	invalid := func(setting string, format string, a ...any) {
+		errs = append(errs, fmt.Errorf("%s : %s", setting, fmt.Sprintf(format, a...)))
+	}
+
+	if !validPort(cfg.Server.Port) {
+		invalid("server.port (APP_PORT)", "must be between 1 and 65535, got %d", cfg.Server.Port)
+	}
+	if !oneOf(cfg.Server.GinMode, "", "debug", "release", "test") {
+		invalid("server.ginMode (GIN_MODE)", `must be "debug", "release" or "test", got "%s"`, cfg.Server.GinMode)
+	}
+	for _, p := range cfg.Server.Proxies() {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				invalid("server.trustedProxies (TRUSTED_PROXIES)", `must be IPs or CIDRs separated by ",", got "%s"`, p)
+			}
+		}
+	}
+	if !validPort(cfg.Dapr.GrpcPort) {
+		invalid("dapr.grpcPort (DAPR_GRPC_PORT)", "must be between 1 and 65535, got %d", cfg.Dapr.GrpcPort)
+	}
+	if cfg.Dapr.MaxRequestSizeMb <= 0 {
+		invalid("dapr.maxRequestSizeMb (DAPR_MAX_REQUEST_SIZE_MB)", "must be positive, got %d", cfg.Dapr.MaxRequestSizeMb)
+	}
+	if cfg.Dapr.ObjectStore == "" {
+		invalid("dapr.objectStore (OBJECT_STORE_NAME)", "is required")
+	}
+	if cfg.PubSub.Name != "" {
+		if cfg.PubSub.ProgressTopic == "" {
+			invalid("pubsub.progressTopic (PUBSUB_TOPIC_PROGRESS)", "can't be empty when a pubsub is defined")
+		}
+		if cfg.PubSub.EventsTopic == "" {
+			invalid("pubsub.eventsTopic (PUBSUB_TOPIC_EVENTS)", "can't be empty when a pubsub is defined")
+		}
+	}
+	if cfg.PubSub.CommandsName != "" && cfg.PubSub.CommandsTopic == "" {
+		invalid("pubsub.commandsTopic (PUBSUB_TOPIC_COMMANDS)", "can't be empty when a pubsub is defined")
+	}
+	// The sidecar can't send the credentials of the users, only the Dapr API token.
+	// Without it, the commands routes would only accept the users granted commands:write
+	if cfg.usersAuthenticated() && cfg.Dapr.AppApiToken == "" && cfg.PubSub.CommandsName != "" {
+		invalid("dapr.appApiToken (APP_API_TOKEN)", "is required for the Dapr sidecar to deliver commands when the users are authenticated (AUTH_API_KEYS or AUTH_JWKS_URL)")
+	}
+	if cfg.RateLimit.Rps < 0 {
+		invalid("rateLimit.rps (RATE_LIMIT_RPS)", "can't be negative, got %g", cfg.RateLimit.Rps)
+	}
+	if cfg.RateLimit.Burst < 0 {
+		invalid("rateLimit.burst (RATE_LIMIT_BURST)", "can't be negative, got %d", cfg.RateLimit.Burst)
+	}
+	if !oneOf(cfg.Tracing.Exporter, "", "none", "stdout", "otlp") {
+		invalid("tracing.exporter (OTEL_TRACES_EXPORTER)", `must be "none", "stdout" or "otlp", got "%s"`, cfg.Tracing.Exporter)
+	}
+	if cfg.Health.CacheTTL < 0 {
+		invalid("health.cacheTTL (HEALTH_CACHE_TTL)", "can't be negative")
+	}
+	if cfg.Shutdown.DrainTimeout < 0 {
+		invalid("shutdown.drainTimeout (SHUTDOWN_DRAIN_TIMEOUT)", "can't be negative")
+	}
+	if cfg.OAuth.RedirectUrl != "" {
+		if u, err := url.Parse(cfg.OAuth.RedirectUrl); err != nil || !oneOf(u.Scheme, "http", "https") || u.Host == "" {
+			invalid("oauth.redirectUrl (OAUTH_REDIRECT_URL)", `must be an absolute http(s) URL, got "%s"`, cfg.OAuth.RedirectUrl)
+		}
+		if cfg.OAuth.StateStore == "" {
+			invalid("oauth.stateStore (OAUTH_STATE_STORE_NAME)", "is required to save the refresh tokens obtained with a consent")
+		}
+	}
+	if cfg.Secrets.RefreshInterval < 0 {
+		invalid("secrets.refreshInterval (SECRET_REFRESH_INTERVAL)", "can't be negative")
+	}
+	if cfg.Catalog.ReconcileInterval < 0 {
+		invalid("catalog.reconcileInterval (CATALOG_RECONCILE_INTERVAL)", "can't be negative")
+	}
+	if cfg.Processing.PollInterval < 0 {
+		invalid("processing.pollInterval (PROCESSING_POLL_INTERVAL)", "can't be negative")
+	}
+	if cfg.Processing.Timeout < 0 {
+		invalid("processing.timeout (PROCESSING_TIMEOUT)", "can't be negative")
+	}
+	templateNames := make([]string, 0, len(cfg.Templates.Items))
+	for name := range cfg.Templates.Items {
+		templateNames = append(templateNames, name)
+	}
+	sort.Strings(templateNames)
+	for _, name := range templateNames {
+		if _, err := cfg.Templates.Items[name].Compile(name); err != nil {
+			invalid("templates.items."+name, "%s", err.Error())
+		}
+	}
+
+	if len(cfg.Hosts) == 0 {
+		invalid("hosts", "at least one video hosting platform is required (YT_CLIENT_ID, YT_CLIENT_SECRET and YT_REFRESH_TOKEN, or YT_SECRET_NAME)")
+	} else if cfg.Host() == nil {
+		if cfg.DefaultHost == "" {
+			invalid("defaultHost", "is required when there is more than one host")
+		} else {
+			invalid("defaultHost", `no host is named "%s"`, cfg.DefaultHost)
+		}
+	}
+	names := make(map[string]bool, len(cfg.Hosts))
+	for i, host := range cfg.Hosts {
+		setting := fmt.Sprintf("hosts[%d]", i)
+		if host.Name == "" {
+			invalid(setting+".name", "is required")
+		} else if names[host.Name] {
+			invalid(setting+".name", `"%s" is used by more than one host`, host.Name)
+		}
+		names[host.Name] = true
+		if _, ok := hostDefaults[host.Type]; !ok {
+			invalid(setting+".type", `must be "%s", got "%s"`, Youtube, host.Type)
+		}
+		if host.SecretName != "" {
+			// The credentials are only known once read from the store
+			if cfg.Secrets.Store == "" {
+				invalid("secrets.store (SECRET_STORE_NAME)", `is required by the secret of host "%s"`, host.Name)
+			}
+		} else {
+			if host.ClientId == "" {
+				invalid(setting+".clientId (YT_CLIENT_ID)", "is required")
+			}
+			if host.ClientSecret == "" {
+				invalid(setting+".clientSecret (YT_CLIENT_SECRET)", "is required")
+			}
+			// The refresh token can also be obtained with a consent, then saved in the state store
+			if host.RefreshToken == "" && cfg.OAuth.StateStore == "" {
+				invalid(setting+".refreshToken (YT_REFRESH_TOKEN)", "is required")
+			}
+		}
+		if host.DailyQuota <= 0 {
+			invalid(setting+".dailyQuota (YT_DAILY_QUOTA)", "must be positive, got %d", host.DailyQuota)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Warnings Settings that are valid, but most likely unintended
+func (cfg *Config) Warnings() []string {
+	var warnings []string
+	switch {
+	case !cfg.usersAuthenticated() && cfg.Dapr.AppApiToken == "":
+		warnings = append(warnings, "No API keys nor JWKS provided. The API is open to anyone who can reach it !")
+	case !cfg.usersAuthenticated():
+		warnings = append(warnings, "No API keys nor JWKS provided. Only the Dapr sidecar is authenticated, every other route is open to anyone who can reach it !")
+	case cfg.Dapr.AppApiToken == "":
+		warnings = append(warnings, "No APP_API_TOKEN provided. The commands routes only accept the users granted commands:write, the Dapr sidecar can't call them")
+	}
+	return warnings
+}
+
+// Whether the users must authenticate with an API key or a bearer token
+func (cfg *Config) usersAuthenticated() bool {
+	return cfg.Auth.ApiKeys != "" || cfg.Auth.JwksUrl != ""
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package credentials_store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dapr/go-sdk/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"video-manager/internal/tracing"
+)
+
+// Prefix of the state keys holding the credentials of each host
+const keyPrefix = "credentials-"
+
+// CredentialsStore Persists the credentials obtained while running (OAuth consent...) in a state store,
+// so they survive a restart. The credentials of a host are saved as the same key/value map a secret would hold
+type CredentialsStore[T StateProxy] struct {
+	// Name of the Dapr component to use
+	componentName string
+	// Client to query the backend store
+	client *T
+}
+
+// NewDaprCredentialsStore Prod ready constructor for a credentials store using a Dapr state store
+func NewDaprCredentialsStore(daprClient *client.Client, component string) *CredentialsStore[client.Client] {
+	return &CredentialsStore[client.Client]{
+		componentName: component,
+		client:        daprClient,
+	}
+}
+
+// NewCredentialsStore General purpose credentials store
+func NewCredentialsStore[T StateProxy](component string, client T) *CredentialsStore[T] {
+	return &CredentialsStore[T]{
+		componentName: component,
+		client:        &client,
+	}
+}
+
+// StateProxy Proxy to query the backend store
+type StateProxy interface {
+	SaveState(ctx context.Context, storeName, key string, data []byte, meta map[string]string, so ...client.StateOption) error
+	GetState(ctx context.Context, storeName, key string, meta map[string]string) (item *client.StateItem, err error)
+}
+
+// Save Replace the credentials of host
+func (cs CredentialsStore[T]) Save(ctx context.Context, host string, secret map[string]string) error {
+	ctx, span := cs.start(ctx, "save", host)
+	data, err := json.Marshal(secret)
+	if err == nil {
+		err = (*cs.client).SaveState(ctx, cs.componentName, keyPrefix+host, data, nil)
+	}
+	tracing.End(span, err)
+	return err
+}
+
+// Get Retrieve the credentials of host. Nil if none were ever saved
+func (cs CredentialsStore[T]) Get(ctx context.Context, host string) (map[string]string, error) {
+	ctx, span := cs.start(ctx, "get", host)
+	item, err := (*cs.client).GetState(ctx, cs.componentName, keyPrefix+host, nil)
+	var secret map[string]string
+	if err == nil && item != nil && len(item.Value) > 0 {
+		if err = json.Unmarshal(item.Value, &secret); err != nil {
+			err = fmt.Errorf(`invalid credentials saved for host "%s" : %w`, host, err)
+		}
+	}
+	tracing.End(span, err)
+	return secret, err
+}
+
+func (cs CredentialsStore[T]) start(ctx context.Context, operation string, host string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "credentials-store "+operation, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("dapr.state_store", cs.componentName),
+			attribute.String("credentials_store.host", host),
+		))
+}
@@ -0,0 +1,63 @@
+package credentials_store
+
+import (
+	"context"
+	"fmt"
+	"github.com/dapr/go-sdk/client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	mock_client "video-manager/internal/mock/dapr"
+)
+
+func TestCredentialsStore_Save(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	daprClient.EXPECT().SaveState(gomock.Any(), "statestore", "credentials-youtube", []byte(`{"refreshToken":"token"}`), gomock.Any()).Return(nil)
+	cs := NewCredentialsStore[*mock_client.MockClient]("statestore", daprClient)
+
+	assert.Nil(t, cs.Save(context.Background(), "youtube", map[string]string{"refreshToken": "token"}))
+}
+
+func TestCredentialsStore_Get(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	daprClient.EXPECT().GetState(gomock.Any(), "statestore", "credentials-youtube", gomock.Any()).
+		Return(&client.StateItem{Key: "credentials-youtube", Value: []byte(`{"refreshToken":"token"}`)}, nil)
+	cs := NewCredentialsStore[*mock_client.MockClient]("statestore", daprClient)
+
+	secret, err := cs.Get(context.Background(), "youtube")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"refreshToken": "token"}, secret)
+}
+
+func TestCredentialsStore_Get_NeverSaved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	// Dapr returns an empty item for a missing key
+	daprClient.EXPECT().GetState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&client.StateItem{Key: "credentials-youtube"}, nil)
+	cs := NewCredentialsStore[*mock_client.MockClient]("statestore", daprClient)
+
+	secret, err := cs.Get(context.Background(), "youtube")
+	assert.Nil(t, err)
+	assert.Nil(t, secret)
+}
+
+func TestCredentialsStore_Get_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	gomock.InOrder(
+		daprClient.EXPECT().GetState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("state store unavailable")),
+		daprClient.EXPECT().GetState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&client.StateItem{Value: []byte("not json")}, nil),
+	)
+	cs := NewCredentialsStore[*mock_client.MockClient]("statestore", daprClient)
+
+	_, err := cs.Get(context.Background(), "youtube")
+	assert.NotNil(t, err)
+	_, err = cs.Get(context.Background(), "youtube")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid credentials")
+}
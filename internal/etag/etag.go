@@ -0,0 +1,95 @@
+package etag
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// Quote Format tag as the value of an ETag header. Returns an empty string if there is no tag
+func Quote(tag string) string {
+	if tag == "" {
+		return ""
+	}
+	// Some platforms already quote their tags
+	if len(tag) >= 2 && strings.HasPrefix(tag, `"`) && strings.HasSuffix(tag, `"`) {
+		return tag
+	}
+	return `"` + tag + `"`
+}
+
+// Matches Whether the item tagged with tag satisfies header, the value of an If-Match or If-None-Match header.
+// Weak tags (W/"...") only match with a weak comparison, as required by If-None-Match
+// https://www.rfc-editor.org/rfc/rfc9110#section-8.8.3.2
+func Matches(header string, tag string, weak bool) bool {
+	header = strings.TrimSpace(header)
+	// Any current version of the item
+	if header == "*" {
+		return true
+	}
+	if tag == "" {
+		return false
+	}
+	quoted := Quote(tag)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == quoted {
+			return true
+		}
+	}
+	return false
+}
+
+// Variant Version of a representation of the item tagged with tag, holding an additional part.
+// Each variant of the item has its own tag, changing whenever part changes
+func Variant(tag string, name string, part any) string {
+	if tag == "" {
+		return ""
+	}
+	data, err := json.Marshal(part)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(append([]byte(tag+"+"+name+":"), data...))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Set Send tag as the ETag header of the response, if there is one
+func Set(c *gin.Context, tag string) {
+	if quoted := Quote(tag); quoted != "" {
+		c.Header("ETag", quoted)
+	}
+}
+
+// NotModified Answer 304 Not Modified, with the ETag header, if the client already has the version tag of the item,
+// according to the If-None-Match header. Returns whether the response was sent
+func NotModified(c *gin.Context, tag string) bool {
+	if !Matches(header(c, "If-None-Match"), tag, true) {
+		return false
+	}
+	Set(c, tag)
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
+
+// IfMatch The If-Match header of the request, empty if there is none
+func IfMatch(c *gin.Context) string {
+	return header(c, "If-Match")
+}
+
+// The header called name of the request, empty if there is none
+func header(c *gin.Context, name string) string {
+	if c.Request == nil {
+		return ""
+	}
+	return c.GetHeader(name)
+}
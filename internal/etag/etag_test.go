@@ -0,0 +1,59 @@
+package etag
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `"abc"`, Quote("abc"))
+	assert.Equal(t, `"abc"`, Quote(`"abc"`))
+	assert.Equal(t, "", Quote(""))
+}
+
+func TestMatches(t *testing.T) {
+	assert.True(t, Matches(`"abc"`, "abc", false))
+	assert.True(t, Matches(`"xyz", "abc"`, "abc", false))
+	assert.True(t, Matches(`*`, "abc", false))
+	assert.False(t, Matches(`"xyz"`, "abc", false))
+	assert.False(t, Matches(``, "abc", false))
+	assert.False(t, Matches(`abc`, "abc", false))
+	// Weak tags only match weakly
+	assert.False(t, Matches(`W/"abc"`, "abc", false))
+	assert.True(t, Matches(`W/"abc"`, "abc", true))
+	// An item without tag only matches *
+	assert.False(t, Matches(`""`, "", true))
+	assert.True(t, Matches(`*`, "", false))
+}
+
+func TestNotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("If-None-Match", `W/"abc"`)
+	assert.True(t, NotModified(c, "abc"))
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, `"abc"`, w.Header().Get("ETag"))
+	assert.Empty(t, w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("If-None-Match", `"xyz"`)
+	assert.False(t, NotModified(c, "abc"))
+	assert.False(t, c.IsAborted())
+}
+
+func TestVariant(t *testing.T) {
+	variant := Variant("abc", "statistics", map[string]int{"viewCount": 1})
+	assert.NotEqual(t, "abc", variant)
+	assert.Equal(t, variant, Variant("abc", "statistics", map[string]int{"viewCount": 1}))
+	// Changes with the part, and with the version of the item
+	assert.NotEqual(t, variant, Variant("abc", "statistics", map[string]int{"viewCount": 2}))
+	assert.NotEqual(t, variant, Variant("xyz", "statistics", map[string]int{"viewCount": 1}))
+	assert.Empty(t, Variant("", "statistics", nil))
+}
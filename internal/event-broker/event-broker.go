@@ -0,0 +1,107 @@
+package event_broker
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dapr/go-sdk/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+	progress_broker "video-manager/internal/progress-broker"
+	"video-manager/internal/tracing"
+)
+
+// EventBroker Publish domain events about every change made on the hosted items
+type EventBroker[T progress_broker.PubSubProxy] struct {
+	// Name of the Dapr Component to use
+	componentName string
+	// Name of the topic to publish into
+	topic string
+	// Client to publish event into
+	client *T
+	// Name of the host the changes are made on
+	host string
+}
+
+// EventType Kind of change that happened on the video hosting platform
+type EventType string
+
+const (
+	VideoCreated      EventType = "video.created"
+	VideoUpdated      EventType = "video.updated"
+	VideoDeleted      EventType = "video.deleted"
+	PlaylistCreated   EventType = "playlist.created"
+	PlaylistUpdated   EventType = "playlist.updated"
+	PlaylistDeleted   EventType = "playlist.deleted"
+	PlaylistItemAdded EventType = "playlist.item.added"
+	ThumbnailSet      EventType = "thumbnail.set"
+	TagsSet           EventType = "tags.set"
+	CaptionAdded      EventType = "caption.added"
+	VideoScheduled    EventType = "video.scheduled"
+	// The credentials of the host were refused, it must be authorized again
+	AuthExpired EventType = "auth.expired"
+	// A recorded item was changed or deleted on the host without going through the service
+	VideoDrifted    EventType = "video.drifted"
+	PlaylistDrifted EventType = "playlist.drifted"
+	// The host is processing an uploaded video, or completed it
+	ProcessingProgress EventType = "processing.progress"
+	ProcessingDone     EventType = "processing.done"
+	ProcessingFailed   EventType = "processing.failed"
+)
+
+// Event A single change made on the video hosting platform
+type Event struct {
+	// What happened
+	Type EventType `json:"type"`
+	// ID of the item (video or playlist) the event is about
+	Subject string `json:"subject"`
+	// Name of the host the change was made on
+	Host string `json:"host,omitempty"`
+	// When the change was made
+	Time time.Time `json:"time"`
+	// Optional payload, usually the updated item
+	Data interface{} `json:"data,omitempty"`
+	// W3C trace context of the operation that caused the event
+	TraceParent string `json:"traceparent,omitempty"`
+	TraceState  string `json:"tracestate,omitempty"`
+}
+
+type NewBrokerOptions struct {
+	Component string
+	Topic     string
+	// Name of the host stamped on each event, if any
+	Host string
+}
+
+func NewEventBroker[T progress_broker.PubSubProxy](client *T, opt NewBrokerOptions) (*EventBroker[T], error) {
+	return &EventBroker[T]{
+		componentName: opt.Component,
+		topic:         opt.Topic,
+		client:        client,
+		host:          opt.Host,
+	}, nil
+}
+
+// Publish Send a new event on the broker. The event time is set to now if not provided
+func (eb *EventBroker[T]) Publish(ctx context.Context, evt Event) (err error) {
+	ctx, span := tracing.Start(ctx, eb.topic+" publish", trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "dapr"),
+			attribute.String("messaging.destination.name", eb.topic),
+			attribute.String("event.type", string(evt.Type)),
+		))
+	defer func() { tracing.End(span, err) }()
+	if evt.Host == "" {
+		evt.Host = eb.host
+	}
+	if evt.Time.IsZero() {
+		evt.Time = time.Now().UTC()
+	}
+	carrier := tracing.Carrier(ctx)
+	evt.TraceParent, evt.TraceState = carrier["traceparent"], carrier["tracestate"]
+	b, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	return (*eb.client).PublishEvent(ctx, eb.componentName, eb.topic, b, client.PublishEventWithContentType("application/json"))
+}
@@ -0,0 +1,131 @@
+package event_broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dapr/go-sdk/client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+	"time"
+	mock_client "video-manager/internal/mock/dapr"
+	"video-manager/internal/tracing"
+)
+
+func TestEventBroker_Publish(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	daprClient.EXPECT().PublishEvent(gomock.Any(), "pubsub", "events", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, data interface{}, _ ...client.PublishEventOption) error {
+			var evt Event
+			if err := json.Unmarshal(data.([]byte), &evt); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, VideoDeleted, evt.Type)
+			assert.Equal(t, "1", evt.Subject)
+			// The time must have been filled automatically
+			assert.False(t, evt.Time.IsZero())
+			return nil
+		})
+	eb, err := NewEventBroker[*mock_client.MockClient](&daprClient, NewBrokerOptions{
+		Component: "pubsub",
+		Topic:     "events",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eb.Publish(ctx, Event{Type: VideoDeleted, Subject: "1"})
+	assert.Nil(t, err)
+}
+
+func TestEventBroker_Publish_KeepTime(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	evtTime := time.Unix(1662202180, 0).UTC()
+	daprClient.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, data interface{}, _ ...client.PublishEventOption) error {
+			var evt Event
+			if err := json.Unmarshal(data.([]byte), &evt); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, evtTime, evt.Time)
+			return nil
+		})
+	eb, err := NewEventBroker[*mock_client.MockClient](&daprClient, NewBrokerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eb.Publish(ctx, Event{Type: VideoCreated, Subject: "1", Time: evtTime})
+	assert.Nil(t, err)
+}
+
+func TestEventBroker_Publish_Host(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	daprClient.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, data interface{}, _ ...client.PublishEventOption) error {
+			var evt Event
+			if err := json.Unmarshal(data.([]byte), &evt); err != nil {
+				t.Fatal(err)
+			}
+			// Consumers of a topic shared by several hosts can tell them apart
+			assert.Equal(t, "backup", evt.Host)
+			return nil
+		})
+	eb, err := NewEventBroker[*mock_client.MockClient](&daprClient, NewBrokerOptions{Host: "backup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eb.Publish(ctx, Event{Type: VideoCreated, Subject: "1"})
+	assert.Nil(t, err)
+}
+
+func TestEventBroker_CouldNotPublish(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	daprClient.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("test"))
+	eb, err := NewEventBroker[*mock_client.MockClient](&daprClient, NewBrokerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eb.Publish(ctx, Event{Type: PlaylistCreated, Subject: "1"})
+	assert.NotNil(t, err)
+}
+
+func TestEventBroker_Publish_TraceContext(t *testing.T) {
+	if _, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.None}); err != nil {
+		t.Fatal(err)
+	}
+	traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	daprClient.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, data interface{}, _ ...client.PublishEventOption) error {
+			var evt Event
+			if err := json.Unmarshal(data.([]byte), &evt); err != nil {
+				t.Fatal(err)
+			}
+			// Subscribers must be able to continue the trace of the request
+			assert.Contains(t, evt.TraceParent, "4bf92f3577b34da6a3ce929d0e0e4736")
+			return nil
+		})
+	eb, err := NewEventBroker[*mock_client.MockClient](&daprClient, NewBrokerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eb.Publish(ctx, Event{Type: VideoCreated, Subject: "1"})
+	assert.Nil(t, err)
+}
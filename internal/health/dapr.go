@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	runtime "github.com/dapr/dapr/pkg/proto/runtime/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"strings"
+)
+
+// MetadataProxy Proxy to query the metadata of the Dapr sidecar.
+// Implemented by the gRPC client of the Dapr SDK
+type MetadataProxy interface {
+	GetMetadata(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*runtime.GetMetadataResponse, error)
+}
+
+// DaprSidecar Checks the Dapr sidecar can be reached
+func DaprSidecar(proxy MetadataProxy) Check {
+	return Check{
+		Name: "dapr",
+		Run: func(ctx context.Context) error {
+			_, err := proxy.GetMetadata(ctx, &emptypb.Empty{})
+			if err != nil {
+				return fmt.Errorf("dapr sidecar unreachable : %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// DaprComponent Checks the Dapr component "component" is loaded by the sidecar, with a type starting with "kind" (ie "pubsub")
+func DaprComponent(name string, proxy MetadataProxy, component string, kind string) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			meta, err := proxy.GetMetadata(ctx, &emptypb.Empty{})
+			if err != nil {
+				return fmt.Errorf("dapr sidecar unreachable : %w", err)
+			}
+			for _, registered := range meta.RegisteredComponents {
+				if registered.Name == component && strings.HasPrefix(registered.Type, kind+".") {
+					return nil
+				}
+			}
+			return fmt.Errorf(`no %s component named "%s" is loaded by the dapr sidecar`, kind, component)
+		},
+	}
+}
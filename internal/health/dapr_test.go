@@ -0,0 +1,47 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	runtime "github.com/dapr/dapr/pkg/proto/runtime/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"testing"
+)
+
+// Sidecar with a static set of components
+type fakeMetadata struct {
+	components []*runtime.RegisteredComponents
+	err        error
+}
+
+func (f fakeMetadata) GetMetadata(_ context.Context, _ *emptypb.Empty, _ ...grpc.CallOption) (*runtime.GetMetadataResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &runtime.GetMetadataResponse{RegisteredComponents: f.components}, nil
+}
+
+func TestDaprSidecar(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, DaprSidecar(fakeMetadata{}).Run(ctx))
+	assert.NotNil(t, DaprSidecar(fakeMetadata{err: fmt.Errorf("test")}).Run(ctx))
+}
+
+func TestDaprComponent(t *testing.T) {
+	ctx := context.Background()
+	proxy := fakeMetadata{components: []*runtime.RegisteredComponents{
+		{Name: "pubsub", Type: "pubsub.redis"},
+		{Name: "object-store", Type: "bindings.aws.s3"},
+	}}
+	check := DaprComponent("pubsub", proxy, "pubsub", "pubsub")
+	assert.Equal(t, "pubsub", check.Name)
+	assert.Nil(t, check.Run(ctx))
+	// Right name, wrong type
+	assert.NotNil(t, DaprComponent("pubsub", proxy, "object-store", "pubsub").Run(ctx))
+	// Not loaded at all
+	assert.NotNil(t, DaprComponent("pubsub", proxy, "other", "pubsub").Run(ctx))
+	// Sidecar unreachable
+	assert.NotNil(t, DaprComponent("pubsub", fakeMetadata{err: fmt.Errorf("test")}, "pubsub", "pubsub").Run(ctx))
+}
@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheTTL How long the result of a check is reused before running it again
+	DefaultCacheTTL = 10 * time.Second
+	// DefaultTimeout Time after which a check still running is considered failed
+	DefaultTimeout = 3 * time.Second
+)
+
+// Status of a dependency, or of the whole service
+type Status string
+
+const (
+	Up   Status = "up"
+	Down Status = "down"
+)
+
+// Check A dependency of the service, which must be available for the service to be ready
+type Check struct {
+	// Name of the dependency in the report
+	Name string
+	// Returns an error if the dependency can't be used
+	Run func(ctx context.Context) error
+}
+
+// Result of a single check
+type Result struct {
+	Status Status `json:"status"`
+	// Why the dependency is down
+	Error string `json:"error,omitempty"`
+	// When the check actually ran. Results are cached, this may be in the past
+	CheckedAt time.Time `json:"checkedAt"`
+	// Time spent running the check, in milliseconds
+	DurationMs int64 `json:"durationMs"`
+}
+
+// Report Status of all the dependencies of the service
+type Report struct {
+	// Up only if all dependencies are up
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Options Checker configuration
+type Options struct {
+	// Defaults to DefaultCacheTTL
+	CacheTTL time.Duration
+	// Defaults to DefaultTimeout
+	Timeout time.Duration
+}
+
+// Checker Runs the checks of all dependencies, caching their results.
+// Probes are frequent and some checks aren't free (they may consume some quota), the cache keeps
+// the load on the dependencies constant no matter how often the service is probed
+type Checker struct {
+	checks []Check
+	opt    Options
+	mu     sync.Mutex
+	cache  map[string]Result
+	// Current time, overridable in tests
+	now func() time.Time
+}
+
+// NewChecker Checker for all the provided dependencies
+func NewChecker(opt Options, checks ...Check) *Checker {
+	if opt.CacheTTL <= 0 {
+		opt.CacheTTL = DefaultCacheTTL
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = DefaultTimeout
+	}
+	return &Checker{
+		checks: checks,
+		opt:    opt,
+		cache:  make(map[string]Result),
+		now:    time.Now,
+	}
+}
+
+// Report Status of all dependencies. Checks whose cached result expired are run again, concurrently
+func (hc *Checker) Report(ctx context.Context) Report {
+	// Concurrent probes wait for the running checks instead of running them again
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	var wg sync.WaitGroup
+	results := make([]Result, len(hc.checks))
+	stale := make([]bool, len(hc.checks))
+	for i, check := range hc.checks {
+		if cached, ok := hc.cache[check.Name]; ok && hc.now().Sub(cached.CheckedAt) < hc.opt.CacheTTL {
+			continue
+		}
+		stale[i] = true
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = hc.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+	for i, check := range hc.checks {
+		if stale[i] {
+			hc.cache[check.Name] = results[i]
+		}
+	}
+
+	report := Report{Status: Up, Checks: make(map[string]Result, len(hc.checks))}
+	for _, check := range hc.checks {
+		res := hc.cache[check.Name]
+		if res.Status != Up {
+			report.Status = Down
+		}
+		report.Checks[check.Name] = res
+	}
+	return report
+}
+
+// Invalidate Forget the cached result of the check named name, it is run again on the next report.
+// Allows a dependency known to be down to be reported right away
+func (hc *Checker) Invalidate(name string) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	delete(hc.cache, name)
+}
+
+// Run a single check, giving up after the timeout even if the check doesn't honour its context
+func (hc *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, hc.opt.Timeout)
+	defer cancel()
+	start := hc.now()
+	done := make(chan error, 1)
+	go func() { done <- check.Run(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out after %s", hc.opt.Timeout)
+	}
+
+	res := Result{Status: Up, CheckedAt: start, DurationMs: hc.now().Sub(start).Milliseconds()}
+	if err != nil {
+		res.Status = Down
+		res.Error = err.Error()
+	}
+	return res
+}
@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// Check counting its runs, failing with err
+func countingCheck(name string, runs *int, err error) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		*runs++
+		return err
+	}}
+}
+
+func TestChecker_Report_Up(t *testing.T) {
+	var runsA, runsB int
+	hc := NewChecker(Options{}, countingCheck("a", &runsA, nil), countingCheck("b", &runsB, nil))
+	report := hc.Report(context.Background())
+	assert.Equal(t, Up, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, Up, report.Checks["a"].Status)
+	assert.Empty(t, report.Checks["a"].Error)
+	assert.Equal(t, 1, runsA)
+	assert.Equal(t, 1, runsB)
+}
+
+func TestChecker_Report_Down(t *testing.T) {
+	var runsA, runsB int
+	hc := NewChecker(Options{}, countingCheck("a", &runsA, nil), countingCheck("b", &runsB, fmt.Errorf("test")))
+	report := hc.Report(context.Background())
+	// A single failing dependency is enough
+	assert.Equal(t, Down, report.Status)
+	assert.Equal(t, Up, report.Checks["a"].Status)
+	assert.Equal(t, Down, report.Checks["b"].Status)
+	assert.Equal(t, "test", report.Checks["b"].Error)
+}
+
+func TestChecker_Report_Cached(t *testing.T) {
+	var runs int
+	now := time.Unix(1662202180, 0)
+	hc := NewChecker(Options{CacheTTL: time.Minute}, countingCheck("a", &runs, fmt.Errorf("test")))
+	hc.now = func() time.Time { return now }
+
+	first := hc.Report(context.Background())
+	now = now.Add(30 * time.Second)
+	second := hc.Report(context.Background())
+	// Failures are cached as well
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, first, second)
+
+	// Once the cache expired, the check runs again
+	now = now.Add(time.Minute)
+	third := hc.Report(context.Background())
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, now, third.Checks["a"].CheckedAt)
+}
+
+func TestChecker_Invalidate(t *testing.T) {
+	var runs int
+	hc := NewChecker(Options{CacheTTL: time.Minute}, countingCheck("a", &runs, nil))
+	hc.Report(context.Background())
+	hc.Invalidate("a")
+	hc.Report(context.Background())
+	assert.Equal(t, 2, runs)
+	// Unknown checks are ignored
+	hc.Invalidate("b")
+}
+
+func TestChecker_Report_Timeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	hc := NewChecker(Options{Timeout: 10 * time.Millisecond}, Check{Name: "stuck", Run: func(ctx context.Context) error {
+		// Doesn't honour the context
+		<-block
+		return nil
+	}})
+	report := hc.Report(context.Background())
+	assert.Equal(t, Down, report.Status)
+	assert.Contains(t, report.Checks["stuck"].Error, "timed out")
+}
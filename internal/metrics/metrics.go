@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"strconv"
+	"time"
+)
+
+// Prefix of all metrics names
+const namespace = "video_store"
+
+// Outcome label values
+const (
+	Ok    = "ok"
+	Error = "error"
+)
+
+var (
+	// HttpRequests Number of handled HTTP requests, by route and status code
+	HttpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of handled HTTP requests",
+	}, []string{"method", "route", "status"})
+	// HttpRequestDuration Time spent handling HTTP requests, by route
+	HttpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time spent handling HTTP requests",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	// UploadDuration Time spent uploading a video to the hosting platform
+	UploadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_duration_seconds",
+		Help:      "Time spent uploading a video to the hosting platform",
+		// From 1s to ~1h
+		Buckets: prometheus.ExponentialBuckets(1, 2, 13),
+	}, []string{"outcome"})
+	// UploadBytes Bytes sent to the hosting platform while uploading videos
+	UploadBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_bytes_total",
+		Help:      "Bytes sent to the hosting platform while uploading videos",
+	})
+	// StorageFetchAttempts Number of attempts at retrieving a video from the object storage.
+	// Failed attempts are retried
+	StorageFetchAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "object_storage_fetch_attempts_total",
+		Help:      "Number of attempts at retrieving a video from the object storage",
+	}, []string{"outcome"})
+	// HostCalls Number of calls to the hosting platform, by method and resulting status code
+	HostCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "host_calls_total",
+		Help:      "Number of calls to the hosting platform",
+	}, []string{"method", "code"})
+	// HostCallDuration Time spent waiting for the hosting platform, by method
+	HostCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "host_call_duration_seconds",
+		Help:      "Time spent waiting for the hosting platform",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+	// ActiveJobs Number of upload jobs currently running
+	ActiveJobs = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_jobs",
+		Help:      "Number of upload jobs currently running",
+	})
+	// ProgressPublishFailures Number of upload progress events that couldn't be published
+	ProgressPublishFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "progress_publish_failures_total",
+		Help:      "Number of upload progress events that couldn't be published",
+	})
+)
+
+// Handler Middleware recording the count and latency of requests, by route
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		// Use the route template and not the actual path, to keep the cardinality bounded
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		HttpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		HttpRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Endpoint Handler exposing all metrics in the Prometheus format
+func Endpoint() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// Outcome Returns the outcome label value matching err
+func Outcome(err error) string {
+	if err != nil {
+		return Error
+	}
+	return Ok
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Handler())
+	router.GET("/videos/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.GET("/metrics", Endpoint())
+	return router
+}
+
+func TestHandler_RecordsRouteTemplate(t *testing.T) {
+	router := setupRouter()
+	before := testutil.ToFloat64(HttpRequests.WithLabelValues("GET", "/videos/:id", "204"))
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/videos/%d", i), nil)
+		router.ServeHTTP(w, req)
+	}
+	assert.Equal(t, before+2, testutil.ToFloat64(HttpRequests.WithLabelValues("GET", "/videos/:id", "204")))
+}
+
+func TestHandler_Unmatched(t *testing.T) {
+	router := setupRouter()
+	before := testutil.ToFloat64(HttpRequests.WithLabelValues("GET", "unmatched", "404"))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/nope", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, before+1, testutil.ToFloat64(HttpRequests.WithLabelValues("GET", "unmatched", "404")))
+}
+
+func TestEndpoint(t *testing.T) {
+	router := setupRouter()
+	ActiveJobs.Inc()
+	defer ActiveJobs.Dec()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), "video_store_active_jobs 1"))
+}
+
+func TestOutcome(t *testing.T) {
+	assert.Equal(t, Ok, Outcome(nil))
+	assert.Equal(t, Error, Outcome(fmt.Errorf("test")))
+}
@@ -5,11 +5,14 @@
 package mock_video_hosting
 
 import (
+	context "context"
 	io "io"
 	reflect "reflect"
+	time "time"
 	video_hosting "video-manager/internal/video-hosting"
 
 	gomock "github.com/golang/mock/gomock"
+	oauth2 "golang.org/x/oauth2"
 )
 
 // MockIVideoHost is a mock of IVideoHost interface.
@@ -35,76 +38,90 @@ func (m *MockIVideoHost) EXPECT() *MockIVideoHostMockRecorder {
 	return m.recorder
 }
 
+// AddVideoCaption mocks base method.
+func (m *MockIVideoHost) AddVideoCaption(ctx context.Context, videoId string, caption *video_hosting.Caption, content io.Reader) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddVideoCaption", ctx, videoId, caption, content)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddVideoCaption indicates an expected call of AddVideoCaption.
+func (mr *MockIVideoHostMockRecorder) AddVideoCaption(ctx, videoId, caption, content interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddVideoCaption", reflect.TypeOf((*MockIVideoHost)(nil).AddVideoCaption), ctx, videoId, caption, content)
+}
+
 // AddVideoToPlaylist mocks base method.
-func (m *MockIVideoHost) AddVideoToPlaylist(videoId, playlistId string) error {
+func (m *MockIVideoHost) AddVideoToPlaylist(ctx context.Context, videoId, playlistId string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddVideoToPlaylist", videoId, playlistId)
+	ret := m.ctrl.Call(m, "AddVideoToPlaylist", ctx, videoId, playlistId)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // AddVideoToPlaylist indicates an expected call of AddVideoToPlaylist.
-func (mr *MockIVideoHostMockRecorder) AddVideoToPlaylist(videoId, playlistId interface{}) *gomock.Call {
+func (mr *MockIVideoHostMockRecorder) AddVideoToPlaylist(ctx, videoId, playlistId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddVideoToPlaylist", reflect.TypeOf((*MockIVideoHost)(nil).AddVideoToPlaylist), videoId, playlistId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddVideoToPlaylist", reflect.TypeOf((*MockIVideoHost)(nil).AddVideoToPlaylist), ctx, videoId, playlistId)
 }
 
 // CreatePlaylist mocks base method.
-func (m *MockIVideoHost) CreatePlaylist(meta *video_hosting.ItemMetadata) (*video_hosting.Playlist, error) {
+func (m *MockIVideoHost) CreatePlaylist(ctx context.Context, meta *video_hosting.ItemMetadata) (*video_hosting.Playlist, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreatePlaylist", meta)
+	ret := m.ctrl.Call(m, "CreatePlaylist", ctx, meta)
 	ret0, _ := ret[0].(*video_hosting.Playlist)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreatePlaylist indicates an expected call of CreatePlaylist.
-func (mr *MockIVideoHostMockRecorder) CreatePlaylist(meta interface{}) *gomock.Call {
+func (mr *MockIVideoHostMockRecorder) CreatePlaylist(ctx, meta interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePlaylist", reflect.TypeOf((*MockIVideoHost)(nil).CreatePlaylist), meta)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePlaylist", reflect.TypeOf((*MockIVideoHost)(nil).CreatePlaylist), ctx, meta)
 }
 
 // CreateVideo mocks base method.
-func (m *MockIVideoHost) CreateVideo(meta *video_hosting.ItemMetadata, uploadContent io.Reader, onProgress *video_hosting.ProgressFunc) (*video_hosting.Video, error) {
+func (m *MockIVideoHost) CreateVideo(ctx context.Context, meta *video_hosting.ItemMetadata, uploadContent io.Reader, onProgress *video_hosting.ProgressFunc) (*video_hosting.Video, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateVideo", meta, uploadContent, onProgress)
+	ret := m.ctrl.Call(m, "CreateVideo", ctx, meta, uploadContent, onProgress)
 	ret0, _ := ret[0].(*video_hosting.Video)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateVideo indicates an expected call of CreateVideo.
-func (mr *MockIVideoHostMockRecorder) CreateVideo(meta, uploadContent, onProgress interface{}) *gomock.Call {
+func (mr *MockIVideoHostMockRecorder) CreateVideo(ctx, meta, uploadContent, onProgress interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVideo", reflect.TypeOf((*MockIVideoHost)(nil).CreateVideo), meta, uploadContent, onProgress)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVideo", reflect.TypeOf((*MockIVideoHost)(nil).CreateVideo), ctx, meta, uploadContent, onProgress)
 }
 
 // DeletePlaylist mocks base method.
-func (m *MockIVideoHost) DeletePlaylist(id string) error {
+func (m *MockIVideoHost) DeletePlaylist(ctx context.Context, id string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeletePlaylist", id)
+	ret := m.ctrl.Call(m, "DeletePlaylist", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeletePlaylist indicates an expected call of DeletePlaylist.
-func (mr *MockIVideoHostMockRecorder) DeletePlaylist(id interface{}) *gomock.Call {
+func (mr *MockIVideoHostMockRecorder) DeletePlaylist(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePlaylist", reflect.TypeOf((*MockIVideoHost)(nil).DeletePlaylist), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePlaylist", reflect.TypeOf((*MockIVideoHost)(nil).DeletePlaylist), ctx, id)
 }
 
 // DeleteVideo mocks base method.
-func (m *MockIVideoHost) DeleteVideo(id string) error {
+func (m *MockIVideoHost) DeleteVideo(ctx context.Context, id string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteVideo", id)
+	ret := m.ctrl.Call(m, "DeleteVideo", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteVideo indicates an expected call of DeleteVideo.
-func (mr *MockIVideoHostMockRecorder) DeleteVideo(id interface{}) *gomock.Call {
+func (mr *MockIVideoHostMockRecorder) DeleteVideo(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVideo", reflect.TypeOf((*MockIVideoHost)(nil).DeleteVideo), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVideo", reflect.TypeOf((*MockIVideoHost)(nil).DeleteVideo), ctx, id)
 }
 
 // GetVideoAccessPrefix mocks base method.
@@ -121,76 +138,359 @@ func (mr *MockIVideoHostMockRecorder) GetVideoAccessPrefix() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVideoAccessPrefix", reflect.TypeOf((*MockIVideoHost)(nil).GetVideoAccessPrefix))
 }
 
+// ListPlaylists mocks base method.
+func (m *MockIVideoHost) ListPlaylists(ctx context.Context, ids []string) ([]*video_hosting.Playlist, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPlaylists", ctx, ids)
+	ret0, _ := ret[0].([]*video_hosting.Playlist)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPlaylists indicates an expected call of ListPlaylists.
+func (mr *MockIVideoHostMockRecorder) ListPlaylists(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPlaylists", reflect.TypeOf((*MockIVideoHost)(nil).ListPlaylists), ctx, ids)
+}
+
+// ListVideos mocks base method.
+func (m *MockIVideoHost) ListVideos(ctx context.Context, ids []string) ([]*video_hosting.Video, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListVideos", ctx, ids)
+	ret0, _ := ret[0].([]*video_hosting.Video)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListVideos indicates an expected call of ListVideos.
+func (mr *MockIVideoHostMockRecorder) ListVideos(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVideos", reflect.TypeOf((*MockIVideoHost)(nil).ListVideos), ctx, ids)
+}
+
 // RetrievePlaylist mocks base method.
-func (m *MockIVideoHost) RetrievePlaylist(id string) (*video_hosting.Playlist, error) {
+func (m *MockIVideoHost) RetrievePlaylist(ctx context.Context, id string) (*video_hosting.Playlist, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrievePlaylist", id)
+	ret := m.ctrl.Call(m, "RetrievePlaylist", ctx, id)
 	ret0, _ := ret[0].(*video_hosting.Playlist)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RetrievePlaylist indicates an expected call of RetrievePlaylist.
-func (mr *MockIVideoHostMockRecorder) RetrievePlaylist(id interface{}) *gomock.Call {
+func (mr *MockIVideoHostMockRecorder) RetrievePlaylist(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrievePlaylist", reflect.TypeOf((*MockIVideoHost)(nil).RetrievePlaylist), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrievePlaylist", reflect.TypeOf((*MockIVideoHost)(nil).RetrievePlaylist), ctx, id)
 }
 
 // RetrieveVideo mocks base method.
-func (m *MockIVideoHost) RetrieveVideo(id string) (*video_hosting.Video, error) {
+func (m *MockIVideoHost) RetrieveVideo(ctx context.Context, id string) (*video_hosting.Video, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveVideo", id)
+	ret := m.ctrl.Call(m, "RetrieveVideo", ctx, id)
 	ret0, _ := ret[0].(*video_hosting.Video)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RetrieveVideo indicates an expected call of RetrieveVideo.
-func (mr *MockIVideoHostMockRecorder) RetrieveVideo(id interface{}) *gomock.Call {
+func (mr *MockIVideoHostMockRecorder) RetrieveVideo(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveVideo", reflect.TypeOf((*MockIVideoHost)(nil).RetrieveVideo), ctx, id)
+}
+
+// ScheduleVideo mocks base method.
+func (m *MockIVideoHost) ScheduleVideo(ctx context.Context, videoId string, publishAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleVideo", ctx, videoId, publishAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ScheduleVideo indicates an expected call of ScheduleVideo.
+func (mr *MockIVideoHostMockRecorder) ScheduleVideo(ctx, videoId, publishAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleVideo", reflect.TypeOf((*MockIVideoHost)(nil).ScheduleVideo), ctx, videoId, publishAt)
+}
+
+// SetVideoTags mocks base method.
+func (m *MockIVideoHost) SetVideoTags(ctx context.Context, videoId string, tags []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVideoTags", ctx, videoId, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVideoTags indicates an expected call of SetVideoTags.
+func (mr *MockIVideoHostMockRecorder) SetVideoTags(ctx, videoId, tags interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveVideo", reflect.TypeOf((*MockIVideoHost)(nil).RetrieveVideo), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVideoTags", reflect.TypeOf((*MockIVideoHost)(nil).SetVideoTags), ctx, videoId, tags)
 }
 
 // UpdatePlaylist mocks base method.
-func (m *MockIVideoHost) UpdatePlaylist(id string, replacement *video_hosting.Playlist) (*video_hosting.Playlist, error) {
+func (m *MockIVideoHost) UpdatePlaylist(ctx context.Context, id string, replacement *video_hosting.Playlist) (*video_hosting.Playlist, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdatePlaylist", id, replacement)
+	ret := m.ctrl.Call(m, "UpdatePlaylist", ctx, id, replacement)
 	ret0, _ := ret[0].(*video_hosting.Playlist)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdatePlaylist indicates an expected call of UpdatePlaylist.
-func (mr *MockIVideoHostMockRecorder) UpdatePlaylist(id, replacement interface{}) *gomock.Call {
+func (mr *MockIVideoHostMockRecorder) UpdatePlaylist(ctx, id, replacement interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePlaylist", reflect.TypeOf((*MockIVideoHost)(nil).UpdatePlaylist), id, replacement)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePlaylist", reflect.TypeOf((*MockIVideoHost)(nil).UpdatePlaylist), ctx, id, replacement)
 }
 
 // UpdateVideo mocks base method.
-func (m *MockIVideoHost) UpdateVideo(id string, replacement *video_hosting.Video) (*video_hosting.Video, error) {
+func (m *MockIVideoHost) UpdateVideo(ctx context.Context, id string, replacement *video_hosting.Video) (*video_hosting.Video, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateVideo", id, replacement)
+	ret := m.ctrl.Call(m, "UpdateVideo", ctx, id, replacement)
 	ret0, _ := ret[0].(*video_hosting.Video)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateVideo indicates an expected call of UpdateVideo.
-func (mr *MockIVideoHostMockRecorder) UpdateVideo(id, replacement interface{}) *gomock.Call {
+func (mr *MockIVideoHostMockRecorder) UpdateVideo(ctx, id, replacement interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVideo", reflect.TypeOf((*MockIVideoHost)(nil).UpdateVideo), id, replacement)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVideo", reflect.TypeOf((*MockIVideoHost)(nil).UpdateVideo), ctx, id, replacement)
 }
 
 // UpdateVideoThumbnail mocks base method.
-func (m *MockIVideoHost) UpdateVideoThumbnail(videoId string, thumbnailContent io.Reader) error {
+func (m *MockIVideoHost) UpdateVideoThumbnail(ctx context.Context, videoId string, thumbnailContent io.Reader) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateVideoThumbnail", videoId, thumbnailContent)
+	ret := m.ctrl.Call(m, "UpdateVideoThumbnail", ctx, videoId, thumbnailContent)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateVideoThumbnail indicates an expected call of UpdateVideoThumbnail.
-func (mr *MockIVideoHostMockRecorder) UpdateVideoThumbnail(videoId, thumbnailContent interface{}) *gomock.Call {
+func (mr *MockIVideoHostMockRecorder) UpdateVideoThumbnail(ctx, videoId, thumbnailContent interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVideoThumbnail", reflect.TypeOf((*MockIVideoHost)(nil).UpdateVideoThumbnail), ctx, videoId, thumbnailContent)
+}
+
+// MockPinger is a mock of Pinger interface.
+type MockPinger struct {
+	ctrl     *gomock.Controller
+	recorder *MockPingerMockRecorder
+}
+
+// MockPingerMockRecorder is the mock recorder for MockPinger.
+type MockPingerMockRecorder struct {
+	mock *MockPinger
+}
+
+// NewMockPinger creates a new mock instance.
+func NewMockPinger(ctrl *gomock.Controller) *MockPinger {
+	mock := &MockPinger{ctrl: ctrl}
+	mock.recorder = &MockPingerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPinger) EXPECT() *MockPingerMockRecorder {
+	return m.recorder
+}
+
+// Ping mocks base method.
+func (m *MockPinger) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockPingerMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockPinger)(nil).Ping), ctx)
+}
+
+// MockCredentialsRotator is a mock of CredentialsRotator interface.
+type MockCredentialsRotator struct {
+	ctrl     *gomock.Controller
+	recorder *MockCredentialsRotatorMockRecorder
+}
+
+// MockCredentialsRotatorMockRecorder is the mock recorder for MockCredentialsRotator.
+type MockCredentialsRotatorMockRecorder struct {
+	mock *MockCredentialsRotator
+}
+
+// NewMockCredentialsRotator creates a new mock instance.
+func NewMockCredentialsRotator(ctrl *gomock.Controller) *MockCredentialsRotator {
+	mock := &MockCredentialsRotator{ctrl: ctrl}
+	mock.recorder = &MockCredentialsRotatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCredentialsRotator) EXPECT() *MockCredentialsRotatorMockRecorder {
+	return m.recorder
+}
+
+// RotateCredentials mocks base method.
+func (m *MockCredentialsRotator) RotateCredentials(secret map[string]string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateCredentials", secret)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// RotateCredentials indicates an expected call of RotateCredentials.
+func (mr *MockCredentialsRotatorMockRecorder) RotateCredentials(secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateCredentials", reflect.TypeOf((*MockCredentialsRotator)(nil).RotateCredentials), secret)
+}
+
+// MockAuthorizer is a mock of Authorizer interface.
+type MockAuthorizer struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthorizerMockRecorder
+}
+
+// MockAuthorizerMockRecorder is the mock recorder for MockAuthorizer.
+type MockAuthorizerMockRecorder struct {
+	mock *MockAuthorizer
+}
+
+// NewMockAuthorizer creates a new mock instance.
+func NewMockAuthorizer(ctrl *gomock.Controller) *MockAuthorizer {
+	mock := &MockAuthorizer{ctrl: ctrl}
+	mock.recorder = &MockAuthorizerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthorizer) EXPECT() *MockAuthorizerMockRecorder {
+	return m.recorder
+}
+
+// DeviceOAuthConfig mocks base method.
+func (m *MockAuthorizer) DeviceOAuthConfig() *oauth2.Config {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeviceOAuthConfig")
+	ret0, _ := ret[0].(*oauth2.Config)
+	return ret0
+}
+
+// DeviceOAuthConfig indicates an expected call of DeviceOAuthConfig.
+func (mr *MockAuthorizerMockRecorder) DeviceOAuthConfig() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeviceOAuthConfig", reflect.TypeOf((*MockAuthorizer)(nil).DeviceOAuthConfig))
+}
+
+// OAuthConfig mocks base method.
+func (m *MockAuthorizer) OAuthConfig(redirectUrl string) *oauth2.Config {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OAuthConfig", redirectUrl)
+	ret0, _ := ret[0].(*oauth2.Config)
+	return ret0
+}
+
+// OAuthConfig indicates an expected call of OAuthConfig.
+func (mr *MockAuthorizerMockRecorder) OAuthConfig(redirectUrl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OAuthConfig", reflect.TypeOf((*MockAuthorizer)(nil).OAuthConfig), redirectUrl)
+}
+
+// MockAuthWatcher is a mock of AuthWatcher interface.
+type MockAuthWatcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthWatcherMockRecorder
+}
+
+// MockAuthWatcherMockRecorder is the mock recorder for MockAuthWatcher.
+type MockAuthWatcherMockRecorder struct {
+	mock *MockAuthWatcher
+}
+
+// NewMockAuthWatcher creates a new mock instance.
+func NewMockAuthWatcher(ctrl *gomock.Controller) *MockAuthWatcher {
+	mock := &MockAuthWatcher{ctrl: ctrl}
+	mock.recorder = &MockAuthWatcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthWatcher) EXPECT() *MockAuthWatcherMockRecorder {
+	return m.recorder
+}
+
+// OnAuthExpired mocks base method.
+func (m *MockAuthWatcher) OnAuthExpired(fn func(error)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnAuthExpired", fn)
+}
+
+// OnAuthExpired indicates an expected call of OnAuthExpired.
+func (mr *MockAuthWatcherMockRecorder) OnAuthExpired(fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAuthExpired", reflect.TypeOf((*MockAuthWatcher)(nil).OnAuthExpired), fn)
+}
+
+// MockMetadataValidator is a mock of MetadataValidator interface.
+type MockMetadataValidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockMetadataValidatorMockRecorder
+}
+
+// MockMetadataValidatorMockRecorder is the mock recorder for MockMetadataValidator.
+type MockMetadataValidatorMockRecorder struct {
+	mock *MockMetadataValidator
+}
+
+// NewMockMetadataValidator creates a new mock instance.
+func NewMockMetadataValidator(ctrl *gomock.Controller) *MockMetadataValidator {
+	mock := &MockMetadataValidator{ctrl: ctrl}
+	mock.recorder = &MockMetadataValidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMetadataValidator) EXPECT() *MockMetadataValidatorMockRecorder {
+	return m.recorder
+}
+
+// ValidatePlaylist mocks base method.
+func (m *MockMetadataValidator) ValidatePlaylist(meta *video_hosting.ItemMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidatePlaylist", meta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidatePlaylist indicates an expected call of ValidatePlaylist.
+func (mr *MockMetadataValidatorMockRecorder) ValidatePlaylist(meta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidatePlaylist", reflect.TypeOf((*MockMetadataValidator)(nil).ValidatePlaylist), meta)
+}
+
+// ValidateTags mocks base method.
+func (m *MockMetadataValidator) ValidateTags(tags []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateTags", tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateTags indicates an expected call of ValidateTags.
+func (mr *MockMetadataValidatorMockRecorder) ValidateTags(tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateTags", reflect.TypeOf((*MockMetadataValidator)(nil).ValidateTags), tags)
+}
+
+// ValidateVideo mocks base method.
+func (m *MockMetadataValidator) ValidateVideo(meta *video_hosting.ItemMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateVideo", meta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateVideo indicates an expected call of ValidateVideo.
+func (mr *MockMetadataValidatorMockRecorder) ValidateVideo(meta interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVideoThumbnail", reflect.TypeOf((*MockIVideoHost)(nil).UpdateVideoThumbnail), videoId, thumbnailContent)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateVideo", reflect.TypeOf((*MockMetadataValidator)(nil).ValidateVideo), meta)
 }
@@ -0,0 +1,119 @@
+package oauth_consent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"sync"
+	"time"
+)
+
+// DefaultTTL How long a user has to give their consent once the flow started
+const DefaultTTL = 10 * time.Minute
+
+var (
+	// ErrUnknownState The state sent back by the authorization server was never issued, already used, or expired
+	ErrUnknownState = errors.New("unknown or expired consent state")
+	// ErrNoRefreshToken The authorization server granted an access token only
+	ErrNoRefreshToken = errors.New("no refresh token granted")
+)
+
+// Consent Three-legged OAuth flow, obtaining a refresh token once the user gave their consent.
+// Each flow is protected by a single-use state and PKCE. The started flows are only known to this process :
+// a flow must be completed by the instance that started it
+type Consent struct {
+	// OAuth configuration of the platform, read on each flow as the client credentials may be rotated
+	config func() *oauth2.Config
+	// How long a started flow can be completed
+	ttl time.Duration
+	mu  sync.Mutex
+	// Flows waiting for the user consent, by state
+	pending map[string]pendingConsent
+	now     func() time.Time
+}
+
+// A flow waiting for the user consent
+type pendingConsent struct {
+	// PKCE code verifier, never sent to the user
+	verifier  string
+	expiresAt time.Time
+}
+
+// NewConsent Consent flows using the configuration returned by config. A zero ttl uses DefaultTTL
+func NewConsent(config func() *oauth2.Config, ttl time.Duration) *Consent {
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	return &Consent{config: config, ttl: ttl, pending: make(map[string]pendingConsent), now: time.Now}
+}
+
+// Start a new flow, returning the URL of the consent screen the user must be sent to
+func (c *Consent) Start() (string, error) {
+	state, err := randomState()
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+	now := c.now()
+	c.mu.Lock()
+	// Abandoned flows are dropped along the way
+	for s, p := range c.pending {
+		if now.After(p.expiresAt) {
+			delete(c.pending, s)
+		}
+	}
+	c.pending[state] = pendingConsent{verifier: verifier, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+	// The refresh token is only granted for an offline access, and only on the first consent unless forced
+	return c.config().AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Complete the flow identified by state, exchanging the authorization code for a token.
+// The state can't be used again, whatever the outcome
+func (c *Consent) Complete(ctx context.Context, state string, code string) (*oauth2.Token, error) {
+	c.mu.Lock()
+	p, ok := c.pending[state]
+	delete(c.pending, state)
+	c.mu.Unlock()
+	if !ok || c.now().After(p.expiresAt) {
+		return nil, ErrUnknownState
+	}
+	token, err := c.config().Exchange(ctx, code, oauth2.VerifierOption(p.verifier))
+	if err != nil {
+		return nil, fmt.Errorf("could not exchange the authorization code : %w", err)
+	}
+	if token.RefreshToken == "" {
+		return nil, ErrNoRefreshToken
+	}
+	return token, nil
+}
+
+// Device Run the device flow, for a user without a browser on this machine.
+// prompt is given the code the user must enter and where, the call then blocks until the user gave their consent
+func Device(ctx context.Context, config *oauth2.Config, prompt func(auth *oauth2.DeviceAuthResponse)) (*oauth2.Token, error) {
+	auth, err := config.DeviceAuth(ctx, oauth2.AccessTypeOffline)
+	if err != nil {
+		return nil, fmt.Errorf("could not start the device flow : %w", err)
+	}
+	prompt(auth)
+	token, err := config.DeviceAccessToken(ctx, auth)
+	if err != nil {
+		return nil, fmt.Errorf("the device flow failed : %w", err)
+	}
+	if token.RefreshToken == "" {
+		return nil, ErrNoRefreshToken
+	}
+	return token, nil
+}
+
+// Random value binding the consent screen to the callback
+func randomState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,155 @@
+package oauth_consent
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Fake authorization server. The token endpoint checks the PKCE verifier against the last challenge issued,
+// and grants the refresh token "refresh"
+func setupServer(t *testing.T, challenge *string, refreshToken string) *oauth2.Config {
+	var polls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Form.Get("grant_type") == "urn:ietf:params:oauth:grant-type:device_code" {
+			// The user gives their consent on the second poll
+			if polls.Add(1) == 1 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"authorization_pending"}`)
+				return
+			}
+		} else if r.Form.Get("code") != "code" || oauth2.S256ChallengeFromVerifier(r.Form.Get("code_verifier")) != *challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"access","token_type":"Bearer","expires_in":3600,"refresh_token":"%s"}`, refreshToken)
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"device_code":"device","user_code":"ABCD-EFGH","verification_url":"https://example.com/device","expires_in":60,"interval":1}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return &oauth2.Config{
+		ClientID:     "id",
+		ClientSecret: "secret",
+		RedirectURL:  "https://video-store.example.com/v1/auth/youtube/callback",
+		Endpoint: oauth2.Endpoint{
+			AuthURL:       "https://accounts.example.com/auth",
+			TokenURL:      server.URL + "/token",
+			DeviceAuthURL: server.URL + "/device",
+		},
+	}
+}
+
+// Start a flow, returning the state and PKCE challenge of the consent URL
+func start(t *testing.T, c *Consent) (string, string) {
+	consentUrl, err := c.Start()
+	assert.Nil(t, err)
+	parsed, err := url.Parse(consentUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	assert.Equal(t, "offline", query.Get("access_type"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.NotEmpty(t, query.Get("state"))
+	return query.Get("state"), query.Get("code_challenge")
+}
+
+func TestConsent(t *testing.T) {
+	var challenge string
+	config := setupServer(t, &challenge, "refresh")
+	c := NewConsent(func() *oauth2.Config { return config }, 0)
+
+	var state string
+	state, challenge = start(t, c)
+	token, err := c.Complete(context.Background(), state, "code")
+	assert.Nil(t, err)
+	assert.Equal(t, "refresh", token.RefreshToken)
+
+	// The state is single use
+	_, err = c.Complete(context.Background(), state, "code")
+	assert.ErrorIs(t, err, ErrUnknownState)
+}
+
+func TestConsent_UnknownState(t *testing.T) {
+	var challenge string
+	config := setupServer(t, &challenge, "refresh")
+	c := NewConsent(func() *oauth2.Config { return config }, 0)
+	_, challenge = start(t, c)
+
+	_, err := c.Complete(context.Background(), "forged", "code")
+	assert.ErrorIs(t, err, ErrUnknownState)
+}
+
+func TestConsent_Expired(t *testing.T) {
+	var challenge string
+	config := setupServer(t, &challenge, "refresh")
+	c := NewConsent(func() *oauth2.Config { return config }, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	var state string
+	state, challenge = start(t, c)
+	now = now.Add(2 * time.Minute)
+	_, err := c.Complete(context.Background(), state, "code")
+	assert.ErrorIs(t, err, ErrUnknownState)
+
+	// Abandoned flows are eventually dropped
+	_, challenge = start(t, c)
+	now = now.Add(2 * time.Minute)
+	start(t, c)
+	assert.Len(t, c.pending, 1)
+}
+
+func TestConsent_InvalidVerifier(t *testing.T) {
+	var challenge string
+	config := setupServer(t, &challenge, "refresh")
+	c := NewConsent(func() *oauth2.Config { return config }, 0)
+
+	state, _ := start(t, c)
+	// The code was intercepted and used with another flow
+	_, challenge = start(t, c)
+	_, err := c.Complete(context.Background(), state, "code")
+	assert.NotNil(t, err)
+	assert.NotErrorIs(t, err, ErrUnknownState)
+}
+
+func TestConsent_NoRefreshToken(t *testing.T) {
+	var challenge string
+	config := setupServer(t, &challenge, "")
+	c := NewConsent(func() *oauth2.Config { return config }, 0)
+
+	var state string
+	state, challenge = start(t, c)
+	_, err := c.Complete(context.Background(), state, "code")
+	assert.ErrorIs(t, err, ErrNoRefreshToken)
+}
+
+func TestDevice(t *testing.T) {
+	config := setupServer(t, new(string), "refresh")
+	var prompted *oauth2.DeviceAuthResponse
+	token, err := Device(context.Background(), config, func(auth *oauth2.DeviceAuthResponse) {
+		prompted = auth
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "refresh", token.RefreshToken)
+	assert.Equal(t, "ABCD-EFGH", prompted.UserCode)
+	assert.Equal(t, "https://example.com/device", prompted.VerificationURI)
+}
@@ -7,9 +7,12 @@ import (
 	"encoding/base64"
 	"fmt"
 	"github.com/dapr/go-sdk/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"io"
 	"os"
 	"path/filepath"
+	"video-manager/internal/tracing"
 )
 
 // ObjectStorage any S3-like storage solution
@@ -20,12 +23,10 @@ type ObjectStorage[T BindingProxy] struct {
 	componentName string
 	// Client to query the backend storage
 	client *T
-	// Current running context
-	ctx *context.Context
 }
 
 // NewDaprObjectStorage Prod ready constructor for an object-storage using Dapr
-func NewDaprObjectStorage(ctx *context.Context, daprClient *client.Client, component string) (*ObjectStorage[client.Client], error) {
+func NewDaprObjectStorage(daprClient *client.Client, component string) (*ObjectStorage[client.Client], error) {
 	dir, err := os.MkdirTemp("", "downloader-")
 	if err != nil {
 		return nil, err
@@ -34,17 +35,15 @@ func NewDaprObjectStorage(ctx *context.Context, daprClient *client.Client, compo
 		assetsPath:    dir,
 		componentName: component,
 		client:        daprClient,
-		ctx:           ctx,
 	}, nil
 }
 
 // NewObjectStorage General purpose object storage
-func NewObjectStorage[T BindingProxy](ctx *context.Context, assetsPath string, client T) *ObjectStorage[T] {
+func NewObjectStorage[T BindingProxy](assetsPath string, client T) *ObjectStorage[T] {
 	return &ObjectStorage[T]{
 		assetsPath:    assetsPath,
 		componentName: "",
 		client:        &client,
-		ctx:           ctx,
 	}
 }
 
@@ -55,8 +54,8 @@ type BindingProxy interface {
 }
 
 // Download a file from the backend storage
-func (od ObjectStorage[T]) Download(key string) (path *string, err error) {
-	reader, err := od.Buffer(key)
+func (od ObjectStorage[T]) Download(ctx context.Context, key string) (path *string, err error) {
+	reader, err := od.Buffer(ctx, key)
 	if err != nil {
 		return nil, err
 	}
@@ -71,8 +70,8 @@ func (od ObjectStorage[T]) Download(key string) (path *string, err error) {
 }
 
 // Buffer the content of a file in memory
-func (od ObjectStorage[T]) Buffer(key string) (data *io.Reader, err error) {
-	res, err := (*od.client).InvokeBinding(*od.ctx, &client.InvokeBindingRequest{
+func (od ObjectStorage[T]) Buffer(ctx context.Context, key string) (data *io.Reader, err error) {
+	res, err := od.invoke(ctx, &client.InvokeBindingRequest{
 		Name:      od.componentName,
 		Operation: "get",
 		Data:      nil,
@@ -87,12 +86,12 @@ func (od ObjectStorage[T]) Buffer(key string) (data *io.Reader, err error) {
 }
 
 // Upload Uploads a file on the backend storage
-func (od ObjectStorage[T]) Upload(path string, key string) error {
+func (od ObjectStorage[T]) Upload(ctx context.Context, path string, key string) error {
 	b64bytes, err := readFileToB64(path)
 	if err != nil {
 		return err
 	}
-	_, err = (*od.client).InvokeBinding(*od.ctx, &client.InvokeBindingRequest{
+	_, err = od.invoke(ctx, &client.InvokeBindingRequest{
 		Name:      od.componentName,
 		Operation: "create",
 		Data:      b64bytes,
@@ -107,8 +106,8 @@ func (od ObjectStorage[T]) Upload(path string, key string) error {
 }
 
 // Delete a file in the remote object storage
-func (od ObjectStorage[T]) Delete(key string) error {
-	_, err := (*od.client).InvokeBinding(*od.ctx, &client.InvokeBindingRequest{
+func (od ObjectStorage[T]) Delete(ctx context.Context, key string) error {
+	_, err := od.invoke(ctx, &client.InvokeBindingRequest{
 		Name:      od.componentName,
 		Operation: "delete",
 		Data:      nil,
@@ -119,6 +118,29 @@ func (od ObjectStorage[T]) Delete(key string) error {
 	return err
 }
 
+// Ping Checks the backend storage responds, by listing at most one of its files
+func (od ObjectStorage[T]) Ping(ctx context.Context) error {
+	_, err := od.invoke(ctx, &client.InvokeBindingRequest{
+		Name:      od.componentName,
+		Operation: "list",
+		Data:      []byte(`{"maxResults":1}`),
+	})
+	return err
+}
+
+// Invoke the binding, tracing the call
+func (od ObjectStorage[T]) invoke(ctx context.Context, in *client.InvokeBindingRequest) (*client.BindingEvent, error) {
+	ctx, span := tracing.Start(ctx, "object-storage "+in.Operation, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("dapr.binding", in.Name),
+			attribute.String("dapr.binding.operation", in.Operation),
+			attribute.String("object_storage.key", in.Metadata["key"]),
+		))
+	res, err := (*od.client).InvokeBinding(ctx, in)
+	tracing.End(span, err)
+	return res, err
+}
+
 // Read a file into a base64 bytes-array
 func readFileToB64(path string) ([]byte, error) {
 	var buf bytes.Buffer
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"io/ioutil"
+	"os"
 	"path"
 	"testing"
 )
@@ -45,7 +46,6 @@ func (s *e2eTestSuite) SetupSuite() {
 		s.Fail(err.Error())
 	}
 	s.client = daprClient
-	ctx := context.Background()
 	// Copy a file in the bucket directory to download it later
 	err = copy(&s.client, path.Join(ResPath, TestFileName), TestDownloadAssetKey)
 	err = copy(&s.client, path.Join(ResPath, TestFileName), TestDeleteAssetKey)
@@ -56,13 +56,12 @@ func (s *e2eTestSuite) SetupSuite() {
 		assetsPath:    dir,
 		componentName: DaprComponent,
 		client:        &daprClient,
-		ctx:           &ctx,
 	}
 
 }
 
 func (s *e2eTestSuite) TestDownload_Int() {
-	expectedPath, err := s.objStore.Download(TestDownloadAssetKey)
+	expectedPath, err := s.objStore.Download(context.Background(), TestDownloadAssetKey)
 	if err != nil {
 		s.T().Fatal(err)
 	}
@@ -76,7 +75,7 @@ func (s *e2eTestSuite) TestDownload_Int() {
 }
 
 func (s *e2eTestSuite) TestDownload_Int_NotExists() {
-	_, err := s.objStore.Download("notexists")
+	_, err := s.objStore.Download(context.Background(), "notexists")
 	if err == nil {
 		s.T().Fatal(err)
 	}
@@ -86,7 +85,7 @@ func (s *e2eTestSuite) TestDownload_Int_NotExists() {
 
 func (s *e2eTestSuite) TestUpload_Int() {
 	file := "audio.m4a"
-	err := s.objStore.Upload(path.Join(ResPath, file), file)
+	err := s.objStore.Upload(context.Background(), path.Join(ResPath, file), file)
 	if err != nil {
 		s.T().Fatal(err)
 	}
@@ -94,17 +93,17 @@ func (s *e2eTestSuite) TestUpload_Int() {
 
 func (s *e2eTestSuite) TestDelete_Int() {
 	// Check that the file can be downloaded
-	_, err := s.objStore.Download(TestDeleteAssetKey)
+	_, err := s.objStore.Download(context.Background(), TestDeleteAssetKey)
 	if err != nil {
 		s.T().Fatal(err)
 	}
 	// Then delete it
-	err = s.objStore.Delete(TestDeleteAssetKey)
+	err = s.objStore.Delete(context.Background(), TestDeleteAssetKey)
 	if err != nil {
 		s.T().Fatal(err)
 	}
 	// And check that it cannot be downloaded anymore
-	res, err := s.objStore.Download(TestDeleteAssetKey)
+	res, err := s.objStore.Download(context.Background(), TestDeleteAssetKey)
 	if err == nil {
 		s.T().Fatal(err)
 	}
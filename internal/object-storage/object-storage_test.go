@@ -48,9 +48,8 @@ func TestObjectStorage_Download(t *testing.T) {
 		assetsPath:    dir,
 		componentName: "test",
 		client:        &daprClient,
-		ctx:           &ctx,
 	}
-	path, _ := od.Download("test.txt")
+	path, _ := od.Download(ctx, "test.txt")
 	writtenFileContent, err := os.ReadFile(*path)
 	if err != nil {
 		t.Fatal(err)
@@ -73,9 +72,8 @@ func TestObjectStorage_Upload(t *testing.T) {
 		assetsPath:    dir,
 		componentName: "test",
 		client:        &daprClient,
-		ctx:           &ctx,
 	}
-	err := od.Upload(path.Join(ResPath, "test.txt"), "key")
+	err := od.Upload(ctx, path.Join(ResPath, "test.txt"), "key")
 	assert.Nil(t, err)
 }
 
@@ -94,9 +92,8 @@ func TestObjectStorage_DeleteOk(t *testing.T) {
 		assetsPath:    dir,
 		componentName: "test",
 		client:        &daprClient,
-		ctx:           &ctx,
 	}
-	err := od.Delete("key")
+	err := od.Delete(ctx, "key")
 	assert.Nil(t, err)
 }
 
@@ -115,12 +112,31 @@ func TestObjectStorage_DeleteError(t *testing.T) {
 		assetsPath:    dir,
 		componentName: "test",
 		client:        &daprClient,
-		ctx:           &ctx,
 	}
-	err := od.Delete("key")
+	err := od.Delete(ctx, "key")
 	assert.NotNil(t, err)
 }
 
+func TestObjectStorage_Ping(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	daprClient.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, in *client.InvokeBindingRequest) (*client.BindingEvent, error) {
+			assert.Equal(t, "test", in.Name)
+			assert.Equal(t, "list", in.Operation)
+			return &client.BindingEvent{Data: []byte("[]")}, nil
+		})
+	daprClient.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
+
+	ctx := context.Background()
+	od := ObjectStorage[*mock_client.MockClient]{
+		componentName: "test",
+		client:        &daprClient,
+	}
+	assert.Nil(t, od.Ping(ctx))
+	assert.NotNil(t, od.Ping(ctx))
+}
+
 // Check that the streamijng way to build the B64 signature is identical to the
 // non-streaming way
 func TestObjectStorage_readFileToB64(t *testing.T) {
@@ -0,0 +1,152 @@
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// ContentType Media type of a problem details response
+// https://www.rfc-editor.org/rfc/rfc7807
+const ContentType = "application/problem+json"
+
+// Prefix of all problem types. Types are stable, clients can rely on them to tell errors apart
+const typePrefix = "urn:video-store:problem:"
+
+// Type Stable URI identifying a kind of problem
+type Type string
+
+const (
+	NotFound           = Type(typePrefix + string(video_hosting.NotFound))
+	QuotaExceeded      = Type(typePrefix + string(video_hosting.QuotaExceeded))
+	InvalidMetadata    = Type(typePrefix + string(video_hosting.InvalidMetadata))
+	Forbidden          = Type(typePrefix + string(video_hosting.Forbidden))
+	Upstream           = Type(typePrefix + string(video_hosting.Upstream))
+	StorageUnavailable = Type(typePrefix + string(video_hosting.StorageUnavailable))
+	ShuttingDown       = Type(typePrefix + string(video_hosting.ShuttingDown))
+	AuthExpired        = Type(typePrefix + string(video_hosting.AuthExpired))
+	PreconditionFailed = Type(typePrefix + string(video_hosting.PreconditionFailed))
+	// The request itself is malformed (invalid body, missing parameter...)
+	BadRequest = Type(typePrefix + "bad-request")
+	// The request credentials are missing or invalid
+	Unauthorized = Type(typePrefix + "unauthorized")
+	// The client is authenticated but lacks a required scope
+	InsufficientScope = Type(typePrefix + "insufficient-scope")
+	// The client sent too many requests
+	RateLimited = Type(typePrefix + "rate-limited")
+	// The request body exceeds the size limit
+	PayloadTooLarge = Type(typePrefix + "payload-too-large")
+	// The request body is not of an accepted media type
+	UnsupportedMediaType = Type(typePrefix + "unsupported-media-type")
+	// Anything unexpected
+	Internal = Type(typePrefix + "internal")
+)
+
+// Title and status of each problem type
+var definitions = map[Type]struct {
+	title  string
+	status int
+}{
+	NotFound:             {"Resource not found", http.StatusNotFound},
+	QuotaExceeded:        {"Hosting platform quota exceeded", http.StatusTooManyRequests},
+	InvalidMetadata:      {"Invalid metadata", http.StatusBadRequest},
+	Forbidden:            {"Operation forbidden by the hosting platform", http.StatusForbidden},
+	Upstream:             {"Hosting platform failure", http.StatusBadGateway},
+	StorageUnavailable:   {"Object storage unavailable", http.StatusServiceUnavailable},
+	ShuttingDown:         {"Service shutting down", http.StatusServiceUnavailable},
+	AuthExpired:          {"Hosting platform authorization expired", http.StatusServiceUnavailable},
+	PreconditionFailed:   {"Precondition failed", http.StatusPreconditionFailed},
+	BadRequest:           {"Bad request", http.StatusBadRequest},
+	Unauthorized:         {"Authentication required", http.StatusUnauthorized},
+	InsufficientScope:    {"Insufficient scope", http.StatusForbidden},
+	RateLimited:          {"Too many requests", http.StatusTooManyRequests},
+	PayloadTooLarge:      {"Payload too large", http.StatusRequestEntityTooLarge},
+	UnsupportedMediaType: {"Unsupported media type", http.StatusUnsupportedMediaType},
+	Internal:             {"Internal server error", http.StatusInternalServerError},
+}
+
+// Problem Details of an error, as described by RFC 7807
+type Problem struct {
+	// URI identifying the kind of problem
+	Type Type `json:"type" swaggertype:"string" example:"urn:video-store:problem:not-found"`
+	// Short human-readable summary of the kind of problem
+	Title string `json:"title" example:"Resource not found"`
+	// HTTP status code
+	Status int `json:"status" example:"404"`
+	// Human-readable explanation specific to this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+	// Path of the request that caused the problem
+	Instance string `json:"instance,omitempty"`
+	// Fields of the metadata refused by the hosting platform, for an invalid-metadata problem
+	Errors []video_hosting.FieldError `json:"errors,omitempty"`
+}
+
+// New Build a problem of type t
+func New(t Type, detail string) *Problem {
+	def, ok := definitions[t]
+	if !ok {
+		def = definitions[Internal]
+	}
+	return &Problem{Type: t, Title: def.title, Status: def.status, Detail: detail}
+}
+
+// FromError Build the problem matching err
+func FromError(err error) *Problem {
+	var re *video_hosting.RequestError
+	if !errors.As(err, &re) {
+		return New(Internal, err.Error())
+	}
+	p := New(Type(typePrefix+string(video_hosting.KindOf(re))), re.Error())
+	// The status code of the error prevails, the same kind of error may be reported differently
+	if re.StatusCode != 0 {
+		p.Status = re.StatusCode
+	}
+	p.Errors = video_hosting.FieldErrors(re)
+	return p
+}
+
+// Render Write p as the response
+func Render(c *gin.Context, p *Problem) {
+	if p.Instance == "" && c.Request != nil {
+		p.Instance = c.Request.URL.Path
+	}
+	// The JSON renderer keeps an already set content type
+	c.Header("Content-Type", ContentType)
+	c.JSON(p.Status, p)
+}
+
+// Abort Render the problem matching err and stop the handlers chain.
+// Server-side errors are also recorded in the context to be logged
+func Abort(c *gin.Context, err error) {
+	p := FromError(err)
+	if p.Status >= http.StatusInternalServerError {
+		_ = c.Error(err)
+	}
+	Render(c, p)
+	c.Abort()
+}
+
+// AbortWith Render a problem of type t and stop the handlers chain
+func AbortWith(c *gin.Context, t Type, format string, a ...any) {
+	Render(c, New(t, fmt.Sprintf(format, a...)))
+	c.Abort()
+}
+
+// Handler Middleware rendering the last error recorded by a handler as a problem,
+// unless the handler already wrote a response
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.Writer.Written() || len(c.Errors) == 0 {
+			return
+		}
+		p := FromError(c.Errors.Last().Err)
+		// A handler may have chosen a status without writing any body
+		if status := c.Writer.Status(); p.Type == Internal && status >= http.StatusBadRequest {
+			p.Status = status
+		}
+		Render(c, p)
+	}
+}
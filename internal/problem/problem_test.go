@@ -0,0 +1,130 @@
+package problem
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// Run a request through a router using the problem middleware
+func do(t *testing.T, handler gin.HandlerFunc) (*httptest.ResponseRecorder, Problem) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Handler())
+	router.GET("/items/1", handler)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1", nil)
+	router.ServeHTTP(w, req)
+	var p Problem
+	if w.Header().Get("Content-Type") == ContentType {
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w, p
+}
+
+func TestAbort_RequestError(t *testing.T) {
+	w, p := do(t, func(c *gin.Context) {
+		Abort(c, video_hosting.NewRequestError(video_hosting.QuotaExceeded, fmt.Errorf("no more units")))
+	})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, QuotaExceeded, p.Type)
+	assert.Equal(t, http.StatusTooManyRequests, p.Status)
+	assert.Equal(t, "no more units", p.Detail)
+	assert.Equal(t, "/items/1", p.Instance)
+	assert.NotEmpty(t, p.Title)
+}
+
+func TestAbort_WrappedRequestError(t *testing.T) {
+	w, p := do(t, func(c *gin.Context) {
+		err := video_hosting.NewRequestError(video_hosting.NotFound, fmt.Errorf("no video"))
+		Abort(c, fmt.Errorf("while uploading : %w", err))
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, NotFound, p.Type)
+}
+
+func TestAbort_AuthExpired(t *testing.T) {
+	w, p := do(t, func(c *gin.Context) {
+		Abort(c, video_hosting.NewRequestError(video_hosting.AuthExpired, fmt.Errorf("refresh token revoked")))
+	})
+	// The service can't do anything until an operator authorizes the host again
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, AuthExpired, p.Type)
+	assert.Equal(t, "refresh token revoked", p.Detail)
+}
+
+func TestAbort_FieldErrors(t *testing.T) {
+	fields := []video_hosting.FieldError{{Field: "title", Reason: "must not contain < or >"}, {Field: "description", Reason: "too long"}}
+	w, p := do(t, func(c *gin.Context) {
+		Abort(c, video_hosting.NewValidationError(fields))
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, InvalidMetadata, p.Type)
+	assert.Equal(t, fields, p.Errors)
+}
+
+func TestAbort_KindFromStatusCode(t *testing.T) {
+	// Errors built without a kind are classified with their status code
+	w, p := do(t, func(c *gin.Context) {
+		Abort(c, &video_hosting.RequestError{StatusCode: http.StatusNotFound, Err: fmt.Errorf("nope")})
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, NotFound, p.Type)
+}
+
+func TestAbort_UnknownError(t *testing.T) {
+	var errs int
+	w, p := do(t, func(c *gin.Context) {
+		Abort(c, fmt.Errorf("boom"))
+		errs = len(c.Errors)
+	})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, Internal, p.Type)
+	// Server-side errors are kept for logging
+	assert.Equal(t, 1, errs)
+}
+
+func TestAbortWith(t *testing.T) {
+	w, p := do(t, func(c *gin.Context) {
+		AbortWith(c, BadRequest, "missing %s", "id")
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, BadRequest, p.Type)
+	assert.Equal(t, "missing id", p.Detail)
+}
+
+func TestHandler_RendersRecordedError(t *testing.T) {
+	w, p := do(t, func(c *gin.Context) {
+		_ = c.Error(video_hosting.NewRequestError(video_hosting.Upstream, fmt.Errorf("google is down")))
+	})
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, Upstream, p.Type)
+}
+
+func TestHandler_KeepsChosenStatus(t *testing.T) {
+	w, p := do(t, func(c *gin.Context) {
+		c.Status(http.StatusConflict)
+		_ = c.Error(fmt.Errorf("conflict"))
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, Internal, p.Type)
+	assert.Equal(t, http.StatusConflict, p.Status)
+}
+
+func TestHandler_WrittenResponse(t *testing.T) {
+	w, _ := do(t, func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("logged only"))
+		c.String(http.StatusOK, "fine")
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "fine", w.Body.String())
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/dapr/go-sdk/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"video-manager/internal/metrics"
+	"video-manager/internal/tracing"
 )
 
 // ObjectStorage any S3-like storage solution
@@ -14,8 +18,8 @@ type ProgressBroker[T PubSubProxy] struct {
 	topic string
 	// Client to publish event into
 	client *T
-	// Current running context
-	ctx *context.Context
+	// Name of the host the uploads are made on
+	host string
 }
 
 type UploadState int8
@@ -24,14 +28,21 @@ const (
 	InProgress UploadState = iota
 	Done
 	Error
+	// Interrupted The service shut down before the upload could complete. It can be resumed by sending the same job again
+	Interrupted
 )
 
 type UploadInfos struct {
 	// Upload job identifier
 	JobId string `json:"jobId"`
+	// Name of the host the video is uploaded on
+	Host string `json:"host,omitempty"`
 	// Current state of the upload
 	State UploadState `json:"state"`
 	Data  interface{} `json:"data"`
+	// W3C trace context of the upload, to link the progress events to its trace
+	TraceParent string `json:"traceparent,omitempty"`
+	TraceState  string `json:"tracestate,omitempty"`
 }
 
 type PubSubProxy interface {
@@ -41,24 +52,39 @@ type PubSubProxy interface {
 type NewBrokerOptions struct {
 	Component string
 	Topic     string
+	// Name of the host stamped on each event, if any
+	Host string
 }
 
-func NewProgressBroker[T PubSubProxy](ctx *context.Context, client *T, opt NewBrokerOptions) (*ProgressBroker[T], error) {
+func NewProgressBroker[T PubSubProxy](client *T, opt NewBrokerOptions) (*ProgressBroker[T], error) {
 	return &ProgressBroker[T]{
 		componentName: opt.Component,
 		topic:         opt.Topic,
 		client:        client,
-		ctx:           ctx,
+		host:          opt.Host,
 	}, nil
 }
 
-func (eb *ProgressBroker[T]) SendProgress(data UploadInfos) error {
+func (eb *ProgressBroker[T]) SendProgress(ctx context.Context, data UploadInfos) (err error) {
+	ctx, span := tracing.Start(ctx, eb.topic+" publish", trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "dapr"),
+			attribute.String("messaging.destination.name", eb.topic),
+			attribute.String("upload.job_id", data.JobId),
+		))
+	defer func() { tracing.End(span, err) }()
+	if data.Host == "" {
+		data.Host = eb.host
+	}
+	carrier := tracing.Carrier(ctx)
+	data.TraceParent, data.TraceState = carrier["traceparent"], carrier["tracestate"]
 	b, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
-	err = (*eb.client).PublishEvent(*eb.ctx, eb.componentName, eb.topic, string(b))
+	err = (*eb.client).PublishEvent(ctx, eb.componentName, eb.topic, string(b))
 	if err != nil {
+		metrics.ProgressPublishFailures.Inc()
 		return err
 	}
 	return nil
@@ -2,9 +2,13 @@ package progress_broker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
 	"testing"
+	"video-manager/internal/metrics"
 	mock_client "video-manager/internal/mock/dapr"
 )
 
@@ -13,14 +17,14 @@ func TestProgressBroker_SendProgress(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	daprClient := mock_client.NewMockClient(ctrl)
 	daprClient.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-	pg, err := NewProgressBroker[*mock_client.MockClient](&ctx, &daprClient, NewBrokerOptions{
+	pg, err := NewProgressBroker[*mock_client.MockClient](&daprClient, NewBrokerOptions{
 		Component: "",
 		Topic:     "",
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = pg.SendProgress(UploadInfos{
+	err = pg.SendProgress(ctx, UploadInfos{
 		JobId: "1",
 		State: InProgress,
 	})
@@ -29,19 +33,44 @@ func TestProgressBroker_SendProgress(t *testing.T) {
 	}
 }
 
+func TestProgressBroker_SendProgress_Host(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	daprClient.EXPECT().PublishEvent(gomock.Any(), "pubsub", "backup-upload-state", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, data interface{}, _ ...interface{}) error {
+			var infos UploadInfos
+			if err := json.Unmarshal([]byte(data.(string)), &infos); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "backup", infos.Host)
+			return nil
+		})
+	pg, err := NewProgressBroker[*mock_client.MockClient](&daprClient, NewBrokerOptions{
+		Component: "pubsub",
+		Topic:     "backup-upload-state",
+		Host:      "backup",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pg.SendProgress(ctx, UploadInfos{JobId: "1", State: InProgress})
+	assert.Nil(t, err)
+}
+
 func TestProgressBroker_SendError(t *testing.T) {
 	ctx := context.Background()
 	ctrl := gomock.NewController(t)
 	daprClient := mock_client.NewMockClient(ctrl)
 	daprClient.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-	pg, err := NewProgressBroker[*mock_client.MockClient](&ctx, &daprClient, NewBrokerOptions{
+	pg, err := NewProgressBroker[*mock_client.MockClient](&daprClient, NewBrokerOptions{
 		Component: "",
 		Topic:     "",
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = pg.SendProgress(UploadInfos{
+	err = pg.SendProgress(ctx, UploadInfos{
 		JobId: "1",
 		State: Error,
 		Data:  fmt.Errorf("Test"),
@@ -56,14 +85,14 @@ func TestProgressBroker_SendDone(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	daprClient := mock_client.NewMockClient(ctrl)
 	daprClient.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-	pg, err := NewProgressBroker[*mock_client.MockClient](&ctx, &daprClient, NewBrokerOptions{
+	pg, err := NewProgressBroker[*mock_client.MockClient](&daprClient, NewBrokerOptions{
 		Component: "",
 		Topic:     "",
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = pg.SendProgress(UploadInfos{
+	err = pg.SendProgress(ctx, UploadInfos{
 		JobId: "1",
 		State: Done,
 		Data:  nil,
@@ -78,14 +107,15 @@ func TestProgressBroker_CouldNotSend(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	daprClient := mock_client.NewMockClient(ctrl)
 	daprClient.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("test"))
-	pg, err := NewProgressBroker[*mock_client.MockClient](&ctx, &daprClient, NewBrokerOptions{
+	pg, err := NewProgressBroker[*mock_client.MockClient](&daprClient, NewBrokerOptions{
 		Component: "",
 		Topic:     "",
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = pg.SendProgress(UploadInfos{
+	failures := testutil.ToFloat64(metrics.ProgressPublishFailures)
+	err = pg.SendProgress(ctx, UploadInfos{
 		JobId: "1",
 		State: Done,
 		Data:  nil,
@@ -93,4 +123,5 @@ func TestProgressBroker_CouldNotSend(t *testing.T) {
 	if err == nil {
 		t.Fatal(err)
 	}
+	assert.Equal(t, failures+1, testutil.ToFloat64(metrics.ProgressPublishFailures))
 }
@@ -0,0 +1,113 @@
+package rate_limiter
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+	"math"
+	"sync"
+	"time"
+	"video-manager/internal/auth"
+	"video-manager/internal/problem"
+)
+
+// Clients that didn't make any request for this long are forgotten
+const idleTimeout = 10 * time.Minute
+
+// Key under which the budget of the client is stored in the gin context, see Allow
+const budgetKey = "rate-limiter-budget"
+
+// RateLimiter Limit the number of requests each client can make, using a token bucket per client
+type RateLimiter struct {
+	// Sustained number of requests per second allowed for each client
+	rps rate.Limit
+	// Maximum number of requests a client can make in a single burst
+	burst int
+	// Limiter of each client, by client key
+	clients map[string]*client
+	mu      sync.Mutex
+	// Last time idle clients were removed
+	lastCleanup time.Time
+}
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter Allow each client "rps" requests per second on average, with bursts of at most "burst" requests.
+// A rps of 0 disables the rate limiting altogether
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rps:         rate.Limit(rps),
+		burst:       burst,
+		clients:     make(map[string]*client),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Handler Middleware answering 429 to any client exceeding its rate.
+// Authenticated clients are identified by their principal, anonymous ones by their IP.
+// The middleware must then be placed after the authentication one
+func (rl *RateLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rl.rps <= 0 {
+			c.Next()
+			return
+		}
+		key := "ip:" + c.ClientIP()
+		if p, exists := c.Get(auth.PrincipalKey); exists {
+			key = "principal:" + p.(*auth.Principal).Id
+		}
+		limiter := rl.limiter(key)
+		take := func() time.Duration {
+			if limiter.Allow() {
+				return 0
+			}
+			// Time until a token is available again
+			return time.Duration(float64(time.Second) / float64(rl.rps))
+		}
+		if wait := take(); wait > 0 {
+			c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(wait.Seconds()))))
+			problem.AbortWith(c, problem.RateLimited, `rate limit exceeded, retry in %s`, wait.Round(time.Second))
+			return
+		}
+		c.Set(budgetKey, take)
+		c.Next()
+	}
+}
+
+// Allow Take one more request from the budget of the client of c, for a request running several operations.
+// Returns an error if the client exceeded its rate. Always nil if the request didn't go through the rate limiter
+func Allow(c *gin.Context) error {
+	take, exists := c.Get(budgetKey)
+	if !exists {
+		return nil
+	}
+	if wait := take.(func() time.Duration)(); wait > 0 {
+		return fmt.Errorf(`rate limit exceeded, retry in %s`, wait.Round(time.Second))
+	}
+	return nil
+}
+
+// Retrieve the limiter of the client identified by key, creating it if needed
+func (rl *RateLimiter) limiter(key string) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := time.Now()
+	if now.Sub(rl.lastCleanup) > idleTimeout {
+		for k, cl := range rl.clients {
+			if now.Sub(cl.lastSeen) > idleTimeout {
+				delete(rl.clients, k)
+			}
+		}
+		rl.lastCleanup = now
+	}
+	cl, exists := rl.clients[key]
+	if !exists {
+		cl = &client{limiter: rate.NewLimiter(rl.rps, rl.burst)}
+		rl.clients[key] = cl
+	}
+	cl.lastSeen = now
+	return cl.limiter
+}
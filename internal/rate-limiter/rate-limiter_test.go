@@ -0,0 +1,128 @@
+package rate_limiter
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"video-manager/internal/auth"
+)
+
+// Make a router with a single rate-limited route. The principal header simulates an authenticated client
+func Setup(rl *RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		if id := c.GetHeader("X-Principal"); id != "" {
+			c.Set(auth.PrincipalKey, &auth.Principal{Id: id})
+		}
+	}, rl.Handler(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func do(router *gin.Engine, ip string, principal string) *httptest.ResponseRecorder {
+	return doForwarded(router, ip, principal, "")
+}
+
+// Make a request from ip, claiming to be forwarded for "forwardedFor" if not empty
+func doForwarded(router *gin.Engine, ip string, principal string, forwardedFor string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.RemoteAddr = ip + ":1234"
+	if principal != "" {
+		req.Header.Set("X-Principal", principal)
+	}
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiter_Burst(t *testing.T) {
+	router := Setup(NewRateLimiter(1, 2))
+	assert.Equal(t, http.StatusOK, do(router, "10.0.0.1", "").Code)
+	assert.Equal(t, http.StatusOK, do(router, "10.0.0.1", "").Code)
+	w := do(router, "10.0.0.1", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
+
+func TestRateLimiter_PerClient(t *testing.T) {
+	router := Setup(NewRateLimiter(1, 1))
+	assert.Equal(t, http.StatusOK, do(router, "10.0.0.1", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do(router, "10.0.0.1", "").Code)
+	// Another IP has its own budget
+	assert.Equal(t, http.StatusOK, do(router, "10.0.0.2", "").Code)
+	// As well as each authenticated client, even from the same IP
+	assert.Equal(t, http.StatusOK, do(router, "10.0.0.1", "alice").Code)
+	assert.Equal(t, http.StatusOK, do(router, "10.0.0.1", "bob").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do(router, "10.0.0.1", "bob").Code)
+}
+
+func TestRateLimiter_SpoofedForwardedFor(t *testing.T) {
+	router := Setup(NewRateLimiter(1, 1))
+	// As the service is configured without trusted proxies
+	assert.Nil(t, router.SetTrustedProxies(nil))
+	assert.Equal(t, http.StatusOK, doForwarded(router, "10.0.0.1", "", "1.1.1.1").Code)
+	// Another forwarded IP doesn't give a fresh budget
+	assert.Equal(t, http.StatusTooManyRequests, doForwarded(router, "10.0.0.1", "", "2.2.2.2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do(router, "10.0.0.1", "").Code)
+}
+
+func TestRateLimiter_TrustedProxy(t *testing.T) {
+	router := Setup(NewRateLimiter(1, 1))
+	assert.Nil(t, router.SetTrustedProxies([]string{"10.0.0.0/8"}))
+	// Clients behind a trusted proxy are told apart by the forwarded IP
+	assert.Equal(t, http.StatusOK, doForwarded(router, "10.0.0.1", "", "1.1.1.1").Code)
+	assert.Equal(t, http.StatusOK, doForwarded(router, "10.0.0.1", "", "2.2.2.2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doForwarded(router, "10.0.0.1", "", "1.1.1.1").Code)
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	var allowed []bool
+	router := gin.New()
+	router.GET("/", NewRateLimiter(1, 3).Handler(), func(c *gin.Context) {
+		for i := 0; i < 3; i++ {
+			allowed = append(allowed, Allow(c) == nil)
+		}
+		c.Status(http.StatusOK)
+	})
+	// The request takes one request from the budget, and then each operation it runs
+	assert.Equal(t, http.StatusOK, do(router, "10.0.0.1", "").Code)
+	assert.Equal(t, []bool{true, true, false}, allowed)
+
+	// Without the rate limiter, everything is allowed
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Nil(t, Allow(c))
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	router := Setup(NewRateLimiter(20, 1))
+	assert.Equal(t, http.StatusOK, do(router, "10.0.0.1", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do(router, "10.0.0.1", "").Code)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, do(router, "10.0.0.1", "").Code)
+}
+
+func TestRateLimiter_ForgetIdleClients(t *testing.T) {
+	rl := NewRateLimiter(1, 1)
+	rl.limiter("a")
+	rl.clients["a"].lastSeen = time.Now().Add(-2 * idleTimeout)
+	rl.lastCleanup = time.Now().Add(-2 * idleTimeout)
+	rl.limiter("b")
+	_, exists := rl.clients["a"]
+	assert.False(t, exists)
+	assert.Len(t, rl.clients, 1)
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	router := Setup(NewRateLimiter(0, 0))
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, do(router, "10.0.0.1", "").Code)
+	}
+}
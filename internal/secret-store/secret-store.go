@@ -0,0 +1,90 @@
+package secret_store
+
+import (
+	"context"
+	"github.com/dapr/go-sdk/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"reflect"
+	"time"
+	"video-manager/internal/logger"
+	"video-manager/internal/tracing"
+)
+
+var (
+	log = logger.Build()
+)
+
+// SecretStore any vault-like storage solution, holding secrets as key/value maps
+type SecretStore[T SecretProxy] struct {
+	// Name of the Dapr component to use
+	componentName string
+	// Client to query the backend store
+	client *T
+}
+
+// NewDaprSecretStore Prod ready constructor for a secret store using Dapr
+func NewDaprSecretStore(daprClient *client.Client, component string) *SecretStore[client.Client] {
+	return &SecretStore[client.Client]{
+		componentName: component,
+		client:        daprClient,
+	}
+}
+
+// NewSecretStore General purpose secret store
+func NewSecretStore[T SecretProxy](component string, client T) *SecretStore[T] {
+	return &SecretStore[T]{
+		componentName: component,
+		client:        &client,
+	}
+}
+
+// SecretProxy Proxy to query the backend store
+type SecretProxy interface {
+	GetSecret(ctx context.Context, storeName, key string, meta map[string]string) (data map[string]string, err error)
+}
+
+// Get Retrieve the secret "name". A secret can hold multiple values, depending on the backend store
+func (ss SecretStore[T]) Get(ctx context.Context, name string) (map[string]string, error) {
+	ctx, span := tracing.Start(ctx, "secret-store get", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("dapr.secret_store", ss.componentName),
+			attribute.String("secret_store.secret", name),
+		))
+	secret, err := (*ss.client).GetSecret(ctx, ss.componentName, name, nil)
+	tracing.End(span, err)
+	return secret, err
+}
+
+// Source Anything secrets can be read from
+type Source interface {
+	Get(ctx context.Context, name string) (map[string]string, error)
+}
+
+// Watch Retrieve the secret "name" from source every interval, calling onChange each time its value differs from
+// the previous one. initial is the value already known, onChange isn't called as long as the secret keeps this value.
+// A failed retrieval keeps the previous value. Blocks until ctx is done
+func Watch(ctx context.Context, source Source, name string, initial map[string]string, interval time.Duration, onChange func(secret map[string]string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	current := initial
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		secret, err := source.Get(ctx, name)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warnf(`Could not refresh secret "%s", keeping the previous value : %s`, name, err.Error())
+			}
+			continue
+		}
+		if reflect.DeepEqual(secret, current) {
+			continue
+		}
+		current = secret
+		onChange(secret)
+	}
+}
@@ -0,0 +1,72 @@
+package secret_store
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	mock_client "video-manager/internal/mock/dapr"
+)
+
+func TestSecretStore_Get(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	daprClient.EXPECT().GetSecret(gomock.Any(), "vault", "youtube", gomock.Any()).
+		Return(map[string]string{"refreshToken": "token"}, nil)
+	ss := NewSecretStore[*mock_client.MockClient]("vault", daprClient)
+
+	secret, err := ss.Get(context.Background(), "youtube")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"refreshToken": "token"}, secret)
+}
+
+func TestSecretStore_Get_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	daprClient.EXPECT().GetSecret(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("secret not found"))
+	ss := NewSecretStore[*mock_client.MockClient]("vault", daprClient)
+
+	_, err := ss.Get(context.Background(), "youtube")
+	assert.NotNil(t, err)
+}
+
+func TestWatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	// Unchanged, then unavailable, then rotated. The last value is kept afterwards
+	gomock.InOrder(
+		daprClient.EXPECT().GetSecret(gomock.Any(), "vault", "youtube", gomock.Any()).
+			Return(map[string]string{"refreshToken": "old"}, nil),
+		daprClient.EXPECT().GetSecret(gomock.Any(), "vault", "youtube", gomock.Any()).
+			Return(nil, fmt.Errorf("store unavailable")),
+		daprClient.EXPECT().GetSecret(gomock.Any(), "vault", "youtube", gomock.Any()).
+			Return(map[string]string{"refreshToken": "new"}, nil),
+		daprClient.EXPECT().GetSecret(gomock.Any(), "vault", "youtube", gomock.Any()).
+			Return(map[string]string{"refreshToken": "new"}, nil).AnyTimes(),
+	)
+	ss := NewSecretStore[*mock_client.MockClient]("vault", daprClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan map[string]string, 10)
+	done := make(chan struct{})
+	go func() {
+		Watch(ctx, ss, "youtube", map[string]string{"refreshToken": "old"}, time.Millisecond, func(secret map[string]string) {
+			changes <- secret
+		})
+		close(done)
+	}()
+
+	select {
+	case secret := <-changes:
+		assert.Equal(t, map[string]string{"refreshToken": "new"}, secret)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the rotated secret was never reported")
+	}
+	cancel()
+	<-done
+	// The rotated value is only reported once
+	assert.Empty(t, changes)
+}
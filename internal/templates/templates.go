@@ -0,0 +1,154 @@
+package templates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dapr/go-sdk/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"text/template"
+	"unicode/utf8"
+	"video-manager/internal/tracing"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// Prefix of the state keys holding the templates
+const keyPrefix = "template-"
+
+// Template Patterns of the title and description of a video, as Go text/template.
+// Both are rendered with the same variables, ie "Session {{.Number}} – {{.Campaign}}"
+type Template struct {
+	Title       string `yaml:"title" toml:"title" json:"title" example:"Session {{.Number}} – {{.Campaign}}"`
+	Description string `yaml:"description" toml:"description" json:"description"`
+}
+
+// Rendered Title and description of a video, obtained from a template
+type Rendered struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// Compiled A parsed template, ready to be rendered
+type Compiled struct {
+	title       *template.Template
+	description *template.Template
+}
+
+// Compile Parse both patterns of t. A variable missing when rendering is an error, not an empty string
+func (t Template) Compile(name string) (*Compiled, error) {
+	title, err := template.New(name + ".title").Option("missingkey=error").Parse(t.Title)
+	if err != nil {
+		return nil, fmt.Errorf("invalid title : %w", err)
+	}
+	description, err := template.New(name + ".description").Option("missingkey=error").Parse(t.Description)
+	if err != nil {
+		return nil, fmt.Errorf("invalid description : %w", err)
+	}
+	return &Compiled{title: title, description: description}, nil
+}
+
+// Render Fill the template with vars, and check the result fits in the metadata of a video
+func (c *Compiled) Render(vars map[string]any) (*Rendered, error) {
+	var title, description bytes.Buffer
+	if err := c.title.Execute(&title, vars); err != nil {
+		return nil, fmt.Errorf("could not render the title : %w", err)
+	}
+	if err := c.description.Execute(&description, vars); err != nil {
+		return nil, fmt.Errorf("could not render the description : %w", err)
+	}
+	rendered := &Rendered{Title: title.String(), Description: description.String()}
+	return rendered, rendered.Validate()
+}
+
+// Validate Check the rendered metadata stay within the limits of video_hosting.ItemMetadata.
+// The hosting platform may be stricter, see video_hosting.MetadataValidator
+func (r *Rendered) Validate() error {
+	switch {
+	case r.Title == "":
+		return fmt.Errorf("the rendered title is empty")
+	case utf8.RuneCountInString(r.Title) > video_hosting.MaxTitleLength:
+		return fmt.Errorf("the rendered title is %d characters long, at most %d are allowed", utf8.RuneCountInString(r.Title), video_hosting.MaxTitleLength)
+	// Hosts count the description in bytes, not characters
+	case len(r.Description) > video_hosting.MaxDescriptionLength:
+		return fmt.Errorf("the rendered description is %d bytes long, at most %d are allowed", len(r.Description), video_hosting.MaxDescriptionLength)
+	}
+	return nil
+}
+
+// Templates Named metadata templates, defined in the configuration or stored in a state store
+type Templates[T StateProxy] struct {
+	// Templates of the configuration, taking precedence over the stored ones
+	defined map[string]Template
+	// Name of the Dapr component to use. Only the defined templates are available if empty
+	componentName string
+	// Client to query the backend store
+	client *T
+}
+
+// NewDaprTemplates Prod ready constructor for templates also stored in a Dapr state store, if component isn't empty
+func NewDaprTemplates(defined map[string]Template, daprClient *client.Client, component string) *Templates[client.Client] {
+	return &Templates[client.Client]{
+		defined:       defined,
+		componentName: component,
+		client:        daprClient,
+	}
+}
+
+// NewTemplates General purpose templates
+func NewTemplates[T StateProxy](defined map[string]Template, component string, client T) *Templates[T] {
+	return &Templates[T]{
+		defined:       defined,
+		componentName: component,
+		client:        &client,
+	}
+}
+
+// StateProxy Proxy to query the backend store
+type StateProxy interface {
+	GetState(ctx context.Context, storeName, key string, meta map[string]string) (item *client.StateItem, err error)
+}
+
+// Get The template called name. Fails with a NotFound error if there is none
+func (ts *Templates[T]) Get(ctx context.Context, name string) (tpl *Template, err error) {
+	if t, ok := ts.defined[name]; ok {
+		return &t, nil
+	}
+	if ts.componentName != "" {
+		ctx, span := tracing.Start(ctx, "templates get", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("dapr.state_store", ts.componentName)))
+		defer func() { tracing.End(span, err) }()
+		var item *client.StateItem
+		item, err = (*ts.client).GetState(ctx, ts.componentName, keyPrefix+name, nil)
+		if err != nil {
+			return nil, fmt.Errorf(`could not read template "%s" : %w`, name, err)
+		}
+		if item != nil && len(item.Value) > 0 {
+			var stored Template
+			if err = json.Unmarshal(item.Value, &stored); err != nil {
+				return nil, fmt.Errorf(`invalid template "%s" : %w`, name, err)
+			}
+			return &stored, nil
+		}
+	}
+	return nil, video_hosting.NewRequestError(video_hosting.NotFound, fmt.Errorf(`no template named "%s"`, name))
+}
+
+// Render Fill the template called name with vars.
+// The result must fit in the metadata of a video, any failure to render it is an InvalidMetadata error
+func (ts *Templates[T]) Render(ctx context.Context, name string, vars map[string]any) (*Rendered, error) {
+	tpl, err := ts.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := tpl.Compile(name)
+	if err != nil {
+		return nil, video_hosting.NewRequestError(video_hosting.InvalidMetadata, fmt.Errorf(`template "%s" : %w`, name, err))
+	}
+	rendered, err := compiled.Render(vars)
+	if err != nil {
+		return nil, video_hosting.NewRequestError(video_hosting.InvalidMetadata, fmt.Errorf(`template "%s" : %w`, name, err))
+	}
+	return rendered, nil
+}
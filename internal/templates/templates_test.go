@@ -0,0 +1,118 @@
+package templates
+
+import (
+	"context"
+	"fmt"
+	"github.com/dapr/go-sdk/client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	mock_client "video-manager/internal/mock/dapr"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+func TestTemplate_Render(t *testing.T) {
+	compiled, err := Template{Title: "Session {{.Number}} – {{.Campaign}}", Description: "{{.Summary}}"}.Compile("session")
+	assert.Nil(t, err)
+	rendered, err := compiled.Render(map[string]any{"Number": 12, "Campaign": "Dragons", "Summary": "The party meets a dragon"})
+	assert.Nil(t, err)
+	assert.Equal(t, &Rendered{Title: "Session 12 – Dragons", Description: "The party meets a dragon"}, rendered)
+}
+
+func TestTemplate_Compile_Invalid(t *testing.T) {
+	_, err := Template{Title: "Session {{.Number"}.Compile("session")
+	assert.NotNil(t, err)
+	_, err = Template{Title: "Session", Description: "{{if}}"}.Compile("session")
+	assert.NotNil(t, err)
+}
+
+func TestTemplate_Render_MissingVariable(t *testing.T) {
+	compiled, err := Template{Title: "Session {{.Number}}"}.Compile("session")
+	assert.Nil(t, err)
+	_, err = compiled.Render(map[string]any{})
+	assert.NotNil(t, err)
+}
+
+func TestRendered_Validate(t *testing.T) {
+	cases := []struct {
+		name     string
+		rendered Rendered
+		valid    bool
+	}{
+		{"valid", Rendered{Title: "Session 1", Description: "Summary"}, true},
+		{"empty title", Rendered{Description: "Summary"}, false},
+		// The title is limited in characters, not bytes
+		{"multibyte title", Rendered{Title: strings.Repeat("é", video_hosting.MaxTitleLength)}, true},
+		{"title too long", Rendered{Title: strings.Repeat("a", video_hosting.MaxTitleLength+1)}, false},
+		{"description too long", Rendered{Title: "a", Description: strings.Repeat("a", video_hosting.MaxDescriptionLength+1)}, false},
+		// Counted in bytes, not characters
+		{"multibyte description", Rendered{Title: "a", Description: strings.Repeat("🎲", video_hosting.MaxDescriptionLength/4)}, true},
+		{"multibyte description too long", Rendered{Title: "a", Description: strings.Repeat("é", video_hosting.MaxDescriptionLength/2+1)}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rendered.Validate()
+			assert.Equal(t, tc.valid, err == nil, err)
+		})
+	}
+}
+
+func TestTemplates_Render_Defined(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	// The defined templates take precedence, the store is never queried
+	daprClient := mock_client.NewMockClient(ctrl)
+	ts := NewTemplates[*mock_client.MockClient](map[string]Template{"session": {Title: "Session {{.Number}}"}}, "statestore", daprClient)
+
+	rendered, err := ts.Render(context.Background(), "session", map[string]any{"Number": 3})
+	assert.Nil(t, err)
+	assert.Equal(t, "Session 3", rendered.Title)
+}
+
+func TestTemplates_Render_Stored(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	daprClient.EXPECT().GetState(gomock.Any(), "statestore", "template-session", gomock.Any()).
+		Return(&client.StateItem{Key: "template-session", Value: []byte(`{"title":"Session {{.Number}}","description":"{{.Summary}}"}`)}, nil)
+	ts := NewTemplates[*mock_client.MockClient](nil, "statestore", daprClient)
+
+	rendered, err := ts.Render(context.Background(), "session", map[string]any{"Number": 3, "Summary": "Summary"})
+	assert.Nil(t, err)
+	assert.Equal(t, &Rendered{Title: "Session 3", Description: "Summary"}, rendered)
+}
+
+func TestTemplates_Render_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	// Dapr returns an empty item for a missing key
+	daprClient.EXPECT().GetState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&client.StateItem{Key: "template-none"}, nil)
+	ts := NewTemplates[*mock_client.MockClient](nil, "statestore", daprClient)
+
+	_, err := ts.Render(context.Background(), "none", nil)
+	assert.Equal(t, video_hosting.NotFound, video_hosting.KindOf(err))
+
+	// Without a store, only the defined templates exist
+	_, err = NewTemplates[*mock_client.MockClient](nil, "", nil).Render(context.Background(), "none", nil)
+	assert.Equal(t, video_hosting.NotFound, video_hosting.KindOf(err))
+}
+
+func TestTemplates_Render_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	daprClient := mock_client.NewMockClient(ctrl)
+	gomock.InOrder(
+		daprClient.EXPECT().GetState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&client.StateItem{Value: []byte(`{"title":"{{.Number"}`)}, nil),
+		daprClient.EXPECT().GetState(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("state store unavailable")),
+	)
+	ts := NewTemplates[*mock_client.MockClient](map[string]Template{"session": {Title: "Session {{.Number}}"}}, "statestore", daprClient)
+
+	// Missing variable
+	_, err := ts.Render(context.Background(), "session", nil)
+	assert.Equal(t, video_hosting.InvalidMetadata, video_hosting.KindOf(err))
+	// Stored template that doesn't compile
+	_, err = ts.Render(context.Background(), "stored", nil)
+	assert.Equal(t, video_hosting.InvalidMetadata, video_hosting.KindOf(err))
+	_, err = ts.Render(context.Background(), "stored", nil)
+	assert.NotNil(t, err)
+}
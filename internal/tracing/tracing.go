@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"os"
+)
+
+// Name of the instrumentation library, as reported in each span
+const instrumentationName = "video-manager"
+
+// Exporter Where the spans are sent
+type Exporter string
+
+const (
+	// None Spans aren't recorded at all, but the trace context is still propagated
+	None Exporter = "none"
+	// Stdout Spans are printed on the standard output, for debugging purposes
+	Stdout Exporter = "stdout"
+	// Otlp Spans are sent to an OpenTelemetry collector using OTLP over gRPC.
+	// The collector is configured with the standard OTEL_EXPORTER_OTLP_* environment variables
+	Otlp Exporter = "otlp"
+)
+
+// Options Tracing configuration
+type Options struct {
+	Exporter Exporter
+	// Name of this service in the traces
+	ServiceName string
+	// Destination of the Stdout exporter. Defaults to os.Stdout
+	Writer io.Writer
+}
+
+// Setup Install the global tracer provider and the W3C trace context propagator.
+// The returned function flushes the remaining spans and must be called before exiting
+func Setup(ctx context.Context, opt Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opt.Exporter {
+	case None, "":
+		return func(context.Context) error { return nil }, nil
+	case Stdout:
+		w := opt.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case Otlp:
+		exporter, err = otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf(`unknown traces exporter "%s", expected one of "%s", "%s" or "%s"`, opt.Exporter, None, Stdout, Otlp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opt.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start a new span named "name", child of the span in ctx if any
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End the span, marking it as failed if err isn't nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Detach Returns a context carrying the span of ctx, but neither its deadline nor its cancellation.
+// Long-running jobs started by a request must outlive it, while still being part of its trace
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+}
+
+// Carrier Returns the trace context of ctx, as W3C "traceparent" and "tracestate" entries.
+// Empty if ctx isn't part of any trace
+func Carrier(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
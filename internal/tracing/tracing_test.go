@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func TestSetup_Stdout(t *testing.T) {
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Options{Exporter: Stdout, ServiceName: "test", Writer: &out})
+	assert.Nil(t, err)
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	_, span := Start(context.Background(), "failing")
+	End(span, fmt.Errorf("boom"))
+	// Flush the spans
+	assert.Nil(t, shutdown(context.Background()))
+	assert.Contains(t, out.String(), `"Name":"failing"`)
+	assert.Contains(t, out.String(), "boom")
+}
+
+func TestSetup_None(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{Exporter: None})
+	assert.Nil(t, err)
+	assert.Nil(t, shutdown(context.Background()))
+	shutdown, err = Setup(context.Background(), Options{})
+	assert.Nil(t, err)
+	assert.Nil(t, shutdown(context.Background()))
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Options{Exporter: "zipkin"})
+	assert.NotNil(t, err)
+}
+
+// A context carrying a remote span, as it would be after extracting the trace context of a request
+func remoteContext(t *testing.T) context.Context {
+	traceId, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spanId, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+}
+
+func TestCarrier(t *testing.T) {
+	_, err := Setup(context.Background(), Options{Exporter: None})
+	assert.Nil(t, err)
+	carrier := Carrier(remoteContext(t))
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", carrier["traceparent"])
+
+	// No trace, nothing to propagate
+	assert.Empty(t, Carrier(context.Background())["traceparent"])
+}
+
+func TestDetach(t *testing.T) {
+	ctx, cancel := context.WithCancel(remoteContext(t))
+	detached := Detach(ctx)
+	cancel()
+	assert.NotNil(t, ctx.Err())
+	// The detached context outlives the original one...
+	assert.Nil(t, detached.Err())
+	// ... but is still part of the same trace
+	assert.Equal(t, trace.SpanContextFromContext(ctx).TraceID(), trace.SpanContextFromContext(detached).TraceID())
+}
@@ -0,0 +1,159 @@
+package video_hosting
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"strconv"
+	"time"
+	"video-manager/internal/metrics"
+	"video-manager/internal/tracing"
+)
+
+// InstrumentedHost An IVideoHost recording the count, status and latency of each call made to another IVideoHost,
+// and tracing each of them
+type InstrumentedHost struct {
+	host IVideoHost
+}
+
+// NewInstrumentedHost Record metrics and spans for all calls made to host
+func NewInstrumentedHost(host IVideoHost) *InstrumentedHost {
+	return &InstrumentedHost{host: host}
+}
+
+// Start recording a single call to the host. The returned function must be called with the call result
+func startCall(ctx context.Context, method string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "video-host "+method, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("video_host.method", method)))
+	return ctx, func(err error) {
+		code := callCode(err)
+		metrics.HostCallDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		metrics.HostCalls.WithLabelValues(method, code).Inc()
+		span.SetAttributes(attribute.String("video_host.code", code))
+		tracing.End(span, err)
+	}
+}
+
+// Status code of a call. Errors that didn't come from the host have no status code
+func callCode(err error) string {
+	if err == nil {
+		return metrics.Ok
+	}
+	var re *RequestError
+	if errors.As(err, &re) && re.StatusCode != 0 {
+		return strconv.Itoa(re.StatusCode)
+	}
+	return metrics.Error
+}
+
+func (ih *InstrumentedHost) CreateVideo(ctx context.Context, meta *ItemMetadata, uploadContent io.Reader, onProgress *ProgressFunc) (*Video, error) {
+	ctx, done := startCall(ctx, "CreateVideo")
+	vid, err := ih.host.CreateVideo(ctx, meta, uploadContent, onProgress)
+	done(err)
+	return vid, err
+}
+
+func (ih *InstrumentedHost) RetrieveVideo(ctx context.Context, id string) (*Video, error) {
+	ctx, done := startCall(ctx, "RetrieveVideo")
+	vid, err := ih.host.RetrieveVideo(ctx, id)
+	done(err)
+	return vid, err
+}
+
+func (ih *InstrumentedHost) UpdateVideo(ctx context.Context, id string, replacement *Video) (*Video, error) {
+	ctx, done := startCall(ctx, "UpdateVideo")
+	vid, err := ih.host.UpdateVideo(ctx, id, replacement)
+	done(err)
+	return vid, err
+}
+
+func (ih *InstrumentedHost) DeleteVideo(ctx context.Context, id string) error {
+	ctx, done := startCall(ctx, "DeleteVideo")
+	err := ih.host.DeleteVideo(ctx, id)
+	done(err)
+	return err
+}
+
+func (ih *InstrumentedHost) GetVideoAccessPrefix() string {
+	return ih.host.GetVideoAccessPrefix()
+}
+
+func (ih *InstrumentedHost) CreatePlaylist(ctx context.Context, meta *ItemMetadata) (*Playlist, error) {
+	ctx, done := startCall(ctx, "CreatePlaylist")
+	playlist, err := ih.host.CreatePlaylist(ctx, meta)
+	done(err)
+	return playlist, err
+}
+
+func (ih *InstrumentedHost) RetrievePlaylist(ctx context.Context, id string) (*Playlist, error) {
+	ctx, done := startCall(ctx, "RetrievePlaylist")
+	playlist, err := ih.host.RetrievePlaylist(ctx, id)
+	done(err)
+	return playlist, err
+}
+
+func (ih *InstrumentedHost) UpdatePlaylist(ctx context.Context, id string, replacement *Playlist) (*Playlist, error) {
+	ctx, done := startCall(ctx, "UpdatePlaylist")
+	playlist, err := ih.host.UpdatePlaylist(ctx, id, replacement)
+	done(err)
+	return playlist, err
+}
+
+func (ih *InstrumentedHost) DeletePlaylist(ctx context.Context, id string) error {
+	ctx, done := startCall(ctx, "DeletePlaylist")
+	err := ih.host.DeletePlaylist(ctx, id)
+	done(err)
+	return err
+}
+
+func (ih *InstrumentedHost) AddVideoToPlaylist(ctx context.Context, videoId string, playlistId string) error {
+	ctx, done := startCall(ctx, "AddVideoToPlaylist")
+	err := ih.host.AddVideoToPlaylist(ctx, videoId, playlistId)
+	done(err)
+	return err
+}
+
+func (ih *InstrumentedHost) UpdateVideoThumbnail(ctx context.Context, videoId string, thumbnailContent io.Reader) error {
+	ctx, done := startCall(ctx, "UpdateVideoThumbnail")
+	err := ih.host.UpdateVideoThumbnail(ctx, videoId, thumbnailContent)
+	done(err)
+	return err
+}
+
+func (ih *InstrumentedHost) ListVideos(ctx context.Context, ids []string) ([]*Video, error) {
+	ctx, done := startCall(ctx, "ListVideos")
+	videos, err := ih.host.ListVideos(ctx, ids)
+	done(err)
+	return videos, err
+}
+
+func (ih *InstrumentedHost) ListPlaylists(ctx context.Context, ids []string) ([]*Playlist, error) {
+	ctx, done := startCall(ctx, "ListPlaylists")
+	playlists, err := ih.host.ListPlaylists(ctx, ids)
+	done(err)
+	return playlists, err
+}
+
+func (ih *InstrumentedHost) SetVideoTags(ctx context.Context, videoId string, tags []string) error {
+	ctx, done := startCall(ctx, "SetVideoTags")
+	err := ih.host.SetVideoTags(ctx, videoId, tags)
+	done(err)
+	return err
+}
+
+func (ih *InstrumentedHost) ScheduleVideo(ctx context.Context, videoId string, publishAt time.Time) error {
+	ctx, done := startCall(ctx, "ScheduleVideo")
+	err := ih.host.ScheduleVideo(ctx, videoId, publishAt)
+	done(err)
+	return err
+}
+
+func (ih *InstrumentedHost) AddVideoCaption(ctx context.Context, videoId string, caption *Caption, content io.Reader) error {
+	ctx, done := startCall(ctx, "AddVideoCaption")
+	err := ih.host.AddVideoCaption(ctx, videoId, caption, content)
+	done(err)
+	return err
+}
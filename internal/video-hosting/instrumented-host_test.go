@@ -0,0 +1,42 @@
+package video_hosting
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"video-manager/internal/metrics"
+)
+
+func TestInstrumentedHost_CountsCalls(t *testing.T) {
+	host := &fakeHost{}
+	ih := NewInstrumentedHost(host)
+	ok := testutil.ToFloat64(metrics.HostCalls.WithLabelValues("CreateVideo", metrics.Ok))
+
+	_, err := ih.CreateVideo(context.Background(), &ItemMetadata{}, bytes.NewBuffer(nil), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, host.calls)
+	assert.Equal(t, ok+1, testutil.ToFloat64(metrics.HostCalls.WithLabelValues("CreateVideo", metrics.Ok)))
+}
+
+func TestInstrumentedHost_ErrorCodes(t *testing.T) {
+	host := &fakeHost{err: NewRequestError(NotFound, fmt.Errorf("no video"))}
+	ih := NewInstrumentedHost(host)
+	notFound := testutil.ToFloat64(metrics.HostCalls.WithLabelValues("RetrieveVideo", "404"))
+	_, err := ih.RetrieveVideo(context.Background(), "1")
+	assert.NotNil(t, err)
+	assert.Equal(t, notFound+1, testutil.ToFloat64(metrics.HostCalls.WithLabelValues("RetrieveVideo", "404")))
+
+	// Errors without any status code
+	host.err = fmt.Errorf("unknown")
+	unknown := testutil.ToFloat64(metrics.HostCalls.WithLabelValues("DeleteVideo", metrics.Error))
+	assert.NotNil(t, ih.DeleteVideo(context.Background(), "1"))
+	assert.Equal(t, unknown+1, testutil.ToFloat64(metrics.HostCalls.WithLabelValues("DeleteVideo", metrics.Error)))
+}
+
+func TestInstrumentedHost_Passthrough(t *testing.T) {
+	ih := NewInstrumentedHost(&fakeHost{})
+	assert.Equal(t, "prefix", ih.GetVideoAccessPrefix())
+}
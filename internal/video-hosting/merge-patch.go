@@ -0,0 +1,112 @@
+package video_hosting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MergePatchContentType Media type of a merge patch
+// https://www.rfc-editor.org/rfc/rfc7396
+const MergePatchContentType = "application/merge-patch+json"
+
+// MergePatch An RFC 7396 merge patch of a T, either a Video or a Playlist.
+// Only the fields tagged as updatable (validate:"updatable") can be patched. These are all plain values, so each
+// patched field is replaced as a whole, and reset to its zero value when patched with null
+type MergePatch[T any] map[string]json.RawMessage
+
+// ParseMergePatch Decode an RFC 7396 merge patch of a T.
+// Patching a read-only or unknown field, or with a value of the wrong type, is an InvalidMetadata error listing each refused field
+func ParseMergePatch[T any](data []byte) (MergePatch[T], error) {
+	var patch MergePatch[T]
+	if err := json.Unmarshal(data, &patch); err != nil || patch == nil {
+		return nil, NewRequestError(InvalidMetadata, fmt.Errorf("a merge patch must be a JSON object"))
+	}
+	fields := jsonFields[T]()
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var refused []FieldError
+	for _, key := range keys {
+		field, ok := fields[key]
+		switch {
+		case !ok:
+			refused = append(refused, FieldError{Field: key, Reason: "is unknown"})
+		case !isUpdatable(field):
+			refused = append(refused, FieldError{Field: key, Reason: "is read-only"})
+		case !isNull(patch[key]) && json.Unmarshal(patch[key], reflect.New(field.Type).Interface()) != nil:
+			refused = append(refused, FieldError{Field: key, Reason: fmt.Sprintf("must be a %s", jsonType(field.Type))})
+		}
+	}
+	if err := NewValidationError(refused); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// Apply Returns a copy of item with the patch applied, item itself is left untouched
+func (p MergePatch[T]) Apply(item *T) (*T, error) {
+	patched := *item
+	value := reflect.ValueOf(&patched).Elem()
+	fields := jsonFields[T]()
+	for key, raw := range p {
+		field := value.FieldByIndex(fields[key].Index)
+		if isNull(raw) {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		if err := json.Unmarshal(raw, field.Addr().Interface()); err != nil {
+			return nil, NewRequestError(InvalidMetadata, fmt.Errorf(`invalid value for "%s" : %w`, key, err))
+		}
+	}
+	return &patched, nil
+}
+
+// The fields of T, by JSON name
+func jsonFields[T any]() map[string]reflect.StructField {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for _, field := range reflect.VisibleFields(t) {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name != "" && name != "-" && field.IsExported() {
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+// Whether field is tagged as updatable
+func isUpdatable(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == "updatable" {
+			return true
+		}
+	}
+	return false
+}
+
+func isNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// Name of the JSON type a value of t is encoded as
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
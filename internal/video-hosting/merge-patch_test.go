@@ -0,0 +1,44 @@
+package video_hosting
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMergePatch_Apply(t *testing.T) {
+	vid := &Video{Id: "vid", Title: "Session 1", Description: "The first session", Visibility: Private, CreatedAt: time.Unix(1662202180, 0).UTC(), Duration: 3600}
+	patch, err := ParseMergePatch[Video]([]byte(`{"title": "Session 2", "visibility": "public"}`))
+	assert.Nil(t, err)
+	patched, err := patch.Apply(vid)
+	assert.Nil(t, err)
+	// Only the patched fields change, the read-only ones are kept as is
+	assert.Equal(t, &Video{Id: "vid", Title: "Session 2", Description: "The first session", Visibility: Public, CreatedAt: vid.CreatedAt, Duration: 3600}, patched)
+	// The original isn't modified
+	assert.Equal(t, "Session 1", vid.Title)
+}
+
+func TestMergePatch_Apply_Null(t *testing.T) {
+	// Null removes the value
+	patch, err := ParseMergePatch[Playlist]([]byte(`{"description": null}`))
+	assert.Nil(t, err)
+	patched, err := patch.Apply(&Playlist{Id: "pl", Title: "Campaign", Description: "All the sessions"})
+	assert.Nil(t, err)
+	assert.Equal(t, &Playlist{Id: "pl", Title: "Campaign"}, patched)
+}
+
+func TestParseMergePatch_Refused(t *testing.T) {
+	_, err := ParseMergePatch[Video]([]byte(`{"id": "other", "createdAt": "2020-01-01T00:00:00Z", "tittle": "typo", "title": 12, "description": "ok"}`))
+	assert.Equal(t, InvalidMetadata, KindOf(err))
+	assert.Equal(t, []FieldError{
+		{Field: "createdAt", Reason: "is read-only"},
+		{Field: "id", Reason: "is read-only"},
+		{Field: "title", Reason: "must be a string"},
+		{Field: "tittle", Reason: "is unknown"},
+	}, FieldErrors(err))
+
+	for _, invalid := range []string{``, `[]`, `"title"`, `null`, `{"title": }`} {
+		_, err = ParseMergePatch[Video]([]byte(invalid))
+		assert.Equal(t, InvalidMetadata, KindOf(err), invalid)
+	}
+}
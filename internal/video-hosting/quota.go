@@ -0,0 +1,316 @@
+package video_hosting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/api/googleapi"
+	"io"
+	"sync"
+	"time"
+	// Embed the timezone database, the quota reset time is computed in the Pacific timezone
+	_ "time/tzdata"
+)
+
+// QuotaCosts Number of quota units consumed by each operation of a video host
+type QuotaCosts struct {
+	CreateVideo          int64
+	RetrieveVideo        int64
+	UpdateVideo          int64
+	DeleteVideo          int64
+	CreatePlaylist       int64
+	RetrievePlaylist     int64
+	UpdatePlaylist       int64
+	DeletePlaylist       int64
+	AddVideoToPlaylist   int64
+	UpdateVideoThumbnail int64
+	ListVideos           int64
+	ListPlaylists        int64
+	SetVideoTags         int64
+	ScheduleVideo        int64
+	AddVideoCaption      int64
+}
+
+var (
+	// YoutubeQuotaCosts Cost of each operation on the Youtube Data API, including the additional calls
+	// YoutubeVideoStore makes (CreateVideo and the updates are followed/preceded by a "list" call,
+	// UpdateVideo by both)
+	// https://developers.google.com/youtube/v3/determine_quota_cost
+	YoutubeQuotaCosts = QuotaCosts{
+		CreateVideo:          1600 + 1,
+		RetrieveVideo:        1,
+		UpdateVideo:          50 + 2,
+		DeleteVideo:          50,
+		CreatePlaylist:       50,
+		RetrievePlaylist:     1,
+		UpdatePlaylist:       50 + 1,
+		DeletePlaylist:       50,
+		AddVideoToPlaylist:   50,
+		UpdateVideoThumbnail: 50,
+		ListVideos:           1,
+		ListPlaylists:        1,
+		SetVideoTags:         50 + 1,
+		ScheduleVideo:        50 + 1,
+		AddVideoCaption:      400,
+	}
+)
+
+const (
+	// YoutubeDefaultDailyQuota Daily quota granted to any new Google project
+	YoutubeDefaultDailyQuota = 10000
+	// Timezone in which the Youtube quota is reset at midnight
+	youtubeQuotaTimezone = "America/Los_Angeles"
+)
+
+// ErrQuotaExceeded The operation would exceed the daily quota of the host
+var ErrQuotaExceeded = errors.New("daily quota exceeded")
+
+// QuotaStatus Current consumption of the daily quota
+type QuotaStatus struct {
+	// Total units available each day
+	Limit int64 `json:"limit"`
+	// Units consumed since the last reset
+	Used int64 `json:"used"`
+	// Units still available until the next reset
+	Remaining int64 `json:"remaining"`
+	// Time of the next reset
+	ResetAt time.Time `json:"resetAt"`
+}
+
+// QuotaGuard An IVideoHost keeping track of the daily quota consumption of another IVideoHost,
+// rejecting any operation that would exceed it
+type QuotaGuard struct {
+	// Guarded host
+	host  IVideoHost
+	costs QuotaCosts
+	limit int64
+	// Timezone in which the quota is reset at midnight
+	location *time.Location
+	// Units consumed since the last reset
+	used    int64
+	resetAt time.Time
+	mu      sync.Mutex
+	// Clock, overridable for testing
+	now func() time.Time
+}
+
+// NewQuotaGuard Track the consumption of "limit" daily units on host, reset every day at midnight in the Pacific timezone
+func NewQuotaGuard(host IVideoHost, costs QuotaCosts, limit int64) (*QuotaGuard, error) {
+	loc, err := time.LoadLocation(youtubeQuotaTimezone)
+	if err != nil {
+		return nil, err
+	}
+	qg := &QuotaGuard{host: host, costs: costs, limit: limit, location: loc, now: time.Now}
+	qg.resetAt = qg.nextReset()
+	return qg, nil
+}
+
+// Status Returns the current consumption of the quota
+func (qg *QuotaGuard) Status() QuotaStatus {
+	qg.mu.Lock()
+	defer qg.mu.Unlock()
+	qg.resetIfNeeded()
+	return QuotaStatus{
+		Limit:     qg.limit,
+		Used:      qg.used,
+		Remaining: qg.limit - qg.used,
+		ResetAt:   qg.resetAt,
+	}
+}
+
+// Consume "cost" units, failing if the quota would be exceeded
+func (qg *QuotaGuard) consume(operation string, cost int64) error {
+	qg.mu.Lock()
+	defer qg.mu.Unlock()
+	qg.resetIfNeeded()
+	if qg.used+cost > qg.limit {
+		return NewRequestError(QuotaExceeded, fmt.Errorf("%w : %s costs %d units, %d remaining until %s",
+			ErrQuotaExceeded, operation, cost, qg.limit-qg.used, qg.resetAt.Format(time.RFC3339)))
+	}
+	qg.used += cost
+	return nil
+}
+
+// Sync the local accounting with the host. If the host tells us the quota is exhausted, there
+// is no point in sending any other request until the next reset
+func (qg *QuotaGuard) observe(err error) {
+	if err == nil || !isHostQuotaError(err) {
+		return
+	}
+	qg.mu.Lock()
+	defer qg.mu.Unlock()
+	qg.used = qg.limit
+}
+
+// Must be called with the lock held
+func (qg *QuotaGuard) resetIfNeeded() {
+	if !qg.now().Before(qg.resetAt) {
+		qg.used = 0
+		qg.resetAt = qg.nextReset()
+	}
+}
+
+// Next midnight in the quota timezone
+func (qg *QuotaGuard) nextReset() time.Time {
+	now := qg.now().In(qg.location)
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, qg.location)
+}
+
+// Returns true if err is a Youtube "quotaExceeded" error
+func isHostQuotaError(err error) bool {
+	var ge *googleapi.Error
+	if !errors.As(err, &ge) {
+		return false
+	}
+	for _, e := range ge.Errors {
+		if e.Reason == "quotaExceeded" || e.Reason == "dailyLimitExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+func (qg *QuotaGuard) CreateVideo(ctx context.Context, meta *ItemMetadata, uploadContent io.Reader, onProgress *ProgressFunc) (*Video, error) {
+	if err := qg.consume("CreateVideo", qg.costs.CreateVideo); err != nil {
+		return nil, err
+	}
+	vid, err := qg.host.CreateVideo(ctx, meta, uploadContent, onProgress)
+	qg.observe(err)
+	return vid, err
+}
+
+func (qg *QuotaGuard) RetrieveVideo(ctx context.Context, id string) (*Video, error) {
+	if err := qg.consume("RetrieveVideo", qg.costs.RetrieveVideo); err != nil {
+		return nil, err
+	}
+	vid, err := qg.host.RetrieveVideo(ctx, id)
+	qg.observe(err)
+	return vid, err
+}
+
+func (qg *QuotaGuard) UpdateVideo(ctx context.Context, id string, replacement *Video) (*Video, error) {
+	if err := qg.consume("UpdateVideo", qg.costs.UpdateVideo); err != nil {
+		return nil, err
+	}
+	vid, err := qg.host.UpdateVideo(ctx, id, replacement)
+	qg.observe(err)
+	return vid, err
+}
+
+func (qg *QuotaGuard) DeleteVideo(ctx context.Context, id string) error {
+	if err := qg.consume("DeleteVideo", qg.costs.DeleteVideo); err != nil {
+		return err
+	}
+	err := qg.host.DeleteVideo(ctx, id)
+	qg.observe(err)
+	return err
+}
+
+func (qg *QuotaGuard) GetVideoAccessPrefix() string {
+	return qg.host.GetVideoAccessPrefix()
+}
+
+func (qg *QuotaGuard) CreatePlaylist(ctx context.Context, meta *ItemMetadata) (*Playlist, error) {
+	if err := qg.consume("CreatePlaylist", qg.costs.CreatePlaylist); err != nil {
+		return nil, err
+	}
+	playlist, err := qg.host.CreatePlaylist(ctx, meta)
+	qg.observe(err)
+	return playlist, err
+}
+
+func (qg *QuotaGuard) RetrievePlaylist(ctx context.Context, id string) (*Playlist, error) {
+	if err := qg.consume("RetrievePlaylist", qg.costs.RetrievePlaylist); err != nil {
+		return nil, err
+	}
+	playlist, err := qg.host.RetrievePlaylist(ctx, id)
+	qg.observe(err)
+	return playlist, err
+}
+
+func (qg *QuotaGuard) UpdatePlaylist(ctx context.Context, id string, replacement *Playlist) (*Playlist, error) {
+	if err := qg.consume("UpdatePlaylist", qg.costs.UpdatePlaylist); err != nil {
+		return nil, err
+	}
+	playlist, err := qg.host.UpdatePlaylist(ctx, id, replacement)
+	qg.observe(err)
+	return playlist, err
+}
+
+func (qg *QuotaGuard) DeletePlaylist(ctx context.Context, id string) error {
+	if err := qg.consume("DeletePlaylist", qg.costs.DeletePlaylist); err != nil {
+		return err
+	}
+	err := qg.host.DeletePlaylist(ctx, id)
+	qg.observe(err)
+	return err
+}
+
+func (qg *QuotaGuard) AddVideoToPlaylist(ctx context.Context, videoId string, playlistId string) error {
+	if err := qg.consume("AddVideoToPlaylist", qg.costs.AddVideoToPlaylist); err != nil {
+		return err
+	}
+	err := qg.host.AddVideoToPlaylist(ctx, videoId, playlistId)
+	qg.observe(err)
+	return err
+}
+
+func (qg *QuotaGuard) UpdateVideoThumbnail(ctx context.Context, videoId string, thumbnailContent io.Reader) error {
+	if err := qg.consume("UpdateVideoThumbnail", qg.costs.UpdateVideoThumbnail); err != nil {
+		return err
+	}
+	err := qg.host.UpdateVideoThumbnail(ctx, videoId, thumbnailContent)
+	qg.observe(err)
+	return err
+}
+
+// ListVideos Listing no video doesn't call the host, and doesn't consume any unit
+func (qg *QuotaGuard) ListVideos(ctx context.Context, ids []string) ([]*Video, error) {
+	if len(ids) > 0 {
+		if err := qg.consume("ListVideos", qg.costs.ListVideos); err != nil {
+			return nil, err
+		}
+	}
+	videos, err := qg.host.ListVideos(ctx, ids)
+	qg.observe(err)
+	return videos, err
+}
+
+// ListPlaylists Listing no playlist doesn't call the host, and doesn't consume any unit
+func (qg *QuotaGuard) ListPlaylists(ctx context.Context, ids []string) ([]*Playlist, error) {
+	if len(ids) > 0 {
+		if err := qg.consume("ListPlaylists", qg.costs.ListPlaylists); err != nil {
+			return nil, err
+		}
+	}
+	playlists, err := qg.host.ListPlaylists(ctx, ids)
+	qg.observe(err)
+	return playlists, err
+}
+
+func (qg *QuotaGuard) SetVideoTags(ctx context.Context, videoId string, tags []string) error {
+	if err := qg.consume("SetVideoTags", qg.costs.SetVideoTags); err != nil {
+		return err
+	}
+	err := qg.host.SetVideoTags(ctx, videoId, tags)
+	qg.observe(err)
+	return err
+}
+
+func (qg *QuotaGuard) ScheduleVideo(ctx context.Context, videoId string, publishAt time.Time) error {
+	if err := qg.consume("ScheduleVideo", qg.costs.ScheduleVideo); err != nil {
+		return err
+	}
+	err := qg.host.ScheduleVideo(ctx, videoId, publishAt)
+	qg.observe(err)
+	return err
+}
+
+func (qg *QuotaGuard) AddVideoCaption(ctx context.Context, videoId string, caption *Caption, content io.Reader) error {
+	if err := qg.consume("AddVideoCaption", qg.costs.AddVideoCaption); err != nil {
+		return err
+	}
+	err := qg.host.AddVideoCaption(ctx, videoId, caption, content)
+	qg.observe(err)
+	return err
+}
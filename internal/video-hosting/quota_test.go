@@ -0,0 +1,167 @@
+package video_hosting
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// A video host succeeding at everything, counting the calls made
+type fakeHost struct {
+	calls int
+	err   error
+}
+
+func (f *fakeHost) CreateVideo(context.Context, *ItemMetadata, io.Reader, *ProgressFunc) (*Video, error) {
+	f.calls++
+	return &Video{}, f.err
+}
+func (f *fakeHost) RetrieveVideo(context.Context, string) (*Video, error) {
+	f.calls++
+	return &Video{}, f.err
+}
+func (f *fakeHost) UpdateVideo(context.Context, string, *Video) (*Video, error) {
+	f.calls++
+	return &Video{}, f.err
+}
+func (f *fakeHost) DeleteVideo(context.Context, string) error { f.calls++; return f.err }
+func (f *fakeHost) GetVideoAccessPrefix() string              { return "prefix" }
+func (f *fakeHost) CreatePlaylist(context.Context, *ItemMetadata) (*Playlist, error) {
+	f.calls++
+	return &Playlist{}, f.err
+}
+func (f *fakeHost) RetrievePlaylist(context.Context, string) (*Playlist, error) {
+	f.calls++
+	return &Playlist{}, f.err
+}
+func (f *fakeHost) UpdatePlaylist(context.Context, string, *Playlist) (*Playlist, error) {
+	f.calls++
+	return &Playlist{}, f.err
+}
+func (f *fakeHost) DeletePlaylist(context.Context, string) error             { f.calls++; return f.err }
+func (f *fakeHost) AddVideoToPlaylist(context.Context, string, string) error { f.calls++; return f.err }
+func (f *fakeHost) UpdateVideoThumbnail(context.Context, string, io.Reader) error {
+	f.calls++
+	return f.err
+}
+func (f *fakeHost) ListVideos(context.Context, []string) ([]*Video, error) {
+	f.calls++
+	return []*Video{}, f.err
+}
+func (f *fakeHost) ListPlaylists(context.Context, []string) ([]*Playlist, error) {
+	f.calls++
+	return []*Playlist{}, f.err
+}
+func (f *fakeHost) SetVideoTags(context.Context, string, []string) error   { f.calls++; return f.err }
+func (f *fakeHost) ScheduleVideo(context.Context, string, time.Time) error { f.calls++; return f.err }
+func (f *fakeHost) AddVideoCaption(context.Context, string, *Caption, io.Reader) error {
+	f.calls++
+	return f.err
+}
+
+func SetupQuota(t *testing.T, limit int64, now time.Time) (*QuotaGuard, *fakeHost) {
+	host := &fakeHost{}
+	qg, err := NewQuotaGuard(host, YoutubeQuotaCosts, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qg.now = func() time.Time { return now }
+	qg.resetAt = qg.nextReset()
+	return qg, host
+}
+
+func TestQuotaGuard_Consume(t *testing.T) {
+	qg, host := SetupQuota(t, YoutubeDefaultDailyQuota, time.Now())
+	_, err := qg.CreateVideo(context.Background(), &ItemMetadata{}, bytes.NewBuffer(nil), nil)
+	assert.Nil(t, err)
+	_, err = qg.RetrieveVideo(context.Background(), "1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, host.calls)
+	status := qg.Status()
+	assert.Equal(t, YoutubeQuotaCosts.CreateVideo+YoutubeQuotaCosts.RetrieveVideo, status.Used)
+	assert.Equal(t, status.Limit-status.Used, status.Remaining)
+	// Access prefix is free
+	assert.Equal(t, "prefix", qg.GetVideoAccessPrefix())
+	assert.Equal(t, status.Used, qg.Status().Used)
+}
+
+func TestQuotaGuard_List(t *testing.T) {
+	qg, host := SetupQuota(t, YoutubeDefaultDailyQuota, time.Now())
+	// A whole batch of IDs costs a single call
+	_, err := qg.ListVideos(context.Background(), []string{"1", "2", "3"})
+	assert.Nil(t, err)
+	_, err = qg.ListPlaylists(context.Background(), []string{"1", "2"})
+	assert.Nil(t, err)
+	assert.Equal(t, YoutubeQuotaCosts.ListVideos+YoutubeQuotaCosts.ListPlaylists, qg.Status().Used)
+	// Listing nothing is free
+	_, err = qg.ListVideos(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, YoutubeQuotaCosts.ListVideos+YoutubeQuotaCosts.ListPlaylists, qg.Status().Used)
+	assert.Equal(t, 3, host.calls)
+}
+
+func TestQuotaGuard_Publication(t *testing.T) {
+	qg, host := SetupQuota(t, YoutubeDefaultDailyQuota, time.Now())
+	assert.Nil(t, qg.SetVideoTags(context.Background(), "1", []string{"tag"}))
+	assert.Nil(t, qg.ScheduleVideo(context.Background(), "1", time.Now().Add(time.Hour)))
+	assert.Nil(t, qg.AddVideoCaption(context.Background(), "1", &Caption{Language: "en"}, bytes.NewBuffer(nil)))
+	assert.Equal(t, 3, host.calls)
+	assert.Equal(t, YoutubeQuotaCosts.SetVideoTags+YoutubeQuotaCosts.ScheduleVideo+YoutubeQuotaCosts.AddVideoCaption, qg.Status().Used)
+}
+
+func TestQuotaGuard_Exceeded(t *testing.T) {
+	// Enough for a single upload
+	qg, host := SetupQuota(t, YoutubeQuotaCosts.CreateVideo+10, time.Now())
+	_, err := qg.CreateVideo(context.Background(), &ItemMetadata{}, bytes.NewBuffer(nil), nil)
+	assert.Nil(t, err)
+	_, err = qg.CreateVideo(context.Background(), &ItemMetadata{}, bytes.NewBuffer(nil), nil)
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	var re *RequestError
+	assert.True(t, errors.As(err, &re))
+	assert.Equal(t, http.StatusTooManyRequests, re.StatusCode)
+	// The host must not have been called the second time
+	assert.Equal(t, 1, host.calls)
+	// Cheap operations can still go through
+	_, err = qg.RetrievePlaylist(context.Background(), "1")
+	assert.Nil(t, err)
+}
+
+func TestQuotaGuard_ResetAtPacificMidnight(t *testing.T) {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2022, 9, 3, 23, 30, 0, 0, loc)
+	qg, _ := SetupQuota(t, YoutubeDefaultDailyQuota, now)
+	assert.Equal(t, time.Date(2022, 9, 4, 0, 0, 0, 0, loc), qg.Status().ResetAt)
+	err = qg.DeleteVideo(context.Background(), "1")
+	assert.Nil(t, err)
+	assert.Equal(t, YoutubeQuotaCosts.DeleteVideo, qg.Status().Used)
+
+	// Past midnight, the consumption is reset
+	qg.now = func() time.Time { return now.Add(time.Hour) }
+	status := qg.Status()
+	assert.Equal(t, int64(0), status.Used)
+	assert.Equal(t, time.Date(2022, 9, 5, 0, 0, 0, 0, loc), status.ResetAt)
+}
+
+func TestQuotaGuard_HostQuotaError(t *testing.T) {
+	qg, host := SetupQuota(t, YoutubeDefaultDailyQuota, time.Now())
+	// The host may have been used by someone else, and tell us the quota is already exhausted
+	host.err = handleGoogleApiError(&googleapi.Error{
+		Code:   http.StatusForbidden,
+		Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}},
+	})
+	err := qg.AddVideoToPlaylist(context.Background(), "1", "2")
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(0), qg.Status().Remaining)
+	host.err = nil
+	err = qg.UpdateVideoThumbnail(context.Background(), "1", bytes.NewBuffer(nil))
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+}
@@ -1,7 +1,15 @@
 package video_hosting
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"golang.org/x/oauth2"
 	"io"
+	"net/http"
+	"strings"
 	"time"
 )
 
@@ -15,15 +23,15 @@ type IVideoHost interface {
 
 	// CreateVideo Upload a new video on the hosting platform
 	// onProgress is an optional callback, set it to null to ignore it
-	CreateVideo(meta *ItemMetadata, uploadContent io.Reader, onProgress *ProgressFunc) (*Video, error)
+	CreateVideo(ctx context.Context, meta *ItemMetadata, uploadContent io.Reader, onProgress *ProgressFunc) (*Video, error)
 	// RetrieveVideo Search an existing video given its ID.
 	// Return nil if the video with this specific ID doesn't exists
-	RetrieveVideo(id string) (*Video, error)
+	RetrieveVideo(ctx context.Context, id string) (*Video, error)
 	// UpdateVideo Update the info of the video identified by id with the infos of replacement
 	// /!\ Some attributes ("id", "creationDate", "duration") are READ-ONLY
-	UpdateVideo(id string, replacement *Video) (*Video, error)
+	UpdateVideo(ctx context.Context, id string, replacement *Video) (*Video, error)
 	// DeleteVideo Delete an existing video from the remote video hosting platform
-	DeleteVideo(id string) error
+	DeleteVideo(ctx context.Context, id string) error
 	// GetVideoAccessPrefix Returns the url prefix necessary to watch a video on the
 	// hosting platform
 	// i.e for Youtube it would be "https://www.youtube.com/watch?v="
@@ -31,23 +39,82 @@ type IVideoHost interface {
 	/* Playlist CRUD */
 
 	// CreatePlaylist Creates a playlist on the remote video hosting platform
-	CreatePlaylist(meta *ItemMetadata) (*Playlist, error)
+	CreatePlaylist(ctx context.Context, meta *ItemMetadata) (*Playlist, error)
 	// RetrievePlaylist Search an existing playlist with its ID.
 	// Return nil if the playlist with this specific ID doesn't exists
-	RetrievePlaylist(id string) (*Playlist, error)
+	RetrievePlaylist(ctx context.Context, id string) (*Playlist, error)
 	// UpdatePlaylist Update the info of the playlist identified by id with the infos of replacement
 	// /!\ Some attributes ("id", "creationDate") are READ-ONLY
-	UpdatePlaylist(id string, replacement *Playlist) (*Playlist, error)
+	UpdatePlaylist(ctx context.Context, id string, replacement *Playlist) (*Playlist, error)
 	// DeletePlaylist Delete an existing playlist from the remote video hosting platform
-	DeletePlaylist(id string) error
+	DeletePlaylist(ctx context.Context, id string) error
 
 	/* Utilities */
 
 	// AddVideoToPlaylist Add an existing video to an existing playlist on the hosting platform
 	// TODO
-	AddVideoToPlaylist(videoId string, playlistId string) error
+	AddVideoToPlaylist(ctx context.Context, videoId string, playlistId string) error
 	// UpdateVideoThumbnail Set the thumbnail for a video
-	UpdateVideoThumbnail(videoId string, thumbnailContent io.Reader) error
+	UpdateVideoThumbnail(ctx context.Context, videoId string, thumbnailContent io.Reader) error
+
+	/* Batched retrieval */
+
+	// ListVideos Search several existing videos at once, given their IDs.
+	// At most MaxListIds IDs can be requested at once, videos that don't exist are left out
+	ListVideos(ctx context.Context, ids []string) ([]*Video, error)
+	// ListPlaylists Search several existing playlists at once, given their IDs.
+	// At most MaxListIds IDs can be requested at once, playlists that don't exist are left out
+	ListPlaylists(ctx context.Context, ids []string) ([]*Playlist, error)
+
+	/* Publication */
+
+	// SetVideoTags Replace the keywords of an existing video
+	SetVideoTags(ctx context.Context, videoId string, tags []string) error
+	// ScheduleVideo Keep an existing video private until publishAt, it is then made public by the hosting platform
+	ScheduleVideo(ctx context.Context, videoId string, publishAt time.Time) error
+	// AddVideoCaption Add a caption track to an existing video
+	AddVideoCaption(ctx context.Context, videoId string, caption *Caption, content io.Reader) error
+}
+
+// MaxListIds Maximum number of IDs a single ListVideos or ListPlaylists call can request
+const MaxListIds = 50
+
+// Pinger A video hosting platform able to tell if it can currently be used
+type Pinger interface {
+	// Ping Returns an error if the hosting platform can't be reached, or the credentials are refused
+	Ping(ctx context.Context) error
+}
+
+// CredentialsRotator A video hosting platform whose credentials can be replaced while it is running
+type CredentialsRotator interface {
+	// RotateCredentials Replace the credentials with the values found in secret, missing values are kept.
+	// Returns whether the credentials changed
+	RotateCredentials(secret map[string]string) bool
+}
+
+// Authorizer A video hosting platform whose account can be granted access through an OAuth consent
+type Authorizer interface {
+	// OAuthConfig The OAuth configuration to obtain a refresh token with, from the current client credentials
+	OAuthConfig(redirectUrl string) *oauth2.Config
+	// DeviceOAuthConfig Same as OAuthConfig, for the device flow
+	DeviceOAuthConfig() *oauth2.Config
+}
+
+// AuthWatcher A video hosting platform able to tell when its credentials were revoked or expired
+type AuthWatcher interface {
+	// OnAuthExpired Call fn once each time the credentials stop being accepted, until they are accepted again
+	OnAuthExpired(fn func(err error))
+}
+
+// MetadataValidator A video hosting platform with limits of its own on the metadata of the items.
+// Each method returns an InvalidMetadata error listing every refused field, see FieldErrors
+type MetadataValidator interface {
+	// ValidateVideo Check the metadata of a video to upload or update
+	ValidateVideo(meta *ItemMetadata) error
+	// ValidatePlaylist Check the metadata of a playlist to create or update
+	ValidatePlaylist(meta *ItemMetadata) error
+	// ValidateTags Check the keywords of a video
+	ValidateTags(tags []string) error
 }
 
 // Video A video hosted on a video storage website
@@ -67,8 +134,73 @@ type Video struct {
 	ThumbnailUrl string `json:"thumbnailUrl,omitempty"`
 	// Url prefix necessary to watch the video. ie https://www.youtube.com/watch?v= for Youtube
 	WatchPrefix string `json:"watchPrefix"`
+	// Upload status on the hosting platform, a failed or rejected upload won't ever be watchable
+	UploadStatus UploadStatus `json:"uploadStatus,omitempty" example:"processed"`
+	// Processing status on the hosting platform. The duration is only final once the processing succeeded
+	ProcessingStatus ProcessingStatus `json:"processingStatus,omitempty" example:"succeeded"`
+	// Advancement of the processing, while it is running
+	ProcessingProgress *ProcessingProgress `json:"processingProgress,omitempty"`
+	// Why the upload or its processing failed
+	FailureReason string `json:"failureReason,omitempty" example:"codec"`
+	// Why the hosting platform rejected the video
+	RejectionReason string `json:"rejectionReason,omitempty" example:"duplicate"`
+	// Audience of the video, only when requested
+	Statistics *Statistics `json:"statistics,omitempty"`
+	// Version of the video, changing each time any of its attributes change, except its statistics. Sent as an ETag header
+	ETag string `json:"-"`
 }
 
+// Version of a video, hashed from all its attributes but the statistics.
+// The statistics change with each view, and would make the version useless to detect concurrent edits
+func videoVersion(vid Video) string {
+	vid.Statistics, vid.ETag = nil, ""
+	data, err := json.Marshal(vid)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// Statistics Audience of a video, as counted by the hosting platform
+type Statistics struct {
+	ViewCount     uint64 `json:"viewCount"`
+	LikeCount     uint64 `json:"likeCount"`
+	CommentCount  uint64 `json:"commentCount"`
+	FavoriteCount uint64 `json:"favoriteCount"`
+}
+
+// ProcessingProgress Advancement of the processing of an uploaded video
+type ProcessingProgress struct {
+	// Number of parts already processed
+	PartsProcessed int64 `json:"partsProcessed"`
+	// Estimated number of parts to process
+	PartsTotal int64 `json:"partsTotal"`
+	// Estimated time until the processing completes, in milliseconds
+	TimeLeftMs int64 `json:"timeLeftMs"`
+}
+
+// ProcessingStatus State of the processing of an uploaded video by the hosting platform
+type ProcessingStatus string
+
+const (
+	StillProcessing      ProcessingStatus = "processing"
+	ProcessingSucceeded  ProcessingStatus = "succeeded"
+	ProcessingFailed     ProcessingStatus = "failed"
+	ProcessingTerminated ProcessingStatus = "terminated"
+)
+
+// UploadStatus State of a video uploaded on the hosting platform
+type UploadStatus string
+
+const (
+	Uploaded  UploadStatus = "uploaded"
+	Processed UploadStatus = "processed"
+	Failed    UploadStatus = "failed"
+	Rejected  UploadStatus = "rejected"
+	Deleted   UploadStatus = "deleted"
+)
+
 // Playlist A collection of videos hosted on a video storage
 // website
 type Playlist struct {
@@ -87,6 +219,16 @@ type Playlist struct {
 	ThumbnailUrl string `json:"thumbnailUrl,omitempty"`
 	// Url prefix necessary to watch the playlist. ie https://www.youtube.com/playlist?list= for Youtube
 	WatchPrefix string `json:"watchPrefix"`
+	// Version of the playlist on the hosting platform, changing each time the platform changes it. Sent as an ETag header
+	ETag string `json:"-"`
+}
+
+// Caption A caption track of a video
+type Caption struct {
+	// BCP-47 language of the track
+	Language string `json:"language" binding:"required" example:"en"`
+	// Name of the track, displayed to the viewers
+	Name string `json:"name" binding:"max=150"`
 }
 
 // Represent the visibility of an object on the video storage
@@ -98,27 +240,142 @@ const (
 	Unlisted Visibility = "unlisted"
 )
 
+// Limits of the metadata of a rendered template whatever the hosting platform : the title in characters, the description
+// in bytes, as hosts count it. Each platform may be stricter, see MetadataValidator.
+// The description is also limited in characters by ItemMetadata
+const (
+	MaxTitleLength       = 100
+	MaxDescriptionLength = 5000
+)
+
 // All metadata about the item to update
 type ItemMetadata struct {
 	// Short text describing the content of the item
-	// Youtube actually limits to 5000 bytes, which *isn't* 5000 characters
+	// Youtube actually limits to 5000 bytes, which *isn't* 5000 characters. This is checked by its MetadataValidator
 	// https://developers.google.com/youtube/v3/docs/videos#properties
-	Description string `json:"description" binding:"max=1000"`
+	Description string `json:"description" binding:"max=5000"`
 	// Title of the item
-	// The max character limitation is currently taken from the Yt docs
-	// https://developers.google.com/youtube/v3/docs/videos#properties
-	// This may change if another provider is requiring less than 100 characters
-	Title string `json:"title" binding:"required,max=100"`
+	// Its length is limited by each host, and depends on the kind of item. This is checked by its MetadataValidator
+	Title string `json:"title" binding:"required"`
 	// Visibility of the item
 	Visibility Visibility `json:"visibility" binding:"required"`
 }
 
+// ErrorKind Category of a failure, independent of the hosting platform
+type ErrorKind string
+
+const (
+	// NotFound The requested item doesn't exist on the hosting platform
+	NotFound ErrorKind = "not-found"
+	// QuotaExceeded The hosting platform refuses any more request for now
+	QuotaExceeded ErrorKind = "quota-exceeded"
+	// InvalidMetadata The provided metadata (title, description...) are refused by the hosting platform
+	InvalidMetadata ErrorKind = "invalid-metadata"
+	// Forbidden The credentials aren't allowed to perform this operation
+	Forbidden ErrorKind = "forbidden"
+	// Upstream The hosting platform failed in an unexpected way
+	Upstream ErrorKind = "upstream"
+	// StorageUnavailable The object storage couldn't provide the requested content
+	StorageUnavailable ErrorKind = "storage-unavailable"
+	// ShuttingDown The service is shutting down and can't run the operation
+	ShuttingDown ErrorKind = "shutting-down"
+	// AuthExpired The hosting platform refuses the credentials (revoked or expired refresh token), they must be granted again
+	AuthExpired ErrorKind = "auth-expired"
+	// PreconditionFailed The item changed since the version the request expects
+	PreconditionFailed ErrorKind = "precondition-failed"
+)
+
+// HTTP status code matching each kind of error
+var kindStatus = map[ErrorKind]int{
+	NotFound:           http.StatusNotFound,
+	QuotaExceeded:      http.StatusTooManyRequests,
+	InvalidMetadata:    http.StatusBadRequest,
+	Forbidden:          http.StatusForbidden,
+	Upstream:           http.StatusBadGateway,
+	StorageUnavailable: http.StatusServiceUnavailable,
+	ShuttingDown:       http.StatusServiceUnavailable,
+	AuthExpired:        http.StatusServiceUnavailable,
+	PreconditionFailed: http.StatusPreconditionFailed,
+}
+
 // This error is only thrown when an error
 type RequestError struct {
 	StatusCode int
-	Err        error
+	// Category of the error. If empty, it is derived from StatusCode
+	Kind ErrorKind
+	Err  error
+}
+
+// NewRequestError Wrap err into a RequestError of the provided kind
+func NewRequestError(kind ErrorKind, err error) *RequestError {
+	return &RequestError{StatusCode: kindStatus[kind], Kind: kind, Err: err}
 }
 
 func (r *RequestError) Error() string {
 	return r.Err.Error()
 }
+
+func (r *RequestError) Unwrap() error {
+	return r.Err
+}
+
+// FieldError A field of the metadata refused by the hosting platform
+type FieldError struct {
+	// JSON name of the field
+	Field string `json:"field" example:"title"`
+	// Why the field is refused
+	Reason string `json:"reason" example:"must not contain < or >"`
+}
+
+// ValidationError Every field of the metadata refused by the hosting platform
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (v *ValidationError) Error() string {
+	reasons := make([]string, 0, len(v.Fields))
+	for _, f := range v.Fields {
+		reasons = append(reasons, f.Field+" "+f.Reason)
+	}
+	return "invalid metadata : " + strings.Join(reasons, ", ")
+}
+
+// NewValidationError Wrap the refused fields into an InvalidMetadata error. Returns nil if there is none
+func NewValidationError(fields []FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return NewRequestError(InvalidMetadata, &ValidationError{Fields: fields})
+}
+
+// FieldErrors Returns the fields refused by err, or nil if err isn't a ValidationError
+func FieldErrors(err error) []FieldError {
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		return nil
+	}
+	return ve.Fields
+}
+
+// KindOf Returns the kind of err, or an empty kind if err isn't a RequestError
+func KindOf(err error) ErrorKind {
+	var re *RequestError
+	if !errors.As(err, &re) {
+		return ""
+	}
+	if re.Kind != "" {
+		return re.Kind
+	}
+	switch {
+	case re.StatusCode == http.StatusNotFound:
+		return NotFound
+	case re.StatusCode == http.StatusTooManyRequests:
+		return QuotaExceeded
+	case re.StatusCode == http.StatusUnauthorized || re.StatusCode == http.StatusForbidden:
+		return Forbidden
+	case re.StatusCode >= 400 && re.StatusCode < 500:
+		return InvalidMetadata
+	default:
+		return Upstream
+	}
+}
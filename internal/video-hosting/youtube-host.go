@@ -2,6 +2,7 @@ package video_hosting
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/senseyeio/duration"
 	"golang.org/x/oauth2"
@@ -10,12 +11,12 @@ import (
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 	"io"
-	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
-func (ytP YoutubeVideoStore) CreateVideo(meta *ItemMetadata, uploadContent io.Reader, onProgress *ProgressFunc) (*Video, error) {
+func (ytP YoutubeVideoStore) CreateVideo(ctx context.Context, meta *ItemMetadata, uploadContent io.Reader, onProgress *ProgressFunc) (*Video, error) {
 	call := ytP.Service.Videos.Insert([]string{"id", "snippet", "status", "contentDetails"}, &youtube.Video{
 		Snippet: &youtube.VideoSnippet{
 			Description: meta.Description,
@@ -32,13 +33,13 @@ func (ytP YoutubeVideoStore) CreateVideo(meta *ItemMetadata, uploadContent io.Re
 		call.ProgressUpdater(googleapi.ProgressUpdater(*onProgress))
 	}
 
-	ytVid, err := call.Media(uploadContent).Do()
+	ytVid, err := call.Media(uploadContent).Context(ctx).Do()
 	if err != nil {
 		return nil, handleGoogleApiError(err)
 	}
 
 	// We are forced to make a separate API call to get all the files details.
-	ytVid2, err := ytP.getYoutubeVideoById(ytVid.Id)
+	ytVid2, err := ytP.getYoutubeVideoById(ctx, ytVid.Id)
 	if err != nil {
 		return nil, handleGoogleApiError(err)
 	}
@@ -46,41 +47,63 @@ func (ytP YoutubeVideoStore) CreateVideo(meta *ItemMetadata, uploadContent io.Re
 	return toGenericVideo(ytVid2)
 }
 
-func (ytP YoutubeVideoStore) RetrieveVideo(id string) (*Video, error) {
-	ytVid, err := ytP.getYoutubeVideoById(id)
+func (ytP YoutubeVideoStore) RetrieveVideo(ctx context.Context, id string) (*Video, error) {
+	ytVid, err := ytP.getYoutubeVideoById(ctx, id)
 	if err != nil {
 		return nil, handleGoogleApiError(err)
 	}
 	return toGenericVideo(ytVid)
 }
 
-func (ytP YoutubeVideoStore) UpdateVideo(id string, replacement *Video) (*Video, error) {
-	ytVid, err := ytP.getYoutubeVideoById(id)
+func (ytP YoutubeVideoStore) UpdateVideo(ctx context.Context, id string, replacement *Video) (*Video, error) {
+	ytVid, err := ytP.getYoutubeVideoById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, handleGoogleApiError(err)
 	}
 	err = patchYoutubeVideo(ytVid, replacement)
 	if err != nil {
-		return nil, err
+		return nil, NewRequestError(InvalidMetadata, err)
 	}
+	// Read-only parts, they can't be sent back
+	ytVid.ProcessingDetails, ytVid.Statistics = nil, nil
 	call := ytP.Service.Videos.Update([]string{"snippet", "status", "contentDetails", "id"}, ytVid)
-	updated, err := call.Do()
+	_, err = call.Context(ctx).Do()
 	if err != nil {
 		return nil, handleGoogleApiError(err)
 	}
-	return toGenericVideo(updated)
+	// The update only answers with the parts sent, the video is read again to be returned whole, with the same version as a retrieval
+	return ytP.RetrieveVideo(ctx, id)
 }
 
-func (ytP YoutubeVideoStore) DeleteVideo(id string) error {
+func (ytP YoutubeVideoStore) DeleteVideo(ctx context.Context, id string) error {
 	call := ytP.Service.Videos.Delete(id)
-	return call.Do()
+	err := call.Context(ctx).Do()
+	if err != nil {
+		return handleGoogleApiError(err)
+	}
+	return nil
+}
+
+// Ping Checks an access token can be minted from the refresh token.
+// This doesn't consume any quota
+func (ytP YoutubeVideoStore) Ping(_ context.Context) error {
+	if ytP.tokens == nil {
+		return fmt.Errorf("no youtube credentials configured")
+	}
+	if _, err := ytP.tokens.Token(); err != nil {
+		if isAuthExpired(err) {
+			return authExpiredError(err)
+		}
+		return fmt.Errorf("could not mint a youtube access token : %w", err)
+	}
+	return nil
 }
 
 func (ytP YoutubeVideoStore) GetVideoAccessPrefix() string {
 	return getYoutubeVideoPrefix()
 }
 
-func (ytP YoutubeVideoStore) CreatePlaylist(meta *ItemMetadata) (*Playlist, error) {
+func (ytP YoutubeVideoStore) CreatePlaylist(ctx context.Context, meta *ItemMetadata) (*Playlist, error) {
 	call := ytP.Service.Playlists.Insert([]string{"snippet", "status", "contentDetails"}, &youtube.Playlist{
 		Snippet: &youtube.PlaylistSnippet{
 			Description: meta.Description,
@@ -90,7 +113,7 @@ func (ytP YoutubeVideoStore) CreatePlaylist(meta *ItemMetadata) (*Playlist, erro
 			PrivacyStatus: string(meta.Visibility),
 		},
 	})
-	YtPlaylist, err := call.Do()
+	YtPlaylist, err := call.Context(ctx).Do()
 	if err != nil {
 		return nil, handleGoogleApiError(err)
 	}
@@ -101,8 +124,8 @@ func (ytP YoutubeVideoStore) CreatePlaylist(meta *ItemMetadata) (*Playlist, erro
 	return playlist, nil
 }
 
-func (ytP YoutubeVideoStore) RetrievePlaylist(id string) (*Playlist, error) {
-	ytPlaylist, err := ytP.getYoutubePlaylistById(id)
+func (ytP YoutubeVideoStore) RetrievePlaylist(ctx context.Context, id string) (*Playlist, error) {
+	ytPlaylist, err := ytP.getYoutubePlaylistById(ctx, id)
 	if err != nil {
 		return nil, handleGoogleApiError(err)
 	}
@@ -113,43 +136,43 @@ func (ytP YoutubeVideoStore) RetrievePlaylist(id string) (*Playlist, error) {
 	return playlist, nil
 }
 
-func (ytP YoutubeVideoStore) UpdatePlaylist(id string, replacement *Playlist) (*Playlist, error) {
-	currentPlaylist, err := ytP.getYoutubePlaylistById(id)
+func (ytP YoutubeVideoStore) UpdatePlaylist(ctx context.Context, id string, replacement *Playlist) (*Playlist, error) {
+	currentPlaylist, err := ytP.getYoutubePlaylistById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, handleGoogleApiError(err)
 	}
 	err = patchYoutubePlaylist(currentPlaylist, replacement)
 	if err != nil {
-		return nil, err
+		return nil, NewRequestError(InvalidMetadata, err)
 	}
 	call := ytP.Service.Playlists.Update([]string{"snippet", "status", "contentDetails"}, currentPlaylist)
-	updated, err := call.Do()
+	updated, err := call.Context(ctx).Do()
 	if err != nil {
 		return nil, handleGoogleApiError(err)
 	}
 	return toGenericPlaylist(updated)
 }
 
-func (ytP YoutubeVideoStore) DeletePlaylist(id string) error {
+func (ytP YoutubeVideoStore) DeletePlaylist(ctx context.Context, id string) error {
 	call := ytP.Service.Playlists.Delete(id)
-	err := call.Do()
+	err := call.Context(ctx).Do()
 	if err != nil {
 		return handleGoogleApiError(err)
 	}
 	return nil
 }
 
-func (ytP YoutubeVideoStore) UpdateVideoThumbnail(videoId string, thumbnailContent io.Reader) error {
+func (ytP YoutubeVideoStore) UpdateVideoThumbnail(ctx context.Context, videoId string, thumbnailContent io.Reader) error {
 	call := ytP.Service.Thumbnails.Set(videoId)
 	call.Media(thumbnailContent)
-	_, err := call.Do()
+	_, err := call.Context(ctx).Do()
 	if err != nil {
 		return handleGoogleApiError(err)
 	}
 	return nil
 }
 
-func (ytP YoutubeVideoStore) AddVideoToPlaylist(videoId string, playlistId string) error {
+func (ytP YoutubeVideoStore) AddVideoToPlaylist(ctx context.Context, videoId string, playlistId string) error {
 	call := ytP.Service.PlaylistItems.Insert([]string{"snippet"}, &youtube.PlaylistItem{
 		Snippet: &youtube.PlaylistItemSnippet{
 			PlaylistId: playlistId,
@@ -159,19 +182,115 @@ func (ytP YoutubeVideoStore) AddVideoToPlaylist(videoId string, playlistId strin
 			},
 		},
 	})
-	_, err := call.Do()
+	_, err := call.Context(ctx).Do()
+	if err != nil {
+		return handleGoogleApiError(err)
+	}
+	return nil
+}
+
+func (ytP YoutubeVideoStore) SetVideoTags(ctx context.Context, videoId string, tags []string) error {
+	ytVid, err := ytP.getYoutubeVideoById(ctx, videoId)
+	if err != nil {
+		return handleGoogleApiError(err)
+	}
+	ytVid.Snippet.Tags = tags
+	// An empty list must still be sent to remove all the tags
+	ytVid.Snippet.ForceSendFields = append(ytVid.Snippet.ForceSendFields, "Tags")
+	_, err = ytP.Service.Videos.Update([]string{"snippet"}, &youtube.Video{Id: ytVid.Id, Snippet: ytVid.Snippet}).Context(ctx).Do()
+	if err != nil {
+		return handleGoogleApiError(err)
+	}
+	return nil
+}
+
+// ScheduleVideo Youtube only publishes private videos at the scheduled time
+func (ytP YoutubeVideoStore) ScheduleVideo(ctx context.Context, videoId string, publishAt time.Time) error {
+	ytVid, err := ytP.getYoutubeVideoById(ctx, videoId)
+	if err != nil {
+		return handleGoogleApiError(err)
+	}
+	ytVid.Status.PrivacyStatus = string(Private)
+	ytVid.Status.PublishAt = publishAt.UTC().Format(time.RFC3339)
+	_, err = ytP.Service.Videos.Update([]string{"status"}, &youtube.Video{Id: ytVid.Id, Status: ytVid.Status}).Context(ctx).Do()
+	if err != nil {
+		return handleGoogleApiError(err)
+	}
+	return nil
+}
+
+func (ytP YoutubeVideoStore) AddVideoCaption(ctx context.Context, videoId string, caption *Caption, content io.Reader) error {
+	call := ytP.Service.Captions.Insert([]string{"snippet"}, &youtube.Caption{
+		Snippet: &youtube.CaptionSnippet{
+			VideoId:  videoId,
+			Language: caption.Language,
+			Name:     caption.Name,
+		},
+	})
+	_, err := call.Media(content).Context(ctx).Do()
 	if err != nil {
 		return handleGoogleApiError(err)
 	}
 	return nil
 }
 
+func (ytP YoutubeVideoStore) ListVideos(ctx context.Context, ids []string) ([]*Video, error) {
+	if len(ids) > MaxListIds {
+		return nil, fmt.Errorf("at most %d videos can be listed at once, %d requested", MaxListIds, len(ids))
+	}
+	videos := make([]*Video, 0, len(ids))
+	if len(ids) == 0 {
+		return videos, nil
+	}
+	call := ytP.Service.Videos.List(youtubeVideoParts)
+	call.Id(ids...)
+	res, err := call.Context(ctx).Do()
+	if err != nil {
+		return nil, handleGoogleApiError(err)
+	}
+	for _, item := range res.Items {
+		video, err := toGenericVideo(item)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, nil
+}
+
+func (ytP YoutubeVideoStore) ListPlaylists(ctx context.Context, ids []string) ([]*Playlist, error) {
+	if len(ids) > MaxListIds {
+		return nil, fmt.Errorf("at most %d playlists can be listed at once, %d requested", MaxListIds, len(ids))
+	}
+	playlists := make([]*Playlist, 0, len(ids))
+	if len(ids) == 0 {
+		return playlists, nil
+	}
+	call := ytP.Service.Playlists.List([]string{"snippet", "status", "contentDetails"})
+	call.Id(ids...)
+	res, err := call.Context(ctx).Do()
+	if err != nil {
+		return nil, handleGoogleApiError(err)
+	}
+	for _, item := range res.Items {
+		playlist, err := toGenericPlaylist(item)
+		if err != nil {
+			return nil, err
+		}
+		playlists = append(playlists, playlist)
+	}
+	return playlists, nil
+}
+
+// Parts read when looking up videos. Listing more parts doesn't cost any more quota
+var youtubeVideoParts = []string{"contentDetails", "id", "snippet", "status", "fileDetails", "processingDetails", "statistics"}
+
 // Retrieve a youtube video with the provided ID
 // Errors if not found
-func (ytP YoutubeVideoStore) getYoutubeVideoById(id string) (*youtube.Video, error) {
-	call := ytP.Service.Videos.List([]string{"contentDetails", "id", "snippet", "status", "fileDetails"})
+func (ytP YoutubeVideoStore) getYoutubeVideoById(ctx context.Context, id string) (*youtube.Video, error) {
+	call := ytP.Service.Videos.List(youtubeVideoParts)
 	call.Id(id)
-	res, err := call.Do()
+	res, err := call.Context(ctx).Do()
 	if err != nil {
 		return nil, err
 	}
@@ -183,10 +302,10 @@ func (ytP YoutubeVideoStore) getYoutubeVideoById(id string) (*youtube.Video, err
 
 // Retrieve a youtube playlist with the provided ID
 // Errors if not found
-func (ytP YoutubeVideoStore) getYoutubePlaylistById(id string) (*youtube.Playlist, error) {
+func (ytP YoutubeVideoStore) getYoutubePlaylistById(ctx context.Context, id string) (*youtube.Playlist, error) {
 	call := ytP.Service.Playlists.List([]string{"snippet", "status", "contentDetails"})
 	call.Id(id)
-	res, err := call.Do()
+	res, err := call.Context(ctx).Do()
 	if err != nil {
 		return nil, err
 	}
@@ -205,7 +324,8 @@ func getYoutubePlaylistPrefix() string {
 }
 
 // Converts a Youtube-specific video in a generic video
-// /!\ The youtube video input must contain the parts "fileDetails", "id", "snippet" and "status"
+// /!\ The youtube video input must contain the parts "fileDetails", "id", "snippet" and "status".
+// The processing state is only filled with the part "processingDetails", and the statistics with the part "statistics"
 func toGenericVideo(in *youtube.Video) (*Video, error) {
 	if in.Snippet == nil || in.Status == nil {
 		return nil, fmt.Errorf(`Missing some required parts (snippet or status`)
@@ -225,16 +345,56 @@ func toGenericVideo(in *youtube.Video) (*Video, error) {
 	if in.FileDetails != nil {
 		duration = int64(in.FileDetails.DurationMs / 1000)
 	}
-	return &Video{
-		Id:           in.Id,
-		Title:        in.Snippet.Title,
-		Description:  in.Snippet.Description,
-		CreatedAt:    creationDate,
-		Duration:     duration,
-		Visibility:   Visibility(in.Status.PrivacyStatus),
-		ThumbnailUrl: thumbUrl,
-		WatchPrefix:  getYoutubeVideoPrefix(),
-	}, nil
+	// Once processed, the contentDetails hold the final duration
+	if in.ContentDetails != nil && in.ContentDetails.Duration != "" {
+		if final, err := iSO8601DurationToSeconds(in.ContentDetails.Duration); err == nil && *final > 0 {
+			duration = *final
+		}
+	}
+	processingStatus := ProcessingStatus("")
+	var progress *ProcessingProgress
+	failureReason := in.Status.FailureReason
+	if details := in.ProcessingDetails; details != nil {
+		processingStatus = ProcessingStatus(details.ProcessingStatus)
+		if details.ProcessingProgress != nil && processingStatus == StillProcessing {
+			progress = &ProcessingProgress{
+				PartsProcessed: int64(details.ProcessingProgress.PartsProcessed),
+				PartsTotal:     int64(details.ProcessingProgress.PartsTotal),
+				TimeLeftMs:     int64(details.ProcessingProgress.TimeLeftMs),
+			}
+		}
+		if failureReason == "" {
+			failureReason = details.ProcessingFailureReason
+		}
+	}
+	var statistics *Statistics
+	if in.Statistics != nil {
+		statistics = &Statistics{
+			ViewCount:     in.Statistics.ViewCount,
+			LikeCount:     in.Statistics.LikeCount,
+			CommentCount:  in.Statistics.CommentCount,
+			FavoriteCount: in.Statistics.FavoriteCount,
+		}
+	}
+	vid := &Video{
+		Id:                 in.Id,
+		Title:              in.Snippet.Title,
+		Description:        in.Snippet.Description,
+		CreatedAt:          creationDate,
+		Duration:           duration,
+		Visibility:         Visibility(in.Status.PrivacyStatus),
+		ThumbnailUrl:       thumbUrl,
+		WatchPrefix:        getYoutubeVideoPrefix(),
+		UploadStatus:       UploadStatus(in.Status.UploadStatus),
+		ProcessingStatus:   processingStatus,
+		ProcessingProgress: progress,
+		FailureReason:      failureReason,
+		RejectionReason:    in.Status.RejectionReason,
+		Statistics:         statistics,
+	}
+	// The Youtube etag depends on the parts listed, statistics included
+	vid.ETag = videoVersion(*vid)
+	return vid, nil
 }
 
 func iSO8601DurationToSeconds(in string) (*int64, error) {
@@ -276,6 +436,7 @@ func toGenericPlaylist(in *youtube.Playlist) (*Playlist, error) {
 		Visibility:   Visibility(in.Status.PrivacyStatus),
 		ThumbnailUrl: thumbUrl,
 		WatchPrefix:  getYoutubePlaylistPrefix(),
+		ETag:         in.Etag,
 	}, nil
 }
 
@@ -324,32 +485,145 @@ func patchYoutubeVideo(src *youtube.Video, patch *Video) error {
 }
 
 func NewYoutubeStore(ctx context.Context, creds *YoutubeStoreCredentials, opt *YoutubeStoreOptions) (*YoutubeVideoStore, error) {
+	// Using a rotating token source, the access token will get auto refreshed,
+	// and the credentials can be replaced without re-creating the service
+	tokens := newRotatingTokenSource(ctx, *creds)
+	ytService, err := youtube.NewService(ctx, option.WithTokenSource(tokens))
+	if err != nil {
+		return nil, err
+	}
+
+	//Assign default values to options and go on
+	if opt == nil {
+		opt = &YoutubeStoreOptions{}
+	}
+	assignDefault(opt)
+	return &YoutubeVideoStore{Service: ytService, Options: opt, tokens: tokens}, nil
+}
+
+// RotateCredentials Replace the credentials with the values found in secret, under the keys
+// YoutubeSecretClientId, YoutubeSecretClientSecret and YoutubeSecretRefreshToken.
+// The access tokens are minted from the new credentials from now on
+func (ytP YoutubeVideoStore) RotateCredentials(secret map[string]string) bool {
+	if ytP.tokens == nil {
+		return false
+	}
+	return ytP.tokens.rotate(secret)
+}
+
+// YoutubeOAuthConfig The OAuth configuration of the Google project in creds, granting access to Youtube.
+// redirectUrl is only needed to obtain a refresh token with the consent flow
+func YoutubeOAuthConfig(creds YoutubeStoreCredentials, redirectUrl string) *oauth2.Config {
 	// Generalist Google oauth config
-	config := oauth2.Config{
+	return &oauth2.Config{
 		// Project ID
 		ClientID: creds.ClientId,
 		// Project's secret
 		ClientSecret: creds.ClientSecret,
 		// URL to Google auth services
 		Endpoint: google.Endpoint,
+		// Where Google sends the user back after the consent
+		RedirectURL: redirectUrl,
 		// We want to get access to Youtube, impersonating the user and to be able to upload videos
 		Scopes: []string{youtube.YoutubeScope, youtube.YoutubeUploadScope},
 	}
+}
+
+// OnAuthExpired Call fn once each time the refresh token is refused, until an access token can be minted again
+func (ytP YoutubeVideoStore) OnAuthExpired(fn func(err error)) {
+	if ytP.tokens == nil {
+		return
+	}
+	ytP.tokens.mu.Lock()
+	defer ytP.tokens.mu.Unlock()
+	ytP.tokens.onExpired = append(ytP.tokens.onExpired, fn)
+}
+
+// OAuthConfig The OAuth configuration to obtain a refresh token with, from the current client credentials
+func (ytP YoutubeVideoStore) OAuthConfig(redirectUrl string) *oauth2.Config {
+	return YoutubeOAuthConfig(ytP.credentials(), redirectUrl)
+}
+
+// DeviceOAuthConfig The OAuth configuration to obtain a refresh token with the device flow.
+// Google refuses the upload scope on this flow, the Youtube scope covers uploads anyway
+func (ytP YoutubeVideoStore) DeviceOAuthConfig() *oauth2.Config {
+	config := YoutubeOAuthConfig(ytP.credentials(), "")
+	config.Scopes = []string{youtube.YoutubeScope}
+	return config
+}
+
+// Current credentials of the store
+func (ytP YoutubeVideoStore) credentials() YoutubeStoreCredentials {
+	if ytP.tokens == nil {
+		return YoutubeStoreCredentials{}
+	}
+	ytP.tokens.mu.RLock()
+	defer ytP.tokens.mu.RUnlock()
+	return ytP.tokens.creds
+}
+
+// Build a token source minting access tokens from the refresh token in creds
+func youtubeTokenSource(ctx context.Context, creds YoutubeStoreCredentials) oauth2.TokenSource {
 	// Initialize an "empty" token, only using the refresh token.
 	// We'll let the token source initialize the access token and expiry
 	token := &oauth2.Token{RefreshToken: creds.RefreshToken}
-	// Using token source, the access token will get auto refreshed
-	ytService, err := youtube.NewService(ctx, option.WithTokenSource(config.TokenSource(ctx, token)))
-	if err != nil {
-		return nil, err
-	}
+	return YoutubeOAuthConfig(creds, "").TokenSource(ctx, token)
+}
 
-	//Assign default values to options and go on
-	if opt == nil {
-		opt = &YoutubeStoreOptions{}
-	}
-	assignDefault(opt)
-	return &YoutubeVideoStore{Service: ytService, Options: opt}, nil
+// A token source whose credentials can be replaced while in use
+type rotatingTokenSource struct {
+	ctx    context.Context
+	mu     sync.RWMutex
+	creds  YoutubeStoreCredentials
+	source oauth2.TokenSource
+	// Whether the refresh token was refused by the last attempt to mint an access token
+	expired bool
+	// Called when the refresh token starts being refused
+	onExpired []func(err error)
+}
+
+func newRotatingTokenSource(ctx context.Context, creds YoutubeStoreCredentials) *rotatingTokenSource {
+	return &rotatingTokenSource{ctx: ctx, creds: creds, source: youtubeTokenSource(ctx, creds)}
+}
+
+func (rts *rotatingTokenSource) Token() (*oauth2.Token, error) {
+	rts.mu.RLock()
+	source := rts.source
+	rts.mu.RUnlock()
+	token, err := source.Token()
+
+	expired := err != nil && isAuthExpired(err)
+	rts.mu.Lock()
+	// Outcomes of replaced credentials are outdated. Only the first refusal is notified,
+	// every call fails until the credentials are replaced
+	notify := false
+	if source == rts.source {
+		notify = expired && !rts.expired
+		rts.expired = expired
+	}
+	callbacks := rts.onExpired
+	rts.mu.Unlock()
+	if notify {
+		for _, fn := range callbacks {
+			fn(authExpiredError(err))
+		}
+	}
+	return token, err
+}
+
+// Apply the credentials found in secret, rebuilding the underlying token source if they changed
+func (rts *rotatingTokenSource) rotate(secret map[string]string) bool {
+	rts.mu.Lock()
+	defer rts.mu.Unlock()
+	next := rts.creds.WithSecret(secret)
+	if next == rts.creds {
+		return false
+	}
+	rts.creds = next
+	rts.source = youtubeTokenSource(rts.ctx, next)
+	// The new credentials may be accepted, the next refusal is notified again
+	rts.expired = false
+	return true
 }
 
 // Assign all default options to the youtube store
@@ -362,16 +636,76 @@ func assignDefault(opt *YoutubeStoreOptions) {
 	}
 }
 
-// Handle a google api error, extracting the status code
+// Youtube error reasons, by kind of error
+// https://developers.google.com/youtube/v3/docs/errors
+var googleReasonKinds = map[string]ErrorKind{
+	"videoNotFound":           NotFound,
+	"playlistNotFound":        NotFound,
+	"playlistItemNotFound":    NotFound,
+	"channelNotFound":         NotFound,
+	"quotaExceeded":           QuotaExceeded,
+	"dailyLimitExceeded":      QuotaExceeded,
+	"rateLimitExceeded":       QuotaExceeded,
+	"userRateLimitExceeded":   QuotaExceeded,
+	"uploadLimitExceeded":     QuotaExceeded,
+	"invalidTitle":            InvalidMetadata,
+	"invalidDescription":      InvalidMetadata,
+	"invalidTags":             InvalidMetadata,
+	"invalidCategoryId":       InvalidMetadata,
+	"invalidVideoMetadata":    InvalidMetadata,
+	"invalidPublishAt":        InvalidMetadata,
+	"invalidPlaylistSnippet":  InvalidMetadata,
+	"mediaBodyRequired":       InvalidMetadata,
+	"invalidImage":            InvalidMetadata,
+	"mediaBodyTooLarge":       InvalidMetadata,
+	"forbidden":               Forbidden,
+	"insufficientPermissions": Forbidden,
+	"authError":               Forbidden,
+}
+
+// OAuth error codes meaning the refresh token or the client won't ever be accepted again
+// https://www.rfc-editor.org/rfc/rfc6749#section-5.2
+var authExpiredCodes = map[string]bool{
+	"invalid_grant":       true,
+	"invalid_client":      true,
+	"unauthorized_client": true,
+}
+
+// Whether err is Google refusing to mint an access token from the credentials
+func isAuthExpired(err error) bool {
+	var re *oauth2.RetrieveError
+	return errors.As(err, &re) && authExpiredCodes[re.ErrorCode]
+}
+
+// Wrap err, a refused refresh token, into an AuthExpired RequestError
+func authExpiredError(err error) error {
+	return NewRequestError(AuthExpired,
+		fmt.Errorf("the youtube refresh token was revoked or has expired, the host must be authorized again : %w", err))
+}
+
+// Handle a google api error, extracting the status code and classifying it
 func handleGoogleApiError(err error) error {
+	if isAuthExpired(err) {
+		return authExpiredError(err)
+	}
 	if ge, ok := err.(*googleapi.Error); ok {
-		return &RequestError{ge.Code, ge}
+		for _, item := range ge.Errors {
+			if kind, ok := googleReasonKinds[item.Reason]; ok {
+				return &RequestError{StatusCode: kindStatus[kind], Kind: kind, Err: ge}
+			}
+		}
+		// Unknown reason, only the status code is left to make a decision
+		re := &RequestError{StatusCode: ge.Code, Err: ge}
+		re.Kind = KindOf(re)
+		re.StatusCode = kindStatus[re.Kind]
+		return re
 	} else if strings.Contains(strings.ToLower(err.Error()), "not found") {
 		// Special case : A not found is thrown when a call to a Google APIs succeeded
 		// but no result were found
-		return &RequestError{http.StatusNotFound, err}
+		return NewRequestError(NotFound, err)
 	}
-	return err
+	// Anything else is a failure to reach Google (network, oauth...)
+	return NewRequestError(Upstream, err)
 }
 
 // YoutubeStoreCredentials all info required to authenticate to Youtube Data API v3
@@ -384,6 +718,27 @@ type YoutubeStoreCredentials struct {
 	RefreshToken string
 }
 
+// Keys of the Youtube credentials in a secret
+const (
+	YoutubeSecretClientId     = "clientId"
+	YoutubeSecretClientSecret = "clientSecret"
+	YoutubeSecretRefreshToken = "refreshToken"
+)
+
+// WithSecret Returns a copy of creds, with each value found in secret replacing the current one
+func (creds YoutubeStoreCredentials) WithSecret(secret map[string]string) YoutubeStoreCredentials {
+	if v, ok := secret[YoutubeSecretClientId]; ok {
+		creds.ClientId = v
+	}
+	if v, ok := secret[YoutubeSecretClientSecret]; ok {
+		creds.ClientSecret = v
+	}
+	if v, ok := secret[YoutubeSecretRefreshToken]; ok {
+		creds.RefreshToken = v
+	}
+	return creds
+}
+
 // YoutubeStoreOptions all options to initialize a Youtube store
 type YoutubeStoreOptions struct {
 	CategoryId string
@@ -392,4 +747,6 @@ type YoutubeStoreOptions struct {
 type YoutubeVideoStore struct {
 	Service *youtube.Service
 	Options *YoutubeStoreOptions
+	// Source of the access tokens used by Service
+	tokens *rotatingTokenSource
 }
@@ -42,7 +42,7 @@ func Setup(t *testing.T) *YoutubeVideoStore {
 func Test_YoutubeStore_PlaylistLifecycle(t *testing.T) {
 	store := Setup(t)
 	// Create a playlist
-	p, err := store.CreatePlaylist(&ItemMetadata{
+	p, err := store.CreatePlaylist(context.Background(), &ItemMetadata{
 		Description: "test-go-api",
 		Title:       "test",
 		Visibility:  Unlisted,
@@ -54,16 +54,16 @@ func Test_YoutubeStore_PlaylistLifecycle(t *testing.T) {
 	// Change its title and check that the changes propagated
 	const changedTitle = "test2"
 	p.Title = changedTitle
-	p2, err := store.UpdatePlaylist(p.Id, p)
+	p2, err := store.UpdatePlaylist(context.Background(), p.Id, p)
 	assert.Nil(t, err)
 	assert.Equal(t, changedTitle, p2.Title)
 	time.Sleep(10 * time.Second)
-	p3, err := store.RetrievePlaylist(p.Id)
+	p3, err := store.RetrievePlaylist(context.Background(), p.Id)
 	assert.Nil(t, err)
 	assert.Equal(t, changedTitle, p3.Title)
 
 	// Finally delete the playlist
-	err = store.DeletePlaylist(p.Id)
+	err = store.DeletePlaylist(context.Background(), p.Id)
 	assert.Nil(t, err)
 }
 
@@ -75,7 +75,7 @@ func Test_YoutubeStore_VideoLifecycle(t *testing.T) {
 		fmt.Println(err)
 		t.FailNow()
 	}
-	v, err := store.CreateVideo(&ItemMetadata{
+	v, err := store.CreateVideo(context.Background(), &ItemMetadata{
 		Description: "test-go-api",
 		Title:       "test",
 		Visibility:  Unlisted,
@@ -87,16 +87,16 @@ func Test_YoutubeStore_VideoLifecycle(t *testing.T) {
 	// Change its title and check that the changes propagated
 	const changedTitle = "test2"
 	v.Title = changedTitle
-	v2, err := store.UpdateVideo(v.Id, v)
+	v2, err := store.UpdateVideo(context.Background(), v.Id, v)
 	assert.Nil(t, err)
 	assert.Equal(t, changedTitle, v2.Title)
 	time.Sleep(10 * time.Second)
-	v3, err := store.RetrieveVideo(v.Id)
+	v3, err := store.RetrieveVideo(context.Background(), v.Id)
 	assert.Nil(t, err)
 	assert.Equal(t, changedTitle, v3.Title)
 
 	// Finally delete the video
-	//err = store.DeleteVideo(v.Id)
+	//err = store.DeleteVideo(context.Background(), v.Id)
 	assert.Nil(t, err)
 }
 
@@ -108,7 +108,7 @@ func Test_YoutubeStore_SetThumbnail(t *testing.T) {
 		fmt.Println(err)
 		t.FailNow()
 	}
-	v, err := store.CreateVideo(&ItemMetadata{
+	v, err := store.CreateVideo(context.Background(), &ItemMetadata{
 		Description: "test-go-api",
 		Title:       "test",
 		Visibility:  Unlisted,
@@ -119,12 +119,12 @@ func Test_YoutubeStore_SetThumbnail(t *testing.T) {
 		fmt.Println(err)
 		t.FailNow()
 	}
-	err = store.UpdateVideoThumbnail(v.Id, f)
+	err = store.UpdateVideoThumbnail(context.Background(), v.Id, f)
 	if err != nil {
 		fmt.Println(err)
 		t.FailNow()
 	}
-	err = store.DeleteVideo(v.Id)
+	err = store.DeleteVideo(context.Background(), v.Id)
 	if err != nil {
 		fmt.Println(err)
 		t.FailNow()
@@ -134,7 +134,7 @@ func Test_YoutubeStore_SetThumbnail(t *testing.T) {
 func Test_YoutubeStore_AddVideoToPlaylist(t *testing.T) {
 	store := Setup(t)
 	// Create a playlist
-	p, err := store.CreatePlaylist(&ItemMetadata{
+	p, err := store.CreatePlaylist(context.Background(), &ItemMetadata{
 		Description: "test-go-api",
 		Title:       "test",
 		Visibility:  Unlisted,
@@ -146,24 +146,24 @@ func Test_YoutubeStore_AddVideoToPlaylist(t *testing.T) {
 		fmt.Println(err)
 		t.FailNow()
 	}
-	v, err := store.CreateVideo(&ItemMetadata{
+	v, err := store.CreateVideo(context.Background(), &ItemMetadata{
 		Description: "test-go-api",
 		Title:       "test",
 		Visibility:  Unlisted,
 	}, f, nil)
 	assert.Nil(t, err)
 
-	err = store.AddVideoToPlaylist(v.Id, p.Id)
+	err = store.AddVideoToPlaylist(context.Background(), v.Id, p.Id)
 	if err != nil {
 		fmt.Println(err)
 		t.FailNow()
 	}
-	//err = store.DeleteVideo(v.Id)
+	//err = store.DeleteVideo(context.Background(), v.Id)
 	if err != nil {
 		fmt.Println(err)
 		t.FailNow()
 	}
-	// err = store.DeletePlaylist(p.Id)
+	// err = store.DeletePlaylist(context.Background(), p.Id)
 	if err != nil {
 		fmt.Println(err)
 		t.FailNow()
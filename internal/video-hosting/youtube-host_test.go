@@ -1,8 +1,15 @@
 package video_hosting
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/youtube/v3"
+	"net/http"
+	"net/url"
 	"testing"
 	"time"
 )
@@ -155,6 +162,7 @@ func TestToGenericVideo(t *testing.T) {
 		},
 		Status: &youtube.VideoStatus{
 			PrivacyStatus: visibility,
+			UploadStatus:  "rejected",
 		},
 	}
 	vid, err := toGenericVideo(&ytVid)
@@ -166,6 +174,7 @@ func TestToGenericVideo(t *testing.T) {
 	assert.Equal(t, creationDate, vid.CreatedAt.Format(time.RFC3339))
 	assert.Equal(t, title, vid.Title)
 	assert.Equal(t, visibility, string(vid.Visibility))
+	assert.Equal(t, Rejected, vid.UploadStatus)
 
 	// adding a thumbnail
 	ytVid.Snippet.Thumbnails = &youtube.ThumbnailDetails{
@@ -185,6 +194,107 @@ func TestToGenericVideo(t *testing.T) {
 	assert.Equal(t, thumbUrl, vid.ThumbnailUrl)
 }
 
+func TestToGenericVideo_Processing(t *testing.T) {
+	ytVid := youtube.Video{
+		FileDetails: &youtube.VideoFileDetails{DurationMs: 7200000},
+		Snippet:     &youtube.VideoSnippet{PublishedAt: "2018-08-25T11:12:35Z"},
+		Status:      &youtube.VideoStatus{UploadStatus: "uploaded"},
+		ProcessingDetails: &youtube.VideoProcessingDetails{
+			ProcessingStatus:   "processing",
+			ProcessingProgress: &youtube.VideoProcessingDetailsProcessingProgress{PartsProcessed: 2, PartsTotal: 10, TimeLeftMs: 3000},
+		},
+	}
+	vid, err := toGenericVideo(&ytVid)
+	assert.Nil(t, err)
+	assert.Equal(t, StillProcessing, vid.ProcessingStatus)
+	assert.Equal(t, &ProcessingProgress{PartsProcessed: 2, PartsTotal: 10, TimeLeftMs: 3000}, vid.ProcessingProgress)
+	assert.Equal(t, int64(7200), vid.Duration)
+
+	// Once processed, the final duration is used
+	ytVid.Status.UploadStatus = "processed"
+	ytVid.ProcessingDetails.ProcessingStatus = "succeeded"
+	ytVid.ContentDetails = &youtube.VideoContentDetails{Duration: "PT2H0M3S"}
+	vid, err = toGenericVideo(&ytVid)
+	assert.Nil(t, err)
+	assert.Equal(t, Processed, vid.UploadStatus)
+	assert.Nil(t, vid.ProcessingProgress)
+	assert.Equal(t, int64(7203), vid.Duration)
+
+	// The failure reason is taken from either part
+	ytVid.Status = &youtube.VideoStatus{UploadStatus: "failed"}
+	ytVid.ProcessingDetails = &youtube.VideoProcessingDetails{ProcessingStatus: "failed", ProcessingFailureReason: "transcodeFailed"}
+	vid, err = toGenericVideo(&ytVid)
+	assert.Nil(t, err)
+	assert.Equal(t, ProcessingFailed, vid.ProcessingStatus)
+	assert.Equal(t, "transcodeFailed", vid.FailureReason)
+	ytVid.Status = &youtube.VideoStatus{UploadStatus: "rejected", RejectionReason: "duplicate"}
+	vid, err = toGenericVideo(&ytVid)
+	assert.Nil(t, err)
+	assert.Equal(t, "duplicate", vid.RejectionReason)
+}
+
+func TestToGenericVideo_Statistics(t *testing.T) {
+	ytVid := youtube.Video{
+		Snippet: &youtube.VideoSnippet{PublishedAt: "2018-08-25T11:12:35Z"},
+		Status:  &youtube.VideoStatus{},
+	}
+	vid, err := toGenericVideo(&ytVid)
+	assert.Nil(t, err)
+	assert.Nil(t, vid.Statistics)
+
+	ytVid.Statistics = &youtube.VideoStatistics{ViewCount: 100, LikeCount: 10, CommentCount: 2, FavoriteCount: 1}
+	vid, err = toGenericVideo(&ytVid)
+	assert.Nil(t, err)
+	assert.Equal(t, &Statistics{ViewCount: 100, LikeCount: 10, CommentCount: 2, FavoriteCount: 1}, vid.Statistics)
+}
+
+func TestToGeneric_ETag(t *testing.T) {
+	ytVid := youtube.Video{
+		Etag:    "XI7nbFXulYBIpL0ayR_gDh3eu1k",
+		Snippet: &youtube.VideoSnippet{PublishedAt: "2018-08-25T11:12:35Z", Title: "title"},
+		Status:  &youtube.VideoStatus{},
+	}
+	vid, err := toGenericVideo(&ytVid)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, vid.ETag)
+
+	// Neither the parts listed nor the statistics change the version of a video
+	watched := ytVid
+	watched.Etag = "Bdx4f4ps3xCOOo1WZ91nTLkRZ_c"
+	watched.Statistics = &youtube.VideoStatistics{ViewCount: 100}
+	watchedVid, err := toGenericVideo(&watched)
+	assert.Nil(t, err)
+	assert.Equal(t, vid.ETag, watchedVid.ETag)
+
+	// Its attributes do
+	edited := ytVid
+	edited.Snippet = &youtube.VideoSnippet{PublishedAt: "2018-08-25T11:12:35Z", Title: "edited"}
+	editedVid, err := toGenericVideo(&edited)
+	assert.Nil(t, err)
+	assert.NotEqual(t, vid.ETag, editedVid.ETag)
+
+	playlist, err := toGenericPlaylist(&youtube.Playlist{
+		Etag:           "Bdx4f4ps3xCOOo1WZ91nTLkRZ_c",
+		ContentDetails: &youtube.PlaylistContentDetails{},
+		Snippet:        &youtube.PlaylistSnippet{PublishedAt: "2018-08-25T11:12:35Z"},
+		Status:         &youtube.PlaylistStatus{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "Bdx4f4ps3xCOOo1WZ91nTLkRZ_c", playlist.ETag)
+}
+
+func TestYoutubeVideoStore_List_TooManyIds(t *testing.T) {
+	ids := make([]string, MaxListIds+1)
+	_, err := YoutubeVideoStore{}.ListVideos(context.Background(), ids)
+	assert.NotNil(t, err)
+	_, err = YoutubeVideoStore{}.ListPlaylists(context.Background(), ids)
+	assert.NotNil(t, err)
+	// Nothing to list, the platform isn't called
+	videos, err := YoutubeVideoStore{}.ListVideos(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.Empty(t, videos)
+}
+
 func TestISO8601DurationToSeconds(t *testing.T) {
 	// Err
 	parsed, err := iSO8601DurationToSeconds("test")
@@ -204,3 +314,147 @@ func TestAssignDefault(t *testing.T) {
 	assignDefault(&opt)
 	assert.Equal(t, "24", opt.CategoryId)
 }
+
+func TestHandleGoogleApiError(t *testing.T) {
+	googleErr := func(code int, reason string) error {
+		return &googleapi.Error{Code: code, Errors: []googleapi.ErrorItem{{Reason: reason}}}
+	}
+	cases := []struct {
+		err  error
+		kind ErrorKind
+		code int
+	}{
+		{googleErr(404, "videoNotFound"), NotFound, http.StatusNotFound},
+		// Youtube reports quota errors as 403
+		{googleErr(403, "quotaExceeded"), QuotaExceeded, http.StatusTooManyRequests},
+		{googleErr(403, "forbidden"), Forbidden, http.StatusForbidden},
+		{googleErr(400, "invalidTitle"), InvalidMetadata, http.StatusBadRequest},
+		// Unknown reasons fall back on the status code
+		{googleErr(400, "somethingNew"), InvalidMetadata, http.StatusBadRequest},
+		{googleErr(503, "backendError"), Upstream, http.StatusBadGateway},
+		{fmt.Errorf("video with id 1 not found"), NotFound, http.StatusNotFound},
+		{fmt.Errorf("dial tcp: connection refused"), Upstream, http.StatusBadGateway},
+		// A revoked refresh token fails every call until the host is authorized again
+		{revokedToken, AuthExpired, http.StatusServiceUnavailable},
+		{&oauth2.RetrieveError{ErrorCode: "invalid_request"}, Upstream, http.StatusBadGateway},
+	}
+	for _, tc := range cases {
+		err := handleGoogleApiError(tc.err)
+		var re *RequestError
+		assert.True(t, errors.As(err, &re))
+		assert.Equal(t, tc.kind, KindOf(err), tc.err.Error())
+		assert.Equal(t, tc.code, re.StatusCode, tc.err.Error())
+		assert.ErrorIs(t, err, tc.err)
+	}
+}
+
+// What the Youtube client returns once the refresh token was revoked
+var revokedToken = &url.Error{Op: "Post", URL: "https://oauth2.googleapis.com/token", Err: &oauth2.RetrieveError{
+	ErrorCode:        "invalid_grant",
+	ErrorDescription: "Token has been expired or revoked.",
+}}
+
+// Token source refusing the refresh token, or returning a static token
+type fakeTokenSource struct {
+	err error
+}
+
+func (f fakeTokenSource) Token() (*oauth2.Token, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &oauth2.Token{AccessToken: "token"}, nil
+}
+
+func TestYoutubeVideoStore_Ping(t *testing.T) {
+	ctx := context.Background()
+	store := YoutubeVideoStore{tokens: &rotatingTokenSource{source: fakeTokenSource{}}}
+	assert.Nil(t, store.Ping(ctx))
+
+	store = YoutubeVideoStore{tokens: &rotatingTokenSource{source: fakeTokenSource{err: fmt.Errorf("invalid_grant")}}}
+	err := store.Ping(ctx)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid_grant")
+
+	store = YoutubeVideoStore{tokens: &rotatingTokenSource{source: fakeTokenSource{err: revokedToken}}}
+	err = store.Ping(ctx)
+	assert.Equal(t, AuthExpired, KindOf(err))
+	assert.Contains(t, err.Error(), "authorized again")
+
+	// Without credentials at all
+	assert.NotNil(t, YoutubeVideoStore{}.Ping(ctx))
+}
+
+func TestYoutubeVideoStore_OnAuthExpired(t *testing.T) {
+	tokens := &rotatingTokenSource{source: fakeTokenSource{err: revokedToken}}
+	store := YoutubeVideoStore{tokens: tokens}
+	var notified []error
+	store.OnAuthExpired(func(err error) { notified = append(notified, err) })
+
+	// Notified once, no matter how many calls fail
+	_, err := tokens.Token()
+	assert.ErrorIs(t, err, revokedToken)
+	_, _ = tokens.Token()
+	assert.Len(t, notified, 1)
+	assert.Equal(t, AuthExpired, KindOf(notified[0]))
+
+	// Once accepted again, the next refusal is notified as well
+	tokens.source = fakeTokenSource{}
+	_, err = tokens.Token()
+	assert.Nil(t, err)
+	tokens.source = fakeTokenSource{err: revokedToken}
+	_, _ = tokens.Token()
+	assert.Len(t, notified, 2)
+
+	// Other failures aren't an expiry
+	tokens.source = fakeTokenSource{err: fmt.Errorf("dial tcp: connection refused")}
+	_, _ = tokens.Token()
+	assert.Len(t, notified, 2)
+}
+
+func TestYoutubeVideoStore_RotateCredentials(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewYoutubeStore(ctx, &YoutubeStoreCredentials{ClientId: "id", ClientSecret: "secret", RefreshToken: "old"}, nil)
+	assert.Nil(t, err)
+	before := store.tokens.source
+
+	// Same values, nothing to rotate
+	assert.False(t, store.RotateCredentials(map[string]string{YoutubeSecretRefreshToken: "old"}))
+	assert.Same(t, before, store.tokens.source)
+
+	assert.True(t, store.RotateCredentials(map[string]string{YoutubeSecretRefreshToken: "new", "unrelated": "value"}))
+	assert.Equal(t, YoutubeStoreCredentials{ClientId: "id", ClientSecret: "secret", RefreshToken: "new"}, store.tokens.creds)
+	assert.NotSame(t, before, store.tokens.source)
+
+	// Without credentials at all
+	assert.False(t, YoutubeVideoStore{}.RotateCredentials(map[string]string{YoutubeSecretRefreshToken: "new"}))
+}
+
+func TestYoutubeStoreCredentials_WithSecret(t *testing.T) {
+	creds := YoutubeStoreCredentials{ClientId: "id", ClientSecret: "secret", RefreshToken: "token"}
+	assert.Equal(t, creds, creds.WithSecret(nil))
+	assert.Equal(t,
+		YoutubeStoreCredentials{ClientId: "id2", ClientSecret: "secret2", RefreshToken: "token2"},
+		creds.WithSecret(map[string]string{
+			YoutubeSecretClientId:     "id2",
+			YoutubeSecretClientSecret: "secret2",
+			YoutubeSecretRefreshToken: "token2",
+		}))
+	// The receiver is left untouched
+	assert.Equal(t, "id", creds.ClientId)
+}
+
+func TestYoutubeVideoStore_OAuthConfig(t *testing.T) {
+	store, err := NewYoutubeStore(context.Background(), &YoutubeStoreCredentials{ClientId: "id", ClientSecret: "secret"}, nil)
+	assert.Nil(t, err)
+	config := store.OAuthConfig("https://video-store.example.com/v1/auth/youtube/callback")
+	assert.Equal(t, "id", config.ClientID)
+	assert.Equal(t, "https://video-store.example.com/v1/auth/youtube/callback", config.RedirectURL)
+	assert.Contains(t, config.Scopes, youtube.YoutubeUploadScope)
+
+	// The rotated client credentials are used from now on
+	store.RotateCredentials(map[string]string{YoutubeSecretClientId: "id2"})
+	assert.Equal(t, "id2", store.OAuthConfig("").ClientID)
+	assert.Equal(t, []string{youtube.YoutubeScope}, store.DeviceOAuthConfig().Scopes)
+	assert.Equal(t, "id2", store.DeviceOAuthConfig().ClientID)
+}
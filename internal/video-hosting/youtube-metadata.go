@@ -0,0 +1,79 @@
+package video_hosting
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the metadata on Youtube
+// https://developers.google.com/youtube/v3/docs/videos#properties
+// https://developers.google.com/youtube/v3/docs/playlists#properties
+const (
+	youtubeMaxVideoTitleLength    = 100
+	youtubeMaxPlaylistTitleLength = 150
+	youtubeMaxDescriptionBytes    = 5000
+	// Budget of all the tags of a video, see youtubeTagsLength
+	youtubeMaxTagsLength = 500
+	// Characters refused in titles, descriptions and tags
+	youtubeForbiddenChars = "<>"
+)
+
+// ValidateVideo Check the metadata of a video against the limits of Youtube
+func (ytP YoutubeVideoStore) ValidateVideo(meta *ItemMetadata) error {
+	return NewValidationError(youtubeItemErrors(meta, youtubeMaxVideoTitleLength))
+}
+
+// ValidatePlaylist Check the metadata of a playlist against the limits of Youtube
+func (ytP YoutubeVideoStore) ValidatePlaylist(meta *ItemMetadata) error {
+	return NewValidationError(youtubeItemErrors(meta, youtubeMaxPlaylistTitleLength))
+}
+
+// ValidateTags Check the keywords of a video against the limits of Youtube
+func (ytP YoutubeVideoStore) ValidateTags(tags []string) error {
+	var fields []FieldError
+	for i, tag := range tags {
+		if strings.ContainsAny(tag, youtubeForbiddenChars) {
+			fields = append(fields, FieldError{Field: fmt.Sprintf("tags[%d]", i), Reason: "must not contain < or >"})
+		}
+	}
+	if length := youtubeTagsLength(tags); length > youtubeMaxTagsLength {
+		fields = append(fields, FieldError{Field: "tags", Reason: fmt.Sprintf("must be at most %d characters long in total, is %d", youtubeMaxTagsLength, length)})
+	}
+	return NewValidationError(fields)
+}
+
+// Fields of meta refused by Youtube, the title being at most maxTitle characters long
+func youtubeItemErrors(meta *ItemMetadata, maxTitle int) []FieldError {
+	var fields []FieldError
+	if length := utf8.RuneCountInString(meta.Title); length > maxTitle {
+		fields = append(fields, FieldError{Field: "title", Reason: fmt.Sprintf("must be at most %d characters long, is %d", maxTitle, length)})
+	}
+	if strings.ContainsAny(meta.Title, youtubeForbiddenChars) {
+		fields = append(fields, FieldError{Field: "title", Reason: "must not contain < or >"})
+	}
+	// Youtube counts bytes, not characters
+	if length := len(meta.Description); length > youtubeMaxDescriptionBytes {
+		fields = append(fields, FieldError{Field: "description", Reason: fmt.Sprintf("must be at most %d bytes long, is %d", youtubeMaxDescriptionBytes, length)})
+	}
+	if strings.ContainsAny(meta.Description, youtubeForbiddenChars) {
+		fields = append(fields, FieldError{Field: "description", Reason: "must not contain < or >"})
+	}
+	return fields
+}
+
+// Length of tags as counted by Youtube : a comma separates each tag,
+// and a tag containing a space is counted with the quotes surrounding it
+func youtubeTagsLength(tags []string) int {
+	length := 0
+	for i, tag := range tags {
+		if i > 0 {
+			length++
+		}
+		length += utf8.RuneCountInString(tag)
+		if strings.Contains(tag, " ") {
+			length += 2
+		}
+	}
+	return length
+}
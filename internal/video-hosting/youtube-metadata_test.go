@@ -0,0 +1,53 @@
+package video_hosting
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestYoutubeVideoStore_ValidateVideo(t *testing.T) {
+	store := YoutubeVideoStore{}
+	assert.Nil(t, store.ValidateVideo(&ItemMetadata{Title: "Session 1", Description: "The first session", Visibility: Public}))
+	// The title is counted in characters
+	assert.Nil(t, store.ValidateVideo(&ItemMetadata{Title: strings.Repeat("é", 100)}))
+
+	err := store.ValidateVideo(&ItemMetadata{Title: strings.Repeat("a", 101) + "<b>", Description: "<b>"})
+	assert.Equal(t, InvalidMetadata, KindOf(err))
+	assert.Equal(t, []FieldError{
+		{Field: "title", Reason: "must be at most 100 characters long, is 104"},
+		{Field: "title", Reason: "must not contain < or >"},
+		{Field: "description", Reason: "must not contain < or >"},
+	}, FieldErrors(err))
+}
+
+func TestYoutubeVideoStore_ValidateVideo_DescriptionBytes(t *testing.T) {
+	store := YoutubeVideoStore{}
+	assert.Nil(t, store.ValidateVideo(&ItemMetadata{Title: "a", Description: strings.Repeat("a", 5000)}))
+	// 2500 characters, but 5002 bytes
+	err := store.ValidateVideo(&ItemMetadata{Title: "a", Description: strings.Repeat("é", 2501)})
+	assert.Equal(t, []FieldError{{Field: "description", Reason: "must be at most 5000 bytes long, is 5002"}}, FieldErrors(err))
+}
+
+func TestYoutubeVideoStore_ValidatePlaylist(t *testing.T) {
+	store := YoutubeVideoStore{}
+	// Playlists titles are longer than the videos ones
+	assert.Nil(t, store.ValidatePlaylist(&ItemMetadata{Title: strings.Repeat("a", 150)}))
+	err := store.ValidatePlaylist(&ItemMetadata{Title: strings.Repeat("a", 151)})
+	assert.Equal(t, []FieldError{{Field: "title", Reason: "must be at most 150 characters long, is 151"}}, FieldErrors(err))
+}
+
+func TestYoutubeVideoStore_ValidateTags(t *testing.T) {
+	store := YoutubeVideoStore{}
+	assert.Nil(t, store.ValidateTags(nil))
+	assert.Nil(t, store.ValidateTags([]string{"rpg", "tabletop games"}))
+	// 5 tags of 99 characters and 4 commas
+	assert.Nil(t, store.ValidateTags([]string{strings.Repeat("a", 99), strings.Repeat("b", 99), strings.Repeat("c", 99), strings.Repeat("d", 99), strings.Repeat("e", 100)}))
+
+	// The quotes around a tag with a space count as well
+	err := store.ValidateTags([]string{strings.Repeat("a", 99), strings.Repeat("b", 99), strings.Repeat("c", 99), strings.Repeat("d", 99), strings.Repeat("e", 98) + " "})
+	assert.Equal(t, []FieldError{{Field: "tags", Reason: "must be at most 500 characters long in total, is 501"}}, FieldErrors(err))
+
+	err = store.ValidateTags([]string{"rpg", "<script>"})
+	assert.Equal(t, []FieldError{{Field: "tags[1]", Reason: "must not contain < or >"}}, FieldErrors(err))
+}
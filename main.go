@@ -3,42 +3,65 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/dapr/go-sdk/client"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"net"
+	"net/http"
 	"os"
-	"strconv"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
+	catalog_controller "video-manager/controller/catalog"
+	commands_controller "video-manager/controller/commands"
+	config_controller "video-manager/controller/config"
+	health_controller "video-manager/controller/health"
+	hosts_controller "video-manager/controller/hosts"
+	oauth_controller "video-manager/controller/oauth"
 	playlists_controller "video-manager/controller/playlists"
+	publish_controller "video-manager/controller/publish"
+	quota_controller "video-manager/controller/quota"
+	templates_controller "video-manager/controller/templates"
 	videos_controller "video-manager/controller/videos"
 	_ "video-manager/docs"
+	"video-manager/internal/auth"
+	"video-manager/internal/catalog"
+	"video-manager/internal/config"
+	credentials_store "video-manager/internal/credentials-store"
+	event_broker "video-manager/internal/event-broker"
+	"video-manager/internal/health"
 	"video-manager/internal/logger"
+	"video-manager/internal/metrics"
+	oauth_consent "video-manager/internal/oauth-consent"
 	object_storage "video-manager/internal/object-storage"
+	"video-manager/internal/problem"
 	progress_broker "video-manager/internal/progress-broker"
+	rate_limiter "video-manager/internal/rate-limiter"
+	secret_store "video-manager/internal/secret-store"
+	"video-manager/internal/templates"
+	"video-manager/internal/tracing"
 	video_store_service "video-manager/pkg/video-store-service"
 )
 
 const (
-	DefaultAppPort              = 8080
-	DefaultDaprGrpcPort         = 50001
-	DefaultDaprMaxRequestSizeMb = 2000
+	// Time left to the server to answer the requests of the interrupted uploads, once the drain timeout elapsed
+	ShutdownGracePeriod = 5 * time.Second
 	// env
-	APP_PORT                 = "APP_PORT"
-	DAPR_GRPC_PORT           = "DAPR_GRPC_PORT"
-	DAPR_MAX_REQUEST_SIZE_MB = "DAPR_MAX_REQUEST_SIZE_MB"
-	OBJECT_STORE_NAME        = "OBJECT_STORE_NAME"
-	GIN_MODE                 = "GIN_MODE"
-	PUBSUB_NAME              = "PUBSUB_NAME"
-	PUBSUB_TOPIC_PROGRESS    = "PUBSUB_TOPIC_PROGRESS"
-
-	// Topic to send progress event into
-	DefaultPubSubTopic = "upload-state"
+	// Path of the YAML or TOML configuration file
+	CONFIG_FILE = "CONFIG_FILE"
+
+	// Route the Dapr sidecar will deliver commands to
+	CommandsRoute = "/v1/commands"
 )
 
 var (
@@ -51,18 +74,50 @@ var (
 // @host      localhost:8080
 // @BasePath  /
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description Static API key
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT bearer token, as "Bearer <token>"
+
 func main() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Info("No .env file loaded !")
 	}
+	cfg := resolveConfig()
 	// Env is loaded after gin is initialized, we must set it manually
-	if os.Getenv(GIN_MODE) == "release" {
-		gin.SetMode(gin.ReleaseMode)
+	if cfg.Server.GinMode != "" {
+		gin.SetMode(cfg.Server.GinMode)
 	}
 	ctx := context.Background()
-	vidCtrl, playlistCtrl := resolveDI(&ctx)
+	shutdownTracing := resolveTracing(ctx, cfg)
+	defer func() {
+		if err := shutdownTracing(ctx); err != nil {
+			log.Errorf("Could not flush the remaining spans : %s", err.Error())
+		}
+	}()
+	if len(os.Args) > 1 && os.Args[1] == AuthorizeCommand {
+		runAuthorize(ctx, cfg, os.Args[2:])
+		return
+	}
+	vidCtrl, playlistCtrl, cmdCtrl, quotaCtrl, hostsCtrl, catalogCtrl, publishCtrl, templatesCtrl, healthCtrl, consents := resolveDI(&ctx, cfg)
+	cfgCtrl := config_controller.ConfigController{Config: cfg}
+	authn := resolveAuthenticator(cfg)
+	// The rate limiter is always placed after the authentication, to tell the clients apart
+	limiter := resolveRateLimiter(cfg)
 	router := gin.Default()
+	// Otherwise, any client could pick its own IP, and its rate limit with it
+	if err := router.SetTrustedProxies(cfg.Server.Proxies()); err != nil {
+		log.Fatalf("Error during init : %s", err.Error())
+	}
+	// Handlers use the gin context as the request context, this allows them to access the request span
+	router.ContextWithFallback = true
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName))
 
 	router.Use(func() gin.HandlerFunc {
 		// Change default logger to an ecs compliant one
@@ -85,112 +140,429 @@ func main() {
 			return buf.String()
 		})
 	}())
+	router.Use(metrics.Handler())
+	// Errors recorded by any handler are rendered as problem details
+	router.Use(problem.Handler())
 
 	// Define all routes
 	v1 := router.Group("/v1")
 	{
-		videos := v1.Group("/videos")
-		{
-			videos.POST("", vidCtrl.Create)
-			videos.GET(":id", vidCtrl.Retrieve)
-			videos.PUT(":id", vidCtrl.Update)
-			videos.DELETE(":id", vidCtrl.Delete)
-			videos.POST(":id/thumbnail/:tId", vidCtrl.SetThumbnail)
+		v1.GET("hosts", authn.Require(auth.HostsRead), limiter.Handler(), hostsCtrl.List)
+		// The host specific routes are served for the host of the X-Video-Host header, and under /v1/hosts/:host.
+		// The host is only selected once authenticated, not to tell anyone which hosts exist
+		for _, group := range []*gin.RouterGroup{v1.Group(""), v1.Group("/hosts/:host")} {
+			videos := group.Group("/videos")
+			{
+				videos.POST("", authn.Require(auth.VideosWrite), hostsCtrl.Select, limiter.Handler(), vidCtrl.Create)
+				videos.POST("upload", authn.Require(auth.VideosWrite), hostsCtrl.Select, limiter.Handler(), vidCtrl.Upload)
+				videos.GET(":id", authn.Require(auth.VideosRead), hostsCtrl.Select, limiter.Handler(), vidCtrl.Retrieve)
+				videos.PUT(":id", authn.Require(auth.VideosWrite), hostsCtrl.Select, limiter.Handler(), vidCtrl.Update)
+				videos.PATCH(":id", authn.Require(auth.VideosWrite), hostsCtrl.Select, limiter.Handler(), vidCtrl.Patch)
+				videos.DELETE(":id", authn.Require(auth.VideosWrite), hostsCtrl.Select, limiter.Handler(), vidCtrl.Delete)
+				videos.POST(":id/thumbnail/:tId", authn.Require(auth.VideosWrite), hostsCtrl.Select, limiter.Handler(), vidCtrl.SetThumbnail)
+				videos.GET(":id/stats", authn.Require(auth.VideosRead), hostsCtrl.Select, limiter.Handler(), vidCtrl.Stats)
+			}
+			group.GET("stats", authn.Require(auth.VideosRead), hostsCtrl.Select, limiter.Handler(), vidCtrl.ListStats)
+			playlists := group.Group("/playlists")
+			{
+				playlists.POST("", authn.Require(auth.PlaylistsWrite), hostsCtrl.Select, limiter.Handler(), playlistCtrl.Create)
+				playlists.GET(":id", authn.Require(auth.PlaylistsRead), hostsCtrl.Select, limiter.Handler(), playlistCtrl.Retrieve)
+				playlists.PUT(":id", authn.Require(auth.PlaylistsWrite), hostsCtrl.Select, limiter.Handler(), playlistCtrl.Update)
+				playlists.PATCH(":id", authn.Require(auth.PlaylistsWrite), hostsCtrl.Select, limiter.Handler(), playlistCtrl.Patch)
+				playlists.DELETE(":id", authn.Require(auth.PlaylistsWrite), hostsCtrl.Select, limiter.Handler(), playlistCtrl.Delete)
+				playlists.PUT(":id/videos/:vid", authn.Require(auth.PlaylistsWrite), hostsCtrl.Select, limiter.Handler(), playlistCtrl.AddVideo)
+			}
+			group.GET("quota", authn.Require(auth.QuotaRead), hostsCtrl.Select, limiter.Handler(), quotaCtrl.Retrieve)
+			records := group.Group("/catalog")
+			{
+				records.GET("videos", authn.Require(auth.VideosRead), hostsCtrl.Select, limiter.Handler(), catalogCtrl.ListVideos)
+				records.GET("videos/:id", authn.Require(auth.VideosRead), hostsCtrl.Select, limiter.Handler(), catalogCtrl.RetrieveVideo)
+				records.GET("playlists", authn.Require(auth.PlaylistsRead), hostsCtrl.Select, limiter.Handler(), catalogCtrl.ListPlaylists)
+				records.GET("playlists/:id", authn.Require(auth.PlaylistsRead), hostsCtrl.Select, limiter.Handler(), catalogCtrl.RetrievePlaylist)
+			}
+			group.GET("reconcile/report", authn.Require(auth.ReconcileRead), hostsCtrl.Select, limiter.Handler(), catalogCtrl.ReconcileReport)
+			group.POST("batch", authn.Require(auth.VideosWrite, auth.PlaylistsWrite), hostsCtrl.Select, limiter.Handler(), cmdCtrl.Batch)
+			group.POST("publish", authn.Require(auth.VideosWrite, auth.PlaylistsWrite), hostsCtrl.Select, limiter.Handler(), publishCtrl.Publish)
+			group.POST("templates/:name/render", authn.Require(auth.VideosRead), hostsCtrl.Select, limiter.Handler(), templatesCtrl.Render)
 		}
-		playlists := v1.Group("/playlists")
-		{
-			playlists.POST("", playlistCtrl.Create)
-			playlists.GET(":id", playlistCtrl.Retrieve)
-			playlists.PUT(":id", playlistCtrl.Update)
-			playlists.DELETE(":id", playlistCtrl.Delete)
-			playlists.PUT(":id/videos/:vid", playlistCtrl.AddVideo)
+		v1.POST("commands", authn.Require(auth.CommandsWrite), cmdCtrl.Handle)
+		// Route of the "uploads" subscription, see dapr/components/subscribe-to-queue.yml
+		v1.POST("commands/uploads", authn.Require(auth.CommandsWrite), cmdCtrl.HandleUpload)
+		v1.GET("config", authn.Require(auth.ConfigRead), limiter.Handler(), cfgCtrl.Retrieve)
+		if oauthCtrl := consents[cfg.Host().Name]; oauthCtrl != nil {
+			consent := v1.Group("/auth/youtube")
+			{
+				consent.GET("start", authn.Require(auth.HostsAuthorize), limiter.Handler(), oauthCtrl.Start)
+				// Google redirects the user here, the single-use state stands for the authentication
+				consent.GET("callback", limiter.Handler(), oauthCtrl.Callback)
+			}
+		}
+		if len(consents) > 0 {
+			consent := v1.Group("/hosts/:host/auth/youtube")
+			{
+				consent.GET("start", authn.Require(auth.HostsAuthorize), limiter.Handler(), consents.Start)
+				consent.GET("callback", limiter.Handler(), consents.Callback)
+			}
 		}
 	}
+	// Dapr programmatic subscriptions, routing the commands topic to the handler above
+	router.GET("/dapr/subscribe", cmdCtrl.Subscribe)
 
+	// Kubernetes probes
+	router.GET("/healthz", healthCtrl.Live)
+	router.GET("/readyz", healthCtrl.Ready)
+	router.GET("/metrics", metrics.Endpoint())
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
-	appPort := DefaultAppPort
-	if envPort, err := strconv.ParseInt(os.Getenv("APP_PORT"), 10, 32); err == nil && envPort != 0 {
-		appPort = int(envPort)
+	drainTimeout := time.Duration(cfg.Shutdown.DrainTimeout)
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", cfg.Server.Port), Handler: router}
+	go func() {
+		log.Infof("Server listening to 0.0.0.0:%d", cfg.Server.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf(err.Error())
+		}
+	}()
+
+	// Wait for the orchestrator to stop us
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	<-sigCtx.Done()
+	stop()
+	log.Infof("Shutting down, waiting up to %s for the running uploads", drainTimeout)
+
+	// New uploads are refused from now on, and the remaining ones are interrupted after the drain timeout.
+	// The server must stay up meanwhile, the running uploads are still answering their requests
+	drainCtx, cancelDrain := context.WithTimeout(ctx, drainTimeout)
+	defer cancelDrain()
+	drained := make(chan []string, 1)
+	go func() { drained <- hostsCtrl.Tenants.Drain(drainCtx) }()
+	shutdownCtx, cancelShutdown := context.WithTimeout(ctx, drainTimeout+ShutdownGracePeriod)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("Could not shut down the server gracefully : %s", err.Error())
+	}
+	if interrupted := <-drained; len(interrupted) > 0 {
+		log.Warnf("%d uploads interrupted, they must be sent again : %s", len(interrupted), strings.Join(interrupted, ", "))
 	}
+	log.Infof("Server stopped")
+}
 
-	log.Infof("Server listening to 0.0.0.0:%d", appPort)
-	err = router.Run(fmt.Sprintf(":%d", appPort))
+// Load and validate the configuration, exiting with all the problems found if it is invalid
+func resolveConfig() *config.Config {
+	path := os.Getenv(CONFIG_FILE)
+	cfg, err := config.Load(path)
 	if err != nil {
-		log.Fatalf(err.Error())
+		log.Fatalf("Error during init : %s", err.Error())
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Error during init, invalid configuration :\n%s", err.Error())
+	}
+	if path != "" {
+		log.Infof(`Configuration loaded from "%s"`, path)
 	}
+	return cfg
 }
 
 // Resolve the pseudo DI-container
-func resolveDI(ctx *context.Context) (videos_controller.VideoController[client.Client, client.Client], playlists_controller.PlaylistController[client.Client, client.Client]) {
+func resolveDI(ctx *context.Context, cfg *config.Config) (videos_controller.VideoController[client.Client, client.Client], playlists_controller.PlaylistController[client.Client, client.Client], commands_controller.CommandController[client.Client, client.Client], quota_controller.QuotaController[client.Client, client.Client], hosts_controller.HostsController[client.Client, client.Client], catalog_controller.CatalogController[client.Client, client.Client], publish_controller.PublishController[client.Client, client.Client], templates_controller.TemplatesController[client.Client, client.Client], health_controller.HealthController, oauth_controller.HostConsents) {
 	// From bottom to top:
 	// Make a new Dapr instance
-	daprMaxRqSize := DefaultDaprMaxRequestSizeMb
-	if sizeStr, ok := os.LookupEnv(DAPR_MAX_REQUEST_SIZE_MB); ok {
-		if size, err := strconv.ParseUint(sizeStr, 10, 64); err != nil {
-			daprMaxRqSize = int(size)
-		} else {
-			log.Warnf("Invalid dapr max request size supplied %s, defaulting to %d", sizeStr, DefaultAppPort)
-		}
-	}
-	proxy, err := makeDaprClient(daprMaxRqSize)
+	proxy, err := makeDaprClient(cfg.Dapr.GrpcPort, cfg.Dapr.MaxRequestSizeMb)
 	if err != nil {
 		log.Fatalf("Error during init : %s", err.Error())
 	}
 
 	// Resolve the required object storage backend
-	storeName := ""
-	if storeName = os.Getenv(OBJECT_STORE_NAME); storeName == "" {
-		log.Fatalf("Error during init : No dapr store defined !")
+	objStore, err := object_storage.NewDaprObjectStorage(proxy, cfg.Dapr.ObjectStore)
+	if err != nil {
+		log.Fatalf("Error during init : %s", err.Error())
+	}
+
+	// We can then resolve a video store service per host, all sharing the same object storage...
+	services := make(map[string]*video_store_service.VideoStoreService[client.Client, client.Client], len(cfg.Hosts))
+	for i := range cfg.Hosts {
+		host := &cfg.Hosts[i]
+		services[host.Name] = resolveHostService(*ctx, cfg, proxy, objStore, host)
 	}
-	objStore, err := object_storage.NewDaprObjectStorage(ctx, proxy, storeName)
+	tenants, err := video_store_service.NewTenants(cfg.Host().Name, services)
 	if err != nil {
 		log.Fatalf("Error during init : %s", err.Error())
 	}
+	storeService := tenants.Default()
+	log.Infof(`Serving the API with the hosts %s, "%s" by default`, strings.Join(tenants.Names(), ", "), tenants.DefaultName())
+	var consents oauth_controller.HostConsents
+	if cfg.OAuth.StateStore != "" {
+		credentialsStore := credentials_store.NewDaprCredentialsStore(proxy, cfg.OAuth.StateStore)
+		for i := range cfg.Hosts {
+			resolveSavedCredentials(*ctx, cfg, credentialsStore, &cfg.Hosts[i], services[cfg.Hosts[i].Name])
+		}
+		consents = resolveConsents(cfg, credentialsStore, services)
+	}
+	// With in turn give us the controllers
+	vCtrl := videos_controller.VideoController[client.Client, client.Client]{
+		Service: storeService,
+		// Videos sent directly have the same limit as the ones received from the object storage
+		MaxUploadSize: int64(cfg.Dapr.MaxRequestSizeMb) * 1024 * 1024,
+	}
+	pCtrl := playlists_controller.PlaylistController[client.Client, client.Client]{Service: storeService}
+	cCtrl := commands_controller.CommandController[client.Client, client.Client]{Service: storeService, Tenants: tenants, Subscriptions: resolveSubscriptions(cfg)}
+	qCtrl := quota_controller.QuotaController[client.Client, client.Client]{Service: storeService}
+	hostsCtrl := hosts_controller.HostsController[client.Client, client.Client]{Tenants: tenants}
+	catCtrl := catalog_controller.CatalogController[client.Client, client.Client]{Service: storeService}
+	pubCtrl := publish_controller.PublishController[client.Client, client.Client]{Service: storeService}
+	tplCtrl := templates_controller.TemplatesController[client.Client, client.Client]{Service: storeService}
+	hCtrl := health_controller.HealthController{Checker: resolveHealthChecker(cfg, proxy, objStore, tenants)}
+	return vCtrl, pCtrl, cCtrl, qCtrl, hostsCtrl, catCtrl, pubCtrl, tplCtrl, hCtrl, consents
+}
 
-	// Resolve the optional event broker to send upload progress
+// Resolve the video store service of a host, with its own event brokers and quota tracking
+func resolveHostService(ctx context.Context, cfg *config.Config, proxy *client.Client, objStore *object_storage.ObjectStorage[client.Client], host *config.Host) *video_store_service.VideoStoreService[client.Client, client.Client] {
+	// Resolve the optional event brokers to send upload progress and lifecycle events
 	var progressBroker *progress_broker.ProgressBroker[client.Client]
-	pubsubName := ""
-	if pubsubName = os.Getenv(PUBSUB_NAME); pubsubName != "" {
-		topic, exists := os.LookupEnv(PUBSUB_TOPIC_PROGRESS)
-		if !exists {
-			topic = DefaultPubSubTopic
-		}
-		log.Infof(`Initializing pubsub with name "%s" and topic "%s"`, pubsubName, topic)
-		progressBroker, err = progress_broker.NewProgressBroker[client.Client](ctx, proxy, progress_broker.NewBrokerOptions{
+	var eventBroker *event_broker.EventBroker[client.Client]
+	var err error
+	if pubsubName := cfg.PubSub.Name; pubsubName != "" {
+		log.Infof(`Initializing pubsub of host "%s" with name "%s" and topic "%s"`, host.Name, pubsubName, host.ProgressTopic)
+		progressBroker, err = progress_broker.NewProgressBroker[client.Client](proxy, progress_broker.NewBrokerOptions{
 			Component: pubsubName,
-			Topic:     topic,
+			Topic:     host.ProgressTopic,
+			Host:      host.Name,
+		})
+		if err != nil {
+			log.Fatalf("Couldn't init pubsub : %s", err.Error())
+		}
+		log.Infof(`Lifecycle events of host "%s" will be published on topic "%s"`, host.Name, host.EventsTopic)
+		eventBroker, err = event_broker.NewEventBroker[client.Client](proxy, event_broker.NewBrokerOptions{
+			Component: pubsubName,
+			Topic:     host.EventsTopic,
+			Host:      host.Name,
 		})
 		if err != nil {
 			log.Fatalf("Couldn't init pubsub : %s", err.Error())
 		}
-		log.Infof("Pubsub initialized")
 	} else {
-		log.Infof("No pubsub name provided. Skipping pubsub initialization")
+		log.Infof(`No pubsub name provided. Skipping pubsub initialization of host "%s"`, host.Name)
 	}
 
-	// We can then resolve the video store service...
-	storeService, err := video_store_service.MakeVideoStoreService[client.Client](*ctx, video_store_service.Youtube, *objStore, progressBroker)
-	// With in turn give us the controllers
-	vCtrl := videos_controller.VideoController[client.Client, client.Client]{Service: storeService}
-	pCtrl := playlists_controller.PlaylistController[client.Client, client.Client]{Service: storeService}
-	return vCtrl, pCtrl
+	log.Infof(`Initializing the %s host "%s"`, host.Type, host.Name)
+	storeService, err := video_store_service.MakeVideoStoreService[client.Client](ctx, *host, *objStore, progressBroker, eventBroker)
+	if err != nil {
+		log.Fatalf("Error during init : %s", err.Error())
+	}
+	storeService.ProcessingInterval = time.Duration(cfg.Processing.PollInterval)
+	storeService.ProcessingTimeout = time.Duration(cfg.Processing.Timeout)
+	if cfg.Catalog.Store != "" {
+		storeService.Catalog = catalog.NewDaprCatalog(proxy, cfg.Catalog.Store)
+		if interval := time.Duration(cfg.Catalog.ReconcileInterval); interval > 0 {
+			log.Infof(`The catalog of host "%s" will be reconciled with it every %s`, host.Name, interval)
+			go storeService.RunReconciler(ctx, interval)
+		}
+	}
+	storeService.Templates = templates.NewDaprTemplates(cfg.Templates.Items, proxy, cfg.Templates.Store)
+	if host.SecretName != "" {
+		resolveHostSecret(ctx, cfg, proxy, host, storeService)
+	}
+	return storeService
 }
 
-// Make a custom dapr client with a large max request size, to handle large uploads
-func makeDaprClient(maxRequestSizeMB int) (*client.Client, error) {
-	var opts []grpc.CallOption
+// Load the credentials of a host from the secret store, then keep them up to date in the background
+func resolveHostSecret(ctx context.Context, cfg *config.Config, proxy *client.Client, host *config.Host, storeService *video_store_service.VideoStoreService[client.Client, client.Client]) {
+	if storeService.HostCredentials == nil {
+		log.Fatalf(`Error during init : the %s host "%s" can't read its credentials from a secret store`, host.Type, host.Name)
+	}
+	secretStore := secret_store.NewDaprSecretStore(proxy, cfg.Secrets.Store)
+	secret, err := secretStore.Get(ctx, host.SecretName)
+	if err != nil {
+		log.Fatalf(`Error during init : could not read secret "%s" from store "%s" : %s`, host.SecretName, cfg.Secrets.Store, err.Error())
+	}
+	storeService.HostCredentials.RotateCredentials(secret)
+	log.Infof(`Credentials of host "%s" loaded from secret "%s"`, host.Name, host.SecretName)
+
+	interval := time.Duration(cfg.Secrets.RefreshInterval)
+	if interval == 0 {
+		return
+	}
+	go secret_store.Watch(ctx, secretStore, host.SecretName, secret, interval, func(secret map[string]string) {
+		if storeService.HostCredentials.RotateCredentials(secret) {
+			log.Infof(`Credentials of host "%s" rotated`, host.Name)
+		}
+	})
+}
 
-	// Getting dapr grpc port. By default, its 500001
-	port := DefaultDaprGrpcPort
-	// But the sidecar published a env variable with the real value
-	// So we can override the value if it's defined
-	if envPort, err := strconv.ParseInt(os.Getenv(DAPR_GRPC_PORT), 10, 32); err != nil && envPort != 0 {
-		port = int(envPort)
+// Load the credentials of a host saved after a consent, if any, then keep them up to date in the background.
+// They take precedence over the configuration and the secret store
+func resolveSavedCredentials(ctx context.Context, cfg *config.Config, credentialsStore *credentials_store.CredentialsStore[client.Client], host *config.Host, storeService *video_store_service.VideoStoreService[client.Client, client.Client]) {
+	if storeService.HostCredentials == nil {
+		return
 	}
+	saved, err := credentialsStore.Get(ctx, host.Name)
+	if err != nil {
+		log.Fatalf(`Error during init : could not read the saved credentials from store "%s" : %s`, cfg.OAuth.StateStore, err.Error())
+	}
+	if saved != nil {
+		storeService.HostCredentials.RotateCredentials(saved)
+		log.Infof(`Credentials of host "%s" loaded from state store "%s"`, host.Name, cfg.OAuth.StateStore)
+	}
+
+	// Consents given with the CLI are picked up as well
+	interval := time.Duration(cfg.Secrets.RefreshInterval)
+	if interval == 0 {
+		return
+	}
+	go secret_store.Watch(ctx, credentialsStore, host.Name, saved, interval, func(secret map[string]string) {
+		if storeService.HostCredentials.RotateCredentials(secret) {
+			log.Infof(`Credentials of host "%s" rotated`, host.Name)
+		}
+	})
+}
+
+// Resolve the OAuth consent routes of each host supporting them. Empty if they are disabled
+func resolveConsents(cfg *config.Config, credentialsStore *credentials_store.CredentialsStore[client.Client], services map[string]*video_store_service.VideoStoreService[client.Client, client.Client]) oauth_controller.HostConsents {
+	if cfg.OAuth.RedirectUrl == "" {
+		log.Infof("No OAuth redirect URL provided. The consent routes are disabled")
+		return nil
+	}
+	consents := make(oauth_controller.HostConsents, len(cfg.Hosts))
+	for _, host := range cfg.Hosts {
+		storeService := services[host.Name]
+		if storeService.HostAuthorizer == nil || storeService.HostCredentials == nil {
+			log.Warnf(`The %s host "%s" doesn't support the OAuth consent, its consent routes are disabled`, host.Type, host.Name)
+			continue
+		}
+		// The default host keeps the callback it always had
+		redirectUrl := cfg.OAuth.RedirectUrl
+		if host.Name != cfg.Host().Name {
+			var ok bool
+			if redirectUrl, ok = oauth_controller.HostRedirectUrl(cfg.OAuth.RedirectUrl, host.Name); !ok {
+				log.Warnf(`OAUTH_REDIRECT_URL doesn't end with %s, the consent routes of host "%s" are disabled`, oauth_controller.CallbackPath, host.Name)
+				continue
+			}
+		}
+		log.Infof(`OAuth consent enabled for host "%s", redirecting to "%s"`, host.Name, redirectUrl)
+		authorizer := storeService.HostAuthorizer
+		consents[host.Name] = &oauth_controller.OAuthController{
+			Host: host.Name,
+			Consent: oauth_consent.NewConsent(func() *oauth2.Config {
+				return authorizer.OAuthConfig(redirectUrl)
+			}, oauth_consent.DefaultTTL),
+			Store:       credentialsStore,
+			Credentials: storeService.HostCredentials,
+		}
+	}
+	return consents
+}
+
+// Resolve all the dependencies checked by the readiness probe
+func resolveHealthChecker(cfg *config.Config, proxy *client.Client, objStore *object_storage.ObjectStorage[client.Client], tenants *video_store_service.Tenants[client.Client, client.Client]) *health.Checker {
+	metadata := (*proxy).GrpcClient()
+	checks := []health.Check{
+		health.DaprSidecar(metadata),
+		// Invoking the binding also fails if the component isn't loaded
+		{Name: "object-store", Run: objStore.Ping},
+	}
+	if cfg.PubSub.Name != "" {
+		checks = append(checks, health.DaprComponent("pubsub", metadata, cfg.PubSub.Name, "pubsub"))
+	}
+	if cfg.OAuth.StateStore != "" {
+		checks = append(checks, health.DaprComponent("state-store", metadata, cfg.OAuth.StateStore, "state"))
+	}
+	if cfg.Catalog.Store != "" {
+		checks = append(checks, health.DaprComponent("catalog", metadata, cfg.Catalog.Store, "state"))
+	}
+	if cfg.Templates.Store != "" {
+		checks = append(checks, health.DaprComponent("templates", metadata, cfg.Templates.Store, "state"))
+	}
+	// The default host keeps the historical check name
+	hostChecks := make(map[string]string)
+	for _, name := range tenants.Names() {
+		storeService, _ := tenants.Get(name)
+		if storeService.HostHealth == nil {
+			continue
+		}
+		checkName := "video-host"
+		if name != tenants.DefaultName() {
+			checkName = "video-host:" + name
+		}
+		hostChecks[name] = checkName
+		checks = append(checks, health.Check{Name: checkName, Run: storeService.HostHealth.Ping})
+	}
+	checker := health.NewChecker(health.Options{CacheTTL: time.Duration(cfg.Health.CacheTTL)}, checks...)
+	// Refused credentials are reported right away, without waiting for the cached result to expire
+	for name, checkName := range hostChecks {
+		storeService, _ := tenants.Get(name)
+		if storeService.HostAuth == nil {
+			continue
+		}
+		checkName := checkName
+		// Asynchronously, as the health check itself may be the one noticing it
+		storeService.HostAuth.OnAuthExpired(func(error) { go checker.Invalidate(checkName) })
+	}
+	return checker
+}
+
+// Resolve the topics to receive commands from.
+// Commands are received from the same pubsub as the events, unless another one is explicitly defined
+func resolveSubscriptions(cfg *config.Config) []commands_controller.Subscription {
+	pubsubName := cfg.PubSub.CommandsName
+	if pubsubName == "" {
+		log.Infof("No pubsub name provided. Commands won't be received from any topic")
+		return nil
+	}
+	log.Infof(`Receiving commands from pubsub "%s" on topic "%s"`, pubsubName, cfg.PubSub.CommandsTopic)
+	return []commands_controller.Subscription{{PubsubName: pubsubName, Topic: cfg.PubSub.CommandsTopic, Route: CommandsRoute}}
+}
+
+// Resolve the authentication method(s) of the API
+func resolveAuthenticator(cfg *config.Config) *auth.Authenticator {
+	keys, err := auth.ParseApiKeys(cfg.Auth.ApiKeys)
+	if err != nil {
+		log.Fatalf("Error during init : auth.apiKeys (AUTH_API_KEYS) : %s", err.Error())
+	}
+	authn, err := auth.NewAuthenticator(auth.Options{
+		ApiKeys:      keys,
+		JwksLocation: cfg.Auth.JwksUrl,
+		Issuer:       cfg.Auth.JwtIssuer,
+		Audience:     cfg.Auth.JwtAudience,
+		DaprApiToken: cfg.Dapr.AppApiToken,
+	})
+	if err != nil {
+		log.Fatalf("Error during init : %s", err.Error())
+	}
+	for _, warning := range cfg.Warnings() {
+		log.Warnf("%s", warning)
+	}
+	return authn
+}
+
+// Resolve the per-client rate limiting of the API
+func resolveRateLimiter(cfg *config.Config) *rate_limiter.RateLimiter {
+	if cfg.RateLimit.Rps == 0 {
+		log.Warnf("Rate limiting is disabled")
+	}
+	return rate_limiter.NewRateLimiter(cfg.RateLimit.Rps, cfg.RateLimit.Burst)
+}
+
+// Resolve where the traces are exported to
+func resolveTracing(ctx context.Context, cfg *config.Config) func(context.Context) error {
+	exporter := tracing.Exporter(cfg.Tracing.Exporter)
+	shutdown, err := tracing.Setup(ctx, tracing.Options{Exporter: exporter, ServiceName: cfg.Tracing.ServiceName})
+	if err != nil {
+		log.Fatalf("Error during init : %s", err.Error())
+	}
+	if exporter != "" && exporter != tracing.None {
+		log.Infof(`Exporting traces with "%s"`, exporter)
+	}
+	return shutdown
+}
+
+// Make a custom dapr client with a large max request size, to handle large uploads
+func makeDaprClient(port int, maxRequestSizeMB int) (*client.Client, error) {
+	var opts []grpc.CallOption
 	opts = append(opts, grpc.MaxCallRecvMsgSize(maxRequestSizeMB*1024*1024))
 	conn, err := grpc.Dial(net.JoinHostPort("127.0.0.1", fmt.Sprintf("%d", port)),
-		grpc.WithDefaultCallOptions(opts...), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		grpc.WithDefaultCallOptions(opts...), grpc.WithTransportCredentials(insecure.NewCredentials()),
+		// Propagate the trace context to the sidecar in the gRPC metadata
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,113 @@
+package video_store_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// ErrInterrupted Cause of the cancellation of the uploads still running once the service is done draining
+var ErrInterrupted = errors.New("upload interrupted by the service shutdown")
+
+// ErrJobRunning An upload job with the same ID is already running on this instance
+var ErrJobRunning = errors.New("upload job already running")
+
+// A running upload job
+type job struct {
+	id     string
+	cancel context.CancelCauseFunc
+}
+
+// Keeps track of the running upload jobs, allowing to wait for them before shutting down.
+// The zero value is ready to use
+type jobTracker struct {
+	mu sync.Mutex
+	// Once set, no new job can start
+	draining bool
+	running  map[uint64]job
+	// Incremented for each job, the same job ID may be running more than once
+	next uint64
+	wg   sync.WaitGroup
+}
+
+// Register a new job. The returned context is cancelled with ErrInterrupted if the job is still running once the
+// drain timeout elapsed, and the returned function must be called once the job is over
+func (jt *jobTracker) start(ctx context.Context, jobId string) (context.Context, func(), error) {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+	return jt.register(ctx, jobId)
+}
+
+// Register a new job as start does, unless a job with the same ID is already running
+func (jt *jobTracker) startOnce(ctx context.Context, jobId string) (context.Context, func(), error) {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+	for _, j := range jt.running {
+		if j.id == jobId {
+			return nil, nil, fmt.Errorf("job %s : %w", jobId, ErrJobRunning)
+		}
+	}
+	return jt.register(ctx, jobId)
+}
+
+// Register a new job, jt.mu being held
+func (jt *jobTracker) register(ctx context.Context, jobId string) (context.Context, func(), error) {
+	if jt.draining {
+		return nil, nil, video_hosting.NewRequestError(video_hosting.ShuttingDown,
+			fmt.Errorf("the service is shutting down, job %s can't be started", jobId))
+	}
+	if jt.running == nil {
+		jt.running = make(map[uint64]job)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	key := jt.next
+	jt.next++
+	jt.running[key] = job{id: jobId, cancel: cancel}
+	jt.wg.Add(1)
+	return ctx, func() {
+		jt.mu.Lock()
+		delete(jt.running, key)
+		jt.mu.Unlock()
+		cancel(nil)
+		jt.wg.Done()
+	}, nil
+}
+
+// Refuse any new job, and wait for the running ones until ctx is done.
+// Jobs still running at this point are interrupted, their IDs are returned once they stopped
+func (jt *jobTracker) drain(ctx context.Context) []string {
+	jt.mu.Lock()
+	jt.draining = true
+	jt.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		jt.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+	}
+
+	jt.mu.Lock()
+	ids := make([]string, 0, len(jt.running))
+	for _, j := range jt.running {
+		ids = append(ids, j.id)
+		j.cancel(ErrInterrupted)
+	}
+	jt.mu.Unlock()
+	// Interrupted jobs still have to publish their terminal event
+	<-finished
+	sort.Strings(ids)
+	return ids
+}
+
+// Whether ctx has been cancelled because the service is shutting down
+func interrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrInterrupted)
+}
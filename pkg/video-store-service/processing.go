@@ -0,0 +1,105 @@
+package video_store_service
+
+import (
+	"context"
+	"time"
+	"video-manager/internal/catalog"
+	event_broker "video-manager/internal/event-broker"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// Payload of a "processing.progress" event
+type processingProgress struct {
+	// Job that uploaded the video
+	JobId string `json:"jobId"`
+	*video_hosting.ProcessingProgress
+}
+
+// Payload of a "processing.done" event
+type processingDone struct {
+	// Job that uploaded the video
+	JobId string `json:"jobId"`
+	// URL prefix to watch videos on the url
+	WatchPrefix string `json:"watchPrefix"`
+	// Final video duration
+	Duration int64 `json:"duration"`
+}
+
+// Payload of a "processing.failed" event
+type processingFailed struct {
+	// Job that uploaded the video
+	JobId            string                         `json:"jobId"`
+	UploadStatus     video_hosting.UploadStatus     `json:"uploadStatus,omitempty"`
+	ProcessingStatus video_hosting.ProcessingStatus `json:"processingStatus,omitempty"`
+	FailureReason    string                         `json:"failureReason,omitempty"`
+	RejectionReason  string                         `json:"rejectionReason,omitempty"`
+}
+
+// Whether the host completed the processing of vid, successfully or not
+func processed(vid *video_hosting.Video) (done bool, failed bool) {
+	switch {
+	case failedUploads[vid.UploadStatus],
+		vid.ProcessingStatus == video_hosting.ProcessingFailed,
+		vid.ProcessingStatus == video_hosting.ProcessingTerminated:
+		return true, true
+	case vid.UploadStatus == video_hosting.Processed, vid.ProcessingStatus == video_hosting.ProcessingSucceeded:
+		return true, false
+	}
+	return false, false
+}
+
+// Follow the processing of the video "id", freshly uploaded by the job "jobId", until the host completes it
+// or ProcessingTimeout elapses. The host is asked every ProcessingInterval, publishing the progress on the way.
+// Failed lookups are logged and attempted again on the next tick
+func (vsc *VideoStoreService[B, P]) followProcessing(ctx context.Context, jobId string, id string) {
+	if vsc.ProcessingTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, vsc.ProcessingTimeout)
+		defer cancel()
+	}
+	ticker := time.NewTicker(vsc.ProcessingInterval)
+	defer ticker.Stop()
+	var last video_hosting.ProcessingProgress
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warnf("Stopped following the processing of video %s (job %s) : %s", id, jobId, ctx.Err())
+			return
+		case <-ticker.C:
+		}
+		vid, err := vsc.VidHost.RetrieveVideo(ctx, id)
+		if err != nil {
+			if video_hosting.KindOf(err) == video_hosting.NotFound {
+				log.Warnf("Video %s (job %s) was deleted while being processed", id, jobId)
+				return
+			}
+			log.Warnf("Could not retrieve the processing status of video %s (job %s) : %s", id, jobId, err.Error())
+			continue
+		}
+		done, failed := processed(vid)
+		switch {
+		case failed:
+			reason := vid.FailureReason
+			if reason == "" {
+				reason = vid.RejectionReason
+			}
+			log.Warnf("The host failed to process video %s (job %s) : %s", id, jobId, reason)
+			vsc.publish(ctx, event_broker.ProcessingFailed, id, processingFailed{
+				JobId:            jobId,
+				UploadStatus:     vid.UploadStatus,
+				ProcessingStatus: vid.ProcessingStatus,
+				FailureReason:    vid.FailureReason,
+				RejectionReason:  vid.RejectionReason,
+			})
+		case done:
+			vsc.publish(ctx, event_broker.ProcessingDone, id, processingDone{JobId: jobId, WatchPrefix: vid.WatchPrefix, Duration: vid.Duration})
+		case vid.ProcessingProgress != nil && *vid.ProcessingProgress != last:
+			last = *vid.ProcessingProgress
+			vsc.publish(ctx, event_broker.ProcessingProgress, id, processingProgress{JobId: jobId, ProcessingProgress: vid.ProcessingProgress})
+		}
+		if done {
+			vsc.record(ctx, catalog.Video, id, func(entry *catalog.Entry) { entry.UploadStatus = vid.UploadStatus })
+			return
+		}
+	}
+}
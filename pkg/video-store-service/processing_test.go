@@ -0,0 +1,78 @@
+package video_store_service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"video-manager/internal/catalog"
+	event_broker "video-manager/internal/event-broker"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+func SetupProcessing(t *testing.T) *mocked {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	deps.service.ProcessingInterval = time.Millisecond
+	return deps
+}
+
+func processing(parts int64) *video_hosting.Video {
+	return &video_hosting.Video{
+		Id:                 "vid",
+		UploadStatus:       video_hosting.Uploaded,
+		ProcessingStatus:   video_hosting.StillProcessing,
+		ProcessingProgress: &video_hosting.ProcessingProgress{PartsProcessed: parts, PartsTotal: 10},
+	}
+}
+
+func TestVideoStoreService_FollowProcessing(t *testing.T) {
+	deps := SetupProcessing(t)
+	records := &fakeCatalog{entries: map[string]*catalog.Entry{}}
+	deps.service.Catalog = records
+	gomock.InOrder(
+		deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(processing(2), nil),
+		// An unchanged progress isn't published twice
+		deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(processing(2), nil),
+		// Nor does a failed lookup stop the polling
+		deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(nil, fmt.Errorf("network error")),
+		deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(processing(5), nil),
+		deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(&video_hosting.Video{
+			Id:               "vid",
+			UploadStatus:     video_hosting.Processed,
+			ProcessingStatus: video_hosting.ProcessingSucceeded,
+			Duration:         7203,
+		}, nil),
+	)
+	expectEvent(t, deps, event_broker.ProcessingProgress, "vid")
+	expectEvent(t, deps, event_broker.ProcessingProgress, "vid")
+	expectEvent(t, deps, event_broker.ProcessingDone, "vid")
+	deps.service.followProcessing(context.Background(), "job", "vid")
+	assert.Equal(t, video_hosting.Processed, records.entries["/video/vid"].UploadStatus)
+}
+
+func TestVideoStoreService_FollowProcessing_Failed(t *testing.T) {
+	deps := SetupProcessing(t)
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(&video_hosting.Video{
+		Id:              "vid",
+		UploadStatus:    video_hosting.Rejected,
+		RejectionReason: "duplicate",
+	}, nil)
+	expectEvent(t, deps, event_broker.ProcessingFailed, "vid")
+	deps.service.followProcessing(context.Background(), "job", "vid")
+}
+
+func TestVideoStoreService_FollowProcessing_Stop(t *testing.T) {
+	deps := SetupProcessing(t)
+	// A video deleted meanwhile isn't looked up anymore
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").
+		Return(nil, video_hosting.NewRequestError(video_hosting.NotFound, fmt.Errorf("not found")))
+	deps.service.followProcessing(context.Background(), "job", "vid")
+
+	// Nor is a video taking too long to process
+	deps.service.ProcessingTimeout = 20 * time.Millisecond
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(&video_hosting.Video{Id: "vid"}, nil).AnyTimes()
+	deps.service.followProcessing(context.Background(), "job", "vid")
+}
@@ -0,0 +1,274 @@
+package video_store_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+	"io"
+	"time"
+	progress_broker "video-manager/internal/progress-broker"
+	"video-manager/internal/tracing"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// Manifest Everything to publish a recording with, stored as JSON or YAML on the object storage
+type Manifest struct {
+	video_hosting.ItemMetadata
+	// Key to retrieve the video from the object storage
+	StorageKey string `json:"storageKey" binding:"required"`
+	// UUID of the publication, defaults to the key of the manifest
+	JobId string `json:"jobId"`
+	// Keywords of the video
+	Tags []string `json:"tags" binding:"max=500,dive,required,max=100"`
+	// Key to retrieve the thumbnail from the object storage
+	ThumbnailKey string `json:"thumbnailKey"`
+	// Caption tracks to add to the video
+	Captions []ManifestCaption `json:"captions" binding:"dive"`
+	// IDs of existing playlists to add the video to
+	Playlists []string `json:"playlists" binding:"dive,required"`
+	// When the video is made public. It stays private until then
+	PublishAt *time.Time `json:"publishAt"`
+}
+
+// ManifestCaption A caption track of a manifest
+type ManifestCaption struct {
+	video_hosting.Caption
+	// Key to retrieve the track from the object storage
+	StorageKey string `json:"storageKey" binding:"required"`
+}
+
+// StepStatus Outcome of a single step of a publication
+type StepStatus string
+
+const (
+	StepDone   StepStatus = "done"
+	StepFailed StepStatus = "failed"
+	// StepSkipped The step wasn't run, a previous one failed
+	StepSkipped StepStatus = "skipped"
+	// StepReused The video was already uploaded from the same recording, it wasn't uploaded again
+	StepReused StepStatus = "reused"
+)
+
+// PublishStep A single step of a publication
+type PublishStep struct {
+	// What the step does : "upload", "tags", "thumbnail", "caption:<language>", "playlist:<id>" or "schedule"
+	Name   string     `json:"name" example:"upload"`
+	Status StepStatus `json:"status" example:"done"`
+	// Why the step failed
+	Error string `json:"error,omitempty"`
+}
+
+// PublishReport Outcome of each step of a publication, in the order they ran
+type PublishReport struct {
+	ManifestKey string `json:"manifestKey" example:"recordings/1234/manifest.yaml"`
+	JobId       string `json:"jobId" example:"recordings/1234/manifest.yaml"`
+	// Whether all the steps succeeded
+	Succeeded bool `json:"succeeded"`
+	// Published video, absent if the upload failed
+	Video *video_hosting.Video `json:"video,omitempty"`
+	Steps []PublishStep        `json:"steps"`
+}
+
+// Payload of a progress event, sent after each step of a publication
+type publishProgress struct {
+	PublishStep
+	// Number of steps run so far, including this one
+	Completed int `json:"completed"`
+	// Number of steps of the publication
+	Total int `json:"total"`
+}
+
+// A step of a publication, and how to run it
+type publishAction struct {
+	name string
+	run  func(ctx context.Context, report *PublishReport) (StepStatus, error)
+}
+
+// Publish Upload the recording described by the manifest stored under manifestKey, then apply each of its
+// settings (tags, thumbnail, captions, playlists, schedule) in order.
+// The publication stops at the first failed step, the report holds what was already done.
+// A recording already uploaded by a previous attempt isn't uploaded again, the remaining steps run on the existing video
+func (vsc *VideoStoreService[B, P]) Publish(ctx context.Context, manifestKey string) (report *PublishReport, err error) {
+	ctx, span := tracing.Start(ctx, "publish", trace.WithAttributes(attribute.String("object_storage.key", manifestKey)))
+	defer func() { tracing.End(span, err) }()
+	manifest, err := vsc.LoadManifest(ctx, manifestKey)
+	if err != nil {
+		return nil, err
+	}
+	jobId := manifest.JobId
+	if jobId == "" {
+		jobId = manifestKey
+	}
+	report = &PublishReport{ManifestKey: manifestKey, JobId: jobId, Steps: []PublishStep{}}
+	actions := vsc.publishActions(manifest, jobId)
+	for i, action := range actions {
+		step := PublishStep{Name: action.name, Status: StepSkipped}
+		if err == nil {
+			step.Status, err = action.run(ctx, report)
+			if err != nil {
+				step.Status, step.Error = StepFailed, err.Error()
+				log.Warnf(`Step "%s" of publication %s failed : %s`, action.name, jobId, err.Error())
+			}
+		}
+		report.Steps = append(report.Steps, step)
+		vsc.sendProgress(ctx, jobId, progress_broker.InProgress, publishProgress{PublishStep: step, Completed: i + 1, Total: len(actions)})
+	}
+	if err != nil {
+		vsc.sendProgress(ctx, jobId, progress_broker.Error, uploadError{Message: err.Error()})
+		return report, fmt.Errorf("publication %s failed : %w", jobId, err)
+	}
+	report.Succeeded = true
+	vsc.sendProgress(ctx, jobId, progress_broker.Done, report)
+	return report, nil
+}
+
+// LoadManifest Fetch the manifest stored under key on the object storage and validate it
+func (vsc *VideoStoreService[B, P]) LoadManifest(ctx context.Context, key string) (*Manifest, error) {
+	reader, err := vsc.ObjStore.Buffer(ctx, key)
+	if err != nil {
+		return nil, video_hosting.NewRequestError(video_hosting.StorageUnavailable,
+			fmt.Errorf("error while downloading manifest from object storage : %w", err))
+	}
+	data, err := io.ReadAll(*reader)
+	if err != nil {
+		return nil, video_hosting.NewRequestError(video_hosting.StorageUnavailable,
+			fmt.Errorf("error while downloading manifest from object storage : %w", err))
+	}
+	manifest, err := ParseManifest(data, time.Now())
+	if err != nil {
+		return nil, video_hosting.NewRequestError(video_hosting.InvalidMetadata, fmt.Errorf(`invalid manifest "%s" : %w`, key, err))
+	}
+	// Nothing is published if the host would refuse any of the metadata
+	if err := vsc.validateManifest(manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Check the manifest against the limits of the host, if it has any. Every refused field is reported at once
+func (vsc *VideoStoreService[B, P]) validateManifest(manifest *Manifest) error {
+	if vsc.HostMetadata == nil {
+		return nil
+	}
+	fields := video_hosting.FieldErrors(vsc.HostMetadata.ValidateVideo(&manifest.ItemMetadata))
+	fields = append(fields, video_hosting.FieldErrors(vsc.HostMetadata.ValidateTags(manifest.Tags))...)
+	return video_hosting.NewValidationError(fields)
+}
+
+// ParseManifest Decode a JSON or YAML manifest and validate it, now being the current time.
+// Unknown fields are refused, to catch misspelled settings that would otherwise be silently ignored
+func ParseManifest(data []byte, now time.Time) (*Manifest, error) {
+	// JSON being a subset of YAML, both are read the same way, and then decoded as JSON to apply the same rules
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(normalized))
+	decoder.DisallowUnknownFields()
+	var manifest Manifest
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, err
+	}
+	if err := binding.Validator.ValidateStruct(&manifest); err != nil {
+		return nil, err
+	}
+	if manifest.PublishAt != nil && !manifest.PublishAt.After(now) {
+		return nil, fmt.Errorf("publishAt must be in the future")
+	}
+	return &manifest, nil
+}
+
+// The steps needed to publish manifest, in order
+func (vsc *VideoStoreService[B, P]) publishActions(manifest *Manifest, jobId string) []publishAction {
+	actions := []publishAction{{name: "upload", run: func(ctx context.Context, report *PublishReport) (StepStatus, error) {
+		vid, err := vsc.uploaded(ctx, manifest.StorageKey, "")
+		if err != nil || vid != nil {
+			report.Video = vid
+			return StepReused, err
+		}
+		// A scheduled video must stay private until it is published, and not only once all the steps are done
+		meta := manifest.ItemMetadata
+		if manifest.PublishAt != nil {
+			meta.Visibility = video_hosting.Private
+		}
+		// The upload reports its progress as a job of its own, not to be mistaken for the end of the publication
+		report.Video, err = vsc.UploadVideoFromStorage(ctx, jobId+"/upload", manifest.StorageKey, &meta)
+		return StepDone, err
+	}}}
+	if len(manifest.Tags) > 0 {
+		actions = append(actions, publishAction{name: "tags", run: func(ctx context.Context, report *PublishReport) (StepStatus, error) {
+			return StepDone, vsc.SetVideoTags(ctx, report.Video.Id, manifest.Tags)
+		}})
+	}
+	if manifest.ThumbnailKey != "" {
+		actions = append(actions, publishAction{name: "thumbnail", run: func(ctx context.Context, report *PublishReport) (StepStatus, error) {
+			return StepDone, vsc.SetVideoThumbnailFromStorage(ctx, report.Video.Id, manifest.ThumbnailKey)
+		}})
+	}
+	for _, caption := range manifest.Captions {
+		caption := caption
+		actions = append(actions, publishAction{name: "caption:" + caption.Language, run: func(ctx context.Context, report *PublishReport) (StepStatus, error) {
+			reader, err := vsc.ObjStore.Buffer(ctx, caption.StorageKey)
+			if err != nil {
+				return StepDone, video_hosting.NewRequestError(video_hosting.StorageUnavailable,
+					fmt.Errorf("error while downloading caption from object storage : %w", err))
+			}
+			return StepDone, vsc.AddVideoCaption(ctx, report.Video.Id, &caption.Caption, *reader)
+		}})
+	}
+	for _, playlistId := range manifest.Playlists {
+		playlistId := playlistId
+		actions = append(actions, publishAction{name: "playlist:" + playlistId, run: func(ctx context.Context, report *PublishReport) (StepStatus, error) {
+			return StepDone, vsc.AddVideoToPlaylist(ctx, report.Video.Id, playlistId)
+		}})
+	}
+	if manifest.PublishAt != nil {
+		actions = append(actions, publishAction{name: "schedule", run: func(ctx context.Context, report *PublishReport) (StepStatus, error) {
+			return StepDone, vsc.ScheduleVideo(ctx, report.Video.Id, *manifest.PublishAt)
+		}})
+	}
+	return actions
+}
+
+// The video already uploaded from the recording storageKey, according to the catalog. Nil if there is none.
+// If jobId isn't empty, only a video uploaded by this job is returned
+func (vsc *VideoStoreService[B, P]) uploaded(ctx context.Context, storageKey string, jobId string) (*video_hosting.Video, error) {
+	if vsc.Catalog == nil {
+		return nil, nil
+	}
+	entry, err := vsc.Catalog.FindBySource(ctx, vsc.Host, storageKey)
+	if err != nil {
+		// Without the catalog, the recording can only be uploaded again
+		log.Warnf("Could not search the catalog for a video uploaded from %s : %s", storageKey, err.Error())
+		return nil, nil
+	}
+	if entry == nil || (jobId != "" && entry.JobId != jobId) {
+		return nil, nil
+	}
+	vid, err := vsc.VidHost.RetrieveVideo(ctx, entry.Id)
+	// Deleted from the host since then
+	if video_hosting.KindOf(err) == video_hosting.NotFound {
+		return nil, nil
+	}
+	return vid, err
+}
+
+// Send a progress event for job if a progress broker is defined
+func (vsc *VideoStoreService[B, P]) sendProgress(ctx context.Context, jobId string, state progress_broker.UploadState, data any) {
+	if vsc.EvtBroker == nil {
+		return
+	}
+	err := vsc.EvtBroker.SendProgress(ctx, progress_broker.UploadInfos{JobId: jobId, State: state, Data: data})
+	if err != nil {
+		log.Errorf("Could not send event to progress broker : %s", err.Error())
+	}
+}
@@ -0,0 +1,241 @@
+package video_store_service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/dapr/go-sdk/client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+	"video-manager/internal/catalog"
+	progress_broker "video-manager/internal/progress-broker"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+const sampleManifest = `
+title: Session 1
+description: The first session
+visibility: unlisted
+storageKey: recordings/1/video.mp4
+jobId: job
+tags: [rpg, session]
+thumbnailKey: recordings/1/thumbnail.png
+captions:
+  - language: en
+    name: English
+    storageKey: recordings/1/en.vtt
+playlists: [pl]
+publishAt: 2100-01-01T10:00:00Z
+`
+
+// Serve files from the object storage, by key
+func serveFiles(deps *mocked, files map[string]string) {
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, in *client.InvokeBindingRequest) (*client.BindingEvent, error) {
+			content, ok := files[in.Metadata["key"]]
+			if !ok {
+				return nil, fmt.Errorf("no such key %s", in.Metadata["key"])
+			}
+			return &client.BindingEvent{Data: []byte(base64.StdEncoding.EncodeToString([]byte(content)))}, nil
+		}).AnyTimes()
+}
+
+func TestParseManifest(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	manifest, err := ParseManifest([]byte(sampleManifest), now)
+	assert.Nil(t, err)
+	assert.Equal(t, "Session 1", manifest.Title)
+	assert.Equal(t, video_hosting.Unlisted, manifest.Visibility)
+	assert.Equal(t, "recordings/1/video.mp4", manifest.StorageKey)
+	assert.Equal(t, []string{"rpg", "session"}, manifest.Tags)
+	assert.Equal(t, []ManifestCaption{{Caption: video_hosting.Caption{Language: "en", Name: "English"}, StorageKey: "recordings/1/en.vtt"}}, manifest.Captions)
+	assert.Equal(t, []string{"pl"}, manifest.Playlists)
+	assert.Equal(t, time.Date(2100, 1, 1, 10, 0, 0, 0, time.UTC), manifest.PublishAt.UTC())
+
+	// JSON manifests are read the same way
+	manifest, err = ParseManifest([]byte(`{"title": "Session 1", "visibility": "public", "storageKey": "video.mp4"}`), now)
+	assert.Nil(t, err)
+	assert.Equal(t, "video.mp4", manifest.StorageKey)
+	assert.Nil(t, manifest.PublishAt)
+
+	for _, invalid := range []string{
+		``,
+		`not: [valid`,
+		// Missing storage key
+		`{"title": "Session 1", "visibility": "public"}`,
+		// Misspelled field
+		`{"title": "Session 1", "visibility": "public", "storageKey": "video.mp4", "tag": ["rpg"]}`,
+		// Empty tag
+		`{"title": "Session 1", "visibility": "public", "storageKey": "video.mp4", "tags": [""]}`,
+		// Caption without language
+		`{"title": "Session 1", "visibility": "public", "storageKey": "video.mp4", "captions": [{"storageKey": "en.vtt"}]}`,
+		// Already past
+		`{"title": "Session 1", "visibility": "public", "storageKey": "video.mp4", "publishAt": "2000-01-01T00:00:00Z"}`,
+	} {
+		_, err := ParseManifest([]byte(invalid), now)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestVideoStoreService_Publish(t *testing.T) {
+	deps := Setup(t, false)
+	records := &fakeCatalog{entries: map[string]*catalog.Entry{}}
+	deps.service.Host = "youtube"
+	deps.service.Catalog = records
+	serveFiles(deps, map[string]string{
+		"recordings/1/manifest.yaml": sampleManifest,
+		"recordings/1/video.mp4":     "video",
+		"recordings/1/thumbnail.png": "thumbnail",
+		"recordings/1/en.vtt":        "captions",
+	})
+	gomock.InOrder(
+		deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, meta *video_hosting.ItemMetadata, _ io.Reader, _ *video_hosting.ProgressFunc) (*video_hosting.Video, error) {
+				assert.Equal(t, "Session 1", meta.Title)
+				// Scheduled, so kept private until then
+				assert.Equal(t, video_hosting.Private, meta.Visibility)
+				return &video_hosting.Video{Id: "vid"}, nil
+			}),
+		deps.videoStore.EXPECT().SetVideoTags(gomock.Any(), "vid", []string{"rpg", "session"}).Return(nil),
+		deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), "vid", gomock.Any()).Return(nil),
+		deps.videoStore.EXPECT().AddVideoCaption(gomock.Any(), "vid", &video_hosting.Caption{Language: "en", Name: "English"}, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, _ *video_hosting.Caption, content io.Reader) error {
+				data, _ := io.ReadAll(content)
+				assert.Equal(t, "captions", string(data))
+				return nil
+			}),
+		deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), "vid", "pl").Return(nil),
+		deps.videoStore.EXPECT().ScheduleVideo(gomock.Any(), "vid", time.Date(2100, 1, 1, 10, 0, 0, 0, time.UTC)).Return(nil),
+	)
+
+	report, err := deps.service.Publish(context.Background(), "recordings/1/manifest.yaml")
+	assert.Nil(t, err)
+	assert.True(t, report.Succeeded)
+	assert.Equal(t, "job", report.JobId)
+	assert.Equal(t, "vid", report.Video.Id)
+	assert.Equal(t, []PublishStep{
+		{Name: "upload", Status: StepDone},
+		{Name: "tags", Status: StepDone},
+		{Name: "thumbnail", Status: StepDone},
+		{Name: "caption:en", Status: StepDone},
+		{Name: "playlist:pl", Status: StepDone},
+		{Name: "schedule", Status: StepDone},
+	}, report.Steps)
+	// As it is on the host, not to be reported as a drift
+	assert.Equal(t, video_hosting.Private, records.entries["youtube/video/vid"].Metadata.Visibility)
+}
+
+func TestVideoStoreService_Publish_StepFailed(t *testing.T) {
+	deps := Setup(t, false)
+	serveFiles(deps, map[string]string{
+		"recordings/1/manifest.yaml": sampleManifest,
+		"recordings/1/video.mp4":     "video",
+	})
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{Id: "vid"}, nil)
+	deps.videoStore.EXPECT().SetVideoTags(gomock.Any(), "vid", gomock.Any()).Return(nil)
+
+	// The thumbnail is missing from the object storage, nothing is done after that
+	report, err := deps.service.Publish(context.Background(), "recordings/1/manifest.yaml")
+	assert.NotNil(t, err)
+	assert.Equal(t, video_hosting.StorageUnavailable, video_hosting.KindOf(err))
+	assert.False(t, report.Succeeded)
+	assert.Equal(t, "vid", report.Video.Id)
+	statuses := make([]StepStatus, len(report.Steps))
+	for i, step := range report.Steps {
+		statuses[i] = step.Status
+	}
+	assert.Equal(t, []StepStatus{StepDone, StepDone, StepFailed, StepSkipped, StepSkipped, StepSkipped}, statuses)
+	assert.Contains(t, report.Steps[2].Error, "thumbnail")
+}
+
+func TestVideoStoreService_Publish_InvalidManifest(t *testing.T) {
+	deps := Setup(t, false)
+	serveFiles(deps, map[string]string{"manifest.json": `{"title": "Session 1"}`})
+	report, err := deps.service.Publish(context.Background(), "manifest.json")
+	assert.Nil(t, report)
+	assert.Equal(t, video_hosting.InvalidMetadata, video_hosting.KindOf(err))
+
+	// Missing manifest
+	report, err = deps.service.Publish(context.Background(), "other.json")
+	assert.Nil(t, report)
+	assert.Equal(t, video_hosting.StorageUnavailable, video_hosting.KindOf(err))
+}
+
+func TestVideoStoreService_Publish_RefusedByHost(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.HostMetadata = video_hosting.YoutubeVideoStore{}
+	serveFiles(deps, map[string]string{"manifest.json": `{"title": "<Session 1>", "visibility": "public", "storageKey": "video.mp4", "tags": ["<rpg>"]}`})
+	// Nothing is published, and every refused field is reported
+	report, err := deps.service.Publish(context.Background(), "manifest.json")
+	assert.Nil(t, report)
+	assert.Equal(t, video_hosting.InvalidMetadata, video_hosting.KindOf(err))
+	assert.Equal(t, []video_hosting.FieldError{
+		{Field: "title", Reason: "must not contain < or >"},
+		{Field: "tags[0]", Reason: "must not contain < or >"},
+	}, video_hosting.FieldErrors(err))
+}
+
+func TestVideoStoreService_Publish_Reused(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Host = "youtube"
+	deps.service.Catalog = &fakeCatalog{entries: map[string]*catalog.Entry{
+		"youtube/video/vid": {Kind: catalog.Video, Id: "vid", Host: "youtube", StorageKey: "video.mp4"},
+	}}
+	serveFiles(deps, map[string]string{
+		"manifest.json": `{"title": "Session 1", "visibility": "public", "storageKey": "video.mp4", "playlists": ["pl"]}`,
+		"video.mp4":     "video",
+	})
+	// A previous attempt already uploaded the recording
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(&video_hosting.Video{Id: "vid"}, nil)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), "vid", "pl").Return(nil)
+
+	report, err := deps.service.Publish(context.Background(), "manifest.json")
+	assert.Nil(t, err)
+	assert.Equal(t, []PublishStep{{Name: "upload", Status: StepReused}, {Name: "playlist:pl", Status: StepDone}}, report.Steps)
+
+	// Unless it was deleted from the host since then
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(nil, video_hosting.NewRequestError(video_hosting.NotFound, fmt.Errorf("not found")))
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{Id: "vid2"}, nil)
+	deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), "vid2", "pl").Return(nil)
+	report, err = deps.service.Publish(context.Background(), "manifest.json")
+	assert.Nil(t, err)
+	assert.Equal(t, StepDone, report.Steps[0].Status)
+}
+
+func TestVideoStoreService_Publish_Progress(t *testing.T) {
+	deps := Setup(t, true)
+	serveFiles(deps, map[string]string{
+		"manifest.json": `{"title": "Session 1", "visibility": "public", "storageKey": "video.mp4", "jobId": "job", "playlists": ["pl"]}`,
+		"video.mp4":     "video",
+	})
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{Id: "vid"}, nil)
+	deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), "vid", "pl").Return(nil)
+	var sent []progress_broker.UploadInfos
+	deps.brokerProxy.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, data interface{}, _ ...client.PublishEventOption) error {
+			var info progress_broker.UploadInfos
+			if err := json.Unmarshal([]byte(data.(string)), &info); err != nil {
+				t.Fatal(err)
+			}
+			sent = append(sent, info)
+			return nil
+		}).AnyTimes()
+
+	_, err := deps.service.Publish(context.Background(), "manifest.json")
+	assert.Nil(t, err)
+	// The upload is reported as a job of its own, then each step, and finally the whole publication
+	var states []progress_broker.UploadState
+	for _, info := range sent {
+		if info.JobId == "job" {
+			states = append(states, info.State)
+		} else {
+			assert.Equal(t, "job/upload", info.JobId)
+		}
+	}
+	assert.Equal(t, []progress_broker.UploadState{progress_broker.InProgress, progress_broker.InProgress, progress_broker.Done}, states)
+}
@@ -0,0 +1,248 @@
+package video_store_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+	"video-manager/internal/catalog"
+	event_broker "video-manager/internal/event-broker"
+	"video-manager/internal/tracing"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// ErrNoCatalog Nothing was recorded to compare the host with
+var ErrNoCatalog = errors.New("the service doesn't keep a catalog")
+
+// DriftReason What changed on the host without going through the service
+type DriftReason string
+
+const (
+	// Missing The item was deleted from the host
+	Missing DriftReason = "missing"
+	// MetadataChanged The title or the description differ from the recorded ones
+	MetadataChanged DriftReason = "metadata"
+	// VisibilityChanged The visibility differs from the recorded one
+	VisibilityChanged DriftReason = "visibility"
+	// ProcessingFailed The host failed to process the uploaded video, or rejected it
+	ProcessingFailed DriftReason = "processing-failed"
+)
+
+// Upload statuses of a video that won't ever be watchable
+var failedUploads = map[video_hosting.UploadStatus]bool{
+	video_hosting.Failed:   true,
+	video_hosting.Rejected: true,
+	video_hosting.Deleted:  true,
+}
+
+// Drift A difference between a recorded item and the same item on the host.
+// Payload of the "video.drifted" and "playlist.drifted" events
+type Drift struct {
+	Kind   catalog.Kind `json:"kind" example:"video"`
+	Id     string       `json:"id"`
+	Reason DriftReason  `json:"reason" example:"metadata"`
+	// Metadata as last set through the service
+	Recorded *video_hosting.ItemMetadata `json:"recorded,omitempty"`
+	// Metadata found on the host, absent if the item is missing
+	Actual *video_hosting.ItemMetadata `json:"actual,omitempty"`
+	// Upload status found on the host, only for failed processing
+	UploadStatus video_hosting.UploadStatus `json:"uploadStatus,omitempty" example:"rejected"`
+}
+
+// ReconcileReport Summary of a comparison between the catalog and the host
+type ReconcileReport struct {
+	// Name of the compared host
+	Host       string    `json:"host" example:"youtube"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	// Number of recorded videos compared with the host
+	Videos int `json:"videos"`
+	// Number of recorded playlists compared with the host
+	Playlists int `json:"playlists"`
+	// Every difference found
+	Drifts []Drift `json:"drifts"`
+	// Why the comparison couldn't complete, the drifts found until then are still reported
+	Error string `json:"error,omitempty"`
+}
+
+// Reconcile Compare every item recorded in the catalog with the host, batching the lookups,
+// and publish an event for each drift found.
+// The catalog is then aligned with the host, so that each drift is only reported once
+func (vsc *VideoStoreService[B, P]) Reconcile(ctx context.Context) (report *ReconcileReport, err error) {
+	if vsc.Catalog == nil {
+		return nil, ErrNoCatalog
+	}
+	ctx, span := tracing.Start(ctx, "reconcile", trace.WithAttributes(attribute.String("reconcile.host", vsc.Host)))
+	defer func() { tracing.End(span, err) }()
+	report = &ReconcileReport{Host: vsc.Host, StartedAt: time.Now().UTC(), Drifts: []Drift{}}
+	defer func() {
+		report.FinishedAt = time.Now().UTC()
+		if err != nil {
+			report.Error = err.Error()
+		}
+		span.SetAttributes(attribute.Int("reconcile.drifts", len(report.Drifts)))
+		vsc.reconciled.Store(report)
+	}()
+	if err = vsc.reconcileVideos(ctx, report); err != nil {
+		return report, err
+	}
+	err = vsc.reconcilePlaylists(ctx, report)
+	return report, err
+}
+
+// LastReconcileReport The report of the last comparison with the host. Nil if none ran yet
+func (vsc *VideoStoreService[B, P]) LastReconcileReport() *ReconcileReport {
+	return vsc.reconciled.Load()
+}
+
+// RunReconciler Reconcile the catalog with the host every interval, until ctx is cancelled.
+// A failed comparison is logged and attempted again on the next tick
+func (vsc *VideoStoreService[B, P]) RunReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		report, err := vsc.Reconcile(ctx)
+		if err != nil {
+			log.Errorf(`Could not reconcile the catalog with host "%s" : %s`, vsc.Host, err.Error())
+			continue
+		}
+		if len(report.Drifts) > 0 {
+			log.Warnf(`Found %d drifts between the catalog and host "%s"`, len(report.Drifts), vsc.Host)
+		}
+	}
+}
+
+func (vsc *VideoStoreService[B, P]) reconcileVideos(ctx context.Context, report *ReconcileReport) error {
+	entries, err := vsc.Catalog.List(ctx, vsc.Host, catalog.Video)
+	if err != nil {
+		return fmt.Errorf("could not list the recorded videos : %w", err)
+	}
+	for _, batch := range batches(entries) {
+		videos, err := vsc.VidHost.ListVideos(ctx, ids(batch))
+		if err != nil {
+			return err
+		}
+		found := make(map[string]*video_hosting.Video, len(videos))
+		for _, vid := range videos {
+			found[vid.Id] = vid
+		}
+		for _, entry := range batch {
+			report.Videos++
+			vid, ok := found[entry.Id]
+			if !ok {
+				recorded := entry.Metadata
+				vsc.drifted(ctx, report, Drift{Kind: catalog.Video, Id: entry.Id, Reason: Missing, Recorded: &recorded})
+				vsc.forget(ctx, catalog.Video, entry.Id)
+				continue
+			}
+			actual := video_hosting.ItemMetadata{Title: vid.Title, Description: vid.Description, Visibility: vid.Visibility}
+			drifts := metadataDrifts(entry, actual)
+			if failedUploads[vid.UploadStatus] && entry.UploadStatus != vid.UploadStatus {
+				drifts = append(drifts, Drift{Kind: catalog.Video, Id: entry.Id, Reason: ProcessingFailed, UploadStatus: vid.UploadStatus})
+			}
+			for _, drift := range drifts {
+				vsc.drifted(ctx, report, drift)
+			}
+			if entry.Metadata != actual || entry.UploadStatus != vid.UploadStatus {
+				vsc.record(ctx, catalog.Video, entry.Id, func(entry *catalog.Entry) {
+					entry.Metadata = actual
+					entry.UploadStatus = vid.UploadStatus
+				})
+			}
+		}
+	}
+	return nil
+}
+
+func (vsc *VideoStoreService[B, P]) reconcilePlaylists(ctx context.Context, report *ReconcileReport) error {
+	entries, err := vsc.Catalog.List(ctx, vsc.Host, catalog.Playlist)
+	if err != nil {
+		return fmt.Errorf("could not list the recorded playlists : %w", err)
+	}
+	for _, batch := range batches(entries) {
+		playlists, err := vsc.VidHost.ListPlaylists(ctx, ids(batch))
+		if err != nil {
+			return err
+		}
+		found := make(map[string]*video_hosting.Playlist, len(playlists))
+		for _, playlist := range playlists {
+			found[playlist.Id] = playlist
+		}
+		for _, entry := range batch {
+			report.Playlists++
+			playlist, ok := found[entry.Id]
+			if !ok {
+				recorded := entry.Metadata
+				vsc.drifted(ctx, report, Drift{Kind: catalog.Playlist, Id: entry.Id, Reason: Missing, Recorded: &recorded})
+				vsc.forget(ctx, catalog.Playlist, entry.Id)
+				continue
+			}
+			actual := video_hosting.ItemMetadata{Title: playlist.Title, Description: playlist.Description, Visibility: playlist.Visibility}
+			drifts := metadataDrifts(entry, actual)
+			for _, drift := range drifts {
+				vsc.drifted(ctx, report, drift)
+			}
+			if entry.Metadata != actual {
+				vsc.record(ctx, catalog.Playlist, entry.Id, func(entry *catalog.Entry) { entry.Metadata = actual })
+			}
+		}
+	}
+	return nil
+}
+
+// Add drift to the report and publish it
+func (vsc *VideoStoreService[B, P]) drifted(ctx context.Context, report *ReconcileReport, drift Drift) {
+	report.Drifts = append(report.Drifts, drift)
+	evtType := event_broker.VideoDrifted
+	if drift.Kind == catalog.Playlist {
+		evtType = event_broker.PlaylistDrifted
+	}
+	vsc.publish(ctx, evtType, drift.Id, drift)
+}
+
+// Differences between the recorded metadata of entry and the actual ones.
+// An entry recorded without any metadata (only its thumbnail was set) can't drift, it is aligned with the host instead
+func metadataDrifts(entry catalog.Entry, actual video_hosting.ItemMetadata) []Drift {
+	var drifts []Drift
+	recorded := entry.Metadata
+	if recorded.Title == "" && recorded.Description == "" && recorded.Visibility == "" {
+		return drifts
+	}
+	if recorded.Title != actual.Title || recorded.Description != actual.Description {
+		drifts = append(drifts, Drift{Kind: entry.Kind, Id: entry.Id, Reason: MetadataChanged, Recorded: &recorded, Actual: &actual})
+	}
+	// A scheduled video becomes public when the host publishes it
+	published := entry.PublishAt != nil && recorded.Visibility == video_hosting.Private && actual.Visibility == video_hosting.Public
+	if recorded.Visibility != actual.Visibility && !published {
+		drifts = append(drifts, Drift{Kind: entry.Kind, Id: entry.Id, Reason: VisibilityChanged, Recorded: &recorded, Actual: &actual})
+	}
+	return drifts
+}
+
+// Split entries in batches small enough to be looked up with a single call
+func batches(entries []catalog.Entry) [][]catalog.Entry {
+	var split [][]catalog.Entry
+	for start := 0; start < len(entries); start += video_hosting.MaxListIds {
+		end := start + video_hosting.MaxListIds
+		if end > len(entries) {
+			end = len(entries)
+		}
+		split = append(split, entries[start:end])
+	}
+	return split
+}
+
+func ids(entries []catalog.Entry) []string {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.Id
+	}
+	return ids
+}
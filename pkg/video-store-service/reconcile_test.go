@@ -0,0 +1,149 @@
+package video_store_service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+	"video-manager/internal/catalog"
+	event_broker "video-manager/internal/event-broker"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// Record an item of the "youtube" host in records
+func recorded(records *fakeCatalog, kind catalog.Kind, id string, meta video_hosting.ItemMetadata) {
+	records.entries["youtube/"+string(kind)+"/"+id] = &catalog.Entry{Kind: kind, Id: id, Host: "youtube", Metadata: meta}
+}
+
+func SetupReconcile(t *testing.T) (*mocked, *fakeCatalog) {
+	deps := Setup(t, false)
+	records := &fakeCatalog{entries: map[string]*catalog.Entry{}}
+	deps.service.Host = "youtube"
+	deps.service.Catalog = records
+	return deps, records
+}
+
+func TestVideoStoreService_Reconcile(t *testing.T) {
+	deps, records := SetupReconcile(t)
+	meta := video_hosting.ItemMetadata{Title: "title", Description: "desc", Visibility: video_hosting.Unlisted}
+	recorded(records, catalog.Video, "deleted", meta)
+	recorded(records, catalog.Video, "edited", meta)
+	recorded(records, catalog.Video, "rejected", meta)
+	recorded(records, catalog.Video, "same", meta)
+	recorded(records, catalog.Playlist, "published", meta)
+
+	deps.videoStore.EXPECT().ListVideos(gomock.Any(), []string{"deleted", "edited", "rejected", "same"}).Return([]*video_hosting.Video{
+		{Id: "edited", Title: "edited on the host", Description: "desc", Visibility: video_hosting.Unlisted, UploadStatus: video_hosting.Processed},
+		{Id: "rejected", Title: "title", Description: "desc", Visibility: video_hosting.Unlisted, UploadStatus: video_hosting.Rejected},
+		{Id: "same", Title: "title", Description: "desc", Visibility: video_hosting.Unlisted, UploadStatus: video_hosting.Processed},
+	}, nil)
+	deps.videoStore.EXPECT().ListPlaylists(gomock.Any(), []string{"published"}).Return([]*video_hosting.Playlist{
+		{Id: "published", Title: "title", Description: "desc", Visibility: video_hosting.Public},
+	}, nil)
+	report, err := deps.service.Reconcile(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "youtube", report.Host)
+	assert.Equal(t, 4, report.Videos)
+	assert.Equal(t, 1, report.Playlists)
+	assert.Empty(t, report.Error)
+	assert.Equal(t, []DriftReason{Missing, MetadataChanged, ProcessingFailed, VisibilityChanged}, reasons(report))
+	assert.Equal(t, "edited on the host", report.Drifts[1].Actual.Title)
+	assert.Equal(t, "title", report.Drifts[1].Recorded.Title)
+	assert.Equal(t, video_hosting.Rejected, report.Drifts[2].UploadStatus)
+	assert.Same(t, report, deps.service.LastReconcileReport())
+
+	// The catalog is aligned with the host
+	assert.Nil(t, records.entries["youtube/video/deleted"])
+	assert.Equal(t, "edited on the host", records.entries["youtube/video/edited"].Metadata.Title)
+	assert.Equal(t, video_hosting.Rejected, records.entries["youtube/video/rejected"].UploadStatus)
+	assert.Equal(t, video_hosting.Public, records.entries["youtube/playlist/published"].Metadata.Visibility)
+
+	// So that the same drifts aren't reported again
+	deps.videoStore.EXPECT().ListVideos(gomock.Any(), []string{"edited", "rejected", "same"}).Return([]*video_hosting.Video{
+		{Id: "edited", Title: "edited on the host", Description: "desc", Visibility: video_hosting.Unlisted, UploadStatus: video_hosting.Processed},
+		{Id: "rejected", Title: "title", Description: "desc", Visibility: video_hosting.Unlisted, UploadStatus: video_hosting.Rejected},
+		{Id: "same", Title: "title", Description: "desc", Visibility: video_hosting.Unlisted, UploadStatus: video_hosting.Processed},
+	}, nil)
+	deps.videoStore.EXPECT().ListPlaylists(gomock.Any(), []string{"published"}).Return([]*video_hosting.Playlist{
+		{Id: "published", Title: "title", Description: "desc", Visibility: video_hosting.Public},
+	}, nil)
+	report, err = deps.service.Reconcile(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, report.Drifts)
+}
+
+func TestVideoStoreService_Reconcile_Scheduled(t *testing.T) {
+	deps, records := SetupReconcile(t)
+	publishAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	meta := video_hosting.ItemMetadata{Title: "title", Visibility: video_hosting.Private}
+	recorded(records, catalog.Video, "scheduled", meta)
+	records.entries["youtube/video/scheduled"].PublishAt = &publishAt
+	recorded(records, catalog.Video, "private", meta)
+	// Published by the host as scheduled, only the other one drifted
+	deps.videoStore.EXPECT().ListVideos(gomock.Any(), []string{"private", "scheduled"}).Return([]*video_hosting.Video{
+		{Id: "private", Title: "title", Visibility: video_hosting.Public},
+		{Id: "scheduled", Title: "title", Visibility: video_hosting.Public},
+	}, nil)
+	deps.videoStore.EXPECT().ListPlaylists(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	report, err := deps.service.Reconcile(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []DriftReason{VisibilityChanged}, reasons(report))
+	assert.Equal(t, "private", report.Drifts[0].Id)
+	assert.Equal(t, video_hosting.Public, records.entries["youtube/video/scheduled"].Metadata.Visibility)
+}
+
+func TestVideoStoreService_Reconcile_Batches(t *testing.T) {
+	deps, records := SetupReconcile(t)
+	total := video_hosting.MaxListIds + 10
+	for i := 0; i < total; i++ {
+		recorded(records, catalog.Video, fmt.Sprintf("%03d", i), video_hosting.ItemMetadata{})
+	}
+	deps.videoStore.EXPECT().ListVideos(gomock.Any(), gomock.Len(video_hosting.MaxListIds)).Return([]*video_hosting.Video{}, nil)
+	deps.videoStore.EXPECT().ListVideos(gomock.Any(), gomock.Len(10)).Return([]*video_hosting.Video{}, nil)
+	report, err := deps.service.Reconcile(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, total, report.Videos)
+	assert.Len(t, report.Drifts, total)
+}
+
+func TestVideoStoreService_Reconcile_Event(t *testing.T) {
+	deps, records := SetupReconcile(t)
+	deps.service.Events = deps.events
+	recorded(records, catalog.Playlist, "pl", video_hosting.ItemMetadata{Title: "title"})
+	expectEvent(t, deps, event_broker.PlaylistDrifted, "pl")
+	deps.videoStore.EXPECT().ListPlaylists(gomock.Any(), []string{"pl"}).Return([]*video_hosting.Playlist{}, nil)
+	_, err := deps.service.Reconcile(context.Background())
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_Reconcile_Error(t *testing.T) {
+	deps, records := SetupReconcile(t)
+	for i := 0; i < 2; i++ {
+		recorded(records, catalog.Video, strconv.Itoa(i), video_hosting.ItemMetadata{})
+	}
+	deps.videoStore.EXPECT().ListVideos(gomock.Any(), gomock.Any()).
+		Return(nil, video_hosting.NewRequestError(video_hosting.QuotaExceeded, fmt.Errorf("quota exceeded")))
+	report, err := deps.service.Reconcile(context.Background())
+	assert.NotNil(t, err)
+	// The failed attempt is still reported
+	assert.Contains(t, report.Error, "quota exceeded")
+	assert.Same(t, report, deps.service.LastReconcileReport())
+	// And nothing was forgotten
+	assert.Len(t, records.entries, 2)
+
+	// Without any catalog, there is nothing to compare
+	deps.service.Catalog = nil
+	_, err = deps.service.Reconcile(context.Background())
+	assert.ErrorIs(t, err, ErrNoCatalog)
+}
+
+func reasons(report *ReconcileReport) []DriftReason {
+	out := make([]DriftReason, len(report.Drifts))
+	for i, drift := range report.Drifts {
+		out[i] = drift.Reason
+	}
+	return out
+}
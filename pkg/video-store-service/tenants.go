@@ -0,0 +1,91 @@
+package video_store_service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	object_storage "video-manager/internal/object-storage"
+	progress_broker "video-manager/internal/progress-broker"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+// ServiceKey Key under which the service of the host selected by a request is stored in its context
+const ServiceKey = "video-store-service"
+
+// Tenants The video store services of all the hosts, by name.
+// Each host (platform and account) has its own credentials, quota tracking and progress topic
+type Tenants[B object_storage.BindingProxy, P progress_broker.PubSubProxy] struct {
+	services map[string]*VideoStoreService[B, P]
+	// Name of the host used when none is selected
+	defaultHost string
+}
+
+// NewTenants Hosts services by name. defaultHost must be one of them
+func NewTenants[B object_storage.BindingProxy, P progress_broker.PubSubProxy](defaultHost string, services map[string]*VideoStoreService[B, P]) (*Tenants[B, P], error) {
+	if _, ok := services[defaultHost]; !ok {
+		return nil, fmt.Errorf(`the default host "%s" has no service`, defaultHost)
+	}
+	return &Tenants[B, P]{services: services, defaultHost: defaultHost}, nil
+}
+
+// Get The service of the host named name, or of the default host if name is empty
+func (t *Tenants[B, P]) Get(name string) (*VideoStoreService[B, P], error) {
+	if name == "" {
+		name = t.defaultHost
+	}
+	svc, ok := t.services[name]
+	if !ok {
+		return nil, video_hosting.NewRequestError(video_hosting.NotFound, fmt.Errorf(`no host is named "%s"`, name))
+	}
+	return svc, nil
+}
+
+// Default The service of the default host
+func (t *Tenants[B, P]) Default() *VideoStoreService[B, P] {
+	return t.services[t.defaultHost]
+}
+
+// DefaultName Name of the default host
+func (t *Tenants[B, P]) DefaultName() string {
+	return t.defaultHost
+}
+
+// Names Names of all the hosts, sorted
+func (t *Tenants[B, P]) Names() []string {
+	names := make([]string, 0, len(t.services))
+	for name := range t.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Drain Drain all the services at once, see VideoStoreService.Drain.
+// Returns the IDs of the interrupted uploads of every host
+func (t *Tenants[B, P]) Drain(ctx context.Context) []string {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var interrupted []string
+	for _, svc := range t.services {
+		wg.Add(1)
+		go func(svc *VideoStoreService[B, P]) {
+			defer wg.Done()
+			ids := svc.Drain(ctx)
+			mu.Lock()
+			interrupted = append(interrupted, ids...)
+			mu.Unlock()
+		}(svc)
+	}
+	wg.Wait()
+	sort.Strings(interrupted)
+	return interrupted
+}
+
+// FromContext The service of the host selected for the request in ctx, fallback if none was selected
+func FromContext[B object_storage.BindingProxy, P progress_broker.PubSubProxy](ctx context.Context, fallback *VideoStoreService[B, P]) *VideoStoreService[B, P] {
+	if svc, ok := ctx.Value(ServiceKey).(*VideoStoreService[B, P]); ok {
+		return svc
+	}
+	return fallback
+}
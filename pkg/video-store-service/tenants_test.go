@@ -0,0 +1,64 @@
+package video_store_service
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	mock_object_storage "video-manager/internal/mock/object-storage"
+	mock_progress_broker "video-manager/internal/mock/progress-broker"
+	video_hosting "video-manager/internal/video-hosting"
+)
+
+type testService = VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]
+
+func SetupTenants(t *testing.T) (*Tenants[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy], *testService, *testService) {
+	main, backup := Setup(t, false).service, Setup(t, false).service
+	tenants, err := NewTenants("main", map[string]*testService{"main": main, "backup": backup})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tenants, main, backup
+}
+
+func TestNewTenants_UnknownDefault(t *testing.T) {
+	_, err := NewTenants("other", map[string]*testService{"main": Setup(t, false).service})
+	assert.NotNil(t, err)
+}
+
+func TestTenants_Get(t *testing.T) {
+	tenants, main, backup := SetupTenants(t)
+	svc, err := tenants.Get("backup")
+	assert.Nil(t, err)
+	assert.Same(t, backup, svc)
+	// No name means the default host
+	svc, err = tenants.Get("")
+	assert.Nil(t, err)
+	assert.Same(t, main, svc)
+	assert.Same(t, main, tenants.Default())
+	assert.Equal(t, "main", tenants.DefaultName())
+
+	_, err = tenants.Get("other")
+	assert.NotNil(t, err)
+	assert.Equal(t, video_hosting.NotFound, video_hosting.KindOf(err))
+	assert.Equal(t, []string{"backup", "main"}, tenants.Names())
+}
+
+func TestTenants_Drain(t *testing.T) {
+	tenants, main, backup := SetupTenants(t)
+	assert.Empty(t, tenants.Drain(context.Background()))
+	// Every host refuses new uploads
+	for _, svc := range []*testService{main, backup} {
+		_, err := svc.UploadVideoFromStorage(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{Title: "title"})
+		assert.Equal(t, video_hosting.ShuttingDown, video_hosting.KindOf(err))
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	_, main, backup := SetupTenants(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Same(t, main, FromContext(c, main))
+	c.Set(ServiceKey, backup)
+	assert.Same(t, backup, FromContext(c, main))
+}
@@ -3,48 +3,79 @@ package video_store_service
 import (
 	"context"
 	"fmt"
-	"os"
+	"video-manager/internal/config"
+	event_broker "video-manager/internal/event-broker"
 	object_storage "video-manager/internal/object-storage"
 	progress_broker "video-manager/internal/progress-broker"
 	video_hosting "video-manager/internal/video-hosting"
 )
 
-// List of all available video hosting platforms
-type Host uint
-
-const (
-	Youtube Host = iota
-)
-
 // Return an instance of a video storage servcie configured with the provided video host as the backend
-func MakeVideoStoreService[T object_storage.BindingProxy, P progress_broker.PubSubProxy](ctx context.Context, host Host, proxy object_storage.ObjectStorage[T], progressBroker *progress_broker.ProgressBroker[P]) (*VideoStoreService[T, P], error) {
+func MakeVideoStoreService[T object_storage.BindingProxy, P progress_broker.PubSubProxy](ctx context.Context, host config.Host, proxy object_storage.ObjectStorage[T], progressBroker *progress_broker.ProgressBroker[P], eventBroker *event_broker.EventBroker[P]) (*VideoStoreService[T, P], error) {
 	var store video_hosting.IVideoHost
+	var quota *video_hosting.QuotaGuard
+	var pinger video_hosting.Pinger
+	var rotator video_hosting.CredentialsRotator
+	var authorizer video_hosting.Authorizer
+	var watcher video_hosting.AuthWatcher
+	var validator video_hosting.MetadataValidator
 	var err error
-	switch host {
-	case Youtube:
-		store, err = makeYoutubeStoreService(ctx)
+	switch host.Type {
+	case config.Youtube:
+		store, err = makeYoutubeStoreService(ctx, &host)
+		if err == nil {
+			// The decorators below don't forward the health check, the credentials management nor the validation,
+			// keep a reference to the actual store
+			pinger, _ = store.(video_hosting.Pinger)
+			rotator, _ = store.(video_hosting.CredentialsRotator)
+			authorizer, _ = store.(video_hosting.Authorizer)
+			watcher, _ = store.(video_hosting.AuthWatcher)
+			validator, _ = store.(video_hosting.MetadataValidator)
+			// Only the calls actually reaching Youtube are recorded, the quota guard is in front
+			quota, err = makeYoutubeQuotaGuard(video_hosting.NewInstrumentedHost(store), host.DailyQuota)
+			store = quota
+		}
 	default:
-		// This can't actually happen
-		err = fmt.Errorf(`the provided host "%v" has no available implementation`, host)
+		// Already refused when validating the configuration
+		err = fmt.Errorf(`the host "%s" has an unknown type "%s"`, host.Name, host.Type)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &VideoStoreService[T, P]{
-		EvtBroker: progressBroker,
-		ObjStore:  &proxy,
-		VidHost:   store,
-		opt:       VideoStoreOptions{objStoreMaxRetry: 10},
-	}, nil
-
+	svc := &VideoStoreService[T, P]{
+		Host:            host.Name,
+		EvtBroker:       progressBroker,
+		Events:          eventBroker,
+		ObjStore:        &proxy,
+		VidHost:         store,
+		Quota:           quota,
+		HostHealth:      pinger,
+		HostCredentials: rotator,
+		HostAuthorizer:  authorizer,
+		HostAuth:        watcher,
+		HostMetadata:    validator,
+		opt:             VideoStoreOptions{objStoreMaxRetry: 10},
+	}
+	if watcher != nil {
+		watcher.OnAuthExpired(func(err error) { svc.authExpired(host.Name, err) })
+	}
+	return svc, nil
 }
 
 // Returns an instance of a youtube store
-func makeYoutubeStoreService(ctx context.Context) (video_hosting.IVideoHost, error) {
+func makeYoutubeStoreService(ctx context.Context, host *config.Host) (video_hosting.IVideoHost, error) {
 	return video_hosting.NewYoutubeStore(ctx, &video_hosting.YoutubeStoreCredentials{
-		ClientId:     os.Getenv("YT_CLIENT_ID"),
-		ClientSecret: os.Getenv("YT_CLIENT_SECRET"),
-		RefreshToken: os.Getenv("YT_REFRESH_TOKEN"),
-	}, nil)
+		ClientId:     host.ClientId,
+		ClientSecret: host.ClientSecret,
+		RefreshToken: host.RefreshToken,
+	}, &video_hosting.YoutubeStoreOptions{CategoryId: host.CategoryId})
+}
+
+// Track the daily quota consumption of the youtube store
+func makeYoutubeQuotaGuard(store video_hosting.IVideoHost, limit int64) (*video_hosting.QuotaGuard, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf(`invalid daily quota %d`, limit)
+	}
+	return video_hosting.NewQuotaGuard(store, video_hosting.YoutubeQuotaCosts, limit)
 }
@@ -6,10 +6,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
+	"video-manager/internal/config"
 	mock_object_storage "video-manager/internal/mock/object-storage"
 	mock_progress_broker "video-manager/internal/mock/progress-broker"
 	object_storage "video-manager/internal/object-storage"
 	progress_broker "video-manager/internal/progress-broker"
+	video_hosting "video-manager/internal/video-hosting"
 )
 
 func SetupFactory(t *testing.T) (*object_storage.ObjectStorage[*mock_object_storage.MockBindingProxy], *progress_broker.ProgressBroker[*mock_progress_broker.MockPubSubProxy]) {
@@ -19,28 +21,50 @@ func SetupFactory(t *testing.T) (*object_storage.ObjectStorage[*mock_object_stor
 	if err != nil {
 		t.Fatal(err)
 	}
-	ctx := context.TODO()
 	psProxy := mock_progress_broker.NewMockPubSubProxy(ctrl)
-	broker, err := progress_broker.NewProgressBroker[*mock_progress_broker.MockPubSubProxy](&ctx, &psProxy, progress_broker.NewBrokerOptions{})
+	broker, err := progress_broker.NewProgressBroker[*mock_progress_broker.MockPubSubProxy](&psProxy, progress_broker.NewBrokerOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
-	return object_storage.NewObjectStorage[*mock_object_storage.MockBindingProxy](&ctx, dir, proxy), broker
+	return object_storage.NewObjectStorage[*mock_object_storage.MockBindingProxy](dir, proxy), broker
 }
+
+var youtubeHost = config.Host{Name: "youtube", Type: config.Youtube, DailyQuota: video_hosting.YoutubeDefaultDailyQuota}
+
 func Test_VideoServiceFactory_MakeYoutubeVideoStoreService_Youtube(t *testing.T) {
 	objStore, _ := SetupFactory(t)
-	_, err := MakeVideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy](context.TODO(), Youtube, *objStore, nil)
+	vss, err := MakeVideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy](context.TODO(), youtubeHost, *objStore, nil, nil)
+	assert.Nil(t, err)
+	// Youtube has a daily quota, which must be tracked
+	assert.NotNil(t, vss.Quota)
+	assert.Equal(t, int64(video_hosting.YoutubeDefaultDailyQuota), vss.Quota.Status().Limit)
+	// Youtube credentials can be rotated from a secret store
+	assert.NotNil(t, vss.HostCredentials)
+	// ... or obtained with an OAuth consent
+	assert.NotNil(t, vss.HostAuthorizer)
+}
+
+func Test_VideoServiceFactory_MakeYoutubeVideoStoreService_Youtube_CustomQuota(t *testing.T) {
+	objStore, _ := SetupFactory(t)
+	host := youtubeHost
+	host.DailyQuota = 500000
+	vss, err := MakeVideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy](context.TODO(), host, *objStore, nil, nil)
 	assert.Nil(t, err)
+	assert.Equal(t, int64(500000), vss.Quota.Status().Limit)
+
+	host.DailyQuota = 0
+	_, err = MakeVideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy](context.TODO(), host, *objStore, nil, nil)
+	assert.NotNil(t, err)
 }
 
 func Test_VideoServiceFactory_MakeYoutubeVideoStoreService_Youtube_WithBroker(t *testing.T) {
 	objStore, broker := SetupFactory(t)
-	_, err := MakeVideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy](context.TODO(), Youtube, *objStore, broker)
+	_, err := MakeVideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy](context.TODO(), youtubeHost, *objStore, broker, nil)
 	assert.Nil(t, err)
 }
 
 func Test_VideoServiceFactory_MakeVideoStoreService_Error(t *testing.T) {
 	objStore, _ := SetupFactory(t)
-	_, err := MakeVideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy](context.TODO(), Host(4), *objStore, nil)
+	_, err := MakeVideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy](context.TODO(), config.Host{Name: "other", Type: "vimeo"}, *objStore, nil, nil)
 	assert.NotNil(t, err)
 }
@@ -1,13 +1,27 @@
 package video_store_service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"io"
 	"math"
+	"strings"
+	"sync/atomic"
 	"time"
+	"video-manager/internal/catalog"
+	"video-manager/internal/etag"
+	event_broker "video-manager/internal/event-broker"
 	"video-manager/internal/logger"
+	"video-manager/internal/metrics"
 	object_storage "video-manager/internal/object-storage"
 	progress_broker "video-manager/internal/progress-broker"
+	"video-manager/internal/templates"
+	"video-manager/internal/tracing"
 	"video-manager/internal/video-hosting"
 )
 
@@ -18,7 +32,17 @@ var (
 // Fired when an error occured while uploading a video
 type uploadError struct {
 	// Error message
-	Message string `json:message`
+	Message string `json:"message"`
+}
+
+// Fired when the service shut down while uploading a video.
+// Holds everything needed to send the job again
+type uploadInterrupted struct {
+	// Why the upload was interrupted
+	Message string `json:"message"`
+	// Key of the video on the object storage, empty for a video uploaded directly
+	StorageKey string `json:"storageKey,omitempty"`
+	*video_hosting.ItemMetadata
 }
 
 // Fired while uploading a video
@@ -39,10 +63,35 @@ type uploadDone struct {
 	Duration int64 `json:"duration"`
 }
 
+// Payload of an "auth.expired" event
+type authExpired struct {
+	// Why the credentials were refused
+	Message string `json:"message"`
+}
+
+// Payload of a "tags.set" event
+type videoTags struct {
+	Tags []string `json:"tags"`
+}
+
+// Payload of a "video.scheduled" event
+type videoSchedule struct {
+	PublishAt time.Time `json:"publishAt"`
+}
+
+// Payload of a "playlist.item.added" event
+type playlistItem struct {
+	PlaylistId string `json:"playlistId"`
+	VideoId    string `json:"videoId"`
+}
+
 // Fired while uploading a video
 type uploadResult struct {
 	Result *video_hosting.Video
 	Error  error
+	// Job arguments, to describe interrupted jobs
+	StorageKey string
+	Meta       *video_hosting.ItemMetadata
 }
 
 type VideoStoreOptions struct {
@@ -52,21 +101,82 @@ type VideoStoreOptions struct {
 }
 
 // UploadVideoFromStorage Upload a video identified on the object storage by "storageKey" to the video hosting platform
-func (vsc *VideoStoreService[B, P]) UploadVideoFromStorage(jobId string, storageKey string, meta *video_hosting.ItemMetadata) (*video_hosting.Video, error) {
+func (vsc *VideoStoreService[B, P]) UploadVideoFromStorage(ctx context.Context, jobId string, storageKey string, meta *video_hosting.ItemMetadata) (vid *video_hosting.Video, err error) {
+	if err := vsc.checkUpload(meta); err != nil {
+		return nil, err
+	}
+	ctx, span := uploadSpan(ctx, jobId, storageKey)
+	defer func() { tracing.End(span, err) }()
+	// Only cancelled if the service shuts down before the upload completes.
+	// Events are still published with ctx, as they must be sent even when the job is interrupted
+	jobCtx, done, err := vsc.jobs.start(ctx, jobId)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return vsc.uploadFromStorage(ctx, jobCtx, jobId, storageKey, meta)
+}
 
+// UploadVideoFromStorageOnce Upload a video identified on the object storage by "storageKey" as UploadVideoFromStorage
+// does, unless the job "jobId" already uploaded it. Meant for messages delivered more than once : the video the catalog
+// records for the same job and storageKey is returned without uploading anything, and a job still running on this
+// instance fails with ErrJobRunning, to be sent again once it is done.
+// An upload running on another instance is only recorded in the catalog once it completes, and can't be detected before
+func (vsc *VideoStoreService[B, P]) UploadVideoFromStorageOnce(ctx context.Context, jobId string, storageKey string, meta *video_hosting.ItemMetadata) (vid *video_hosting.Video, err error) {
+	if err := vsc.checkUpload(meta); err != nil {
+		return nil, err
+	}
+	ctx, span := uploadSpan(ctx, jobId, storageKey)
+	defer func() { tracing.End(span, err) }()
+	jobCtx, done, err := vsc.jobs.startOnce(ctx, jobId)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	// Registered first, a message delivered again meanwhile waits for this job to be recorded
+	vid, err = vsc.uploaded(ctx, storageKey, jobId)
+	if err != nil {
+		return nil, err
+	}
+	if vid != nil {
+		log.Infof("Upload job %s already uploaded video %s, ignoring it", jobId, vid.Id)
+		return vid, nil
+	}
+	return vsc.uploadFromStorage(ctx, jobCtx, jobId, storageKey, meta)
+}
+
+// Refuse the metadata of an upload before downloading anything
+func (vsc *VideoStoreService[B, P]) checkUpload(meta *video_hosting.ItemMetadata) error {
 	if meta == nil {
-		return nil, fmt.Errorf("no video metadata provided, aborting")
+		return fmt.Errorf("no video metadata provided, aborting")
 	}
+	return vsc.validateVideo(meta)
+}
+
+// Trace the upload job "jobId". The upload may take far longer than the request that started it, and must not be
+// cancelled with it. It is still traced as part of the request
+func uploadSpan(ctx context.Context, jobId string, storageKey string) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{attribute.String("upload.job_id", jobId)}
+	if storageKey != "" {
+		attributes = append(attributes, attribute.String("object_storage.key", storageKey))
+	}
+	return tracing.Start(tracing.Detach(ctx), "upload "+jobId, trace.WithAttributes(attributes...))
+}
+
+// Download the video "storageKey" from the object storage, and upload it as the job "jobId", started with jobCtx
+func (vsc *VideoStoreService[B, P]) uploadFromStorage(ctx, jobCtx context.Context, jobId string, storageKey string, meta *video_hosting.ItemMetadata) (vid *video_hosting.Video, err error) {
+	metrics.ActiveJobs.Inc()
+	defer metrics.ActiveJobs.Dec()
 	// Get the content of the file to upload and buffer it into memory
 	// So there may be a race condition here.
 	// As far as I understand, object uploaded on a storage aren't available immediately after upload, there is a slight
 	// delay that might be caused by the configured B64 decoding. Still, as the file gets bigger, this delay gets longer.
 	// So we actually can't trust the Buffer to work the first time around.
 	var reader *io.Reader
-	var err error
 	// Using "<=", we make sure the loop in entered at least once, event if max retry is 0
-	for attempts := int8(0); attempts <= vsc.opt.objStoreMaxRetry; attempts++ {
-		reader, err = vsc.ObjStore.Buffer(storageKey)
+	for attempts := int8(0); attempts <= vsc.opt.objStoreMaxRetry && jobCtx.Err() == nil; attempts++ {
+		reader, err = vsc.ObjStore.Buffer(jobCtx, storageKey)
+		metrics.StorageFetchAttempts.WithLabelValues(metrics.Outcome(err)).Inc()
 		if err == nil {
 			break
 		}
@@ -75,12 +185,48 @@ func (vsc *VideoStoreService[B, P]) UploadVideoFromStorage(jobId string, storage
 		// The sum 2^n from 0 to 10 = 2047 ~= 30min  of total wait, this is way more than enough, as more will be over an
 		// http session time. Plus, if the waiting time is really because of the b64 decoding, it's a 0(n) time complexity algorithm
 		delaySecs := int64(math.Pow(2, float64(attempts)))
-		time.Sleep(time.Duration(delaySecs) * time.Second)
+		select {
+		case <-time.After(time.Duration(delaySecs) * time.Second):
+		case <-jobCtx.Done():
+		}
+	}
+	if interrupted(jobCtx) {
+		err = vsc.interrupt(ctx, jobId, storageKey, meta)
+		return nil, err
+	}
+	if err != nil {
+		return nil, video_hosting.NewRequestError(video_hosting.StorageUnavailable,
+			fmt.Errorf("error while downloading video from object storage : %w", err))
+	}
+
+	return vsc.sendToHost(ctx, jobCtx, jobId, storageKey, meta, *reader)
+}
+
+// UploadVideo Upload a video read from "content" to the video hosting platform, without going through the object storage.
+// The job is handled exactly as with UploadVideoFromStorage, but content can only be read once : an interrupted job
+// must be sent again with its content
+func (vsc *VideoStoreService[B, P]) UploadVideo(ctx context.Context, jobId string, content io.Reader, meta *video_hosting.ItemMetadata) (vid *video_hosting.Video, err error) {
+	if meta == nil {
+		return nil, fmt.Errorf("no video metadata provided, aborting")
+	}
+	if err := vsc.validateVideo(meta); err != nil {
+		return nil, err
 	}
+	ctx, span := uploadSpan(ctx, jobId, "")
+	defer func() { tracing.End(span, err) }()
+	jobCtx, done, err := vsc.jobs.start(ctx, jobId)
 	if err != nil {
-		return nil, fmt.Errorf("error while downloading video from object storage : %w", err)
+		return nil, err
 	}
+	defer done()
+	metrics.ActiveJobs.Inc()
+	defer metrics.ActiveJobs.Dec()
+	return vsc.sendToHost(ctx, jobCtx, jobId, "", meta, content)
+}
 
+// Send content to the video hosting platform as the job "jobId", reporting its progress on the event broker.
+// storageKey is only recorded, and is empty when the video was not uploaded from the object storage
+func (vsc *VideoStoreService[B, P]) sendToHost(ctx, jobCtx context.Context, jobId string, storageKey string, meta *video_hosting.ItemMetadata, content io.Reader) (vid *video_hosting.Video, err error) {
 	// Progress routine, post upload progress on the event broker if it has defined
 	var onProgress video_hosting.ProgressFunc
 	quit := make(chan uploadResult, 1)
@@ -96,18 +242,26 @@ func (vsc *VideoStoreService[B, P]) UploadVideoFromStorage(jobId string, storage
 				// pgChannel is full
 			}
 		}
-		go vsc.startProgressRoutine(jobId, time.Second, pgChannel, quit)
+		go vsc.startProgressRoutine(ctx, jobId, time.Second, pgChannel, quit)
 	}
 
-	// Upload the buffered content to the video storage
-	vid, err := vsc.VidHost.CreateVideo(meta, *reader, &onProgress)
+	// Upload the content to the video storage
+	start := time.Now()
+	vid, err = vsc.VidHost.CreateVideo(jobCtx, meta, &countingReader{r: content}, &onProgress)
+	metrics.UploadDuration.WithLabelValues(metrics.Outcome(err)).Observe(time.Since(start).Seconds())
+	if err != nil && interrupted(jobCtx) {
+		log.Warnf("Upload job %s interrupted by the service shutdown", jobId)
+		err = video_hosting.NewRequestError(video_hosting.ShuttingDown, fmt.Errorf("job %s : %w", jobId, ErrInterrupted))
+	}
 
 	// Wait for the event broker goroutine
 	if vsc.EvtBroker != nil {
 		// Send the error/nil to the buffered error channel
 		quit <- uploadResult{
-			Result: vid,
-			Error:  err,
+			Result:     vid,
+			Error:      err,
+			StorageKey: storageKey,
+			Meta:       meta,
 		}
 		// And wait for the progress channel to be closed by the goroutine
 		<-pgChannel
@@ -119,13 +273,57 @@ func (vsc *VideoStoreService[B, P]) UploadVideoFromStorage(jobId string, storage
 	if err != nil {
 		return nil, fmt.Errorf("error while uploading video : %w", err)
 	}
+	vsc.publish(ctx, event_broker.VideoCreated, vid.Id, vid)
+	vsc.record(ctx, catalog.Video, vid.Id, func(entry *catalog.Entry) {
+		entry.JobId = jobId
+		entry.StorageKey = storageKey
+		entry.Metadata = *meta
+	})
+	if vsc.ProcessingInterval > 0 {
+		go vsc.followProcessing(ctx, jobId, vid.Id)
+	}
 
 	return vid, err
 }
 
+// Drain Refuse any new upload, and wait for the running ones until ctx is done.
+// Uploads still running at this point are interrupted, marked as such on the progress broker, and their job IDs returned.
+// Interrupted uploads can be resumed by sending the same job again
+func (vsc *VideoStoreService[B, P]) Drain(ctx context.Context) []string {
+	return vsc.jobs.drain(ctx)
+}
+
+// Mark an upload interrupted before the upload to the hosting platform even started
+func (vsc *VideoStoreService[B, P]) interrupt(ctx context.Context, jobId string, storageKey string, meta *video_hosting.ItemMetadata) error {
+	log.Warnf("Upload job %s interrupted by the service shutdown", jobId)
+	err := video_hosting.NewRequestError(video_hosting.ShuttingDown, fmt.Errorf("job %s : %w", jobId, ErrInterrupted))
+	if vsc.EvtBroker != nil {
+		sErr := vsc.EvtBroker.SendProgress(ctx, progress_broker.UploadInfos{
+			JobId: jobId,
+			State: progress_broker.Interrupted,
+			Data:  uploadInterrupted{Message: err.Error(), StorageKey: storageKey, ItemMetadata: meta},
+		})
+		if sErr != nil {
+			log.Errorf("Could not send event to progress broker : %s", sErr.Error())
+		}
+	}
+	return err
+}
+
+// Reader counting the bytes sent to the hosting platform
+type countingReader struct {
+	r io.Reader
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	metrics.UploadBytes.Add(float64(n))
+	return n, err
+}
+
 // Periodically send progress to the event broker
 // If an error is passed in errorCh, send Error, if nil is passed, send Done instead
-func (vsc *VideoStoreService[B, P]) startProgressRoutine(jobId string, every time.Duration, pgChannel chan uploadProgress, resCh chan uploadResult) {
+func (vsc *VideoStoreService[B, P]) startProgressRoutine(ctx context.Context, jobId string, every time.Duration, pgChannel chan uploadProgress, resCh chan uploadResult) {
 	ticker := time.NewTicker(every)
 	for {
 		select {
@@ -134,7 +332,7 @@ func (vsc *VideoStoreService[B, P]) startProgressRoutine(jobId string, every tim
 			// Try to publish a progress event if any is available
 			select {
 			case pg := <-pgChannel:
-				sErr := vsc.EvtBroker.SendProgress(progress_broker.UploadInfos{
+				sErr := vsc.EvtBroker.SendProgress(ctx, progress_broker.UploadInfos{
 					JobId: jobId,
 					State: progress_broker.InProgress,
 					Data:  pg,
@@ -151,7 +349,10 @@ func (vsc *VideoStoreService[B, P]) startProgressRoutine(jobId string, every tim
 			// If an error is detected, send error, else send done
 			state := progress_broker.Done
 			var data interface{}
-			if res.Error != nil {
+			if errors.Is(res.Error, ErrInterrupted) {
+				state = progress_broker.Interrupted
+				data = uploadInterrupted{Message: res.Error.Error(), StorageKey: res.StorageKey, ItemMetadata: res.Meta}
+			} else if res.Error != nil {
 				state = progress_broker.Error
 				data = uploadError{Message: res.Error.Error()}
 			} else {
@@ -161,7 +362,7 @@ func (vsc *VideoStoreService[B, P]) startProgressRoutine(jobId string, every tim
 					Duration:    res.Result.Duration,
 				}
 			}
-			sErr := vsc.EvtBroker.SendProgress(progress_broker.UploadInfos{
+			sErr := vsc.EvtBroker.SendProgress(ctx, progress_broker.UploadInfos{
 				JobId: jobId,
 				State: state,
 				Data:  data,
@@ -175,22 +376,415 @@ func (vsc *VideoStoreService[B, P]) startProgressRoutine(jobId string, every tim
 	}
 }
 
-func (vsc *VideoStoreService[B, P]) SetVideoThumbnailFromStorage(vidId, thumbStorageKey string) error {
-	reader, err := vsc.ObjStore.Buffer(thumbStorageKey)
+// SetVideoThumbnailFromStorage Set the thumbnail of the video "vidId" with an image identified on the object storage by "thumbStorageKey"
+func (vsc *VideoStoreService[B, P]) SetVideoThumbnailFromStorage(ctx context.Context, vidId, thumbStorageKey string) error {
+	reader, err := vsc.ObjStore.Buffer(ctx, thumbStorageKey)
+	if err != nil {
+		return video_hosting.NewRequestError(video_hosting.StorageUnavailable,
+			fmt.Errorf("error while downloading thumbnail from object storage : %w", err))
+	}
+
+	return vsc.setVideoThumbnail(ctx, vidId, *reader, thumbStorageKey)
+}
+
+// SetVideoThumbnail Set the thumbnail of the video "vidId" with the provided image content
+func (vsc *VideoStoreService[B, P]) SetVideoThumbnail(ctx context.Context, vidId string, thumbnailContent io.Reader) error {
+	return vsc.setVideoThumbnail(ctx, vidId, thumbnailContent, "")
+}
+
+// Set the thumbnail of the video "vidId", storageKey being the key of the image on the object storage if it comes from there
+func (vsc *VideoStoreService[B, P]) setVideoThumbnail(ctx context.Context, vidId string, thumbnailContent io.Reader, storageKey string) error {
+	err := vsc.VidHost.UpdateVideoThumbnail(ctx, vidId, thumbnailContent)
+	if err != nil {
+		return err
+	}
+	vsc.publish(ctx, event_broker.ThumbnailSet, vidId, nil)
+	vsc.record(ctx, catalog.Video, vidId, func(entry *catalog.Entry) { entry.ThumbnailKey = storageKey })
+	return nil
+}
+
+// SetVideoTags Replace the keywords of the video "vidId" with tags
+func (vsc *VideoStoreService[B, P]) SetVideoTags(ctx context.Context, vidId string, tags []string) error {
+	if vsc.HostMetadata != nil {
+		if err := vsc.HostMetadata.ValidateTags(tags); err != nil {
+			return err
+		}
+	}
+	if err := vsc.VidHost.SetVideoTags(ctx, vidId, tags); err != nil {
+		return err
+	}
+	vsc.publish(ctx, event_broker.TagsSet, vidId, videoTags{Tags: tags})
+	vsc.record(ctx, catalog.Video, vidId, func(entry *catalog.Entry) { entry.Tags = tags })
+	return nil
+}
+
+// AddVideoCaption Add a caption track read from "content" to the video "vidId"
+func (vsc *VideoStoreService[B, P]) AddVideoCaption(ctx context.Context, vidId string, caption *video_hosting.Caption, content io.Reader) error {
+	if err := vsc.VidHost.AddVideoCaption(ctx, vidId, caption, content); err != nil {
+		return err
+	}
+	vsc.publish(ctx, event_broker.CaptionAdded, vidId, caption)
+	vsc.record(ctx, catalog.Video, vidId, func(entry *catalog.Entry) {
+		for _, language := range entry.Captions {
+			if language == caption.Language {
+				return
+			}
+		}
+		entry.Captions = append(entry.Captions, caption.Language)
+	})
+	return nil
+}
+
+// ScheduleVideo Make the video "vidId" private until publishAt, when the host publishes it
+func (vsc *VideoStoreService[B, P]) ScheduleVideo(ctx context.Context, vidId string, publishAt time.Time) error {
+	if err := vsc.VidHost.ScheduleVideo(ctx, vidId, publishAt); err != nil {
+		return err
+	}
+	vsc.publish(ctx, event_broker.VideoScheduled, vidId, videoSchedule{PublishAt: publishAt})
+	vsc.record(ctx, catalog.Video, vidId, func(entry *catalog.Entry) {
+		entry.PublishAt = &publishAt
+		// Entries recorded without metadata are aligned with the host by the next reconciliation instead
+		if entry.Metadata.Visibility != "" {
+			entry.Metadata.Visibility = video_hosting.Private
+		}
+	})
+	return nil
+}
+
+// UpdateVideo Update the video identified by "id" with all the updatable attributes of "replacement"
+func (vsc *VideoStoreService[B, P]) UpdateVideo(ctx context.Context, id string, replacement *video_hosting.Video) (*video_hosting.Video, error) {
+	err := vsc.validateVideo(&video_hosting.ItemMetadata{Title: replacement.Title, Description: replacement.Description, Visibility: replacement.Visibility})
+	if err != nil {
+		return nil, err
+	}
+	vid, err := vsc.VidHost.UpdateVideo(ctx, id, replacement)
+	if err != nil {
+		return nil, err
+	}
+	vsc.publish(ctx, event_broker.VideoUpdated, id, vid)
+	vsc.record(ctx, catalog.Video, id, func(entry *catalog.Entry) {
+		entry.Metadata = video_hosting.ItemMetadata{Title: vid.Title, Description: vid.Description, Visibility: vid.Visibility}
+	})
+	return vid, nil
+}
+
+// PatchVideo Apply the RFC 7396 merge patch to the video identified by "id". Only its updatable attributes can be patched.
+// Fails with a PreconditionFailed error unless the video matches ifMatch, the value of an If-Match header, if not empty
+func (vsc *VideoStoreService[B, P]) PatchVideo(ctx context.Context, id string, data []byte, ifMatch string) (*video_hosting.Video, error) {
+	// Refused before anything is retrieved from the host
+	patch, err := video_hosting.ParseMergePatch[video_hosting.Video](data)
+	if err != nil {
+		return nil, err
+	}
+	vid, err := vsc.VidHost.RetrieveVideo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := precondition(ifMatch, vid.ETag); err != nil {
+		return nil, err
+	}
+	// Nothing to change, the update would be a waste of quota
+	if len(patch) == 0 {
+		return vid, nil
+	}
+	replacement, err := patch.Apply(vid)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPatched(replacement.Title, replacement.Description, replacement.Visibility); err != nil {
+		return nil, err
+	}
+	return vsc.UpdateVideo(ctx, id, replacement)
+}
+
+// DeleteVideo Delete the video identified by "id" from the hosting platform
+func (vsc *VideoStoreService[B, P]) DeleteVideo(ctx context.Context, id string) error {
+	err := vsc.VidHost.DeleteVideo(ctx, id)
+	if err != nil {
+		return err
+	}
+	vsc.publish(ctx, event_broker.VideoDeleted, id, nil)
+	vsc.forget(ctx, catalog.Video, id)
+	return nil
+}
+
+// CreatePlaylist Create a new empty playlist on the hosting platform
+func (vsc *VideoStoreService[B, P]) CreatePlaylist(ctx context.Context, meta *video_hosting.ItemMetadata) (*video_hosting.Playlist, error) {
+	if err := vsc.validatePlaylist(meta); err != nil {
+		return nil, err
+	}
+	playlist, err := vsc.VidHost.CreatePlaylist(ctx, meta)
+	if err != nil {
+		return nil, err
+	}
+	vsc.publish(ctx, event_broker.PlaylistCreated, playlist.Id, playlist)
+	vsc.record(ctx, catalog.Playlist, playlist.Id, func(entry *catalog.Entry) { entry.Metadata = *meta })
+	return playlist, nil
+}
+
+// UpdatePlaylist Update the playlist identified by "id" with all the updatable attributes of "replacement"
+func (vsc *VideoStoreService[B, P]) UpdatePlaylist(ctx context.Context, id string, replacement *video_hosting.Playlist) (*video_hosting.Playlist, error) {
+	err := vsc.validatePlaylist(&video_hosting.ItemMetadata{Title: replacement.Title, Description: replacement.Description, Visibility: replacement.Visibility})
+	if err != nil {
+		return nil, err
+	}
+	playlist, err := vsc.VidHost.UpdatePlaylist(ctx, id, replacement)
+	if err != nil {
+		return nil, err
+	}
+	vsc.publish(ctx, event_broker.PlaylistUpdated, id, playlist)
+	vsc.record(ctx, catalog.Playlist, id, func(entry *catalog.Entry) {
+		entry.Metadata = video_hosting.ItemMetadata{Title: playlist.Title, Description: playlist.Description, Visibility: playlist.Visibility}
+	})
+	return playlist, nil
+}
+
+// PatchPlaylist Apply the RFC 7396 merge patch to the playlist identified by "id". Only its updatable attributes can be patched.
+// Fails with a PreconditionFailed error unless the playlist matches ifMatch, the value of an If-Match header, if not empty
+func (vsc *VideoStoreService[B, P]) PatchPlaylist(ctx context.Context, id string, data []byte, ifMatch string) (*video_hosting.Playlist, error) {
+	patch, err := video_hosting.ParseMergePatch[video_hosting.Playlist](data)
+	if err != nil {
+		return nil, err
+	}
+	playlist, err := vsc.VidHost.RetrievePlaylist(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := precondition(ifMatch, playlist.ETag); err != nil {
+		return nil, err
+	}
+	if len(patch) == 0 {
+		return playlist, nil
+	}
+	replacement, err := patch.Apply(playlist)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPatched(replacement.Title, replacement.Description, replacement.Visibility); err != nil {
+		return nil, err
+	}
+	return vsc.UpdatePlaylist(ctx, id, replacement)
+}
+
+// IfMatchVideo Fails with a PreconditionFailed error unless the video identified by "id" currently matches ifMatch,
+// the value of an If-Match header. The video isn't even retrieved if ifMatch is empty.
+// The host may still change the video before the request relying on it runs, this only narrows the window
+func (vsc *VideoStoreService[B, P]) IfMatchVideo(ctx context.Context, id string, ifMatch string) error {
+	if ifMatch == "" {
+		return nil
+	}
+	vid, err := vsc.VidHost.RetrieveVideo(ctx, id)
+	if err != nil {
+		return err
+	}
+	return precondition(ifMatch, vid.ETag)
+}
+
+// IfMatchPlaylist Same as IfMatchVideo, for the playlist identified by "id"
+func (vsc *VideoStoreService[B, P]) IfMatchPlaylist(ctx context.Context, id string, ifMatch string) error {
+	if ifMatch == "" {
+		return nil
+	}
+	playlist, err := vsc.VidHost.RetrievePlaylist(ctx, id)
+	if err != nil {
+		return err
+	}
+	return precondition(ifMatch, playlist.ETag)
+}
+
+// Fails with a PreconditionFailed error unless an item tagged with tag satisfies ifMatch, if not empty
+func precondition(ifMatch string, tag string) error {
+	if ifMatch == "" || etag.Matches(ifMatch, tag, false) {
+		return nil
+	}
+	return video_hosting.NewRequestError(video_hosting.PreconditionFailed,
+		fmt.Errorf("the item changed, its current version is %s and not %s", etag.Quote(tag), ifMatch))
+}
+
+// DeletePlaylist Delete the playlist identified by "id" from the hosting platform
+func (vsc *VideoStoreService[B, P]) DeletePlaylist(ctx context.Context, id string) error {
+	err := vsc.VidHost.DeletePlaylist(ctx, id)
+	if err != nil {
+		return err
+	}
+	vsc.publish(ctx, event_broker.PlaylistDeleted, id, nil)
+	vsc.forget(ctx, catalog.Playlist, id)
+	return nil
+}
+
+// AddVideoToPlaylist Append the video "videoId" to the playlist "playlistId"
+func (vsc *VideoStoreService[B, P]) AddVideoToPlaylist(ctx context.Context, videoId string, playlistId string) error {
+	err := vsc.VidHost.AddVideoToPlaylist(ctx, videoId, playlistId)
+	if err != nil {
+		return err
+	}
+	vsc.publish(ctx, event_broker.PlaylistItemAdded, playlistId, playlistItem{PlaylistId: playlistId, VideoId: videoId})
+	vsc.record(ctx, catalog.Playlist, playlistId, func(entry *catalog.Entry) {
+		for _, item := range entry.Items {
+			if item == videoId {
+				return
+			}
+		}
+		entry.Items = append(entry.Items, videoId)
+	})
+	return nil
+}
+
+// RenderTemplate Fill the metadata template called name with vars.
+// Fails with a NotFound error if there is no such template, and an InvalidMetadata one if it can't be rendered
+func (vsc *VideoStoreService[B, P]) RenderTemplate(ctx context.Context, name string, vars map[string]any) (*templates.Rendered, error) {
+	if vsc.Templates == nil {
+		return nil, video_hosting.NewRequestError(video_hosting.NotFound, fmt.Errorf(`no template named "%s"`, name))
+	}
+	rendered, err := vsc.Templates.Render(ctx, name, vars)
+	if err != nil {
+		return nil, err
+	}
+	// The rendered metadata must also fit in the limits of the host
+	if err := vsc.validateVideo(&video_hosting.ItemMetadata{Title: rendered.Title, Description: rendered.Description}); err != nil {
+		return nil, err
+	}
+	return rendered, nil
+}
+
+// Check the metadata of a video against the limits of the host, if it has any
+func (vsc *VideoStoreService[B, P]) validateVideo(meta *video_hosting.ItemMetadata) error {
+	if vsc.HostMetadata == nil {
+		return nil
+	}
+	return vsc.HostMetadata.ValidateVideo(meta)
+}
+
+// Check the metadata of a patched item as a request body would be (see ItemMetadata), as a patch may remove
+// a required field or set any value. Returns an InvalidMetadata error listing every refused field
+func checkPatched(title string, description string, visibility video_hosting.Visibility) error {
+	var fields []video_hosting.FieldError
+	err := binding.Validator.ValidateStruct(&video_hosting.ItemMetadata{Title: title, Description: description, Visibility: visibility})
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		for _, fe := range invalid {
+			field := video_hosting.FieldError{Field: strings.ToLower(fe.Field())}
+			switch fe.Tag() {
+			case "required":
+				field.Reason = "is required"
+			case "max":
+				field.Reason = fmt.Sprintf("must be at most %s characters long", fe.Param())
+			default:
+				field.Reason = fmt.Sprintf("failed on the %s rule", fe.Tag())
+			}
+			fields = append(fields, field)
+		}
+	} else if err != nil {
+		return err
+	}
+	switch visibility {
+	case "", video_hosting.Public, video_hosting.Private, video_hosting.Unlisted:
+	default:
+		fields = append(fields, video_hosting.FieldError{Field: "visibility", Reason: fmt.Sprintf(`must be "%s", "%s" or "%s"`, video_hosting.Public, video_hosting.Private, video_hosting.Unlisted)})
+	}
+	return video_hosting.NewValidationError(fields)
+}
+
+// Check the metadata of a playlist against the limits of the host, if it has any
+func (vsc *VideoStoreService[B, P]) validatePlaylist(meta *video_hosting.ItemMetadata) error {
+	if vsc.HostMetadata == nil {
+		return nil
+	}
+	return vsc.HostMetadata.ValidatePlaylist(meta)
+}
+
+// Publish a domain event if an event broker is defined.
+// A failure to publish is logged but never fails the operation itself, as the change is already made on the host
+func (vsc *VideoStoreService[B, P]) publish(ctx context.Context, evtType event_broker.EventType, subject string, data interface{}) {
+	if vsc.Events == nil {
+		return
+	}
+	err := vsc.Events.Publish(ctx, event_broker.Event{
+		Type:    evtType,
+		Subject: subject,
+		Data:    data,
+	})
 	if err != nil {
-		return fmt.Errorf("error while downloading thumbnail from object storage : %w", err)
+		log.Errorf("Could not publish event %s for %s : %s", evtType, subject, err.Error())
+	}
+}
+
+// Record a change in the catalog if there is one.
+// As with the events, a failure is logged but never fails the operation itself
+func (vsc *VideoStoreService[B, P]) record(ctx context.Context, kind catalog.Kind, id string, fn func(entry *catalog.Entry)) {
+	if vsc.Catalog == nil {
+		return
+	}
+	if _, err := vsc.Catalog.Update(ctx, vsc.Host, kind, id, fn); err != nil {
+		log.Errorf("Could not record %s %s in the catalog : %s", kind, id, err.Error())
+	}
+}
+
+// Remove a deleted item from the catalog if there is one
+func (vsc *VideoStoreService[B, P]) forget(ctx context.Context, kind catalog.Kind, id string) {
+	if vsc.Catalog == nil {
+		return
+	}
+	if err := vsc.Catalog.Remove(ctx, vsc.Host, kind, id); err != nil {
+		log.Errorf("Could not remove %s %s from the catalog : %s", kind, id, err.Error())
 	}
+}
+
+// Report the credentials of the host named host were refused. Every call fails until an operator authorizes it again
+func (vsc *VideoStoreService[B, P]) authExpired(host string, err error) {
+	log.Errorf(`The credentials of host "%s" were refused, it must be authorized again : %s`, host, err.Error())
+	vsc.publish(context.Background(), event_broker.AuthExpired, host, authExpired{Message: err.Error()})
+}
+
+// Catalog Records the items created or modified through the service, see catalog.Catalog
+type Catalog interface {
+	Get(ctx context.Context, host string, kind catalog.Kind, id string) (*catalog.Entry, error)
+	Update(ctx context.Context, host string, kind catalog.Kind, id string, fn func(entry *catalog.Entry)) (*catalog.Entry, error)
+	Remove(ctx context.Context, host string, kind catalog.Kind, id string) error
+	List(ctx context.Context, host string, kind catalog.Kind) ([]catalog.Entry, error)
+	FindBySource(ctx context.Context, host string, storageKey string) (*catalog.Entry, error)
+}
 
-	return vsc.VidHost.UpdateVideoThumbnail(vidId, *reader)
+// Templates Renders the metadata templates, see templates.Templates
+type Templates interface {
+	Render(ctx context.Context, name string, vars map[string]any) (*templates.Rendered, error)
 }
 
 type VideoStoreService[B object_storage.BindingProxy, P progress_broker.PubSubProxy] struct {
+	// Name of the host the service uploads to
+	Host string
 	// Backend object storage
 	ObjStore *object_storage.ObjectStorage[B]
 	// Event broker to send notification into
 	EvtBroker *progress_broker.ProgressBroker[P]
+	// Event broker to publish every change made on the host into
+	Events *event_broker.EventBroker[P]
 	// Video hosting platform
 	VidHost video_hosting.IVideoHost
+	// Daily quota consumption of the video hosting platform. Nil if the platform has no quota
+	Quota *video_hosting.QuotaGuard
+	// Availability of the video hosting platform. Nil if the platform can't tell
+	HostHealth video_hosting.Pinger
+	// Credentials of the video hosting platform. Nil if they can't be replaced while running
+	HostCredentials video_hosting.CredentialsRotator
+	// OAuth consent of the video hosting platform. Nil if the platform doesn't use OAuth
+	HostAuthorizer video_hosting.Authorizer
+	// Expiry of the credentials of the video hosting platform. Nil if the platform can't tell
+	HostAuth video_hosting.AuthWatcher
+	// Limits of the video hosting platform on the metadata. Nil if the platform has none of its own
+	HostMetadata video_hosting.MetadataValidator
+	// Record of the items created or modified through the service. Nil if there is none
+	Catalog Catalog
+	// Templates of the video metadata. Nil if there is none
+	Templates Templates
+	// How often an uploaded video is looked up until the host processed it. 0 disables it
+	ProcessingInterval time.Duration
+	// How long an uploaded video is looked up at most. 0 means until it is processed
+	ProcessingTimeout time.Duration
+	// Last comparison of the catalog with the host
+	reconciled atomic.Pointer[ReconcileReport]
+	// Running upload jobs
+	jobs jobTracker
 	// Customize behaviour of the service
 	// Not using a pointer will initialize a struct will default values
 	opt VideoStoreOptions
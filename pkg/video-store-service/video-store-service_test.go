@@ -1,15 +1,23 @@
 package video_store_service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/dapr/go-sdk/client"
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"os"
+	"sort"
+	"strings"
 	"testing"
 	"time"
+	"video-manager/internal/catalog"
+	event_broker "video-manager/internal/event-broker"
+	"video-manager/internal/metrics"
 	mock_object_storage "video-manager/internal/mock/object-storage"
 	mock_progress_broker "video-manager/internal/mock/progress-broker"
 	mock_video_hosting "video-manager/internal/mock/video-hosting"
@@ -22,11 +30,11 @@ type mocked struct {
 	videoStore       *mock_video_hosting.MockIVideoHost
 	objectStoreProxy *mock_object_storage.MockBindingProxy
 	brokerProxy      *mock_progress_broker.MockPubSubProxy
-	service          VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]
+	events           *event_broker.EventBroker[*mock_progress_broker.MockPubSubProxy]
+	service          *VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]
 }
 
 func Setup(t *testing.T, initBroker bool) *mocked {
-	ctx := context.Background()
 	dir, err := os.MkdirTemp("", "assets")
 	if err != nil {
 		t.Fatal(err)
@@ -34,7 +42,7 @@ func Setup(t *testing.T, initBroker bool) *mocked {
 	ctrl := gomock.NewController(t)
 	// Initialize object storage
 	objStoreProxy := mock_object_storage.NewMockBindingProxy(ctrl)
-	objectStore := object_storage.NewObjectStorage[*mock_object_storage.MockBindingProxy](&ctx, dir, objStoreProxy)
+	objectStore := object_storage.NewObjectStorage[*mock_object_storage.MockBindingProxy](dir, objStoreProxy)
 
 	// Initialize video host
 	vidCtrl := gomock.NewController(t)
@@ -42,13 +50,18 @@ func Setup(t *testing.T, initBroker bool) *mocked {
 
 	//  Initialize event broker
 	psProxy := mock_progress_broker.NewMockPubSubProxy(ctrl)
-	broker, err := progress_broker.NewProgressBroker[*mock_progress_broker.MockPubSubProxy](&ctx, &psProxy, progress_broker.NewBrokerOptions{
+	broker, err := progress_broker.NewProgressBroker[*mock_progress_broker.MockPubSubProxy](&psProxy, progress_broker.NewBrokerOptions{
 		Component: "",
 		Topic:     "",
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	// Lifecycle events broker, only attached on demand by the tests needing it
+	events, err := event_broker.NewEventBroker[*mock_progress_broker.MockPubSubProxy](&psProxy, event_broker.NewBrokerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	vss := VideoStoreService[*mock_object_storage.MockBindingProxy, *mock_progress_broker.MockPubSubProxy]{
 		ObjStore: objectStore,
@@ -63,7 +76,8 @@ func Setup(t *testing.T, initBroker bool) *mocked {
 		videoStore:       vidHost,
 		objectStoreProxy: objStoreProxy,
 		brokerProxy:      psProxy,
-		service:          vss,
+		events:           events,
+		service:          &vss,
 	}
 }
 
@@ -72,7 +86,7 @@ func TestVideoStoreService_SetVideoThumbnailFromStorage_DownloadError(t *testing
 	// Setup the proxy to fail to simulate a download error
 	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
 
-	err := deps.service.SetVideoThumbnailFromStorage("test", "test")
+	err := deps.service.SetVideoThumbnailFromStorage(context.Background(), "test", "test")
 	assert.NotNil(t, err)
 }
 
@@ -82,9 +96,9 @@ func TestVideoStoreService_SetVideoThumbnailFromStorage_UpdateThumbnailError(t *
 	// Setup the proxy to succeed
 	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("a")}, nil)
 	// Setup the video host to fail
-	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), gomock.Any()).Return(fmt.Errorf("test"))
+	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("test"))
 
-	err := deps.service.SetVideoThumbnailFromStorage("test", "test")
+	err := deps.service.SetVideoThumbnailFromStorage(context.Background(), "test", "test")
 	assert.NotNil(t, err)
 }
 
@@ -93,9 +107,9 @@ func TestVideoStoreService_SetVideoThumbnailFromStorage_Ok(t *testing.T) {
 	// Setup the proxy to succeed
 	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("a")}, nil)
 	// Setup the video host to succeed
-	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), gomock.Any()).Return(nil)
+	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
-	err := deps.service.SetVideoThumbnailFromStorage("test", "test")
+	err := deps.service.SetVideoThumbnailFromStorage(context.Background(), "test", "test")
 	assert.Nil(t, err)
 }
 
@@ -104,7 +118,7 @@ func TestVideoStoreService_UploadFromObjectStore_DownloadError(t *testing.T) {
 	// Setup the proxy to fail to simulate a download error
 	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
 
-	_, err := deps.service.UploadVideoFromStorage("jobId", "test", &video_hosting.ItemMetadata{
+	_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{
 		Description: "desc",
 		Title:       "title",
 		Visibility:  "unlisted",
@@ -120,7 +134,7 @@ func TestVideoStoreService_UploadFromObjectStore_DownloadErrorRetry(t *testing.T
 	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
 	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("a")}, nil)
 	// Setup the video store to "upload" a video
-	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{
 		Id:           "test",
 		Title:        "test",
 		Description:  "test",
@@ -130,7 +144,7 @@ func TestVideoStoreService_UploadFromObjectStore_DownloadErrorRetry(t *testing.T
 		ThumbnailUrl: "",
 	}, nil)
 
-	_, err := deps.service.UploadVideoFromStorage("jobId", "test", &video_hosting.ItemMetadata{
+	_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{
 		Description: "desc",
 		Title:       "title",
 		Visibility:  "unlisted",
@@ -138,12 +152,35 @@ func TestVideoStoreService_UploadFromObjectStore_DownloadErrorRetry(t *testing.T
 	assert.Nil(t, err)
 }
 
+func TestVideoStoreService_UploadFromObjectStore_Metrics(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.opt.objStoreMaxRetry = 1
+	failed := testutil.ToFloat64(metrics.StorageFetchAttempts.WithLabelValues(metrics.Error))
+	sent := testutil.ToFloat64(metrics.UploadBytes)
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
+	// Base64 of "abc"
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("YWJj")}, nil)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, meta *video_hosting.ItemMetadata, content io.Reader, onProgress *video_hosting.ProgressFunc) (*video_hosting.Video, error) {
+			// The job is running while uploading
+			assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ActiveJobs))
+			_, err := io.ReadAll(content)
+			return &video_hosting.Video{Id: "test"}, err
+		})
+
+	_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{Title: "title"})
+	assert.Nil(t, err)
+	assert.Equal(t, failed+1, testutil.ToFloat64(metrics.StorageFetchAttempts.WithLabelValues(metrics.Error)))
+	assert.Equal(t, sent+3, testutil.ToFloat64(metrics.UploadBytes))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.ActiveJobs))
+}
+
 func TestVideoStoreService_UploadFromObjectStore_CreateVideoError(t *testing.T) {
 	deps := Setup(t, false)
 	// Setup the proxy to fail to simulate a download error
 	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("a")}, nil)
-	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
-	_, err := deps.service.UploadVideoFromStorage("jobId", "test", &video_hosting.ItemMetadata{
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
+	_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{
 		Description: "desc",
 		Title:       "title",
 		Visibility:  "unlisted",
@@ -155,7 +192,7 @@ func TestVideoStoreService_UploadFromObjectStore_CreateVideoError(t *testing.T)
 func TestVideoStoreService_UploadFromObjectStore_Ok(t *testing.T) {
 	deps := Setup(t, false)
 	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("a")}, nil)
-	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{
 		Id:           "test",
 		Title:        "test",
 		Description:  "test",
@@ -164,7 +201,7 @@ func TestVideoStoreService_UploadFromObjectStore_Ok(t *testing.T) {
 		Visibility:   "unlisted",
 		ThumbnailUrl: "",
 	}, nil)
-	_, err := deps.service.UploadVideoFromStorage("jobId", "test", &video_hosting.ItemMetadata{
+	_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{
 		Description: "desc",
 		Title:       "title",
 		Visibility:  "unlisted",
@@ -172,10 +209,138 @@ func TestVideoStoreService_UploadFromObjectStore_Ok(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestVideoStoreService_UploadVideoFromStorageOnce_Recorded(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Host = "youtube"
+	deps.service.Catalog = &fakeCatalog{entries: map[string]*catalog.Entry{
+		"youtube/video/vid": {Kind: catalog.Video, Id: "vid", Host: "youtube", JobId: "jobId", StorageKey: "test"},
+	}}
+	// The job already uploaded the video, it isn't uploaded again
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(&video_hosting.Video{Id: "vid"}, nil)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	vid, err := deps.service.UploadVideoFromStorageOnce(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{Title: "title", Visibility: "unlisted"})
+	assert.Nil(t, err)
+	assert.Equal(t, "vid", vid.Id)
+}
+
+func TestVideoStoreService_UploadVideoFromStorageOnce_OtherJob(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Host = "youtube"
+	deps.service.Catalog = &fakeCatalog{entries: map[string]*catalog.Entry{
+		"youtube/video/vid": {Kind: catalog.Video, Id: "vid", Host: "youtube", JobId: "other", StorageKey: "test"},
+	}}
+	// Another job uploading the same recording is uploaded anyway
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("a")}, nil)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{Id: "new"}, nil)
+	vid, err := deps.service.UploadVideoFromStorageOnce(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{Title: "title", Visibility: "unlisted"})
+	assert.Nil(t, err)
+	assert.Equal(t, "new", vid.Id)
+}
+
+func TestVideoStoreService_UploadVideoFromStorageOnce_Running(t *testing.T) {
+	deps := Setup(t, false)
+	_, done, err := deps.service.jobs.start(context.Background(), "jobId")
+	assert.Nil(t, err)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	_, err = deps.service.UploadVideoFromStorageOnce(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{Title: "title", Visibility: "unlisted"})
+	assert.ErrorIs(t, err, ErrJobRunning)
+	done()
+
+	// Free to run once the job is over
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
+	_, err = deps.service.UploadVideoFromStorageOnce(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{Title: "title", Visibility: "unlisted"})
+	assert.Equal(t, video_hosting.StorageUnavailable, video_hosting.KindOf(err))
+}
+
 func TestVideoStoreService_UploadFromObjectStore_InvalidMetadata(t *testing.T) {
 	deps := Setup(t, false)
-	_, err := deps.service.UploadVideoFromStorage("jobId", "test", nil)
+	_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", nil)
+	assert.NotNil(t, err)
+}
+
+func TestVideoStoreService_UploadVideo_Ok(t *testing.T) {
+	deps := Setup(t, false)
+	records := &fakeCatalog{entries: map[string]*catalog.Entry{}}
+	deps.service.Host = "youtube"
+	deps.service.Catalog = records
+	meta := &video_hosting.ItemMetadata{Title: "title", Visibility: video_hosting.Unlisted}
+	// The content is sent as is, the object storage is never called
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), meta, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ *video_hosting.ItemMetadata, content io.Reader, _ *video_hosting.ProgressFunc) (*video_hosting.Video, error) {
+			data, err := io.ReadAll(content)
+			assert.Nil(t, err)
+			assert.Equal(t, "video content", string(data))
+			return &video_hosting.Video{Id: "vid"}, nil
+		})
+	vid, err := deps.service.UploadVideo(context.Background(), "jobId", strings.NewReader("video content"), meta)
+	assert.Nil(t, err)
+	assert.Equal(t, "vid", vid.Id)
+	entry := records.entries["youtube/video/vid"]
+	assert.Equal(t, "jobId", entry.JobId)
+	assert.Empty(t, entry.StorageKey)
+}
+
+func TestVideoStoreService_UploadVideo_CreateVideoError(t *testing.T) {
+	deps := Setup(t, false)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("test"))
+	_, err := deps.service.UploadVideo(context.Background(), "jobId", strings.NewReader("a"), &video_hosting.ItemMetadata{Title: "title"})
+	assert.NotNil(t, err)
+}
+
+func TestVideoStoreService_UploadVideo_InvalidMetadata(t *testing.T) {
+	deps := Setup(t, false)
+	_, err := deps.service.UploadVideo(context.Background(), "jobId", strings.NewReader("a"), nil)
 	assert.NotNil(t, err)
+	// Refused before reading anything
+	deps.service.HostMetadata = video_hosting.YoutubeVideoStore{}
+	_, err = deps.service.UploadVideo(context.Background(), "jobId", strings.NewReader("a"), &video_hosting.ItemMetadata{Title: "<title>"})
+	assert.Equal(t, []video_hosting.FieldError{{Field: "title", Reason: "must not contain < or >"}}, video_hosting.FieldErrors(err))
+}
+
+func TestVideoStoreService_UploadVideo_WithProgress(t *testing.T) {
+	deps := Setup(t, true)
+	deps.brokerProxy.
+		EXPECT().
+		PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{Id: "vid"}, nil)
+	_, err := deps.service.UploadVideo(context.Background(), "jobId", strings.NewReader("a"), &video_hosting.ItemMetadata{Title: "title"})
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_UploadVideo_Draining(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Drain(context.Background())
+	_, err := deps.service.UploadVideo(context.Background(), "jobId", strings.NewReader("a"), &video_hosting.ItemMetadata{Title: "title"})
+	assert.Equal(t, video_hosting.ShuttingDown, video_hosting.KindOf(err))
+}
+
+func TestVideoStoreService_HostMetadata(t *testing.T) {
+	deps := Setup(t, false)
+	validator := mock_video_hosting.NewMockMetadataValidator(gomock.NewController(t))
+	deps.service.HostMetadata = validator
+	refused := video_hosting.NewValidationError([]video_hosting.FieldError{{Field: "title", Reason: "must not contain < or >"}})
+	validator.EXPECT().ValidateVideo(gomock.Any()).Return(refused).Times(2)
+	validator.EXPECT().ValidatePlaylist(gomock.Any()).Return(refused).Times(2)
+	// Nothing reaches the host nor the object storage
+	meta := &video_hosting.ItemMetadata{Title: "<b>", Visibility: "unlisted"}
+	_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", meta)
+	assert.Equal(t, refused, err)
+	_, err = deps.service.UpdateVideo(context.Background(), "vid", &video_hosting.Video{Title: "<b>"})
+	assert.Equal(t, refused, err)
+	_, err = deps.service.CreatePlaylist(context.Background(), meta)
+	assert.Equal(t, refused, err)
+	_, err = deps.service.UpdatePlaylist(context.Background(), "pid", &video_hosting.Playlist{Title: "<b>"})
+	assert.Equal(t, refused, err)
+}
+
+func TestVideoStoreService_HostMetadata_Accepted(t *testing.T) {
+	deps := Setup(t, false)
+	validator := mock_video_hosting.NewMockMetadataValidator(gomock.NewController(t))
+	deps.service.HostMetadata = validator
+	validator.EXPECT().ValidateVideo(&video_hosting.ItemMetadata{Title: "title", Description: "description"}).Return(nil)
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), "vid", gomock.Any()).Return(&video_hosting.Video{Id: "vid"}, nil)
+	_, err := deps.service.UpdateVideo(context.Background(), "vid", &video_hosting.Video{Id: "vid", Title: "title", Description: "description"})
+	assert.Nil(t, err)
 }
 
 func TestVideoStoreService_UploadFromObjectStore_WithProgress(t *testing.T) {
@@ -184,7 +349,7 @@ func TestVideoStoreService_UploadFromObjectStore_WithProgress(t *testing.T) {
 		EXPECT().
 		PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("a")}, nil)
-	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{
 		Id:           "test",
 		Title:        "test",
 		Description:  "test",
@@ -193,7 +358,7 @@ func TestVideoStoreService_UploadFromObjectStore_WithProgress(t *testing.T) {
 		Visibility:   "unlisted",
 		ThumbnailUrl: "",
 	}, nil)
-	_, err := deps.service.UploadVideoFromStorage("jobId", "test", &video_hosting.ItemMetadata{
+	_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{
 		Description: "desc",
 		Title:       "title",
 		Visibility:  "unlisted",
@@ -245,7 +410,7 @@ func TestVideoStoreService_ProgressRoutine_Ok(t *testing.T) {
 	// Make the channels and start the routine
 	pgChannel := make(chan uploadProgress)
 	resCh := make(chan uploadResult)
-	go deps.service.startProgressRoutine("test", time.Second, pgChannel, resCh)
+	go deps.service.startProgressRoutine(context.Background(), "test", time.Second, pgChannel, resCh)
 
 	// Send the two progress events first
 	pgChannel <- progressEvent
@@ -325,7 +490,7 @@ func TestVideoStoreService_ProgressRoutine_Error(t *testing.T) {
 	// Make the channels and start the routine
 	pgChannel := make(chan uploadProgress)
 	resCh := make(chan uploadResult)
-	go deps.service.startProgressRoutine("test", time.Second, pgChannel, resCh)
+	go deps.service.startProgressRoutine(context.Background(), "test", time.Second, pgChannel, resCh)
 
 	// Send the two progress events first
 	pgChannel <- progressEvent
@@ -350,3 +515,419 @@ func TestVideoStoreService_ProgressRoutine_Error(t *testing.T) {
 		}
 	}
 }
+
+// Expect a single lifecycle event of type "evtType" to be published
+func expectEvent(t *testing.T, deps *mocked, evtType event_broker.EventType, subject string) {
+	deps.brokerProxy.
+		EXPECT().
+		PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, data interface{}, _ ...client.PublishEventOption) error {
+			var evt event_broker.Event
+			if err := json.Unmarshal(data.([]byte), &evt); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, evtType, evt.Type)
+			assert.Equal(t, subject, evt.Subject)
+			return nil
+		})
+}
+
+func TestVideoStoreService_AuthExpired_Event(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	expectEvent(t, deps, event_broker.AuthExpired, "youtube")
+	deps.service.authExpired("youtube", fmt.Errorf("refresh token revoked"))
+}
+
+// Catalog keeping the entries in memory
+type fakeCatalog struct {
+	entries map[string]*catalog.Entry
+	err     error
+}
+
+func (fc *fakeCatalog) Get(_ context.Context, host string, kind catalog.Kind, id string) (*catalog.Entry, error) {
+	return fc.entries[host+"/"+string(kind)+"/"+id], fc.err
+}
+
+func (fc *fakeCatalog) Update(_ context.Context, host string, kind catalog.Kind, id string, fn func(entry *catalog.Entry)) (*catalog.Entry, error) {
+	if fc.err != nil {
+		return nil, fc.err
+	}
+	key := host + "/" + string(kind) + "/" + id
+	if fc.entries[key] == nil {
+		fc.entries[key] = &catalog.Entry{Kind: kind, Id: id, Host: host}
+	}
+	fn(fc.entries[key])
+	return fc.entries[key], nil
+}
+
+func (fc *fakeCatalog) Remove(_ context.Context, host string, kind catalog.Kind, id string) error {
+	delete(fc.entries, host+"/"+string(kind)+"/"+id)
+	return fc.err
+}
+
+// List The entries of host of the provided kind, ordered by ID
+func (fc *fakeCatalog) List(_ context.Context, host string, kind catalog.Kind) ([]catalog.Entry, error) {
+	if fc.err != nil {
+		return nil, fc.err
+	}
+	entries := make([]catalog.Entry, 0)
+	for _, entry := range fc.entries {
+		if entry.Host == host && entry.Kind == kind {
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Id < entries[j].Id })
+	return entries, nil
+}
+
+func (fc *fakeCatalog) FindBySource(_ context.Context, host string, storageKey string) (*catalog.Entry, error) {
+	if fc.err != nil {
+		return nil, fc.err
+	}
+	for _, entry := range fc.entries {
+		if entry.Host == host && entry.Kind == catalog.Video && entry.StorageKey == storageKey {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestVideoStoreService_Catalog(t *testing.T) {
+	deps := Setup(t, false)
+	records := &fakeCatalog{entries: map[string]*catalog.Entry{}}
+	deps.service.Host = "youtube"
+	deps.service.Catalog = records
+	ctx := context.Background()
+	meta := &video_hosting.ItemMetadata{Title: "title", Visibility: video_hosting.Unlisted}
+
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("a")}, nil)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{Id: "vid"}, nil)
+	_, err := deps.service.UploadVideoFromStorage(ctx, "jobId", "recording.mp4", meta)
+	assert.Nil(t, err)
+	entry := records.entries["youtube/video/vid"]
+	assert.Equal(t, "jobId", entry.JobId)
+	assert.Equal(t, "recording.mp4", entry.StorageKey)
+	assert.Equal(t, *meta, entry.Metadata)
+
+	deps.videoStore.EXPECT().CreatePlaylist(gomock.Any(), meta).Return(&video_hosting.Playlist{Id: "pl"}, nil)
+	_, err = deps.service.CreatePlaylist(ctx, meta)
+	assert.Nil(t, err)
+	// A video is only listed once in a playlist
+	deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), "vid", "pl").Return(nil).Times(2)
+	assert.Nil(t, deps.service.AddVideoToPlaylist(ctx, "vid", "pl"))
+	assert.Nil(t, deps.service.AddVideoToPlaylist(ctx, "vid", "pl"))
+	assert.Equal(t, []string{"vid"}, records.entries["youtube/playlist/pl"].Items)
+
+	deps.videoStore.EXPECT().DeleteVideo(gomock.Any(), "vid").Return(nil)
+	assert.Nil(t, deps.service.DeleteVideo(ctx, "vid"))
+	assert.Nil(t, records.entries["youtube/video/vid"])
+}
+
+func TestVideoStoreService_Catalog_Error(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Catalog = &fakeCatalog{err: fmt.Errorf("state store unavailable")}
+	// The video is updated on the host, an unavailable catalog doesn't change that
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), "vid", gomock.Any()).Return(&video_hosting.Video{Id: "vid"}, nil)
+	_, err := deps.service.UpdateVideo(context.Background(), "vid", &video_hosting.Video{Id: "vid"})
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_UploadFromObjectStore_Event(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	expectEvent(t, deps, event_broker.VideoCreated, "test")
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("a")}, nil)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&video_hosting.Video{Id: "test"}, nil)
+	_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{
+		Description: "desc",
+		Title:       "title",
+		Visibility:  "unlisted",
+	})
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_SetVideoThumbnail_Event(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	expectEvent(t, deps, event_broker.ThumbnailSet, "vid")
+	deps.videoStore.EXPECT().UpdateVideoThumbnail(gomock.Any(), "vid", gomock.Any()).Return(nil)
+	err := deps.service.SetVideoThumbnail(context.Background(), "vid", bytes.NewBufferString("a"))
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_SetVideoTags_Event(t *testing.T) {
+	deps := Setup(t, false)
+	records := &fakeCatalog{entries: map[string]*catalog.Entry{}}
+	deps.service.Host = "youtube"
+	deps.service.Catalog = records
+	deps.service.Events = deps.events
+	expectEvent(t, deps, event_broker.TagsSet, "vid")
+	deps.videoStore.EXPECT().SetVideoTags(gomock.Any(), "vid", []string{"rpg"}).Return(nil)
+	assert.Nil(t, deps.service.SetVideoTags(context.Background(), "vid", []string{"rpg"}))
+	assert.Equal(t, []string{"rpg"}, records.entries["youtube/video/vid"].Tags)
+
+	// Refused before reaching the host
+	deps.service.HostMetadata = video_hosting.YoutubeVideoStore{}
+	err := deps.service.SetVideoTags(context.Background(), "vid", []string{"<rpg>"})
+	assert.Equal(t, video_hosting.InvalidMetadata, video_hosting.KindOf(err))
+}
+
+func TestVideoStoreService_AddVideoCaption_Event(t *testing.T) {
+	deps := Setup(t, false)
+	records := &fakeCatalog{entries: map[string]*catalog.Entry{}}
+	deps.service.Host = "youtube"
+	deps.service.Catalog = records
+	deps.service.Events = deps.events
+	caption := &video_hosting.Caption{Language: "en", Name: "English"}
+	expectEvent(t, deps, event_broker.CaptionAdded, "vid")
+	expectEvent(t, deps, event_broker.CaptionAdded, "vid")
+	deps.videoStore.EXPECT().AddVideoCaption(gomock.Any(), "vid", caption, gomock.Any()).Return(nil).Times(2)
+	// A language is only listed once
+	assert.Nil(t, deps.service.AddVideoCaption(context.Background(), "vid", caption, bytes.NewBufferString("a")))
+	assert.Nil(t, deps.service.AddVideoCaption(context.Background(), "vid", caption, bytes.NewBufferString("a")))
+	assert.Equal(t, []string{"en"}, records.entries["youtube/video/vid"].Captions)
+}
+
+func TestVideoStoreService_ScheduleVideo_Event(t *testing.T) {
+	deps := Setup(t, false)
+	records := &fakeCatalog{entries: map[string]*catalog.Entry{
+		"youtube/video/vid": {Kind: catalog.Video, Id: "vid", Host: "youtube", Metadata: video_hosting.ItemMetadata{Title: "title", Visibility: video_hosting.Public}},
+	}}
+	deps.service.Host = "youtube"
+	deps.service.Catalog = records
+	deps.service.Events = deps.events
+	publishAt := time.Date(2100, 1, 1, 10, 0, 0, 0, time.UTC)
+	expectEvent(t, deps, event_broker.VideoScheduled, "vid")
+	deps.videoStore.EXPECT().ScheduleVideo(gomock.Any(), "vid", publishAt).Return(nil)
+	assert.Nil(t, deps.service.ScheduleVideo(context.Background(), "vid", publishAt))
+	entry := records.entries["youtube/video/vid"]
+	assert.Equal(t, publishAt, *entry.PublishAt)
+	assert.Equal(t, video_hosting.Private, entry.Metadata.Visibility)
+}
+
+func TestVideoStoreService_ScheduleVideo_Error_NoEvent(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	deps.videoStore.EXPECT().ScheduleVideo(gomock.Any(), "vid", gomock.Any()).Return(fmt.Errorf("test"))
+	assert.NotNil(t, deps.service.ScheduleVideo(context.Background(), "vid", time.Now()))
+}
+
+func TestVideoStoreService_UpdateVideo_Event(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	expectEvent(t, deps, event_broker.VideoUpdated, "vid")
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), "vid", gomock.Any()).Return(&video_hosting.Video{Id: "vid"}, nil)
+	_, err := deps.service.UpdateVideo(context.Background(), "vid", &video_hosting.Video{Id: "vid"})
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_PatchVideo_Empty(t *testing.T) {
+	deps := Setup(t, false)
+	// An empty patch doesn't update anything
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(&video_hosting.Video{Id: "vid"}, nil)
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	vid, err := deps.service.PatchVideo(context.Background(), "vid", []byte(`{}`), "")
+	assert.Nil(t, err)
+	assert.Equal(t, "vid", vid.Id)
+}
+
+func TestVideoStoreService_PatchVideo_Invalid(t *testing.T) {
+	deps := Setup(t, false)
+	vid := &video_hosting.Video{Id: "vid", Title: "title", Visibility: video_hosting.Public}
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(vid, nil).Times(2)
+	// The patched video is refused as a request body would be
+	deps.videoStore.EXPECT().UpdateVideo(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	_, err := deps.service.PatchVideo(context.Background(), "vid", []byte(`{"title": null}`), "")
+	assert.Equal(t, video_hosting.InvalidMetadata, video_hosting.KindOf(err))
+	assert.Equal(t, []video_hosting.FieldError{{Field: "title", Reason: "is required"}}, video_hosting.FieldErrors(err))
+
+	_, err = deps.service.PatchVideo(context.Background(), "vid", []byte(`{"visibility": "bogus"}`), "")
+	assert.Equal(t, video_hosting.InvalidMetadata, video_hosting.KindOf(err))
+	assert.Equal(t, []video_hosting.FieldError{{Field: "visibility", Reason: `must be "public", "private" or "unlisted"`}}, video_hosting.FieldErrors(err))
+}
+
+func TestVideoStoreService_PatchPlaylist_Invalid(t *testing.T) {
+	deps := Setup(t, false)
+	deps.videoStore.EXPECT().RetrievePlaylist(gomock.Any(), "pid").Return(&video_hosting.Playlist{Id: "pid", Title: "title", Visibility: video_hosting.Public}, nil)
+	deps.videoStore.EXPECT().UpdatePlaylist(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	_, err := deps.service.PatchPlaylist(context.Background(), "pid", []byte(`{"title": null, "visibility": null}`), "")
+	assert.Equal(t, video_hosting.InvalidMetadata, video_hosting.KindOf(err))
+	assert.Equal(t, []video_hosting.FieldError{{Field: "title", Reason: "is required"}, {Field: "visibility", Reason: "is required"}}, video_hosting.FieldErrors(err))
+}
+
+func TestVideoStoreService_IfMatch(t *testing.T) {
+	deps := Setup(t, false)
+	// Nothing to compare, the video isn't retrieved
+	assert.Nil(t, deps.service.IfMatchVideo(context.Background(), "vid", ""))
+
+	deps.videoStore.EXPECT().RetrieveVideo(gomock.Any(), "vid").Return(&video_hosting.Video{Id: "vid", ETag: "v2"}, nil).Times(3)
+	assert.Nil(t, deps.service.IfMatchVideo(context.Background(), "vid", `"v2"`))
+	assert.Nil(t, deps.service.IfMatchVideo(context.Background(), "vid", `*`))
+	err := deps.service.IfMatchVideo(context.Background(), "vid", `"v1"`)
+	assert.Equal(t, video_hosting.PreconditionFailed, video_hosting.KindOf(err))
+
+	deps.videoStore.EXPECT().RetrievePlaylist(gomock.Any(), "pid").Return(nil, video_hosting.NewRequestError(video_hosting.NotFound, fmt.Errorf("no playlist")))
+	err = deps.service.IfMatchPlaylist(context.Background(), "pid", `"v1"`)
+	assert.Equal(t, video_hosting.NotFound, video_hosting.KindOf(err))
+}
+
+func TestVideoStoreService_PatchPlaylist_Event(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	expectEvent(t, deps, event_broker.PlaylistUpdated, "pid")
+	deps.videoStore.EXPECT().RetrievePlaylist(gomock.Any(), "pid").Return(&video_hosting.Playlist{Id: "pid", Title: "title", Visibility: video_hosting.Public}, nil)
+	deps.videoStore.EXPECT().UpdatePlaylist(gomock.Any(), "pid", &video_hosting.Playlist{Id: "pid", Title: "new title", Visibility: video_hosting.Public}).
+		Return(&video_hosting.Playlist{Id: "pid", Title: "new title", Visibility: video_hosting.Public}, nil)
+	playlist, err := deps.service.PatchPlaylist(context.Background(), "pid", []byte(`{"title": "new title"}`), "")
+	assert.Nil(t, err)
+	assert.Equal(t, "new title", playlist.Title)
+}
+
+func TestVideoStoreService_DeleteVideo_Event(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	expectEvent(t, deps, event_broker.VideoDeleted, "vid")
+	deps.videoStore.EXPECT().DeleteVideo(gomock.Any(), "vid").Return(nil)
+	err := deps.service.DeleteVideo(context.Background(), "vid")
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_DeleteVideo_Error_NoEvent(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	// A failed operation must not be advertised
+	deps.brokerProxy.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	deps.videoStore.EXPECT().DeleteVideo(gomock.Any(), "vid").Return(fmt.Errorf("test"))
+	err := deps.service.DeleteVideo(context.Background(), "vid")
+	assert.NotNil(t, err)
+}
+
+func TestVideoStoreService_CreatePlaylist_Event(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	expectEvent(t, deps, event_broker.PlaylistCreated, "pid")
+	deps.videoStore.EXPECT().CreatePlaylist(gomock.Any(), gomock.Any()).Return(&video_hosting.Playlist{Id: "pid"}, nil)
+	_, err := deps.service.CreatePlaylist(context.Background(), &video_hosting.ItemMetadata{Title: "title", Visibility: "unlisted"})
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_UpdatePlaylist_Event(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	expectEvent(t, deps, event_broker.PlaylistUpdated, "pid")
+	deps.videoStore.EXPECT().UpdatePlaylist(gomock.Any(), "pid", gomock.Any()).Return(&video_hosting.Playlist{Id: "pid"}, nil)
+	_, err := deps.service.UpdatePlaylist(context.Background(), "pid", &video_hosting.Playlist{Id: "pid"})
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_DeletePlaylist_Event(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	expectEvent(t, deps, event_broker.PlaylistDeleted, "pid")
+	deps.videoStore.EXPECT().DeletePlaylist(gomock.Any(), "pid").Return(nil)
+	err := deps.service.DeletePlaylist(context.Background(), "pid")
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_AddVideoToPlaylist_Event(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	expectEvent(t, deps, event_broker.PlaylistItemAdded, "pid")
+	deps.videoStore.EXPECT().AddVideoToPlaylist(gomock.Any(), "vid", "pid").Return(nil)
+	err := deps.service.AddVideoToPlaylist(context.Background(), "vid", "pid")
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_Event_PublishError(t *testing.T) {
+	deps := Setup(t, false)
+	deps.service.Events = deps.events
+	// The change is already made on the host, a broker failure must not fail the operation
+	deps.brokerProxy.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("test"))
+	deps.videoStore.EXPECT().DeleteVideo(gomock.Any(), "vid").Return(nil)
+	err := deps.service.DeleteVideo(context.Background(), "vid")
+	assert.Nil(t, err)
+}
+
+func TestVideoStoreService_Drain_RefuseNewJobs(t *testing.T) {
+	deps := Setup(t, false)
+	// Nothing is running, draining is immediate
+	assert.Empty(t, deps.service.Drain(context.Background()))
+	_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{Title: "title"})
+	assert.NotNil(t, err)
+	assert.Equal(t, video_hosting.ShuttingDown, video_hosting.KindOf(err))
+}
+
+func TestVideoStoreService_Drain_WaitRunningJobs(t *testing.T) {
+	deps := Setup(t, false)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("YWJj")}, nil)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ *video_hosting.ItemMetadata, _ io.Reader, _ *video_hosting.ProgressFunc) (*video_hosting.Video, error) {
+			close(started)
+			<-release
+			return &video_hosting.Video{Id: "test"}, nil
+		})
+
+	uploaded := make(chan error, 1)
+	go func() {
+		_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{Title: "title"})
+		uploaded <- err
+	}()
+	<-started
+	drained := make(chan []string, 1)
+	go func() { drained <- deps.service.Drain(context.Background()) }()
+	// The drain must wait for the upload
+	select {
+	case <-drained:
+		t.Fatal("drain returned while an upload is running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	assert.Nil(t, <-uploaded)
+	assert.Empty(t, <-drained)
+}
+
+func TestVideoStoreService_Drain_InterruptRemainingJobs(t *testing.T) {
+	deps := Setup(t, true)
+	started := make(chan struct{})
+	deps.objectStoreProxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("YWJj")}, nil)
+	deps.videoStore.EXPECT().CreateVideo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ *video_hosting.ItemMetadata, _ io.Reader, _ *video_hosting.ProgressFunc) (*video_hosting.Video, error) {
+			close(started)
+			// Only the shutdown can stop this upload
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	// The job must be marked as interrupted, with everything needed to send it again
+	deps.brokerProxy.EXPECT().PublishEvent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, data interface{}, _ ...client.PublishEventOption) error {
+			var infos struct {
+				JobId string                      `json:"jobId"`
+				State progress_broker.UploadState `json:"state"`
+				Data  map[string]interface{}      `json:"data"`
+			}
+			if err := json.Unmarshal([]byte(data.(string)), &infos); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "jobId", infos.JobId)
+			assert.Equal(t, progress_broker.Interrupted, infos.State)
+			assert.Equal(t, "test", infos.Data["storageKey"])
+			assert.Equal(t, "title", infos.Data["title"])
+			return nil
+		})
+
+	uploaded := make(chan error, 1)
+	go func() {
+		_, err := deps.service.UploadVideoFromStorage(context.Background(), "jobId", "test", &video_hosting.ItemMetadata{Title: "title"})
+		uploaded <- err
+	}()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, []string{"jobId"}, deps.service.Drain(ctx))
+	err := <-uploaded
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.Equal(t, video_hosting.ShuttingDown, video_hosting.KindOf(err))
+}
@@ -26,6 +26,7 @@ import (
 	"path/filepath"
 	"testing"
 	videos_controller "video-manager/controller/videos"
+	"video-manager/internal/config"
 	progress_broker "video-manager/internal/progress-broker"
 	video_hosting "video-manager/internal/video-hosting"
 )
@@ -76,7 +77,7 @@ func Setup(t *testing.T) {
 	s := daprd.NewService(":8081")
 	var eventSub = &common.Subscription{
 		PubsubName: "pubsub",
-		Topic:      DefaultPubSubTopic,
+		Topic:      config.Default().PubSub.ProgressTopic,
 		Route:      "/",
 	}
 	if err := s.AddTopicEventHandler(eventSub, onBrokerEvent); err != nil {